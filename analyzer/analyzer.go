@@ -0,0 +1,432 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+var (
+	InvalidFunctionIndex error = errors.New("function index is out of range")
+	InvalidTypeIndex     error = errors.New("type index is out of range")
+)
+
+// Report is the static analysis of a module for auditing it before running.
+type Report struct {
+	Functions     []Function    `json:"functions"`
+	Unreachable   []uint32      `json:"unreachable"`     // defined functions which are never called from the exports or the start function
+	Cycles        [][]uint32    `json:"cycles"`          // functions calling each other recursively
+	MaxBlockDepth int           `json:"max_block_depth"` // the deepest nesting of blocks, loops, ifs and trys
+	Opcodes       []OpcodeCount `json:"opcodes"`         // sorted by the count in descending order
+	Imports       []Import      `json:"imports"`
+}
+
+// Function is a node of the call graph.
+type Function struct {
+	Index      uint32   `json:"index"`
+	Name       string   `json:"name,omitempty"` // the export name
+	Imported   bool     `json:"imported"`
+	Calls      []uint32 `json:"calls"` // including the candidates of indirect calls
+	Reachable  bool     `json:"reachable"`
+	BlockDepth int      `json:"block_depth"`
+}
+
+type OpcodeCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Import reports whether the import is used by reachable functions, segments or exports.
+type Import struct {
+	Module string `json:"module"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Used   bool   `json:"used"`
+}
+
+// function is a function in the function index space.
+type function struct {
+	typ  uint32
+	code *structure.Function // nil when imported
+}
+
+// usage is the entities referred by reachable functions.
+type usage struct {
+	tables   map[uint32]bool
+	memories map[uint32]bool
+	globals  map[uint32]bool
+	tags     map[uint32]bool
+}
+
+// Analyze builds the call graph of the module from calls, indirect calls and element segments,
+// and reports unreachable functions, recursion cycles, block depth, opcode usage and import usage.
+// Indirect calls may call any function of the same type in the element segments of the table.
+func Analyze(mod *structure.Module) (*Report, error) {
+	funcs := functionSpace(mod)
+	names := exportNames(mod)
+	report := &Report{
+		Functions:   make([]Function, 0, len(funcs)),
+		Unreachable: []uint32{},
+		Cycles:      [][]uint32{},
+		Opcodes:     []OpcodeCount{},
+		Imports:     []Import{},
+	}
+	opcodes := make(map[string]int)
+	bodies := make([][]instruction.Instruction, len(funcs))
+	for idx, f := range funcs {
+		node := Function{Index: uint32(idx), Name: names[uint32(idx)], Imported: f.code == nil, Calls: []uint32{}}
+		if f.code != nil {
+			body, err := f.code.Instructions()
+			if err != nil {
+				return nil, fmt.Errorf("Analyze: func[%d]: %w", idx, err)
+			}
+			bodies[idx] = body
+			calls, err := callees(mod, funcs, body)
+			if err != nil {
+				return nil, fmt.Errorf("Analyze: func[%d]: %w", idx, err)
+			}
+			node.Calls = calls
+			node.BlockDepth = blockDepth(body)
+			if node.BlockDepth > report.MaxBlockDepth {
+				report.MaxBlockDepth = node.BlockDepth
+			}
+			for _, instr := range body {
+				opcodes[instr.String()]++
+			}
+		}
+		report.Functions = append(report.Functions, node)
+	}
+	reachable(mod, report.Functions)
+	for _, f := range report.Functions {
+		if !f.Reachable && !f.Imported {
+			report.Unreachable = append(report.Unreachable, f.Index)
+		}
+	}
+	report.Cycles = cycles(report.Functions)
+	for name, count := range opcodes {
+		report.Opcodes = append(report.Opcodes, OpcodeCount{Name: name, Count: count})
+	}
+	sort.Slice(report.Opcodes, func(i, j int) bool {
+		if report.Opcodes[i].Count != report.Opcodes[j].Count {
+			return report.Opcodes[i].Count > report.Opcodes[j].Count
+		}
+		return report.Opcodes[i].Name < report.Opcodes[j].Name
+	})
+	u := newUsage(mod)
+	for idx, body := range bodies {
+		if report.Functions[idx].Reachable {
+			u.add(body)
+		}
+	}
+	report.Imports = imports(mod, report.Functions, u)
+	return report, nil
+}
+
+// functionSpace returns the functions in the function index space. Imported functions come first.
+func functionSpace(mod *structure.Module) []function {
+	funcs := make([]function, 0, len(mod.Functions))
+	for _, imp := range mod.Imports {
+		if imp.Desc.Type == structure.DescTypeFunc {
+			funcs = append(funcs, function{typ: imp.Desc.Func})
+		}
+	}
+	for _, f := range mod.Functions {
+		if !f.Imported {
+			funcs = append(funcs, function{typ: f.Type, code: f})
+		}
+	}
+	return funcs
+}
+
+func exportNames(mod *structure.Module) map[uint32]string {
+	names := make(map[uint32]string)
+	for _, e := range mod.Exports {
+		if e.Desc.Type == structure.DescTypeFunc {
+			if _, ok := names[e.Desc.Val]; !ok {
+				names[e.Desc.Val] = e.Name
+			}
+		}
+	}
+	return names
+}
+
+// callees returns the functions called by the body in ascending order.
+func callees(mod *structure.Module, funcs []function, body []instruction.Instruction) ([]uint32, error) {
+	called := make(map[uint32]bool)
+	for _, instr := range body {
+		switch instr.Opcode() {
+		case instruction.CALL, instruction.RETURN_CALL:
+			idx := instruction.Imm[uint32](instr)
+			if int(idx) >= len(funcs) {
+				return nil, fmt.Errorf("%w: %d", InvalidFunctionIndex, idx)
+			}
+			called[idx] = true
+		case instruction.CALL_INDIRECT, instruction.RETURN_CALL_INDIRECT:
+			imm := instruction.Imm[instruction.CallIndirectImm](instr)
+			targets, err := indirectTargets(mod, funcs, imm)
+			if err != nil {
+				return nil, err
+			}
+			for _, idx := range targets {
+				called[idx] = true
+			}
+		}
+	}
+	return sortedKeys(called), nil
+}
+
+// indirectTargets returns the functions of the type in the element segments of the table.
+func indirectTargets(mod *structure.Module, funcs []function, imm instruction.CallIndirectImm) ([]uint32, error) {
+	want, err := funcType(mod, imm.TypeIndex)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]uint32, 0)
+	for _, e := range mod.Elements {
+		if e.TableIndex != imm.TableIndex {
+			continue
+		}
+		for _, idx := range e.Init {
+			if int(idx) >= len(funcs) {
+				return nil, fmt.Errorf("%w: element %d", InvalidFunctionIndex, idx)
+			}
+			ft, err := funcType(mod, funcs[idx].typ)
+			if err != nil {
+				return nil, err
+			}
+			if ft.Params.Equal(want.Params) && ft.Returns.Equal(want.Returns) {
+				targets = append(targets, idx)
+			}
+		}
+	}
+	return targets, nil
+}
+
+func funcType(mod *structure.Module, idx uint32) (*types.FuncType, error) {
+	if int(idx) >= len(mod.Types) {
+		return nil, fmt.Errorf("%w: %d", InvalidTypeIndex, idx)
+	}
+	return mod.Types[idx], nil
+}
+
+// blockDepth returns the deepest nesting of blocks in the body. The function body itself is not counted.
+func blockDepth(body []instruction.Instruction) int {
+	depth, max := 0, 0
+	for _, instr := range body {
+		switch instr.Opcode() {
+		case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+			depth++
+			if depth > max {
+				max = depth
+			}
+		case instruction.END, instruction.DELEGATE:
+			depth--
+		}
+	}
+	return max
+}
+
+// reachable marks functions called from the exports and the start function.
+// Functions in the element segments of an exported or imported table can be called from the host.
+func reachable(mod *structure.Module, funcs []Function) {
+	roots := make([]uint32, 0)
+	for _, e := range mod.Exports {
+		if e.Desc.Type == structure.DescTypeFunc {
+			roots = append(roots, e.Desc.Val)
+		}
+	}
+	if mod.Start != nil {
+		roots = append(roots, mod.Start.Index)
+	}
+	external := externalTables(mod)
+	for _, e := range mod.Elements {
+		if external[e.TableIndex] {
+			roots = append(roots, e.Init...)
+		}
+	}
+	for len(roots) > 0 {
+		idx := roots[len(roots)-1]
+		roots = roots[:len(roots)-1]
+		if int(idx) >= len(funcs) || funcs[idx].Reachable {
+			continue
+		}
+		funcs[idx].Reachable = true
+		roots = append(roots, funcs[idx].Calls...)
+	}
+}
+
+func externalTables(mod *structure.Module) map[uint32]bool {
+	tables := make(map[uint32]bool)
+	n := uint32(0)
+	for _, imp := range mod.Imports {
+		if imp.Desc.Type == structure.DescTypeTable {
+			tables[n] = true
+			n++
+		}
+	}
+	for _, e := range mod.Exports {
+		if e.Desc.Type == structure.DescTypeTable {
+			tables[e.Desc.Val] = true
+		}
+	}
+	return tables
+}
+
+// cycles returns the strongly connected components of the call graph which contain a recursion.
+// https://en.wikipedia.org/wiki/Tarjan%27s_strongly_connected_components_algorithm
+func cycles(funcs []Function) [][]uint32 {
+	index := make([]int, len(funcs))
+	low := make([]int, len(funcs))
+	onStack := make([]bool, len(funcs))
+	stack := make([]uint32, 0)
+	next := 1
+	res := make([][]uint32, 0)
+	var visit func(v uint32)
+	visit = func(v uint32) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range funcs[v].Calls {
+			if index[w] == 0 {
+				visit(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		component := make([]uint32, 0)
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 || calls(funcs[v], v) {
+			sort.Slice(component, func(i, j int) bool { return component[i] < component[j] })
+			res = append(res, component)
+		}
+	}
+	for v := range funcs {
+		if index[v] == 0 {
+			visit(uint32(v))
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i][0] < res[j][0] })
+	return res
+}
+
+func calls(f Function, idx uint32) bool {
+	for _, c := range f.Calls {
+		if c == idx {
+			return true
+		}
+	}
+	return false
+}
+
+func newUsage(mod *structure.Module) *usage {
+	u := &usage{
+		tables:   make(map[uint32]bool),
+		memories: make(map[uint32]bool),
+		globals:  make(map[uint32]bool),
+		tags:     make(map[uint32]bool),
+	}
+	for _, e := range mod.Exports {
+		switch e.Desc.Type {
+		case structure.DescTypeTable:
+			u.tables[e.Desc.Val] = true
+		case structure.DescTypeMemory:
+			u.memories[e.Desc.Val] = true
+		case structure.DescTypeGlobal:
+			u.globals[e.Desc.Val] = true
+		case structure.DescTypeTag:
+			u.tags[e.Desc.Val] = true
+		}
+	}
+	// segments and initializers
+	for _, e := range mod.Elements {
+		u.tables[e.TableIndex] = true
+		u.add([]instruction.Instruction{e.Offset})
+	}
+	for _, d := range mod.Datas {
+		u.memories[d.MemoryIndex] = true
+		u.add([]instruction.Instruction{d.Offset})
+	}
+	for _, g := range mod.Globals {
+		u.add([]instruction.Instruction{g.Init})
+	}
+	return u
+}
+
+// add records the entities referred by the instructions.
+func (u *usage) add(body []instruction.Instruction) {
+	for _, instr := range body {
+		switch op := instr.Opcode(); {
+		case op == instruction.GET_GLOBAL || op == instruction.SET_GLOBAL:
+			u.globals[instruction.Imm[uint32](instr)] = true
+		case op == instruction.CALL_INDIRECT || op == instruction.RETURN_CALL_INDIRECT:
+			u.tables[instruction.Imm[instruction.CallIndirectImm](instr).TableIndex] = true
+		case op == instruction.THROW || op == instruction.CATCH:
+			u.tags[instruction.Imm[uint32](instr)] = true
+		case op >= instruction.I32_LOAD && op <= instruction.GROW_MEMORY:
+			u.memories[0] = true
+		case op == instruction.ATOMIC:
+			if instr.(*instruction.Atomic).Op != instruction.ATOMIC_FENCE {
+				u.memories[0] = true
+			}
+		case op == instruction.SIMD:
+			vop := instr.(*instruction.Vector).Op
+			if vop <= instruction.V128_STORE || (vop >= instruction.V128_LOAD8_LANE && vop <= instruction.V128_LOAD64_ZERO) {
+				u.memories[0] = true
+			}
+		}
+	}
+}
+
+// imports reports the usage of the imports.
+// Imports come first in the index space of each kind.
+func imports(mod *structure.Module, funcs []Function, u *usage) []Import {
+	res := make([]Import, 0, len(mod.Imports))
+	counts := make(map[structure.DescType]uint32)
+	for _, imp := range mod.Imports {
+		idx := counts[imp.Desc.Type]
+		counts[imp.Desc.Type]++
+		used := false
+		switch imp.Desc.Type {
+		case structure.DescTypeFunc:
+			used = int(idx) < len(funcs) && funcs[idx].Reachable
+		case structure.DescTypeTable:
+			used = u.tables[idx]
+		case structure.DescTypeMemory:
+			used = u.memories[idx]
+		case structure.DescTypeGlobal:
+			used = u.globals[idx]
+		case structure.DescTypeTag:
+			used = u.tags[idx]
+		}
+		res = append(res, Import{Module: imp.Module, Name: imp.Name, Kind: imp.Desc.Type.String(), Used: used})
+	}
+	return res
+}
+
+func sortedKeys(m map[uint32]bool) []uint32 {
+	keys := make([]uint32, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
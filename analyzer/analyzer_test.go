@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestAnalyze(t *testing.T) {
+	empty := types.BlockType(types.EMPTY)
+	mod := &structure.Module{
+		Types: []*types.FuncType{
+			{Params: []types.ValueType{types.I32}, Returns: []types.ValueType{}},
+			{Params: []types.ValueType{}, Returns: []types.ValueType{}},
+		},
+		Imports: []*structure.Import{
+			{Module: "env", Name: "log", Desc: &structure.ImportDesc{Type: structure.DescTypeFunc, Func: 0}},
+			{Module: "env", Name: "unused", Desc: &structure.ImportDesc{Type: structure.DescTypeFunc, Func: 0}},
+			{Module: "env", Name: "g", Desc: &structure.ImportDesc{Type: structure.DescTypeGlobal, Global: &types.GlobalType{ContentType: types.I32}}},
+			{Module: "env", Name: "mem", Desc: &structure.ImportDesc{Type: structure.DescTypeMemory, Mem: &types.MemoryType{Limits: &types.Limits{Min: 1}}}},
+		},
+		Functions: []*structure.Function{
+			{Type: 0, Imported: true},
+			{Type: 0, Imported: true},
+			// func[2] calls the import, a recursive function and the table
+			{Type: 1, Body: []instruction.Instruction{
+				&instruction.GetGlobal{Imm: 0}, &instruction.Call{Imm: 0},
+				&instruction.Call{Imm: 3},
+				&instruction.I32Const{Imm: 0}, &instruction.CallIndirect{Imm: instruction.CallIndirectImm{TypeIndex: 1}},
+				&instruction.Block{Imm: empty}, &instruction.Loop{Imm: empty}, &instruction.End{}, &instruction.End{},
+				&instruction.End{},
+			}},
+			// func[3] calls itself
+			{Type: 1, Body: []instruction.Instruction{&instruction.Call{Imm: 3}, &instruction.End{}}},
+			// func[4] and func[5] call each other but nobody calls them
+			{Type: 1, Body: []instruction.Instruction{&instruction.Call{Imm: 5}, &instruction.End{}}},
+			{Type: 1, Body: []instruction.Instruction{
+				&instruction.I32Const{Imm: 0}, &instruction.I32Load{}, &instruction.Drop{},
+				&instruction.Call{Imm: 4}, &instruction.End{},
+			}},
+			// func[6] is called through the table
+			{Type: 1, Body: []instruction.Instruction{&instruction.Nop{}, &instruction.End{}}},
+			// func[7] is in the table, but its type doesn't match the indirect call
+			{Type: 0, Body: []instruction.Instruction{&instruction.End{}}},
+		},
+		Tables:   []*structure.Table{{Type: &types.TableType{ElementType: types.ElemTypeFuncref, Limits: &types.Limits{Min: 2}}}},
+		Elements: []*structure.Element{{Offset: &instruction.I32Const{Imm: 0}, Init: []uint32{6, 7}}},
+		Exports:  []*structure.Export{{Name: "main", Desc: &structure.ExportDesc{Type: structure.DescTypeFunc, Val: 2}}},
+	}
+	r, err := Analyze(mod)
+	require.NoError(t, err)
+
+	calls := make([][]uint32, 0, len(r.Functions))
+	reachable := make([]bool, 0, len(r.Functions))
+	for _, f := range r.Functions {
+		calls = append(calls, f.Calls)
+		reachable = append(reachable, f.Reachable)
+	}
+	assert.Equal(t, [][]uint32{{}, {}, {0, 3, 6}, {3}, {5}, {4}, {}, {}}, calls)
+	assert.Equal(t, []bool{true, false, true, true, false, false, true, false}, reachable)
+	assert.Equal(t, "main", r.Functions[2].Name)
+	assert.Equal(t, []uint32{4, 5, 7}, r.Unreachable)
+	assert.Equal(t, [][]uint32{{3}, {4, 5}}, r.Cycles)
+	assert.Equal(t, 2, r.MaxBlockDepth)
+	assert.Equal(t, OpcodeCount{Name: "end", Count: 8}, r.Opcodes[0])
+	assert.Equal(t, OpcodeCount{Name: "call", Count: 5}, r.Opcodes[1])
+	// the memory is only accessed by an unreachable function
+	assert.Equal(t, []Import{
+		{Module: "env", Name: "log", Kind: "func", Used: true},
+		{Module: "env", Name: "unused", Kind: "func", Used: false},
+		{Module: "env", Name: "g", Kind: "global", Used: true},
+		{Module: "env", Name: "mem", Kind: "memory", Used: false},
+	}, r.Imports)
+}
+
+func TestAnalyze_InvalidIndex(t *testing.T) {
+	mod := &structure.Module{
+		Types:     []*types.FuncType{{}},
+		Functions: []*structure.Function{{Type: 0, Body: []instruction.Instruction{&instruction.Call{Imm: 1}, &instruction.End{}}}},
+	}
+	_, err := Analyze(mod)
+	assert.ErrorIs(t, err, InvalidFunctionIndex)
+}
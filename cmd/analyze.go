@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/terassyi/gowi/analyzer"
+	"github.com/terassyi/gowi/decoder"
+)
+
+var analyzeCommand = &cobra.Command{
+	Use:   "analyze",
+	Short: "analyze the call graph and statistics of WASM binary file",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := args[0]
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := validateOutput(output); err != nil {
+			log.Fatalln(err)
+		}
+		d, err := decoder.New(file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		mod, err := d.Decode()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		report, err := analyzer.Analyze(mod)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if output == outputJSON {
+			if err := printJSON(report); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
+		fmt.Printf("WASM file: %s\n\n", file)
+		printAnalysis(report)
+	},
+}
+
+func printAnalysis(r *analyzer.Report) {
+	fmt.Println("Call graph:")
+	for _, f := range r.Functions {
+		if f.Imported {
+			fmt.Printf("\t%s (imported)\n", funcString(r, f.Index))
+			continue
+		}
+		callees := make([]string, 0, len(f.Calls))
+		for _, c := range f.Calls {
+			callees = append(callees, funcString(r, c))
+		}
+		fmt.Printf("\t%s -> [%s]\n", funcString(r, f.Index), strings.Join(callees, ", "))
+	}
+	fmt.Println("\nUnreachable functions:")
+	for _, idx := range r.Unreachable {
+		fmt.Printf("\t%s\n", funcString(r, idx))
+	}
+	fmt.Println("\nRecursion cycles:")
+	for _, c := range r.Cycles {
+		funcs := make([]string, 0, len(c))
+		for _, idx := range c {
+			funcs = append(funcs, funcString(r, idx))
+		}
+		fmt.Printf("\t%s\n", strings.Join(funcs, ", "))
+	}
+	fmt.Printf("\nMax block depth: %d\n", r.MaxBlockDepth)
+	fmt.Println("\nOpcodes:")
+	for _, o := range r.Opcodes {
+		fmt.Printf("\t%-24s %d\n", o.Name, o.Count)
+	}
+	fmt.Println("\nImports:")
+	for _, i := range r.Imports {
+		used := "unused"
+		if i.Used {
+			used = "used"
+		}
+		fmt.Printf("\t%s.%s (%s) %s\n", i.Module, i.Name, i.Kind, used)
+	}
+}
+
+func funcString(r *analyzer.Report, idx uint32) string {
+	if int(idx) < len(r.Functions) && r.Functions[idx].Name != "" {
+		return fmt.Sprintf("func[%d] <%s>", idx, r.Functions[idx].Name)
+	}
+	return fmt.Sprintf("func[%d]", idx)
+}
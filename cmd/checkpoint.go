@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/terassyi/gowi/runtime"
+)
+
+// newCheckpointOptions saves checkpoints to path every interval instructions.
+// An interrupt suspends the invocation with a checkpoint. stop must be called when the invocation returns.
+func newCheckpointOptions(path string, interval uint64) (opts runtime.CheckpointOptions, stop func()) {
+	interrupted := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			close(interrupted)
+		case <-done:
+		}
+	}()
+	opts = runtime.CheckpointOptions{
+		Interval: interval,
+		Stop:     interrupted,
+		Save: func(c *runtime.Checkpoint) error {
+			return writeCheckpoint(path, c)
+		},
+	}
+	return opts, func() {
+		signal.Stop(sig)
+		close(done)
+	}
+}
+
+func readCheckpoint(path string) (*runtime.Checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return runtime.ReadCheckpoint(f)
+}
+
+// writeCheckpoint replaces the file with the checkpoint,
+// so that the previous checkpoint is kept when the process dies while writing.
+func writeCheckpoint(path string, c *runtime.Checkpoint) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if err := runtime.WriteCheckpoint(f, c); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -19,6 +19,23 @@ var dumpCommand = &cobra.Command{
 		if err != nil {
 			log.Fatalln(err)
 		}
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := validateOutput(output); err != nil {
+			log.Fatalln(err)
+		}
+		if output == outputJSON {
+			report, err := d.Report()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if err := printJSON(report); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
 		fmt.Printf("WASM file: %s\n\n", file)
 		r, err := cmd.Flags().GetBool("raw")
 		if err != nil {
@@ -49,5 +66,16 @@ var dumpCommand = &cobra.Command{
 			}
 			fmt.Println(detail)
 		}
+		dis, err := cmd.Flags().GetBool("disassemble")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if dis {
+			text, err := d.Disassemble()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			fmt.Println(text)
+		}
 	},
 }
@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/terassyi/gowi/decoder"
 	"github.com/terassyi/gowi/runtime"
 	"github.com/terassyi/gowi/runtime/debugger"
 	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/profiler"
 	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/structure"
 	"github.com/terassyi/gowi/types"
 	"github.com/terassyi/gowi/validator"
 )
@@ -20,7 +24,23 @@ var execCommand = &cobra.Command{
 	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		file := args[0]
-		d, err := decoder.New(file)
+		lazy, err := cmd.Flags().GetBool("lazy")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		mode := decoder.ModeEager
+		if lazy {
+			mode = decoder.ModeLazy
+		}
+		f, err := cmd.Flags().GetString("features")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		features, err := types.ParseFeatures(f)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		d, err := decoder.NewWithFeatures(file, mode, features)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -28,7 +48,7 @@ var execCommand = &cobra.Command{
 		if err != nil {
 			log.Fatalln(err)
 		}
-		v, err := validator.New(mod)
+		v, err := validator.NewWithFeatures(mod, features)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -45,14 +65,17 @@ var execCommand = &cobra.Command{
 		}
 		if listExports {
 			fmt.Printf("WASM fule: %s\n\n", file)
-			fmt.Println("List all exported functions")
-			for _, exp := range mod.Exports {
-				ext, err := inst.GetExport(exp.Name)
-				if err != nil {
-					log.Fatalln(err)
+			fmt.Println("List all exports")
+			exports, err := mod.ExportTypes()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			for _, exp := range exports {
+				if exp.Type.Kind == structure.DescTypeFunc {
+					fmt.Printf("\t%s(%s) -> (%s)\n", exp.Name, exp.Type.Func.Params, exp.Type.Func.Returns)
+					continue
 				}
-				f := instance.GetExternVal[*instance.Function](ext)
-				fmt.Printf("\t%s(%s) -> (%s)\n", exp.Name, f.Type.Params, f.Type.Returns)
+				fmt.Printf("\t%s: %s\n", exp.Name, exp.Type)
 			}
 			fmt.Println()
 			return
@@ -61,15 +84,43 @@ var execCommand = &cobra.Command{
 		if err != nil {
 			log.Fatalln(err)
 		}
+		resume, err := cmd.Flags().GetString("resume")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		var resumed *runtime.Checkpoint
+		if resume != "" {
+			if invoke != "" {
+				log.Fatalln("--invoke can't be used with --resume. The function is taken from the checkpoint.")
+			}
+			resumed, err = readCheckpoint(resume)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			invoke = resumed.Function
+		}
 		if invoke != "" {
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if err := validateOutput(output); err != nil {
+				log.Fatalln(err)
+			}
 			args, err := cmd.Flags().GetStringSlice("args")
 			if err != nil {
 				log.Fatalln(err)
 			}
+			if resumed != nil {
+				args = resumed.Args
+			}
 			ext, err := inst.GetExport(invoke)
 			if err != nil {
 				log.Fatalln(err)
 			}
+			if ext.ExternalValueType() != instance.ExternalValueTypeFunc {
+				log.Fatalf("%s is not an exported function.\n", invoke)
+			}
 			f := instance.GetExternVal[*instance.Function](ext)
 			locals, err := parseArgs(f.Type.Params, args)
 			if err != nil {
@@ -79,19 +130,116 @@ var execCommand = &cobra.Command{
 			if err != nil {
 				log.Fatalln(err)
 			}
-			runner, err := runtime.New(mod, nil, debugger.DebugLevel(debugLevel))
+			dbg := newDebugger(debugger.DebugLevel(debugLevel), output)
+			contextDepth, err := cmd.Flags().GetInt("context-depth")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			dbg.SetContextDepth(contextDepth)
+			trace, err := cmd.Flags().GetString("trace")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if trace != "" {
+				traceFile, err := os.Create(trace)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				defer traceFile.Close()
+				dbg.SetTrace(traceFile)
+			}
+			profile, err := cmd.Flags().GetString("profile")
 			if err != nil {
 				log.Fatalln(err)
 			}
-			results, err := runner.Invoke(invoke, locals)
+			var prof *profiler.Profiler
+			if profile != "" {
+				prof = profiler.New()
+				dbg.SetProfiler(prof)
+			}
+			runner, err := runtime.NewWithFeatures(mod, nil, dbg, features)
 			if err != nil {
 				log.Fatalln(err)
 			}
-			fmt.Println(parseInvocationResult(invoke, locals, results))
+			checkpoint, err := cmd.Flags().GetString("checkpoint")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			interval, err := cmd.Flags().GetUint64("checkpoint-interval")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if checkpoint == "" {
+				// keep checkpointing to the resumed file
+				checkpoint = resume
+			}
+			var results []value.Value
+			if checkpoint == "" {
+				results, err = runner.Invoke(invoke, locals)
+			} else {
+				opts, stop := newCheckpointOptions(checkpoint, interval)
+				if resumed != nil {
+					results, err = runtime.Resume(runner, resumed, opts)
+				} else {
+					results, err = runtime.InvokeWithCheckpoint(runner, invoke, locals, opts)
+				}
+				stop()
+				if errors.Is(err, runtime.Suspended) && output == outputText {
+					log.Printf("checkpoint is written to %s. resume with --resume %s\n", checkpoint, checkpoint)
+				}
+			}
+			if output == outputJSON {
+				if err := printJSON(newInvocationOutput(invoke, locals, results, err, dbg.Steps())); err != nil {
+					log.Fatalln(err)
+				}
+			} else if err == nil {
+				fmt.Println(parseInvocationResult(invoke, locals, results))
+			}
+			// the profile of a trapped invocation is written too
+			if prof != nil {
+				if err := writeProfile(prof, profile); err != nil {
+					log.Fatalln(err)
+				}
+			}
+			if err != nil {
+				if output == outputText {
+					log.Println(err)
+				}
+				os.Exit(1)
+			}
 		}
 	},
 }
 
+// newDebugger creates a debugger which writes to stderr instead of stdout when stdout is reserved for the JSON output.
+func newDebugger(level debugger.DebugLevel, output string) *debugger.Debugger {
+	if output != outputJSON {
+		return debugger.New(level)
+	}
+	switch level {
+	case debugger.DebugLevelLogOnlyStdout:
+		return debugger.WithIO(level, nil, os.Stderr)
+	case debugger.DebugLevelInterrupt:
+		return debugger.WithIO(level, os.Stdin, os.Stderr)
+	default:
+		return debugger.New(level)
+	}
+}
+
+func writeProfile(prof *profiler.Profiler, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := prof.WriteProfile(f); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr)
+	prof.Report(os.Stderr)
+	return nil
+}
+
 func parseArgs(params types.ResultType, args []string) ([]value.Value, error) {
 	values := make([]value.Value, 0, len(params))
 	if len(params) != len(args) {
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/terassyi/gowi/runtime"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+const (
+	outputText string = "text"
+	outputJSON string = "json"
+)
+
+type invocationOutput struct {
+	Function     string        `json:"function"`
+	Args         []valueOutput `json:"args"`
+	Results      []valueOutput `json:"results"`
+	Trap         *trapOutput   `json:"trap,omitempty"`
+	Instructions uint64        `json:"instructions"`
+}
+
+type valueOutput struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type trapOutput struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func validateOutput(output string) error {
+	switch output {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s (text or json)", output)
+	}
+}
+
+func newInvocationOutput(f string, args, results []value.Value, err error, steps uint64) *invocationOutput {
+	out := &invocationOutput{
+		Function:     f,
+		Args:         valueOutputs(args),
+		Results:      valueOutputs(results),
+		Instructions: steps,
+	}
+	if err != nil {
+		out.Trap = &trapOutput{Kind: runtime.TrapKind(err), Message: err.Error()}
+	}
+	return out
+}
+
+func valueOutputs(values []value.Value) []valueOutput {
+	outputs := make([]valueOutput, 0, len(values))
+	for _, v := range values {
+		outputs = append(outputs, valueOutput{Type: valueTypeName(v), Value: fmt.Sprintf("%v", v)})
+	}
+	return outputs
+}
+
+func valueTypeName(v value.Value) string {
+	switch v.(type) {
+	case value.I32:
+		return "i32"
+	case value.I64:
+		return "i64"
+	case value.F32:
+		return "f32"
+	case value.F64:
+		return "f64"
+	case value.Vector:
+		return "v128"
+	default:
+		return "unknown"
+	}
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
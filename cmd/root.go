@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/terassyi/gowi/runtime/debugger"
 )
 
 var rootCmd = &cobra.Command{
@@ -21,13 +22,32 @@ func init() {
 	dumpCommand.Flags().BoolP("section", "s", false, "Show sections in WASM file.")
 	dumpCommand.Flags().BoolP("raw", "r", false, "Show raw binary.")
 	dumpCommand.Flags().BoolP("detail", "x", false, "Show section details.")
+	dumpCommand.Flags().BoolP("disassemble", "d", false, "Disassemble function bodies.")
+	dumpCommand.Flags().StringP("output", "o", outputText, "Output format. (text or json)")
 	rootCmd.AddCommand(dumpCommand)
 	// exec subcommand
-	execCommand.Flags().BoolP("list-all-exports", "l", false, "Show all exported functions.")
+	execCommand.Flags().BoolP("list-all-exports", "l", false, "Show all exports with their types.")
 	execCommand.Flags().StringP("invoke", "i", "", "Invoke an exported function.")
-	execCommand.Flags().IntP("debug", "d", 0, "Debug the invoked function.")
+	execCommand.Flags().IntP("debug", "d", 0, "Debug the invoked function. (1: trace to stderr, 2: trace to stdout, or stderr with --output json, 3: trace with context, 4: interactive debugger)")
+	execCommand.Flags().Int("context-depth", debugger.DEFAULT_CONTEXT_DEPTH, "Number of values on top of the stack shown in the context.")
+	execCommand.Flags().StringP("trace", "t", "", "Write the execution trace as JSON lines to the file.")
+	execCommand.Flags().StringP("profile", "p", "", "Write the pprof profile of the invocation to the file.")
 	execCommand.Flags().StringSliceP("args", "a", []string{}, "Arguments for the invoking function.")
+	execCommand.Flags().StringP("output", "o", outputText, "Output format of the invocation result. (text or json)")
+	execCommand.Flags().String("checkpoint", "", "Write checkpoints of the invocation to the file. An interrupt suspends the invocation with a checkpoint.")
+	execCommand.Flags().Uint64("checkpoint-interval", 0, "Number of instructions between checkpoints. (0: only when interrupted)")
+	execCommand.Flags().String("resume", "", "Resume the invocation from the checkpoint file.")
+	execCommand.Flags().Bool("lazy", false, "Decode and validate function bodies on their first call.")
+	execCommand.Flags().String("features", "all", "Proposals to allow separated by commas. (e.g. none,+simd or all,-threads)")
 	rootCmd.AddCommand(execCommand)
+	// analyze subcommand
+	analyzeCommand.Flags().StringP("output", "o", outputText, "Output format. (text or json)")
+	rootCmd.AddCommand(analyzeCommand)
+	// strip subcommand
+	rootCmd.AddCommand(stripCommand)
+	// validate subcommand
+	validateCommand.Flags().String("features", "all", "Proposals to allow separated by commas. (e.g. none,+simd or all,-threads)")
+	rootCmd.AddCommand(validateCommand)
 }
 
 func Execute() {
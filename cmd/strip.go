@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/terassyi/gowi/decoder"
+)
+
+var stripCommand = &cobra.Command{
+	Use:   "strip <input> <output>",
+	Short: "remove unreachable functions and unused imports and types from WASM binary file",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		d, err := decoder.New(args[0])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		out, result, err := d.Strip()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := os.WriteFile(args[1], out, 0644); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Removed functions: %v\n", result.Functions)
+		fmt.Printf("Removed imports: %v\n", result.Imports)
+		fmt.Printf("Removed types: %v\n", result.Types)
+		fmt.Printf("Size: %d -> %d bytes\n", result.Before, result.After)
+	},
+}
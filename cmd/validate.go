@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/types"
+	"github.com/terassyi/gowi/validator"
+)
+
+var validateCommand = &cobra.Command{
+	Use:   "validate",
+	Short: "validate WASM binary file and report all errors",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := args[0]
+		f, err := cmd.Flags().GetString("features")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		features, err := types.ParseFeatures(f)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		// the decoder allows all features so that the validator reports every use of the disabled ones
+		d, err := decoder.New(file)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		mod, err := d.Decode()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		v, err := validator.NewWithFeatures(mod, features)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		errs := v.ValidateAll()
+		if len(errs) == 0 {
+			fmt.Printf("%s: valid (features: %s)\n", file, features)
+			return
+		}
+		offsets, err := d.Offsets()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, e := range errs {
+			fmt.Println(validationErrorString(e, offsets))
+		}
+		fmt.Printf("%s: %d errors found (features: %s)\n", file, len(errs), features)
+		os.Exit(1)
+	},
+}
+
+// validationErrorString returns the error with the byte offset of the instruction when it is in a function body.
+func validationErrorString(e *validator.Error, offsets [][]int) string {
+	if e.Function < 0 {
+		return fmt.Sprintf("module: %s", e.Err)
+	}
+	if e.Function < len(offsets) && e.Instruction < len(offsets[e.Function]) {
+		return fmt.Sprintf("func[%d] at 0x%06x: %s", e.Function, offsets[e.Function][e.Instruction], e.Err)
+	}
+	return fmt.Sprintf("func[%d] instruction %d: %s", e.Function, e.Instruction, e.Err)
+}
@@ -16,8 +16,11 @@ type code struct {
 type functionBody struct {
 	// BodySize   uint32
 	// LocalCount uint32
-	locals []*localEntry
-	code   []instruction.Instruction
+	locals   []*localEntry
+	code     []instruction.Instruction // nil until decode is called in lazy mode
+	raw      []byte                    // the encoded instructions kept in lazy mode
+	offset   int                       // the byte offset of raw in the payload of the code section
+	features types.Features            // the features allowed in raw
 }
 
 type localEntry struct {
@@ -25,7 +28,7 @@ type localEntry struct {
 	typ   types.ValueType
 }
 
-func newCode(payload []byte) (*code, error) {
+func newCode(payload []byte, lazy bool, features types.Features) (*code, error) {
 	buf := bytes.NewBuffer(payload)
 	count, _, err := types.DecodeVarUint32(buf)
 	if err != nil {
@@ -33,24 +36,31 @@ func newCode(payload []byte) (*code, error) {
 	}
 	funcBodys := make([]*functionBody, 0, int(count))
 	for i := 0; i < int(count); i++ {
-		f, err := newFunctionBody(buf)
+		start := len(payload) - buf.Len()
+		f, err := newFunctionBody(buf, lazy, features)
 		if err != nil {
 			return nil, fmt.Errorf("NewCode: decode function_body: %w", err)
 		}
+		if lazy {
+			f.offset += start
+		}
 		funcBodys = append(funcBodys, f)
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewCode: %w", err)
+	}
 	return &code{
 		bodies: funcBodys,
 	}, nil
 }
 
-func newFunctionBody(buf *bytes.Buffer) (*functionBody, error) {
-	size, _, err := types.DecodeVarUint32(buf)
+func newFunctionBody(buf *bytes.Buffer, lazy bool, features types.Features) (*functionBody, error) {
+	size, n, err := types.DecodeVarUint32(buf)
 	if err != nil {
 		return nil, fmt.Errorf("newFunctionBody: decode body_size: %w", err)
 	}
-	data := make([]byte, int(size))
-	if _, err := buf.Read(data); err != nil {
+	data, err := readBytes(buf, int(size))
+	if err != nil {
 		return nil, fmt.Errorf("newFunctionBody: decode: %w", err)
 	}
 	bodyBuf := bytes.NewBuffer(data)
@@ -66,23 +76,55 @@ func newFunctionBody(buf *bytes.Buffer) (*functionBody, error) {
 		}
 		locals = append(locals, l)
 	}
-	// code, err := buf.ReadBytes(END)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("newFunctionBody: decode code: %w", err)
-	// }
-	// codeBuf := bytes.NewBuffer(code)
-	codes := make([]instruction.Instruction, 0, 1024)
-	for bodyBuf.Len() > 0 {
-		c, err := instruction.Decode(bodyBuf)
+	body := &functionBody{locals: locals}
+	if lazy {
+		body.raw = bodyBuf.Bytes()
+		body.offset = n + len(data) - len(body.raw)
+		body.features = features
+		return body, nil
+	}
+	codes, err := decodeInstructions(bodyBuf.Bytes(), features)
+	if err != nil {
+		return nil, err
+	}
+	body.code = codes
+	return body, nil
+}
+
+// decode decodes the instructions kept in lazy mode.
+func (b *functionBody) decode() ([]instruction.Instruction, error) {
+	return decodeInstructions(b.raw, b.features)
+}
+
+// decodeInstructions decodes the instructions of a function body.
+// The end of the function must be the last byte of the body and the instructions must be in the features.
+func decodeInstructions(raw []byte, features types.Features) ([]instruction.Instruction, error) {
+	buf := bytes.NewBuffer(raw)
+	codes := make([]instruction.Instruction, 0, len(raw))
+	depth := 0
+	for depth >= 0 {
+		if buf.Len() == 0 {
+			return nil, fmt.Errorf("decodeInstructions: %w: the function is not closed", BodySizeMismatch)
+		}
+		c, err := instruction.Decode(buf)
 		if err != nil {
-			return nil, fmt.Errorf("newFunctionBody: decode instruction: %w", err)
+			return nil, fmt.Errorf("decodeInstructions: decode instruction: %w", err)
+		}
+		if fs := instruction.RequiredFeatures(c); !features.Has(fs) {
+			return nil, fmt.Errorf("decodeInstructions: %s: %w: %s", c, FeatureNotEnabled, fs&^features)
+		}
+		switch c.Opcode() {
+		case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+			depth++
+		case instruction.END, instruction.DELEGATE:
+			depth--
 		}
 		codes = append(codes, c)
 	}
-	return &functionBody{
-		locals: locals,
-		code:   codes,
-	}, nil
+	if buf.Len() != 0 {
+		return nil, fmt.Errorf("decodeInstructions: %w: %d bytes are left after the end of the function", BodySizeMismatch, buf.Len())
+	}
+	return codes, nil
 }
 
 func newLocalEntry(buf *bytes.Buffer) (*localEntry, error) {
@@ -107,6 +149,10 @@ func newLocalEntry(buf *bytes.Buffer) (*localEntry, error) {
 func (c *code) detail() string {
 	str := fmt.Sprintf("Code[%d]:\n", len(c.bodies))
 	for i := 0; i < len(c.bodies); i++ {
+		if c.bodies[i].code == nil {
+			str += fmt.Sprintf(" - func[%d] body size=%d (not decoded)\n", i, len(c.bodies[i].raw))
+			continue
+		}
 		str += fmt.Sprintf(" - func[%d] instruction size=%d\n", i, len(c.bodies[i].code))
 	}
 	return str
@@ -94,7 +94,7 @@ func TestNewCode(t *testing.T) {
 			},
 		},
 	} {
-		c, err := newCode(d.payload)
+		c, err := newCode(d.payload, false, types.FeaturesAll)
 		require.NoError(t, err)
 		assert.Equal(t, d.sec, c)
 	}
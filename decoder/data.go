@@ -39,8 +39,8 @@ func newData(payload []byte) (*data, error) {
 		if err != nil {
 			return nil, fmt.Errorf("NewData: decode size: %w", err)
 		}
-		data := make([]byte, int(size))
-		if _, err := buf.Read(data); err != nil {
+		data, err := readBytes(buf, int(size))
+		if err != nil {
 			return nil, fmt.Errorf("NewData: decode data: %w", err)
 		}
 		entries = append(entries, &dataSegment{
@@ -50,6 +50,9 @@ func newData(payload []byte) (*data, error) {
 			data:   data,
 		})
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewData: %w", err)
+	}
 	return &data{
 		entries: entries,
 	}, nil
@@ -26,26 +26,59 @@ var (
 	InvalidFileFormat  error = errors.New("Given file is not .wasm.")
 	InvalidMajicNumber error = errors.New("Invalid Majic Number.")
 	InvalidWasmVersion error = errors.New("Invalid WASM version.")
+
+	// malformed modules
+	// https://webassembly.github.io/spec/core/binary/modules.html#binary-module
+	UnexpectedEnd             error = errors.New("unexpected end")
+	SectionSizeMismatch       error = errors.New("section size mismatch")
+	SectionOutOfOrder         error = errors.New("section out of order")
+	DuplicateSection          error = errors.New("duplicate section")
+	BodySizeMismatch          error = errors.New("body size mismatch")
+	InconsistentFunctionCount error = errors.New("function and code section have inconsistent lengths")
+
+	FeatureNotEnabled error = errors.New("feature is not enabled")
+)
+
+// Mode selects when the instructions of function bodies are decoded.
+type Mode uint8
+
+const (
+	ModeEager Mode = iota // decode all function bodies with the module
+	ModeLazy              // keep the raw bytes of function bodies and decode them on first use
 )
 
 type Decoder struct {
-	path string
-	mod  *mod
+	path     string
+	mode     Mode
+	features types.Features
+	mod      *mod
 }
 
 func New(path string) (*Decoder, error) {
-	m, err := decode(path)
+	return NewWithMode(path, ModeEager)
+}
+
+// NewWithMode creates a decoder which decodes function bodies in the mode.
+func NewWithMode(path string, mode Mode) (*Decoder, error) {
+	return NewWithFeatures(path, mode, types.FeaturesAll)
+}
+
+// NewWithFeatures creates a decoder which rejects modules using proposals not in the features.
+func NewWithFeatures(path string, mode Mode, features types.Features) (*Decoder, error) {
+	m, err := decode(path, mode, features)
 	if err != nil {
 		return nil, fmt.Errorf("Decoder new: %w", err)
 	}
 	return &Decoder{
-		path: path,
-		mod:  m,
+		path:     path,
+		mode:     mode,
+		features: features,
+		mod:      m,
 	}, nil
 }
 
 func (d *Decoder) Decode() (*structure.Module, error) {
-	m, err := decode(d.path)
+	m, err := decode(d.path, d.mode, d.features)
 	if err != nil {
 		return nil, err
 	}
@@ -53,104 +86,122 @@ func (d *Decoder) Decode() (*structure.Module, error) {
 	return m.build()
 }
 
-func decode(path string) (*mod, error) {
+func decode(path string, mode Mode, features types.Features) (*mod, error) {
 	data, err := readWasmFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("decode: %w", err)
 	}
-	buf := bytes.NewBuffer(data)
-	if err := validateMajicNumber(buf); err != nil {
-		return nil, fmt.Errorf("decode: majic_number: %w", err)
-	}
-	module := &mod{}
-	version, err := decodeVersion(buf)
+	return decodeBytes(data, mode, features)
+}
+
+// decodeBytes decodes the binary module.
+// Errors of a section have the id and the byte offset of the section.
+func decodeBytes(data []byte, mode Mode, features types.Features) (*mod, error) {
+	s, err := NewStreamWithFeatures(bytes.NewReader(data), mode, features)
 	if err != nil {
-		return nil, fmt.Errorf("decode: version: %w", err)
+		return nil, err
 	}
-	module.version = version
-	for buf.Len() > 0 {
-		sd, err := newSectionDecoder(buf)
+	for {
+		if _, err := s.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return s.mod, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// decodeSection decodes the payload of the section into the module.
+func (m *mod) decodeSection(sd *sectionDecoder) error {
+	switch sd.id {
+	case CUSTOM:
+		s, err := newCustom(sd.payloadData)
 		if err != nil {
-			return nil, fmt.Errorf("decode: %w", err)
+			return err
 		}
-		switch sd.id {
-		case CUSTOM:
-			s, err := newCustom(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.custom = s
-		case TYPE:
-			s, err := newType(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.typ = s
-		case IMPORT:
-			s, err := newImport(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.imports = s
-		case FUNCTION:
-			s, err := newFunction(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.function = s
-		case TABLE:
-			s, err := newTable(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.table = s
-		case MEMORY:
-			s, err := newMemory(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.memory = s
-		case GLOBAL:
-			s, err := newGlobal(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.global = s
-		case EXPORT:
-			s, err := newExport(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.export = s
-		case START:
-			s, err := newStart(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
+		m.custom = s
+		if string(sd.name) == NAME_SECTION {
+			// a malformed name section doesn't invalidate the module
+			if n, err := newNames(sd.payloadData); err == nil {
+				m.names = n
 			}
-			module.start = s
-		case ELEMENT:
-			s, err := newElement(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.element = s
-		case CODE:
-			s, err := newCode(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.code = s
-		case DATA:
-			s, err := newData(sd.payloadData)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			}
-			module.data = s
-		default:
-			return nil, InvalidSectionCode
 		}
+	case TYPE:
+		s, err := newType(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.typ = s
+	case IMPORT:
+		s, err := newImport(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.imports = s
+	case FUNCTION:
+		s, err := newFunction(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.function = s
+	case TABLE:
+		s, err := newTable(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.table = s
+	case MEMORY:
+		s, err := newMemory(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.memory = s
+	case GLOBAL:
+		s, err := newGlobal(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.global = s
+	case EXPORT:
+		s, err := newExport(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.export = s
+	case START:
+		s, err := newStart(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.start = s
+	case ELEMENT:
+		s, err := newElement(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.element = s
+	case CODE:
+		s, err := newCode(sd.payloadData, m.lazy, m.features)
+		if err != nil {
+			return err
+		}
+		m.code = s
+	case DATA:
+		s, err := newData(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.data = s
+	case TAG:
+		s, err := newTag(sd.payloadData)
+		if err != nil {
+			return err
+		}
+		m.tag = s
+	default:
+		return InvalidSectionCode
 	}
-	return module, nil
+	return m.checkFeatures(sd.id)
 }
 
 func validateExt(path string) error {
@@ -200,29 +251,50 @@ func newSectionDecoder(buf *bytes.Buffer) (*sectionDecoder, error) {
 		return nil, fmt.Errorf("newSectionDecoder: decode payload_length: %w", err)
 	}
 	sd.payloadLength = uint32(payloadLength)
+	data, err := readBytes(buf, int(sd.payloadLength))
+	if err != nil {
+		return nil, fmt.Errorf("newSectionDecoder: decode payload_data: %w", err)
+	}
 	if id == byte(0x00) {
-		nameLength, _, err := types.DecodeVarUint32(buf)
+		// the name is a part of the payload of the custom section
+		payload := bytes.NewBuffer(data)
+		nameLength, _, err := types.DecodeVarUint32(payload)
 		if err != nil {
 			return nil, fmt.Errorf("newSectionDecoder: decode name_length: %w", err)
 		}
 		sd.nameLength = uint32(nameLength)
-		name := make([]byte, int(nameLength))
-		if _, err := buf.Read(name); err != nil {
+		name, err := readBytes(payload, int(nameLength))
+		if err != nil {
 			return nil, fmt.Errorf("newSectionDecoder: decode name: %w", err)
 		}
 		sd.name = name
-	}
-	data := make([]byte, int(sd.payloadLength))
-	if _, err := buf.Read(data); err != nil {
-		return nil, fmt.Errorf("newSectionDecoder: decode payload_data: %w", err)
+		data = payload.Bytes()
 	}
 	sd.payloadData = data
 	return sd, nil
 }
 
+// readBytes reads n bytes from buf. It fails without reading when buf doesn't have n bytes.
+func readBytes(buf *bytes.Buffer, n int) ([]byte, error) {
+	if n > buf.Len() {
+		return nil, fmt.Errorf("%w: %d bytes are required, %d bytes are left", UnexpectedEnd, n, buf.Len())
+	}
+	b := make([]byte, n)
+	copy(b, buf.Next(n))
+	return b, nil
+}
+
+// checkConsumed returns an error when the payload of a section is not decoded to the end.
+func checkConsumed(buf *bytes.Buffer) error {
+	if buf.Len() != 0 {
+		return fmt.Errorf("%w: %d bytes are left", SectionSizeMismatch, buf.Len())
+	}
+	return nil
+}
+
 func validateMajicNumber(buf *bytes.Buffer) error {
-	b := make([]byte, 4)
-	if _, err := buf.Read(b); err != nil {
+	b, err := readBytes(buf, 4)
+	if err != nil {
 		return fmt.Errorf("validateMajicNumber: read: %w", err)
 	}
 	majic := binary.BigEndian.Uint32(b)
@@ -233,11 +305,15 @@ func validateMajicNumber(buf *bytes.Buffer) error {
 }
 
 func decodeVersion(buf *bytes.Buffer) (uint32, error) {
-	b := make([]byte, 4)
-	if _, err := buf.Read(b); err != nil {
+	b, err := readBytes(buf, 4)
+	if err != nil {
 		return 0, fmt.Errorf("decodeVersion: read: %w", err)
 	}
-	return binary.LittleEndian.Uint32(b), nil
+	version := binary.LittleEndian.Uint32(b)
+	if version != WASM_VERSION {
+		return 0, fmt.Errorf("decodeVersion: %w: %d", InvalidWasmVersion, version)
+	}
+	return version, nil
 }
 
 func HexDump(file string) ([]byte, error) {
@@ -269,6 +345,9 @@ func (d *Decoder) DumpSection() string {
 	if d.mod.memory != nil {
 		str += fmt.Sprintf("Memory : count 0x%04x\n", len(d.mod.memory.entries))
 	}
+	if d.mod.tag != nil {
+		str += fmt.Sprintf("Tag : count 0x%04x\n", len(d.mod.tag.entries))
+	}
 	if d.mod.global != nil {
 		str += fmt.Sprintf("Global : count 0x%04x\n", len(d.mod.global.globals))
 	}
@@ -317,6 +396,10 @@ func (d *Decoder) DumpDetail() (string, error) {
 		str += d.mod.memory.detail()
 		str += "\n"
 	}
+	if d.mod.tag != nil {
+		str += d.mod.tag.detail()
+		str += "\n"
+	}
 	if d.mod.global != nil {
 		s, err := d.mod.global.detail()
 		if err != nil {
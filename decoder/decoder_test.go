@@ -89,7 +89,7 @@ func TestDecode(t *testing.T) {
 			},
 		},
 	} {
-		m, err := decode(d.path)
+		m, err := decode(d.path, ModeEager, types.FeaturesAll)
 		require.NoError(t, err)
 		assert.Equal(t, d.mod.version, m.version)
 		if m.typ != nil && d.mod.typ != nil {
@@ -147,3 +147,95 @@ func TestNewSectionDecoder(t *testing.T) {
 		assert.Equal(t, d.sd, sd)
 	}
 }
+
+func TestDecodeBytes_Malformed(t *testing.T) {
+	header := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	typeSection := []byte{0x01, 0x04, 0x01, 0x60, 0x00, 0x00}
+	funcSection := []byte{0x03, 0x02, 0x01, 0x00}
+	module := func(sections ...[]byte) []byte {
+		b := append([]byte{}, header...)
+		for _, s := range sections {
+			b = append(b, s...)
+		}
+		return b
+	}
+	for _, d := range []struct {
+		name string
+		data []byte
+		err  error
+		msg  string
+	}{
+		{name: "valid", data: module(typeSection, funcSection, []byte{0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b})},
+		{name: "custom sections anywhere", data: module([]byte{0x00, 0x04, 0x02, 'h', 'i', 0xff}, typeSection, []byte{0x00, 0x01, 0x00})},
+		{name: "tag before global", data: module([]byte{0x05, 0x01, 0x00}, []byte{0x0d, 0x01, 0x00}, []byte{0x06, 0x01, 0x00})},
+		{name: "unknown binary version", data: []byte{0x00, 0x61, 0x73, 0x6d, 0x02, 0x00, 0x00, 0x00}, err: InvalidWasmVersion},
+		{name: "truncated header", data: []byte{0x00, 0x61, 0x73}, err: UnexpectedEnd},
+		{name: "unknown section id", data: module([]byte{0x0e, 0x00}), err: InvalidSectionCode, msg: "at 0x8"},
+		{name: "duplicate section", data: module(typeSection, typeSection), err: DuplicateSection, msg: "section 1 at 0xe"},
+		{name: "section out of order", data: module(funcSection, typeSection), err: SectionOutOfOrder, msg: "section 1 at 0xc"},
+		{name: "tag after global", data: module([]byte{0x06, 0x01, 0x00}, []byte{0x0d, 0x01, 0x00}), err: SectionOutOfOrder},
+		{name: "section length out of bounds", data: module([]byte{0x01, 0x05, 0x00}), err: UnexpectedEnd},
+		{name: "section size mismatch", data: module([]byte{0x01, 0x02, 0x00, 0x00}), err: SectionSizeMismatch, msg: "section 1 at 0x8"},
+		{name: "custom name out of bounds", data: module([]byte{0x00, 0x02, 0x05, 'a'}), err: UnexpectedEnd},
+		{name: "import name out of bounds", data: module([]byte{0x02, 0x03, 0x01, 0x05, 'a'}), err: UnexpectedEnd},
+		{name: "function without code", data: module(typeSection, funcSection), err: InconsistentFunctionCount},
+		{name: "code without function", data: module(typeSection, []byte{0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b}), err: InconsistentFunctionCount},
+		{name: "missing end", data: module(typeSection, funcSection, []byte{0x0a, 0x04, 0x01, 0x02, 0x00, 0x01}), err: BodySizeMismatch, msg: "section 10 at 0x12"},
+		{name: "bytes after end", data: module(typeSection, funcSection, []byte{0x0a, 0x05, 0x01, 0x03, 0x00, 0x0b, 0x01}), err: BodySizeMismatch},
+		{name: "nested end", data: module(typeSection, funcSection, []byte{0x0a, 0x06, 0x01, 0x04, 0x00, 0x02, 0x40, 0x0b}), err: BodySizeMismatch},
+	} {
+		t.Run(d.name, func(t *testing.T) {
+			_, err := decodeBytes(d.data, ModeEager, types.FeaturesAll)
+			if d.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, d.err)
+			assert.Contains(t, err.Error(), d.msg)
+		})
+	}
+}
+
+func TestNewWithFeatures(t *testing.T) {
+	for _, d := range []struct {
+		path     string
+		features types.Features
+		err      bool
+		lazyErr  bool // the error is found in a function body
+	}{
+		{path: "../examples/tail_call.wasm", features: types.FeaturesAll},
+		{path: "../examples/tail_call.wasm", features: types.FeaturesAll &^ types.FeatureTailCall, lazyErr: true},
+		{path: "../examples/sign_extension.wasm", features: types.FeaturesNone, lazyErr: true},
+		{path: "../examples/simd.wasm", features: types.FeaturesAll &^ types.FeatureSIMD, err: true},
+		{path: "../examples/exception.wasm", features: types.FeaturesAll &^ types.FeatureExceptions, err: true},
+		{path: "../examples/atomic.wasm", features: types.FeaturesAll &^ types.FeatureThreads, err: true},
+		{path: "../examples/loop.wasm", features: types.FeaturesAll &^ types.FeatureMultiValue, err: true},
+		{path: "../examples/func1.wasm", features: types.FeaturesNone},
+	} {
+		_, err := NewWithFeatures(d.path, ModeEager, d.features)
+		if d.err || d.lazyErr {
+			assert.ErrorIs(t, err, FeatureNotEnabled, d.path)
+		} else {
+			assert.NoError(t, err, d.path)
+		}
+
+		dec, err := NewWithFeatures(d.path, ModeLazy, d.features)
+		if d.err {
+			assert.ErrorIs(t, err, FeatureNotEnabled, d.path)
+			continue
+		}
+		require.NoError(t, err, d.path)
+		mod, err := dec.Decode()
+		require.NoError(t, err, d.path)
+		for _, f := range mod.Functions {
+			if _, err = f.Instructions(); err != nil {
+				break
+			}
+		}
+		if d.lazyErr {
+			assert.ErrorIs(t, err, FeatureNotEnabled, d.path)
+		} else {
+			assert.NoError(t, err, d.path)
+		}
+	}
+}
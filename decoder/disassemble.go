@@ -0,0 +1,200 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/types"
+)
+
+// Disassemble returns the function bodies of the module as text like `wasm-objdump -d`.
+// Each instruction is shown with its byte offset in the module and its encoding,
+// and indented by the blocks it is in. Functions and locals are named by the name section.
+func (d *Decoder) Disassemble() (string, error) {
+	data, err := readWasmFile(d.path)
+	if err != nil {
+		return "", fmt.Errorf("Disassemble: %w", err)
+	}
+	// the raw bytes of the bodies are kept in lazy mode
+	m, err := decodeBytes(data, ModeLazy, d.features)
+	if err != nil {
+		return "", fmt.Errorf("Disassemble: %w", err)
+	}
+	str, err := m.disassemble()
+	if err != nil {
+		return "", fmt.Errorf("Disassemble: %w", err)
+	}
+	return d.dumpVersion() + str, nil
+}
+
+// Offsets returns the byte offsets in the module of the instructions of each function in the function index space.
+// Imported functions have no offsets.
+func (d *Decoder) Offsets() ([][]int, error) {
+	data, err := readWasmFile(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("Offsets: %w", err)
+	}
+	m, err := decodeBytes(data, ModeLazy, d.features)
+	if err != nil {
+		return nil, fmt.Errorf("Offsets: %w", err)
+	}
+	imported := m.importedFunctions()
+	offsets := make([][]int, imported)
+	if m.code == nil {
+		return offsets, nil
+	}
+	for i, body := range m.code.bodies {
+		o := make([]int, 0)
+		buf := bytes.NewBuffer(body.raw)
+		for buf.Len() > 0 {
+			pos := len(body.raw) - buf.Len()
+			if _, err := instruction.Decode(buf); err != nil {
+				return nil, fmt.Errorf("Offsets: func[%d]: at 0x%x: %w", imported+i, m.codeOffset+body.offset+pos, err)
+			}
+			o = append(o, m.codeOffset+body.offset+pos)
+		}
+		offsets = append(offsets, o)
+	}
+	return offsets, nil
+}
+
+func (m *mod) disassemble() (string, error) {
+	str := "Code Disassembly:\n"
+	if m.code == nil {
+		return str, nil
+	}
+	imported := m.importedFunctions()
+	for i, body := range m.code.bodies {
+		idx := uint32(imported + i)
+		ft, err := m.definedFuncType(i)
+		if err != nil {
+			return "", fmt.Errorf("func[%d]: %w", idx, err)
+		}
+		str += fmt.Sprintf("\nfunc[%d]%s: (%s) -> (%s)\n", idx, nameString(m.functionName(idx)), ft.Params, ft.Returns)
+		local := uint32(len(ft.Params))
+		for _, l := range body.locals {
+			if l.count == 0 {
+				continue
+			}
+			if l.count == 1 {
+				str += fmt.Sprintf(" local[%d] type=%s%s\n", local, l.typ, nameString(m.names.local(idx, local)))
+			} else {
+				str += fmt.Sprintf(" local[%d..%d] type=%s\n", local, local+l.count-1, l.typ)
+			}
+			local += l.count
+		}
+		s, err := m.disassembleBody(idx, body)
+		if err != nil {
+			return "", fmt.Errorf("func[%d]: %w", idx, err)
+		}
+		str += s
+	}
+	return str, nil
+}
+
+func (m *mod) disassembleBody(idx uint32, body *functionBody) (string, error) {
+	str := ""
+	buf := bytes.NewBuffer(body.raw)
+	depth := 0
+	for buf.Len() > 0 {
+		pos := len(body.raw) - buf.Len()
+		instr, err := instruction.Decode(buf)
+		if err != nil {
+			return "", fmt.Errorf("at 0x%x: %w", m.codeOffset+body.offset+pos, err)
+		}
+		encoded := body.raw[pos : len(body.raw)-buf.Len()]
+		indent := depth
+		switch instr.Opcode() {
+		case instruction.END, instruction.DELEGATE:
+			depth--
+			indent = depth
+		case instruction.ELSE, instruction.CATCH, instruction.CATCH_ALL:
+			indent = depth - 1
+		case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+			depth++
+		}
+		if indent < 0 {
+			// the end of the function
+			indent = 0
+		}
+		text := strings.Repeat("  ", indent) + instr.String()
+		if imm := m.immString(idx, instr); imm != "" {
+			text += " " + imm
+		}
+		str += fmt.Sprintf(" %06x: %-24s | %s\n", m.codeOffset+body.offset+pos, hexBytes(encoded), text)
+	}
+	return str, nil
+}
+
+// immString returns the immediate of the instruction in the function with the names of the referred function or local.
+func (m *mod) immString(f uint32, instr instruction.Instruction) string {
+	switch instr.Opcode() {
+	case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+		if types.ValueType(instruction.Imm[types.BlockType](instr)) == types.EMPTY {
+			return ""
+		}
+	case instruction.CALL, instruction.RETURN_CALL:
+		idx := instruction.Imm[uint32](instr)
+		return fmt.Sprintf("%d%s", idx, nameString(m.functionName(idx)))
+	case instruction.GET_LOCAL, instruction.SET_LOCAL, instruction.TEE_LOCAL:
+		idx := instruction.Imm[uint32](instr)
+		return fmt.Sprintf("%d%s", idx, nameString(m.names.local(f, idx)))
+	}
+	return instr.ImmString()
+}
+
+// functionName returns the name of the function in the name section or the export name.
+func (m *mod) functionName(idx uint32) string {
+	if name := m.names.function(idx); name != "" {
+		return name
+	}
+	if m.export != nil {
+		for _, e := range m.export.entries {
+			if e.kind == types.EXTERNAL_KIND_FUNCTION && e.index == idx {
+				return string(e.fieldString)
+			}
+		}
+	}
+	return ""
+}
+
+func (m *mod) importedFunctions() int {
+	n := 0
+	if m.imports != nil {
+		for _, i := range m.imports.entries {
+			if i.kind == types.EXTERNAL_KIND_FUNCTION {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// definedFuncType returns the type of the i th function defined in the module.
+func (m *mod) definedFuncType(i int) (*types.FuncType, error) {
+	if m.function == nil || i >= len(m.function.types) {
+		return nil, InconsistentFunctionCount
+	}
+	typeidx := m.function.types[i]
+	if m.typ == nil || int(typeidx) >= len(m.typ.entries) {
+		return nil, fmt.Errorf("type index %d is out of range", typeidx)
+	}
+	return m.typ.entries[typeidx], nil
+}
+
+func nameString(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" <%s>", name)
+}
+
+func hexBytes(b []byte) string {
+	strs := make([]string, 0, len(b))
+	for _, c := range b {
+		strs = append(strs, fmt.Sprintf("%02x", c))
+	}
+	return strings.Join(strs, " ")
+}
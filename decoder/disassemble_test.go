@@ -0,0 +1,56 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestDisassemble(t *testing.T) {
+	data := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x06, 0x01, 0x60, 0x01, 0x7f, 0x01, 0x7f,
+		0x03, 0x02, 0x01, 0x00,
+		0x0a, 0x13, 0x01, 0x11, 0x01, 0x01, 0x7f,
+		0x20, 0x00, 0x04, 0x40, 0x20, 0x00, 0x21, 0x01, 0x0b, 0x20, 0x01, 0x10, 0x00, 0x0b,
+		// name section
+		0x00, 0x1a, 0x04, 'n', 'a', 'm', 'e',
+		0x01, 0x06, 0x01, 0x00, 0x03, 'f', 'o', 'o',
+		0x02, 0x0b, 0x01, 0x00, 0x02, 0x00, 0x01, 'n', 0x01, 0x03, 'a', 'c', 'c',
+	}
+	m, err := decodeBytes(data, ModeLazy, types.FeaturesAll)
+	require.NoError(t, err)
+	str, err := m.disassemble()
+	require.NoError(t, err)
+	assert.Equal(t, `Code Disassembly:
+
+func[0] <foo>: (i32) -> (i32)
+ local[1] type=i32 <acc>
+ 00001b: 20 00                    | get_local 0 <n>
+ 00001d: 04 40                    | if
+ 00001f: 20 00                    |   get_local 0 <n>
+ 000021: 21 01                    |   set_local 1 <acc>
+ 000023: 0b                       | end
+ 000024: 20 01                    | get_local 1 <acc>
+ 000026: 10 00                    | call 0 <foo>
+ 000028: 0b                       | end
+`, str)
+}
+
+func TestOffsets(t *testing.T) {
+	for _, d := range []struct {
+		path    string
+		offsets [][]int
+	}{
+		{path: "../examples/import_js.wasm", offsets: [][]int{nil, {0x37, 0x39, 0x3b}}},
+		{path: "../examples/call_func1.wasm", offsets: [][]int{{0x2d, 0x2f}, {0x32, 0x34, 0x36, 0x37}}},
+	} {
+		dec, err := New(d.path)
+		require.NoError(t, err)
+		offsets, err := dec.Offsets()
+		require.NoError(t, err)
+		assert.Equal(t, d.offsets, offsets, d.path)
+	}
+}
@@ -53,6 +53,9 @@ func newElement(payload []byte) (*element, error) {
 			elems:  elems,
 		})
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewElement: %w", err)
+	}
 	return &element{
 		entries: entries,
 	}, nil
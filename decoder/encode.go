@@ -0,0 +1,93 @@
+package decoder
+
+import (
+	"bytes"
+
+	"github.com/terassyi/gowi/types"
+)
+
+// encoder writes the binary format of sections.
+// https://webassembly.github.io/spec/core/binary/modules.html
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) u32(v uint32) {
+	e.buf.Write(types.VarUint32(v).Encode())
+}
+
+func (e *encoder) byte(b byte) {
+	e.buf.WriteByte(b)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.buf.Write(b)
+}
+
+// name writes the length of b followed by b.
+func (e *encoder) name(b []byte) {
+	e.u32(uint32(len(b)))
+	e.bytes(b)
+}
+
+func (e *encoder) funcType(ft *types.FuncType) {
+	e.byte(byte(types.FUNC))
+	e.u32(uint32(len(ft.Params)))
+	for _, p := range ft.Params {
+		e.byte(byte(p))
+	}
+	e.u32(uint32(len(ft.Returns)))
+	for _, r := range ft.Returns {
+		e.byte(byte(r))
+	}
+}
+
+// limits writes the limits with the flag. A maximum of 0 is treated as no maximum.
+func (e *encoder) limits(l *types.Limits, flag uint8) {
+	if l.Max != 0 {
+		flag |= types.LIMITS_FLAG_MAX
+	}
+	e.byte(flag)
+	e.u32(l.Min)
+	if l.Max != 0 {
+		e.u32(l.Max)
+	}
+}
+
+func (e *encoder) tableType(t *types.TableType) {
+	if t.ElementType == types.ElemTypeExternref {
+		e.byte(0x6f)
+	} else {
+		e.byte(byte(types.ANYFUNC))
+	}
+	e.limits(t.Limits, 0)
+}
+
+func (e *encoder) memoryType(t *types.MemoryType) {
+	var flag uint8
+	if t.Shared {
+		flag = types.LIMITS_FLAG_SHARED
+	}
+	e.limits(t.Limits, flag)
+}
+
+func (e *encoder) globalType(t *types.GlobalType) {
+	e.byte(byte(t.ContentType))
+	if t.Mut {
+		e.byte(1)
+	} else {
+		e.byte(0)
+	}
+}
+
+// section writes the section with the payload written by f.
+func (e *encoder) section(id SectionCode, f func(p *encoder) error) error {
+	p := &encoder{}
+	if err := f(p); err != nil {
+		return err
+	}
+	e.byte(byte(id))
+	e.u32(uint32(p.buf.Len()))
+	e.bytes(p.buf.Bytes())
+	return nil
+}
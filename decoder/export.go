@@ -31,8 +31,8 @@ func newExport(payload []byte) (*export, error) {
 		if err != nil {
 			return nil, fmt.Errorf("NewExport: decode fieldLength: %w", err)
 		}
-		field := make([]byte, int(fieldLength))
-		if _, err := buf.Read(field); err != nil {
+		field, err := readBytes(buf, int(fieldLength))
+		if err != nil {
 			return nil, fmt.Errorf("NewExport: decode field_string: %w", err)
 		}
 		entry.fieldString = field
@@ -51,6 +51,9 @@ func newExport(payload []byte) (*export, error) {
 		entry.index = uint32(index)
 		entries = append(entries, entry)
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewExport: %w", err)
+	}
 	return &export{
 		entries: entries,
 	}, nil
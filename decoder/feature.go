@@ -0,0 +1,103 @@
+package decoder
+
+import (
+	"fmt"
+
+	"github.com/terassyi/gowi/types"
+)
+
+// checkFeatures returns an error when the decoded section uses a proposal not in the features of the module.
+// Instructions are checked when they are decoded.
+func (m *mod) checkFeatures(id SectionCode) error {
+	switch id {
+	case TYPE:
+		for i, ft := range m.typ.entries {
+			if len(ft.Returns) > 1 {
+				if err := m.require(types.FeatureMultiValue); err != nil {
+					return fmt.Errorf("type[%d]: %w", i, err)
+				}
+			}
+			for _, vs := range [][]types.ValueType{ft.Params, ft.Returns} {
+				for _, v := range vs {
+					if err := m.requireValueType(v); err != nil {
+						return fmt.Errorf("type[%d]: %w", i, err)
+					}
+				}
+			}
+		}
+	case IMPORT:
+		for _, imp := range m.imports.entries {
+			var err error
+			switch imp.kind {
+			case types.EXTERNAL_KIND_TABLE:
+				err = m.requireTable(imp.typ.(*types.TableType))
+			case types.EXTERNAL_KIND_MEMORY:
+				err = m.requireMemory(imp.typ.(*types.MemoryType))
+			case types.EXTERNAL_KIND_GLOBAL:
+				err = m.requireValueType(imp.typ.(*types.GlobalType).ContentType)
+			case types.EXTERNAL_KIND_TAG:
+				err = m.require(types.FeatureExceptions)
+			}
+			if err != nil {
+				return fmt.Errorf("import %s.%s: %w", imp.moduleName, imp.fieldString, err)
+			}
+		}
+	case TABLE:
+		for i, t := range m.table.entries {
+			if err := m.requireTable(t); err != nil {
+				return fmt.Errorf("table[%d]: %w", i, err)
+			}
+		}
+	case MEMORY:
+		for i, t := range m.memory.entries {
+			if err := m.requireMemory(t); err != nil {
+				return fmt.Errorf("memory[%d]: %w", i, err)
+			}
+		}
+	case TAG:
+		return m.require(types.FeatureExceptions)
+	case GLOBAL:
+		for i, g := range m.global.globals {
+			if err := m.requireValueType(g.typ.ContentType); err != nil {
+				return fmt.Errorf("global[%d]: %w", i, err)
+			}
+		}
+	case CODE:
+		for i, body := range m.code.bodies {
+			for _, l := range body.locals {
+				if err := m.requireValueType(l.typ); err != nil {
+					return fmt.Errorf("code[%d]: %w", i, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mod) require(f types.Features) error {
+	if !m.features.Has(f) {
+		return fmt.Errorf("%w: %s", FeatureNotEnabled, f)
+	}
+	return nil
+}
+
+func (m *mod) requireValueType(v types.ValueType) error {
+	if v == types.V128 {
+		return m.require(types.FeatureSIMD)
+	}
+	return nil
+}
+
+func (m *mod) requireTable(t *types.TableType) error {
+	if t.ElementType == types.ElemTypeExternref {
+		return m.require(types.FeatureReferenceTypes)
+	}
+	return nil
+}
+
+func (m *mod) requireMemory(t *types.MemoryType) error {
+	if t.Shared {
+		return m.require(types.FeatureThreads)
+	}
+	return nil
+}
@@ -25,6 +25,9 @@ func newFunction(payload []byte) (*function, error) {
 		}
 		typs = append(typs, uint32(n))
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewFunction: %w", err)
+	}
 	return &function{
 		types: typs,
 	}, nil
@@ -34,6 +34,9 @@ func newGlobal(payload []byte) (*global, error) {
 		}
 		globals = append(globals, g)
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewGlobal: %w", err)
+	}
 	return &global{
 		globals: globals,
 	}, nil
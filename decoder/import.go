@@ -43,6 +43,11 @@ func fromImportEntry(kind types.ExternalKind, val any) (*structure.ImportDesc, e
 			Type:   structure.DescType(kind),
 			Global: val.(*types.GlobalType),
 		}, nil
+	case structure.DescTypeTag:
+		return &structure.ImportDesc{
+			Type: structure.DescType(kind),
+			Tag:  val.(uint32),
+		}, nil
 	default:
 		return nil, fmt.Errorf("fromImportEntry: %w", types.InvalidExternalKind)
 	}
@@ -62,8 +67,8 @@ func newImport(payload []byte) (*imports, error) {
 			return nil, fmt.Errorf("NewImport: decode module_len: %w", err)
 		}
 		entry.moduleNameLength = uint32(moduleNameLength)
-		name := make([]byte, int(moduleNameLength))
-		if _, err := buf.Read(name); err != nil {
+		name, err := readBytes(buf, int(moduleNameLength))
+		if err != nil {
 			return nil, fmt.Errorf("NewImport: decode module_name: %w", err)
 		}
 		entry.moduleName = name
@@ -72,8 +77,8 @@ func newImport(payload []byte) (*imports, error) {
 			return nil, fmt.Errorf("NewImport: decode field_len: %w", err)
 		}
 		entry.fieldLength = uint32(fieldLength)
-		field := make([]byte, int(fieldLength))
-		if _, err := buf.Read(field); err != nil {
+		field, err := readBytes(buf, int(fieldLength))
+		if err != nil {
 			return nil, fmt.Errorf("NewImport: decode field_string: %w", err)
 		}
 		entry.fieldString = field
@@ -107,10 +112,25 @@ func newImport(payload []byte) (*imports, error) {
 			}
 			// next
 			entry.typ = t
+		case types.EXTERNAL_KIND_GLOBAL:
+			t, err := types.NewGloablType(buf)
+			if err != nil {
+				return nil, fmt.Errorf("NewImport: decode type: %w", err)
+			}
+			entry.typ = t
+		case types.EXTERNAL_KIND_TAG:
+			t, err := decodeTagType(buf)
+			if err != nil {
+				return nil, fmt.Errorf("NewImport: decode type: %w", err)
+			}
+			entry.typ = t
 		}
 		entries = append(entries, entry)
 	}
 
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewImport: %w", err)
+	}
 	return &imports{
 		entries: entries,
 	}, nil
@@ -26,6 +26,9 @@ func newMemory(payload []byte) (*memory, error) {
 		}
 		entries = append(entries, entry)
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewMemory: %w", err)
+	}
 	return &memory{
 		entries: entries,
 	}, nil
@@ -34,7 +37,11 @@ func newMemory(payload []byte) (*memory, error) {
 func (m *memory) detail() string {
 	str := fmt.Sprintf("Memory[%d]:\n", len(m.entries))
 	for i := 0; i < len(m.entries); i++ {
-		str += fmt.Sprintf(" - memory[%d] pages: initial=%d\n", i, m.entries[i].Limits.Min)
+		str += fmt.Sprintf(" - memory[%d] pages: initial=%d", i, m.entries[i].Limits.Min)
+		if m.entries[i].Shared {
+			str += fmt.Sprintf(" max=%d shared", m.entries[i].Limits.Max)
+		}
+		str += "\n"
 	}
 	return str
 }
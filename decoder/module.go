@@ -10,19 +10,24 @@ import (
 )
 
 type mod struct {
-	version  uint32
-	custom   *custom
-	typ      *typ
-	imports  *imports
-	function *function
-	table    *table
-	memory   *memory
-	global   *global
-	export   *export
-	start    *start
-	element  *element
-	code     *code
-	data     *data
+	version    uint32
+	custom     *custom
+	typ        *typ
+	imports    *imports
+	function   *function
+	table      *table
+	memory     *memory
+	tag        *tag
+	global     *global
+	export     *export
+	start      *start
+	element    *element
+	code       *code
+	data       *data
+	names      *names // the name section
+	lazy       bool   // function bodies are decoded on first use
+	codeOffset int    // the byte offset of the payload of the code section
+	features   types.Features
 }
 
 func (m *mod) build() (*structure.Module, error) {
@@ -54,18 +59,26 @@ func (m *mod) build() (*structure.Module, error) {
 			}
 		}
 		for i, typ := range m.function.types {
-			f := &structure.Function{Type: typ}
-			f.Locals = make([]types.ValueType, 0)
-			for _, l := range m.code.bodies[i].locals {
+			if m.code == nil || i >= len(m.code.bodies) {
+				// the code section has not arrived in a stream yet
+				sm.Functions = append(sm.Functions, structure.NewLazyFunction(typ, nil, missingBody))
+				continue
+			}
+			body := m.code.bodies[i]
+			locals := make([]types.ValueType, 0)
+			for _, l := range body.locals {
 				ll := make([]types.ValueType, 0, int(l.count))
 				for i := 0; i < int(l.count); i++ {
 					ll = append(ll, l.typ)
 				}
-				f.Locals = append(f.Locals, ll...)
+				locals = append(locals, ll...)
+			}
+			if body.code == nil {
+				sm.Functions = append(sm.Functions, structure.NewLazyFunction(typ, locals, body.decode))
+				continue
 			}
-			f.Body = m.code.bodies[i].code
 			// sm.Functions[typ] = f
-			sm.Functions = append(sm.Functions, f)
+			sm.Functions = append(sm.Functions, &structure.Function{Type: typ, Locals: locals, Body: body.code})
 		}
 		// for _, i := range sm.Imports {
 		// 	if i.Desc.Type == structure.DescTypeFunc {
@@ -85,6 +98,12 @@ func (m *mod) build() (*structure.Module, error) {
 			sm.Memories = append(sm.Memories, &structure.Memory{Type: mem})
 		}
 	}
+	if m.tag != nil {
+		sm.Tags = make([]*structure.Tag, 0, len(m.tag.entries))
+		for _, t := range m.tag.entries {
+			sm.Tags = append(sm.Tags, &structure.Tag{Type: t})
+		}
+	}
 	if m.global != nil {
 		sm.Globals = make([]*structure.Global, 0, len(m.global.globals))
 		for _, g := range m.global.globals {
@@ -0,0 +1,129 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/terassyi/gowi/types"
+)
+
+const NAME_SECTION string = "name"
+
+// https://webassembly.github.io/spec/core/appendix/custom.html#name-section
+const (
+	nameSubsectionModule   uint8 = 0
+	nameSubsectionFunction uint8 = 1
+	nameSubsectionLocal    uint8 = 2
+)
+
+// names is the name section which names the module, functions and locals for debugging.
+type names struct {
+	module    string
+	functions map[uint32]string            // funcidx -> name
+	locals    map[uint32]map[uint32]string // funcidx -> localidx -> name
+}
+
+func newNames(payload []byte) (*names, error) {
+	buf := bytes.NewBuffer(payload)
+	n := &names{
+		functions: make(map[uint32]string),
+		locals:    make(map[uint32]map[uint32]string),
+	}
+	for buf.Len() > 0 {
+		id, err := buf.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("newNames: decode subsection id: %w", err)
+		}
+		size, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("newNames: decode subsection size: %w", err)
+		}
+		data, err := readBytes(buf, int(size))
+		if err != nil {
+			return nil, fmt.Errorf("newNames: decode subsection: %w", err)
+		}
+		sub := bytes.NewBuffer(data)
+		switch id {
+		case nameSubsectionModule:
+			name, err := decodeName(sub)
+			if err != nil {
+				return nil, fmt.Errorf("newNames: module name: %w", err)
+			}
+			n.module = name
+		case nameSubsectionFunction:
+			if err := decodeNameMap(sub, n.functions); err != nil {
+				return nil, fmt.Errorf("newNames: function names: %w", err)
+			}
+		case nameSubsectionLocal:
+			count, _, err := types.DecodeVarUint32(sub)
+			if err != nil {
+				return nil, fmt.Errorf("newNames: local names: %w", err)
+			}
+			for i := 0; i < int(count); i++ {
+				idx, _, err := types.DecodeVarUint32(sub)
+				if err != nil {
+					return nil, fmt.Errorf("newNames: local names: %w", err)
+				}
+				locals := make(map[uint32]string)
+				if err := decodeNameMap(sub, locals); err != nil {
+					return nil, fmt.Errorf("newNames: local names: %w", err)
+				}
+				n.locals[uint32(idx)] = locals
+			}
+		default:
+			// unknown subsections are skipped
+			continue
+		}
+		if err := checkConsumed(sub); err != nil {
+			return nil, fmt.Errorf("newNames: subsection %d: %w", id, err)
+		}
+	}
+	return n, nil
+}
+
+func decodeNameMap(buf *bytes.Buffer, m map[uint32]string) error {
+	count, _, err := types.DecodeVarUint32(buf)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		idx, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return err
+		}
+		name, err := decodeName(buf)
+		if err != nil {
+			return err
+		}
+		m[uint32(idx)] = name
+	}
+	return nil
+}
+
+func decodeName(buf *bytes.Buffer) (string, error) {
+	length, _, err := types.DecodeVarUint32(buf)
+	if err != nil {
+		return "", err
+	}
+	name, err := readBytes(buf, int(length))
+	if err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+// function returns the name of the function or "" when it is not named.
+func (n *names) function(idx uint32) string {
+	if n == nil {
+		return ""
+	}
+	return n.functions[idx]
+}
+
+// local returns the name of the local of the function or "" when it is not named.
+func (n *names) local(f, idx uint32) string {
+	if n == nil {
+		return ""
+	}
+	return n.locals[f][idx]
+}
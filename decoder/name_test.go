@@ -0,0 +1,25 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNames(t *testing.T) {
+	n, err := newNames([]byte{
+		0x00, 0x04, 0x03, 'm', 'o', 'd',
+		0x01, 0x09, 0x02, 0x00, 0x01, 'a', 0x02, 0x03, 'b', 'c', 'd',
+		// unknown subsections are skipped
+		0x07, 0x01, 0xff,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "mod", n.module)
+	assert.Equal(t, "a", n.function(0))
+	assert.Equal(t, "", n.function(1))
+	assert.Equal(t, "bcd", n.function(2))
+
+	_, err = newNames([]byte{0x01, 0x05, 0x01, 0x00, 0x05, 'a'})
+	assert.ErrorIs(t, err, UnexpectedEnd)
+}
@@ -0,0 +1,244 @@
+package decoder
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+// Report is the machine readable structure of a module printed by `gowi dump --output json`.
+type Report struct {
+	File      string           `json:"file"`
+	Version   uint32           `json:"version"`
+	Sections  []SectionReport  `json:"sections"`
+	Types     []TypeReport     `json:"types"`
+	Imports   []ImportReport   `json:"imports"`
+	Functions []FunctionReport `json:"functions"`
+	Tables    []TableReport    `json:"tables"`
+	Memories  []LimitsReport   `json:"memories"`
+	Globals   []GlobalReport   `json:"globals"`
+	Exports   []ExportReport   `json:"exports"`
+	Start     *uint32          `json:"start,omitempty"`
+	Elements  []ElementReport  `json:"elements"`
+	Datas     []DataReport     `json:"datas"`
+}
+
+type SectionReport struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type TypeReport struct {
+	Index   int      `json:"index"`
+	Params  []string `json:"params"`
+	Results []string `json:"results"`
+}
+
+type ImportReport struct {
+	Module string `json:"module"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Type   string `json:"type"`
+}
+
+type FunctionReport struct {
+	Index        int      `json:"index"`
+	Type         uint32   `json:"type"`
+	Params       []string `json:"params"`
+	Results      []string `json:"results"`
+	Locals       []string `json:"locals"`
+	Imported     bool     `json:"imported"`
+	Instructions int      `json:"instructions"`
+}
+
+type LimitsReport struct {
+	Min uint32  `json:"min"`
+	Max *uint32 `json:"max,omitempty"`
+}
+
+type TableReport struct {
+	ElementType string `json:"element_type"`
+	LimitsReport
+}
+
+type GlobalReport struct {
+	Index   int    `json:"index"`
+	Type    string `json:"type"`
+	Mutable bool   `json:"mutable"`
+	Init    string `json:"init"`
+}
+
+type ExportReport struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Index uint32 `json:"index"`
+}
+
+type ElementReport struct {
+	Table     uint32   `json:"table"`
+	Offset    string   `json:"offset"`
+	Functions []uint32 `json:"functions"`
+}
+
+type DataReport struct {
+	Memory uint32 `json:"memory"`
+	Offset string `json:"offset"`
+	Size   int    `json:"size"`
+	Data   string `json:"data"` // hex encoded
+}
+
+// Report returns the structure of the decoded module.
+func (d *Decoder) Report() (*Report, error) {
+	m, err := d.mod.build()
+	if err != nil {
+		return nil, fmt.Errorf("report: %w", err)
+	}
+	r := &Report{
+		File:      d.path,
+		Version:   m.Version,
+		Sections:  d.sectionReports(),
+		Types:     make([]TypeReport, 0, len(m.Types)),
+		Imports:   make([]ImportReport, 0, len(m.Imports)),
+		Functions: make([]FunctionReport, 0, len(m.Functions)),
+		Tables:    make([]TableReport, 0, len(m.Tables)),
+		Memories:  make([]LimitsReport, 0, len(m.Memories)),
+		Globals:   make([]GlobalReport, 0, len(m.Globals)),
+		Exports:   make([]ExportReport, 0, len(m.Exports)),
+		Elements:  make([]ElementReport, 0, len(m.Elements)),
+		Datas:     make([]DataReport, 0, len(m.Datas)),
+	}
+	for i, t := range m.Types {
+		r.Types = append(r.Types, TypeReport{Index: i, Params: valueTypeNames(t.Params), Results: valueTypeNames(t.Returns)})
+	}
+	for _, imp := range m.Imports {
+		r.Imports = append(r.Imports, ImportReport{Module: imp.Module, Name: imp.Name, Kind: imp.Desc.Type.String(), Type: importDescType(m, imp.Desc)})
+	}
+	for i, f := range m.Functions {
+		fr := FunctionReport{Index: i, Type: f.Type, Locals: valueTypeNames(f.Locals), Imported: f.Imported, Instructions: len(f.Body)}
+		if int(f.Type) < len(m.Types) {
+			fr.Params = valueTypeNames(m.Types[f.Type].Params)
+			fr.Results = valueTypeNames(m.Types[f.Type].Returns)
+		}
+		r.Functions = append(r.Functions, fr)
+	}
+	for _, t := range m.Tables {
+		r.Tables = append(r.Tables, TableReport{ElementType: t.Type.ElementType.String(), LimitsReport: limitsReport(t.Type.Limits)})
+	}
+	for _, mem := range m.Memories {
+		r.Memories = append(r.Memories, limitsReport(mem.Type.Limits))
+	}
+	for i, g := range m.Globals {
+		r.Globals = append(r.Globals, GlobalReport{Index: i, Type: g.Type.ContentType.String(), Mutable: g.Type.Mut, Init: instrString(g.Init)})
+	}
+	for _, e := range m.Exports {
+		r.Exports = append(r.Exports, ExportReport{Name: e.Name, Kind: e.Desc.Type.String(), Index: e.Desc.Val})
+	}
+	if m.Start != nil {
+		start := m.Start.Index
+		r.Start = &start
+	}
+	for _, e := range m.Elements {
+		r.Elements = append(r.Elements, ElementReport{Table: e.TableIndex, Offset: instrString(e.Offset), Functions: e.Init})
+	}
+	for _, data := range m.Datas {
+		r.Datas = append(r.Datas, DataReport{Memory: data.MemoryIndex, Offset: instrString(data.Offset), Size: len(data.Init), Data: hex.EncodeToString(data.Init)})
+	}
+	return r, nil
+}
+
+func (d *Decoder) sectionReports() []SectionReport {
+	sections := make([]SectionReport, 0)
+	if d.mod.custom != nil {
+		sections = append(sections, SectionReport{Name: "custom", Count: 1})
+	}
+	if d.mod.typ != nil {
+		sections = append(sections, SectionReport{Name: "type", Count: len(d.mod.typ.entries)})
+	}
+	if d.mod.imports != nil {
+		sections = append(sections, SectionReport{Name: "import", Count: len(d.mod.imports.entries)})
+	}
+	if d.mod.function != nil {
+		sections = append(sections, SectionReport{Name: "function", Count: len(d.mod.function.types)})
+	}
+	if d.mod.table != nil {
+		sections = append(sections, SectionReport{Name: "table", Count: len(d.mod.table.entries)})
+	}
+	if d.mod.memory != nil {
+		sections = append(sections, SectionReport{Name: "memory", Count: len(d.mod.memory.entries)})
+	}
+	if d.mod.global != nil {
+		sections = append(sections, SectionReport{Name: "global", Count: len(d.mod.global.globals)})
+	}
+	if d.mod.export != nil {
+		sections = append(sections, SectionReport{Name: "export", Count: len(d.mod.export.entries)})
+	}
+	if d.mod.start != nil {
+		sections = append(sections, SectionReport{Name: "start", Count: 1})
+	}
+	if d.mod.element != nil {
+		sections = append(sections, SectionReport{Name: "element", Count: len(d.mod.element.entries)})
+	}
+	if d.mod.code != nil {
+		sections = append(sections, SectionReport{Name: "code", Count: len(d.mod.code.bodies)})
+	}
+	if d.mod.data != nil {
+		sections = append(sections, SectionReport{Name: "data", Count: len(d.mod.data.entries)})
+	}
+	return sections
+}
+
+func importDescType(m *structure.Module, desc *structure.ImportDesc) string {
+	switch desc.Type {
+	case structure.DescTypeFunc:
+		if int(desc.Func) < len(m.Types) {
+			t := m.Types[desc.Func]
+			return fmt.Sprintf("(%s) -> (%s)", t.Params, t.Returns)
+		}
+		return fmt.Sprintf("type[%d]", desc.Func)
+	case structure.DescTypeTable:
+		return fmt.Sprintf("%s %s", desc.Table.ElementType, limitsString(desc.Table.Limits))
+	case structure.DescTypeMemory:
+		return limitsString(desc.Mem.Limits)
+	case structure.DescTypeGlobal:
+		if desc.Global.Mut {
+			return fmt.Sprintf("mut %s", desc.Global.ContentType)
+		}
+		return desc.Global.ContentType.String()
+	default:
+		return "unknown"
+	}
+}
+
+func limitsReport(l *types.Limits) LimitsReport {
+	r := LimitsReport{Min: l.Min}
+	if l.Max != 0 {
+		max := l.Max
+		r.Max = &max
+	}
+	return r
+}
+
+func limitsString(l *types.Limits) string {
+	if l.Max != 0 {
+		return fmt.Sprintf("min=%d max=%d", l.Min, l.Max)
+	}
+	return fmt.Sprintf("min=%d", l.Min)
+}
+
+func valueTypeNames(ts []types.ValueType) []string {
+	names := make([]string, 0, len(ts))
+	for _, t := range ts {
+		names = append(names, t.String())
+	}
+	return names
+}
+
+func instrString(instr instruction.Instruction) string {
+	if instr == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %s", instr, instr.ImmString())
+}
@@ -0,0 +1,42 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	d, err := New("../examples/table.wasm")
+	require.NoError(t, err)
+	r, err := d.Report()
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(1), r.Version)
+	assert.Equal(t, []SectionReport{
+		{Name: "type", Count: 2},
+		{Name: "function", Count: 3},
+		{Name: "table", Count: 1},
+		{Name: "export", Count: 1},
+		{Name: "element", Count: 1},
+		{Name: "code", Count: 3},
+	}, r.Sections)
+	require.Len(t, r.Functions, 3)
+	assert.Equal(t, FunctionReport{Index: 2, Type: 1, Params: []string{"i32"}, Results: []string{"i32"}, Locals: []string{}, Instructions: 3}, r.Functions[2])
+	assert.Equal(t, []TableReport{{ElementType: "funcref", LimitsReport: LimitsReport{Min: 2}}}, r.Tables)
+	assert.Equal(t, []ExportReport{{Name: "callByIndex", Kind: "func", Index: 2}}, r.Exports)
+	assert.Equal(t, []ElementReport{{Table: 0, Offset: "i32.const 0x0", Functions: []uint32{0, 1}}}, r.Elements)
+	assert.Empty(t, r.Datas)
+}
+
+func TestReport_Data(t *testing.T) {
+	d, err := New("../examples/data1.wasm")
+	require.NoError(t, err)
+	r, err := d.Report()
+	require.NoError(t, err)
+
+	assert.Equal(t, []LimitsReport{{Min: 1}}, r.Memories)
+	require.Len(t, r.Datas, 5)
+	assert.Equal(t, DataReport{Memory: 0, Offset: "i32.const 0x64", Size: 3, Data: "636465"}, r.Datas[2])
+}
@@ -24,6 +24,7 @@ const (
 	ELEMENT  SectionCode = 0x9
 	CODE     SectionCode = 0xa
 	DATA     SectionCode = 0xb
+	TAG      SectionCode = 0xd
 )
 
 var (
@@ -56,11 +57,30 @@ func newSectionCode(val uint8) (SectionCode, error) {
 		return CODE, nil
 	case uint8(DATA):
 		return DATA, nil
+	case uint8(TAG):
+		return TAG, nil
 	default:
 		return 0xff, fmt.Errorf("%w: %x", InvalidSectionCode, val)
 	}
 }
 
+// order returns the position where the section appears in a module.
+// Custom sections can appear anywhere.
+// https://webassembly.github.io/spec/core/binary/modules.html#binary-module
+func (code SectionCode) order() int {
+	switch code {
+	case TYPE, IMPORT, FUNCTION, TABLE, MEMORY:
+		return int(code)
+	case TAG:
+		// between the memory section and the global section
+		return int(MEMORY) + 1
+	case GLOBAL, EXPORT, START, ELEMENT, CODE, DATA:
+		return int(code) + 1
+	default:
+		return 0
+	}
+}
+
 func (code SectionCode) String() string {
 	switch code {
 	case CUSTOM:
@@ -87,6 +107,8 @@ func (code SectionCode) String() string {
 		return "Code"
 	case DATA:
 		return "Data"
+	case TAG:
+		return "Tag"
 	default:
 		return ""
 	}
@@ -17,6 +17,9 @@ func newStart(payload []byte) (*start, error) {
 	if err != nil {
 		return nil, fmt.Errorf("NewStart: decode index: %w", err)
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewStart: %w", err)
+	}
 	return &start{
 		index: uint32(index),
 	}, nil
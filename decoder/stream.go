@@ -0,0 +1,149 @@
+package decoder
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+var MissingFunctionBody error = errors.New("function body is missing")
+
+// Stream decodes a module section by section while its bytes are arriving.
+// The sections decoded so far can be built and validated before the rest of the module is read.
+type Stream struct {
+	r       *bufio.Reader
+	mod     *mod
+	offset  int
+	offsets map[SectionCode]int
+	last    SectionCode
+	done    bool
+}
+
+// NewStream reads the header of the module from r.
+func NewStream(r io.Reader, mode Mode) (*Stream, error) {
+	return NewStreamWithFeatures(r, mode, types.FeaturesAll)
+}
+
+// NewStreamWithFeatures reads the header of the module from r and rejects sections using proposals not in the features.
+func NewStreamWithFeatures(r io.Reader, mode Mode, features types.Features) (*Stream, error) {
+	s := &Stream{
+		r:       bufio.NewReader(r),
+		mod:     &mod{lazy: mode == ModeLazy, features: features},
+		offsets: make(map[SectionCode]int),
+		last:    CUSTOM,
+	}
+	header := &bytes.Buffer{}
+	if _, err := io.CopyN(header, s.r, 8); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decode: header: %w", err)
+	}
+	if err := validateMajicNumber(header); err != nil {
+		return nil, fmt.Errorf("decode: majic_number: %w", err)
+	}
+	version, err := decodeVersion(header)
+	if err != nil {
+		return nil, fmt.Errorf("decode: version: %w", err)
+	}
+	s.mod.version = version
+	s.offset = 8
+	return s, nil
+}
+
+// Next reads and decodes the next section and returns its id.
+// It returns io.EOF after the last section.
+func (s *Stream) Next() (SectionCode, error) {
+	if s.done {
+		return 0, io.EOF
+	}
+	offset := s.offset
+	data, err := s.readSection()
+	if err != nil {
+		return 0, fmt.Errorf("decode: at 0x%x: %w", offset, err)
+	}
+	if len(data) == 0 {
+		s.done = true
+		if err := s.checkFunctionCount(); err != nil {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+	s.offset += len(data)
+	sd, err := newSectionDecoder(bytes.NewBuffer(data))
+	if err != nil {
+		return 0, fmt.Errorf("decode: at 0x%x: %w", offset, err)
+	}
+	if sd.id != CUSTOM {
+		if _, ok := s.offsets[sd.id]; ok {
+			return 0, fmt.Errorf("decode: section %d at 0x%x: %w", sd.id, offset, DuplicateSection)
+		}
+		if sd.id.order() < s.last.order() {
+			return 0, fmt.Errorf("decode: section %d at 0x%x: %w: after section %d", sd.id, offset, SectionOutOfOrder, s.last)
+		}
+		s.offsets[sd.id] = offset
+		s.last = sd.id
+	}
+	if err := s.mod.decodeSection(sd); err != nil {
+		if errors.Is(err, io.EOF) {
+			// the payload ends in the middle of an entry, which must not be taken as the end of the module
+			return 0, fmt.Errorf("decode: section %d at 0x%x: %w: %v", sd.id, offset, UnexpectedEnd, err)
+		}
+		return 0, fmt.Errorf("decode: section %d at 0x%x: %w", sd.id, offset, err)
+	}
+	if sd.id == CODE {
+		s.mod.codeOffset = offset + len(data) - len(sd.payloadData)
+	}
+	return sd.id, nil
+}
+
+// readSection reads the bytes of the next section. It returns no bytes at the end of the module.
+// The bytes are truncated when r ends in the section, so that decoding them reports where it ends.
+func (s *Stream) readSection() ([]byte, error) {
+	id, err := s.r.ReadByte()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer([]byte{id})
+	length, _, err := types.DecodeVarUint32(io.TeeReader(s.r, buf))
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, types.Overflow32Error) {
+			return buf.Bytes(), nil
+		}
+		return nil, err
+	}
+	if _, err := io.CopyN(buf, s.r, int64(length)); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Stream) checkFunctionCount() error {
+	functions, bodies := 0, 0
+	if s.mod.function != nil {
+		functions = len(s.mod.function.types)
+	}
+	if s.mod.code != nil {
+		bodies = len(s.mod.code.bodies)
+	}
+	if functions != bodies {
+		return fmt.Errorf("decode: section %d at 0x%x: %w: functions=%d bodies=%d", CODE, s.offsets[CODE], InconsistentFunctionCount, functions, bodies)
+	}
+	return nil
+}
+
+// Module builds the module from the sections decoded so far.
+// Functions whose bodies have not arrived yet are not decoded and fail with MissingFunctionBody when they are decoded.
+func (s *Stream) Module() (*structure.Module, error) {
+	return s.mod.build()
+}
+
+func missingBody() ([]instruction.Instruction, error) {
+	return nil, MissingFunctionBody
+}
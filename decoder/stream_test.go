@@ -0,0 +1,106 @@
+package decoder
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestStream(t *testing.T) {
+	header := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	sections := [][]byte{
+		{0x01, 0x04, 0x01, 0x60, 0x00, 0x00},       // type
+		{0x03, 0x02, 0x01, 0x00},                   // function
+		{0x07, 0x05, 0x01, 0x01, 'f', 0x00, 0x00},  // export
+		{0x0a, 0x05, 0x01, 0x03, 0x00, 0x01, 0x0b}, // code
+	}
+	r, w := io.Pipe()
+	arrived := make(chan struct{})
+	go func() {
+		w.Write(header)
+		for i, s := range sections {
+			if i == len(sections)-1 {
+				// the code section arrives after the other sections are decoded
+				<-arrived
+			}
+			w.Write(s)
+		}
+		w.Close()
+	}()
+
+	s, err := NewStream(r, ModeEager)
+	require.NoError(t, err)
+	for _, id := range []SectionCode{TYPE, FUNCTION, EXPORT} {
+		got, err := s.Next()
+		require.NoError(t, err)
+		assert.Equal(t, id, got)
+	}
+	m, err := s.Module()
+	require.NoError(t, err)
+	require.Len(t, m.Functions, 1)
+	assert.False(t, m.Functions[0].Decoded())
+	_, err = m.Functions[0].Instructions()
+	assert.ErrorIs(t, err, MissingFunctionBody)
+
+	close(arrived)
+	got, err := s.Next()
+	require.NoError(t, err)
+	assert.Equal(t, CODE, got)
+	_, err = s.Next()
+	assert.ErrorIs(t, err, io.EOF)
+	m, err = s.Module()
+	require.NoError(t, err)
+	assert.True(t, m.Functions[0].Decoded())
+	assert.Equal(t, []instruction.Instruction{&instruction.Nop{}, &instruction.End{}}, m.Functions[0].Body)
+}
+
+func TestStream_Malformed(t *testing.T) {
+	// a section split into single bytes is decoded as a whole
+	data := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x04, 0x01, 0x60, 0x00, 0x00, 0x01, 0x05, 0x00}
+	s, err := NewStream(iotest.OneByteReader(bytes.NewReader(data)), ModeEager)
+	require.NoError(t, err)
+	id, err := s.Next()
+	require.NoError(t, err)
+	assert.Equal(t, TYPE, id)
+	_, err = s.Next()
+	assert.ErrorIs(t, err, UnexpectedEnd)
+	assert.Contains(t, err.Error(), "at 0xe")
+
+	// the payload of the type section ends in the last entry
+	data = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x07, 0x02, 0x60, 0x00, 0x00, 0x60, 0x01, 0x7f, 0x03, 0x02, 0x01, 0x00}
+	_, err = decodeBytes(data, ModeEager, types.FeaturesAll)
+	assert.ErrorIs(t, err, UnexpectedEnd)
+	assert.NotErrorIs(t, err, io.EOF)
+}
+
+func TestDecodeBytes_Lazy(t *testing.T) {
+	header := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	data := append(header,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x03, 0x02, 0x00, 0x00,
+		// the second function is not closed
+		0x0a, 0x09, 0x02, 0x03, 0x00, 0x01, 0x0b, 0x03, 0x00, 0x01, 0x01,
+	)
+	_, err := decodeBytes(data, ModeEager, types.FeaturesAll)
+	assert.ErrorIs(t, err, BodySizeMismatch)
+
+	m, err := decodeBytes(data, ModeLazy, types.FeaturesAll)
+	require.NoError(t, err)
+	mod, err := m.build()
+	require.NoError(t, err)
+	require.Len(t, mod.Functions, 2)
+	assert.False(t, mod.Functions[0].Decoded())
+	body, err := mod.Functions[0].Instructions()
+	require.NoError(t, err)
+	assert.Equal(t, []instruction.Instruction{&instruction.Nop{}, &instruction.End{}}, body)
+	assert.True(t, mod.Functions[0].Decoded())
+	assert.False(t, mod.Functions[1].Decoded())
+	_, err = mod.Functions[1].Instructions()
+	assert.ErrorIs(t, err, BodySizeMismatch)
+}
@@ -0,0 +1,552 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+var (
+	InvalidFunctionIndex error = errors.New("invalid function index")
+	RemovedIndexReferred error = errors.New("removed index is referred")
+)
+
+// StripResult is the summary of Strip.
+type StripResult struct {
+	Functions []uint32 // removed functions in the original function index space
+	Imports   []string // removed imports as module.name
+	Types     []uint32 // removed types in the original type index space
+	Before    int      // the size of the module in bytes
+	After     int      // the size of the stripped module in bytes
+}
+
+// indexMap maps the indices of kept entities from the original module to the stripped module.
+type indexMap map[uint32]uint32
+
+func (m indexMap) get(idx uint32) (uint32, error) {
+	n, ok := m[idx]
+	if !ok {
+		return 0, fmt.Errorf("%w: %d", RemovedIndexReferred, idx)
+	}
+	return n, nil
+}
+
+// stripper holds the kept entities of the module being stripped.
+type stripper struct {
+	m       *mod
+	funcs   indexMap
+	globals indexMap
+	types   indexMap
+	imports []bool // kept imports
+}
+
+// Strip removes functions which are not reachable from the exports, the start function and element segments,
+// function and global imports which are not used, and types which are not used, and returns the smaller module.
+// Indices are remapped in all sections. Custom sections are dropped because the name section refers to the original indices.
+func (d *Decoder) Strip() ([]byte, *StripResult, error) {
+	data, err := readWasmFile(d.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Strip: %w", err)
+	}
+	// the raw bytes of function bodies are kept in lazy mode
+	m, err := decodeBytes(data, ModeLazy, d.features)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Strip: %w", err)
+	}
+	sm, err := m.build()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Strip: %w", err)
+	}
+	s, result, err := newStripper(m, sm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Strip: %w", err)
+	}
+	out, err := s.encode()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Strip: %w", err)
+	}
+	result.Before = len(data)
+	result.After = len(out)
+	return out, result, nil
+}
+
+func newStripper(m *mod, sm *structure.Module) (*stripper, *StripResult, error) {
+	// the function index space starts with the imported functions
+	bodies := make([][]instruction.Instruction, 0, len(sm.Functions))
+	typeidx := make([]uint32, 0, len(sm.Functions))
+	for _, imp := range sm.Imports {
+		if imp.Desc.Type == structure.DescTypeFunc {
+			bodies = append(bodies, nil)
+			typeidx = append(typeidx, imp.Desc.Func)
+		}
+	}
+	for _, f := range sm.Functions {
+		if f.Imported {
+			continue
+		}
+		body, err := f.Instructions()
+		if err != nil {
+			return nil, nil, fmt.Errorf("func[%d]: %w", len(bodies), err)
+		}
+		bodies = append(bodies, body)
+		typeidx = append(typeidx, f.Type)
+	}
+
+	// reachable functions
+	roots := make([]uint32, 0)
+	for _, e := range sm.Exports {
+		if e.Desc.Type == structure.DescTypeFunc {
+			roots = append(roots, e.Desc.Val)
+		}
+	}
+	if sm.Start != nil {
+		roots = append(roots, sm.Start.Index)
+	}
+	for _, e := range sm.Elements {
+		// functions in tables can be called indirectly
+		roots = append(roots, e.Init...)
+	}
+	reachable := make([]bool, len(bodies))
+	for len(roots) > 0 {
+		idx := roots[len(roots)-1]
+		roots = roots[:len(roots)-1]
+		if int(idx) >= len(bodies) {
+			return nil, nil, fmt.Errorf("%w: func %d", InvalidFunctionIndex, idx)
+		}
+		if reachable[idx] {
+			continue
+		}
+		reachable[idx] = true
+		for _, instr := range bodies[idx] {
+			if op := instr.Opcode(); op == instruction.CALL || op == instruction.RETURN_CALL {
+				roots = append(roots, instruction.Imm[uint32](instr))
+			}
+		}
+	}
+
+	// used globals and types
+	globals := make(map[uint32]bool)
+	usedTypes := make(map[uint32]bool)
+	exprs := make([]instruction.Instruction, 0)
+	for _, g := range sm.Globals {
+		exprs = append(exprs, g.Init)
+	}
+	for _, e := range sm.Elements {
+		exprs = append(exprs, e.Offset)
+	}
+	for _, d := range sm.Datas {
+		exprs = append(exprs, d.Offset)
+	}
+	for idx, body := range bodies {
+		if !reachable[idx] {
+			continue
+		}
+		usedTypes[typeidx[idx]] = true
+		exprs = append(exprs, body...)
+	}
+	for _, instr := range exprs {
+		switch instr.Opcode() {
+		case instruction.GET_GLOBAL, instruction.SET_GLOBAL:
+			globals[instruction.Imm[uint32](instr)] = true
+		case instruction.CALL_INDIRECT, instruction.RETURN_CALL_INDIRECT:
+			usedTypes[instruction.Imm[instruction.CallIndirectImm](instr).TypeIndex] = true
+		case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+			if idx, ok := blockTypeIndex(instruction.Imm[types.BlockType](instr)); ok {
+				usedTypes[idx] = true
+			}
+		}
+	}
+	for _, e := range sm.Exports {
+		if e.Desc.Type == structure.DescTypeGlobal {
+			globals[e.Desc.Val] = true
+		}
+	}
+	for _, t := range sm.Tags {
+		usedTypes[t.Type] = true
+	}
+
+	s := &stripper{
+		m:       m,
+		funcs:   make(indexMap),
+		globals: make(indexMap),
+		types:   make(indexMap),
+		imports: make([]bool, len(sm.Imports)),
+	}
+	result := &StripResult{Functions: []uint32{}, Imports: []string{}, Types: []uint32{}}
+	funcidx, globalidx := uint32(0), uint32(0)
+	for i, imp := range sm.Imports {
+		keep := true
+		switch imp.Desc.Type {
+		case structure.DescTypeFunc:
+			keep = reachable[funcidx]
+			if keep {
+				s.funcs[funcidx] = uint32(len(s.funcs))
+			} else {
+				result.Functions = append(result.Functions, funcidx)
+			}
+			funcidx++
+		case structure.DescTypeGlobal:
+			keep = globals[globalidx]
+			if keep {
+				s.globals[globalidx] = uint32(len(s.globals))
+			}
+			globalidx++
+		case structure.DescTypeTag:
+			usedTypes[imp.Desc.Tag] = true
+		}
+		s.imports[i] = keep
+		if !keep {
+			result.Imports = append(result.Imports, imp.Module+"."+imp.Name)
+		}
+	}
+	for ; int(funcidx) < len(bodies); funcidx++ {
+		if reachable[funcidx] {
+			s.funcs[funcidx] = uint32(len(s.funcs))
+		} else {
+			result.Functions = append(result.Functions, funcidx)
+		}
+	}
+	for i := range sm.Globals {
+		s.globals[globalidx+uint32(i)] = uint32(len(s.globals))
+	}
+	for i := range sm.Types {
+		if usedTypes[uint32(i)] {
+			s.types[uint32(i)] = uint32(len(s.types))
+		} else {
+			result.Types = append(result.Types, uint32(i))
+		}
+	}
+	return s, result, nil
+}
+
+// instructions re-encodes the instructions replacing the indices of functions, globals and types.
+// Other instructions are copied as they are.
+func (s *stripper) instructions(raw []byte) ([]byte, error) {
+	e := &encoder{}
+	buf := bytes.NewBuffer(raw)
+	for buf.Len() > 0 {
+		pos := len(raw) - buf.Len()
+		instr, err := instruction.Decode(buf)
+		if err != nil {
+			return nil, err
+		}
+		switch op := instr.Opcode(); op {
+		case instruction.CALL, instruction.RETURN_CALL:
+			idx, err := s.funcs.get(instruction.Imm[uint32](instr))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", instr, err)
+			}
+			e.byte(byte(op))
+			e.u32(idx)
+		case instruction.GET_GLOBAL, instruction.SET_GLOBAL:
+			idx, err := s.globals.get(instruction.Imm[uint32](instr))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", instr, err)
+			}
+			e.byte(byte(op))
+			e.u32(idx)
+		case instruction.CALL_INDIRECT, instruction.RETURN_CALL_INDIRECT:
+			imm := instruction.Imm[instruction.CallIndirectImm](instr)
+			idx, err := s.types.get(imm.TypeIndex)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", instr, err)
+			}
+			e.byte(byte(op))
+			e.u32(idx)
+			e.u32(imm.TableIndex)
+		case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+			bt, ok := blockTypeIndex(instruction.Imm[types.BlockType](instr))
+			if !ok {
+				e.bytes(raw[pos : len(raw)-buf.Len()])
+				continue
+			}
+			idx, err := s.types.get(bt)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", instr, err)
+			}
+			e.byte(byte(op))
+			e.u32(idx)
+		default:
+			e.bytes(raw[pos : len(raw)-buf.Len()])
+		}
+	}
+	return e.buf.Bytes(), nil
+}
+
+// blockTypeIndex returns the type index of the block type of multiple values.
+func blockTypeIndex(bt types.BlockType) (uint32, bool) {
+	if types.ValueType(bt) < types.EMPTY {
+		return uint32(bt), true
+	}
+	return 0, false
+}
+
+// expr writes the constant expression with the end.
+func (s *stripper) expr(e *encoder, raw []byte) error {
+	b, err := s.instructions(raw)
+	if err != nil {
+		return err
+	}
+	e.bytes(b)
+	e.byte(END)
+	return nil
+}
+
+func (s *stripper) encode() ([]byte, error) {
+	m := s.m
+	e := &encoder{}
+	e.bytes([]byte{0x00, 0x61, 0x73, 0x6d})
+	version := make([]byte, 4)
+	binary.LittleEndian.PutUint32(version, m.version)
+	e.bytes(version)
+	if m.typ != nil {
+		if err := e.section(TYPE, func(p *encoder) error {
+			p.u32(uint32(len(s.types)))
+			for i, ft := range m.typ.entries {
+				if _, ok := s.types[uint32(i)]; ok {
+					p.funcType(ft)
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if m.imports != nil {
+		if err := e.section(IMPORT, s.importSection); err != nil {
+			return nil, err
+		}
+	}
+	if m.function != nil {
+		if err := e.section(FUNCTION, s.functionSection); err != nil {
+			return nil, err
+		}
+	}
+	if m.table != nil {
+		if err := e.section(TABLE, func(p *encoder) error {
+			p.u32(uint32(len(m.table.entries)))
+			for _, t := range m.table.entries {
+				p.tableType(t)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if m.memory != nil {
+		if err := e.section(MEMORY, func(p *encoder) error {
+			p.u32(uint32(len(m.memory.entries)))
+			for _, t := range m.memory.entries {
+				p.memoryType(t)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if m.tag != nil {
+		if err := e.section(TAG, func(p *encoder) error {
+			p.u32(uint32(len(m.tag.entries)))
+			for _, t := range m.tag.entries {
+				idx, err := s.types.get(t)
+				if err != nil {
+					return fmt.Errorf("tag: %w", err)
+				}
+				p.byte(0x00)
+				p.u32(idx)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if m.global != nil {
+		if err := e.section(GLOBAL, func(p *encoder) error {
+			p.u32(uint32(len(m.global.globals)))
+			for i, g := range m.global.globals {
+				p.globalType(g.typ)
+				if err := s.expr(p, g.init); err != nil {
+					return fmt.Errorf("global[%d]: %w", i, err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if m.export != nil {
+		if err := e.section(EXPORT, s.exportSection); err != nil {
+			return nil, err
+		}
+	}
+	if m.start != nil {
+		if err := e.section(START, func(p *encoder) error {
+			idx, err := s.funcs.get(m.start.index)
+			if err != nil {
+				return fmt.Errorf("start: %w", err)
+			}
+			p.u32(idx)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if m.element != nil {
+		if err := e.section(ELEMENT, s.elementSection); err != nil {
+			return nil, err
+		}
+	}
+	if m.code != nil {
+		if err := e.section(CODE, s.codeSection); err != nil {
+			return nil, err
+		}
+	}
+	if m.data != nil {
+		if err := e.section(DATA, func(p *encoder) error {
+			p.u32(uint32(len(m.data.entries)))
+			for i, d := range m.data.entries {
+				p.u32(d.index)
+				if err := s.expr(p, d.offset); err != nil {
+					return fmt.Errorf("data[%d]: %w", i, err)
+				}
+				p.name(d.data)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return e.buf.Bytes(), nil
+}
+
+func (s *stripper) importSection(p *encoder) error {
+	entries := make([]*importEntry, 0, len(s.m.imports.entries))
+	for i, imp := range s.m.imports.entries {
+		if s.imports[i] {
+			entries = append(entries, imp)
+		}
+	}
+	p.u32(uint32(len(entries)))
+	for _, imp := range entries {
+		p.name(imp.moduleName)
+		p.name(imp.fieldString)
+		p.byte(byte(imp.kind))
+		switch imp.kind {
+		case types.EXTERNAL_KIND_FUNCTION:
+			idx, err := s.types.get(uint32(imp.typ.(types.VarUint32)))
+			if err != nil {
+				return fmt.Errorf("import %s.%s: %w", imp.moduleName, imp.fieldString, err)
+			}
+			p.u32(idx)
+		case types.EXTERNAL_KIND_TABLE:
+			p.tableType(imp.typ.(*types.TableType))
+		case types.EXTERNAL_KIND_MEMORY:
+			p.memoryType(imp.typ.(*types.MemoryType))
+		case types.EXTERNAL_KIND_GLOBAL:
+			p.globalType(imp.typ.(*types.GlobalType))
+		case types.EXTERNAL_KIND_TAG:
+			idx, err := s.types.get(imp.typ.(uint32))
+			if err != nil {
+				return fmt.Errorf("import %s.%s: %w", imp.moduleName, imp.fieldString, err)
+			}
+			p.byte(0x00)
+			p.u32(idx)
+		}
+	}
+	return nil
+}
+
+// definedFunction returns the index of the i-th defined function in the function index space.
+func (s *stripper) definedFunction(i int) uint32 {
+	return uint32(s.m.importedFunctions() + i)
+}
+
+func (s *stripper) functionSection(p *encoder) error {
+	typeidx := make([]uint32, 0, len(s.m.function.types))
+	for i, t := range s.m.function.types {
+		if _, ok := s.funcs[s.definedFunction(i)]; !ok {
+			continue
+		}
+		idx, err := s.types.get(t)
+		if err != nil {
+			return fmt.Errorf("func[%d]: %w", s.definedFunction(i), err)
+		}
+		typeidx = append(typeidx, idx)
+	}
+	p.u32(uint32(len(typeidx)))
+	for _, t := range typeidx {
+		p.u32(t)
+	}
+	return nil
+}
+
+func (s *stripper) exportSection(p *encoder) error {
+	p.u32(uint32(len(s.m.export.entries)))
+	for _, exp := range s.m.export.entries {
+		idx := exp.index
+		var err error
+		switch exp.kind {
+		case types.EXTERNAL_KIND_FUNCTION:
+			idx, err = s.funcs.get(exp.index)
+		case types.EXTERNAL_KIND_GLOBAL:
+			idx, err = s.globals.get(exp.index)
+		}
+		if err != nil {
+			return fmt.Errorf("export %s: %w", exp.fieldString, err)
+		}
+		p.name(exp.fieldString)
+		p.byte(byte(exp.kind))
+		p.u32(idx)
+	}
+	return nil
+}
+
+func (s *stripper) elementSection(p *encoder) error {
+	p.u32(uint32(len(s.m.element.entries)))
+	for i, elem := range s.m.element.entries {
+		p.u32(elem.index)
+		if err := s.expr(p, elem.offset); err != nil {
+			return fmt.Errorf("element[%d]: %w", i, err)
+		}
+		p.u32(uint32(len(elem.elems)))
+		for _, f := range elem.elems {
+			idx, err := s.funcs.get(f)
+			if err != nil {
+				return fmt.Errorf("element[%d]: %w", i, err)
+			}
+			p.u32(idx)
+		}
+	}
+	return nil
+}
+
+func (s *stripper) codeSection(p *encoder) error {
+	bodies := make([][]byte, 0, len(s.m.code.bodies))
+	for i, body := range s.m.code.bodies {
+		if _, ok := s.funcs[s.definedFunction(i)]; !ok {
+			continue
+		}
+		b := &encoder{}
+		b.u32(uint32(len(body.locals)))
+		for _, l := range body.locals {
+			b.u32(l.count)
+			b.byte(byte(l.typ))
+		}
+		instrs, err := s.instructions(body.raw)
+		if err != nil {
+			return fmt.Errorf("func[%d]: %w", s.definedFunction(i), err)
+		}
+		b.bytes(instrs)
+		bodies = append(bodies, b.buf.Bytes())
+	}
+	p.u32(uint32(len(bodies)))
+	for _, b := range bodies {
+		p.name(b)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package decoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestStrip(t *testing.T) {
+	data := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	for _, s := range [][]byte{
+		// type: () -> (), (i32) -> (i32), () -> (i32)
+		{0x01, 0x0d, 0x03, 0x60, 0x00, 0x00, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x00, 0x01, 0x7f},
+		// import: env.unused (func 1), env.g (global i32)
+		{0x02, 0x17, 0x02, 0x03, 'e', 'n', 'v', 0x06, 'u', 'n', 'u', 's', 'e', 'd', 0x00, 0x01, 0x03, 'e', 'n', 'v', 0x01, 'g', 0x03, 0x7f, 0x00},
+		// function: func[1] type 0, func[2] type 2, func[3] type 2
+		{0x03, 0x04, 0x03, 0x00, 0x02, 0x02},
+		// export: main (func 2)
+		{0x07, 0x08, 0x01, 0x04, 'm', 'a', 'i', 'n', 0x00, 0x02},
+		// code
+		{0x0a, 0x16, 0x03,
+			0x04, 0x00, 0x10, 0x00, 0x0b, // call 0
+			0x0a, 0x00, 0x02, 0x02, 0x10, 0x03, 0x0b, 0x23, 0x00, 0x6a, 0x0b, // block (type 2) call 3 end global.get 0 i32.add
+			0x04, 0x00, 0x41, 0x07, 0x0b, // i32.const 7
+		},
+		// custom: name
+		{0x00, 0x05, 0x04, 'n', 'a', 'm', 'e'},
+	} {
+		data = append(data, s...)
+	}
+	path := filepath.Join(t.TempDir(), "strip.wasm")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	d, err := New(path)
+	require.NoError(t, err)
+
+	out, result, err := d.Strip()
+	require.NoError(t, err)
+	assert.Equal(t, &StripResult{
+		Functions: []uint32{0, 1},
+		Imports:   []string{"env.unused"},
+		Types:     []uint32{0, 1},
+		Before:    len(data),
+		After:     len(out),
+	}, result)
+	assert.Less(t, len(out), len(data))
+
+	m, err := decodeBytes(out, ModeEager, types.FeaturesAll)
+	require.NoError(t, err)
+	assert.Nil(t, m.custom)
+	sm, err := m.build()
+	require.NoError(t, err)
+	assert.Equal(t, []*types.FuncType{{Params: []types.ValueType{}, Returns: []types.ValueType{types.I32}}}, sm.Types)
+	assert.Equal(t, []*structure.Import{{Module: "env", Name: "g", Desc: &structure.ImportDesc{Type: structure.DescTypeGlobal, Global: &types.GlobalType{ContentType: types.I32}}}}, sm.Imports)
+	assert.Equal(t, []*structure.Export{{Name: "main", Desc: &structure.ExportDesc{Type: structure.DescTypeFunc, Val: 0}}}, sm.Exports)
+	require.Len(t, sm.Functions, 2)
+	assert.Equal(t, []instruction.Instruction{
+		&instruction.Block{Imm: types.BlockType(0)},
+		&instruction.Call{Imm: 1},
+		&instruction.End{},
+		&instruction.GetGlobal{Imm: 0},
+		&instruction.I32Add{},
+		&instruction.End{},
+	}, sm.Functions[0].Body)
+	assert.Equal(t, []instruction.Instruction{&instruction.I32Const{Imm: 7}, &instruction.End{}}, sm.Functions[1].Body)
+}
+
+func TestStrip_Examples(t *testing.T) {
+	for _, file := range []string{
+		"../examples/block.wasm",
+		"../examples/loop.wasm",
+		"../examples/start1.wasm",
+		"../examples/table1.wasm",
+		"../examples/tail_call.wasm",
+	} {
+		d, err := New(file)
+		require.NoError(t, err)
+		out, _, err := d.Strip()
+		require.NoError(t, err, file)
+		orig, err := readWasmFile(file)
+		require.NoError(t, err)
+		// nothing is removed
+		assert.Equal(t, orig, out, file)
+	}
+}
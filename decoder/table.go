@@ -26,6 +26,9 @@ func newTable(payload []byte) (*table, error) {
 		}
 		entries = append(entries, entry)
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewTable: %w", err)
+	}
 	return &table{
 		entries: entries,
 	}, nil
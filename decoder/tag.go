@@ -0,0 +1,61 @@
+package decoder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/types"
+)
+
+var InvalidTagAttribute error = errors.New("Invalid tag attribute")
+
+// tag is the tag section of the exception handling proposal.
+// https://webassembly.github.io/exception-handling/core/binary/modules.html#tag-section
+type tag struct {
+	entries []uint32 // typeidx
+}
+
+// decodeTagType decodes a tag type, the exception attribute followed by a type index.
+func decodeTagType(buf *bytes.Buffer) (uint32, error) {
+	attr, err := buf.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("decode attribute: %w", err)
+	}
+	if attr != 0x00 {
+		return 0, fmt.Errorf("%w: %x", InvalidTagAttribute, attr)
+	}
+	typ, _, err := types.DecodeVarUint32(buf)
+	if err != nil {
+		return 0, fmt.Errorf("decode type: %w", err)
+	}
+	return uint32(typ), nil
+}
+
+func newTag(payload []byte) (*tag, error) {
+	buf := bytes.NewBuffer(payload)
+	count, _, err := types.DecodeVarUint32(buf)
+	if err != nil {
+		return nil, fmt.Errorf("NewTag: decode count: %w", err)
+	}
+	entries := make([]uint32, 0, int(count))
+	for i := 0; i < int(count); i++ {
+		typ, err := decodeTagType(buf)
+		if err != nil {
+			return nil, fmt.Errorf("NewTag: %w", err)
+		}
+		entries = append(entries, typ)
+	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewTag: %w", err)
+	}
+	return &tag{entries: entries}, nil
+}
+
+func (t *tag) detail() string {
+	str := fmt.Sprintf("Tag[%d]:\n", len(t.entries))
+	for i, typ := range t.entries {
+		str += fmt.Sprintf(" - tag[%d] type=%d\n", i, typ)
+	}
+	return str
+}
@@ -0,0 +1,29 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTag(t *testing.T) {
+	for _, d := range []struct {
+		payload []byte
+		sec     *tag
+		err     bool
+	}{
+		{payload: []byte{0x01, 0x00, 0x01}, sec: &tag{entries: []uint32{1}}},
+		{payload: []byte{0x02, 0x00, 0x00, 0x00, 0x02}, sec: &tag{entries: []uint32{0, 2}}},
+		{payload: []byte{0x00}, sec: &tag{entries: []uint32{}}},
+		{payload: []byte{0x01, 0x01, 0x00}, err: true},
+	} {
+		s, err := newTag(d.payload)
+		if d.err {
+			assert.ErrorIs(t, err, InvalidTagAttribute)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, d.sec, s)
+	}
+}
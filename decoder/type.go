@@ -38,6 +38,9 @@ func newType(payload []byte) (*typ, error) {
 		buf.Next(read)
 		entries = append(entries, f)
 	}
+	if err := checkConsumed(buf); err != nil {
+		return nil, fmt.Errorf("NewType: %w", err)
+	}
 	return &typ{
 		entries: entries,
 	}, nil
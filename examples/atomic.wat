@@ -0,0 +1,36 @@
+(module
+  (import "env" "memory" (memory 1 1 shared))
+  ;; adds 1 to the counter at 0 n times
+  (func (export "increment") (param $n i32)
+    (block
+      (loop
+        get_local $n
+        i32.eqz
+        br_if 1
+        i32.const 0
+        i32.const 1
+        i32.atomic.rmw.add
+        drop
+        get_local $n
+        i32.const 1
+        i32.sub
+        set_local $n
+        br 0)))
+  (func (export "load") (result i32)
+    i32.const 0
+    i32.atomic.load)
+  ;; waits while the flag at 4 is $expected
+  (func (export "wait") (param $expected i32) (result i32)
+    i32.const 4
+    get_local $expected
+    i64.const -1
+    memory.atomic.wait32)
+  ;; sets the flag at 4 and wakes a waiter
+  (func (export "notify") (result i32)
+    i32.const 4
+    i32.const 1
+    i32.atomic.store
+    i32.const 4
+    i32.const 1
+    memory.atomic.notify)
+)
@@ -0,0 +1,52 @@
+(module
+  (tag $e (param i32))
+  (tag $other)
+  (func $thrower (param $v i32)
+    get_local $v
+    throw $e)
+  ;; catches the exception thrown by the callee and returns its value + 1
+  (func (export "catch") (param $v i32) (result i32)
+    (try (result i32)
+      (do
+        get_local $v
+        call $thrower
+        i32.const 0)
+      (catch $e
+        i32.const 1
+        i32.add)))
+  ;; $other is not caught by catch $e
+  (func (export "catch_all") (param $v i32) (result i32)
+    (try (result i32)
+      (do
+        throw $other)
+      (catch $e)
+      (catch_all
+        i32.const 42)))
+  ;; the exception is thrown to the host
+  (func (export "rethrow") (param $v i32)
+    (try
+      (do
+        get_local $v
+        call $thrower)
+      (catch $e
+        drop
+        rethrow 0)))
+  ;; the inner try delegates the exception to the outer try
+  (func (export "delegate") (param $v i32) (result i32)
+    (try (result i32)
+      (do
+        (try
+          (do
+            get_local $v
+            call $thrower)
+          (delegate 0))
+        i32.const 0)
+      (catch $e)))
+  (func (export "no_throw") (result i32)
+    (try (result i32)
+      (do
+        i32.const 7)
+      (catch_all
+        i32.const 0)))
+  (export "tag" (tag $e))
+)
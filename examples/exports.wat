@@ -0,0 +1,11 @@
+(module
+  (type $t (func (param i32) (result i32)))
+  (func $double (export "double") (type $t)
+    get_local 0
+    get_local 0
+    i32.add)
+  (table (export "table") 1 2 funcref)
+  (memory (export "memory") 1)
+  (global (export "counter") (mut i32) (i32.const 0))
+  (global (export "limit") i64 (i64.const 100))
+)
@@ -0,0 +1,4 @@
+(module
+  (func (export "f32_id") (param f32) (result f32) (local.get 0))
+  (func (export "f64_id") (param f64) (result f64) (local.get 0))
+)
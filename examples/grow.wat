@@ -0,0 +1,8 @@
+(module
+  (memory 1 3)
+  (func (export "size") (result i32)
+    current_memory)
+  (func (export "grow") (param $delta i32) (result i32)
+    get_local $delta
+    grow_memory)
+)
@@ -0,0 +1,17 @@
+(module
+  (func (export "i32.extend8_s") (param i32) (result i32)
+    get_local 0
+    i32.extend8_s)
+  (func (export "i32.extend16_s") (param i32) (result i32)
+    get_local 0
+    i32.extend16_s)
+  (func (export "i64.extend8_s") (param i64) (result i64)
+    get_local 0
+    i64.extend8_s)
+  (func (export "i64.extend16_s") (param i64) (result i64)
+    get_local 0
+    i64.extend16_s)
+  (func (export "i64.extend32_s") (param i64) (result i64)
+    get_local 0
+    i64.extend32_s)
+)
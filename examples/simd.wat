@@ -0,0 +1,26 @@
+(module
+  (memory 1)
+  (data (i32.const 0) "\01\00\00\00\02\00\00\00\03\00\00\00\04\00\00\00")
+  (func (export "add") (param $a i32) (param $b i32) (result i32)
+    get_local $a
+    i32x4.splat
+    get_local $b
+    i32x4.splat
+    i32x4.add
+    i32x4.extract_lane 3)
+  ;; sum of the four i32 values at $p
+  (func (export "sum") (param $p i32) (result i32) (local $v v128)
+    get_local $p
+    v128.load
+    tee_local $v
+    get_local $v
+    get_local $v
+    i8x16.shuffle 8 9 10 11 12 13 14 15 0 1 2 3 4 5 6 7
+    i32x4.add
+    tee_local $v
+    get_local $v
+    get_local $v
+    i8x16.shuffle 4 5 6 7 0 1 2 3 12 13 14 15 8 9 10 11
+    i32x4.add
+    i32x4.extract_lane 0)
+)
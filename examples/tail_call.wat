@@ -0,0 +1,30 @@
+(module
+  (type $t (func (param i32) (result i32)))
+  (table 2 funcref)
+  (elem (i32.const 0) $is_even $is_odd)
+  ;; the recursion is deeper than the frame stack limit
+  (func $is_even (export "is_even") (param $n i32) (result i32)
+    get_local $n
+    i32.eqz
+    if (result i32)
+      i32.const 1
+    else
+      get_local $n
+      i32.const 1
+      i32.sub
+      return_call $is_odd
+    end)
+  ;; calls $is_even through the table
+  (func $is_odd (export "is_odd") (param $n i32) (result i32)
+    get_local $n
+    i32.eqz
+    if (result i32)
+      i32.const 0
+    else
+      get_local $n
+      i32.const 1
+      i32.sub
+      i32.const 0
+      return_call_indirect (type $t)
+    end)
+)
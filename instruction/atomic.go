@@ -0,0 +1,210 @@
+package instruction
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/terassyi/gowi/types"
+)
+
+// AtomicOpcode is the opcode of an atomic instruction following the prefix 0xfe.
+// https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+type AtomicOpcode uint32
+
+const (
+	MEMORY_ATOMIC_NOTIFY       AtomicOpcode = 0x00
+	MEMORY_ATOMIC_WAIT32       AtomicOpcode = 0x01
+	MEMORY_ATOMIC_WAIT64       AtomicOpcode = 0x02
+	ATOMIC_FENCE               AtomicOpcode = 0x03
+	I32_ATOMIC_LOAD            AtomicOpcode = 0x10
+	I64_ATOMIC_LOAD            AtomicOpcode = 0x11
+	I32_ATOMIC_LOAD8_U         AtomicOpcode = 0x12
+	I32_ATOMIC_LOAD16_U        AtomicOpcode = 0x13
+	I64_ATOMIC_LOAD8_U         AtomicOpcode = 0x14
+	I64_ATOMIC_LOAD16_U        AtomicOpcode = 0x15
+	I64_ATOMIC_LOAD32_U        AtomicOpcode = 0x16
+	I32_ATOMIC_STORE           AtomicOpcode = 0x17
+	I64_ATOMIC_STORE           AtomicOpcode = 0x18
+	I32_ATOMIC_STORE8          AtomicOpcode = 0x19
+	I32_ATOMIC_STORE16         AtomicOpcode = 0x1a
+	I64_ATOMIC_STORE8          AtomicOpcode = 0x1b
+	I64_ATOMIC_STORE16         AtomicOpcode = 0x1c
+	I64_ATOMIC_STORE32         AtomicOpcode = 0x1d
+	I32_ATOMIC_RMW_ADD         AtomicOpcode = 0x1e
+	I64_ATOMIC_RMW_ADD         AtomicOpcode = 0x1f
+	I32_ATOMIC_RMW8_ADD_U      AtomicOpcode = 0x20
+	I32_ATOMIC_RMW16_ADD_U     AtomicOpcode = 0x21
+	I64_ATOMIC_RMW8_ADD_U      AtomicOpcode = 0x22
+	I64_ATOMIC_RMW16_ADD_U     AtomicOpcode = 0x23
+	I64_ATOMIC_RMW32_ADD_U     AtomicOpcode = 0x24
+	I32_ATOMIC_RMW_SUB         AtomicOpcode = 0x25
+	I64_ATOMIC_RMW_SUB         AtomicOpcode = 0x26
+	I32_ATOMIC_RMW8_SUB_U      AtomicOpcode = 0x27
+	I32_ATOMIC_RMW16_SUB_U     AtomicOpcode = 0x28
+	I64_ATOMIC_RMW8_SUB_U      AtomicOpcode = 0x29
+	I64_ATOMIC_RMW16_SUB_U     AtomicOpcode = 0x2a
+	I64_ATOMIC_RMW32_SUB_U     AtomicOpcode = 0x2b
+	I32_ATOMIC_RMW_AND         AtomicOpcode = 0x2c
+	I64_ATOMIC_RMW_AND         AtomicOpcode = 0x2d
+	I32_ATOMIC_RMW8_AND_U      AtomicOpcode = 0x2e
+	I32_ATOMIC_RMW16_AND_U     AtomicOpcode = 0x2f
+	I64_ATOMIC_RMW8_AND_U      AtomicOpcode = 0x30
+	I64_ATOMIC_RMW16_AND_U     AtomicOpcode = 0x31
+	I64_ATOMIC_RMW32_AND_U     AtomicOpcode = 0x32
+	I32_ATOMIC_RMW_OR          AtomicOpcode = 0x33
+	I64_ATOMIC_RMW_OR          AtomicOpcode = 0x34
+	I32_ATOMIC_RMW8_OR_U       AtomicOpcode = 0x35
+	I32_ATOMIC_RMW16_OR_U      AtomicOpcode = 0x36
+	I64_ATOMIC_RMW8_OR_U       AtomicOpcode = 0x37
+	I64_ATOMIC_RMW16_OR_U      AtomicOpcode = 0x38
+	I64_ATOMIC_RMW32_OR_U      AtomicOpcode = 0x39
+	I32_ATOMIC_RMW_XOR         AtomicOpcode = 0x3a
+	I64_ATOMIC_RMW_XOR         AtomicOpcode = 0x3b
+	I32_ATOMIC_RMW8_XOR_U      AtomicOpcode = 0x3c
+	I32_ATOMIC_RMW16_XOR_U     AtomicOpcode = 0x3d
+	I64_ATOMIC_RMW8_XOR_U      AtomicOpcode = 0x3e
+	I64_ATOMIC_RMW16_XOR_U     AtomicOpcode = 0x3f
+	I64_ATOMIC_RMW32_XOR_U     AtomicOpcode = 0x40
+	I32_ATOMIC_RMW_XCHG        AtomicOpcode = 0x41
+	I64_ATOMIC_RMW_XCHG        AtomicOpcode = 0x42
+	I32_ATOMIC_RMW8_XCHG_U     AtomicOpcode = 0x43
+	I32_ATOMIC_RMW16_XCHG_U    AtomicOpcode = 0x44
+	I64_ATOMIC_RMW8_XCHG_U     AtomicOpcode = 0x45
+	I64_ATOMIC_RMW16_XCHG_U    AtomicOpcode = 0x46
+	I64_ATOMIC_RMW32_XCHG_U    AtomicOpcode = 0x47
+	I32_ATOMIC_RMW_CMPXCHG     AtomicOpcode = 0x48
+	I64_ATOMIC_RMW_CMPXCHG     AtomicOpcode = 0x49
+	I32_ATOMIC_RMW8_CMPXCHG_U  AtomicOpcode = 0x4a
+	I32_ATOMIC_RMW16_CMPXCHG_U AtomicOpcode = 0x4b
+	I64_ATOMIC_RMW8_CMPXCHG_U  AtomicOpcode = 0x4c
+	I64_ATOMIC_RMW16_CMPXCHG_U AtomicOpcode = 0x4d
+	I64_ATOMIC_RMW32_CMPXCHG_U AtomicOpcode = 0x4e
+)
+
+var atomicOpcodeNames = map[AtomicOpcode]string{
+	MEMORY_ATOMIC_NOTIFY:       "memory.atomic.notify",
+	MEMORY_ATOMIC_WAIT32:       "memory.atomic.wait32",
+	MEMORY_ATOMIC_WAIT64:       "memory.atomic.wait64",
+	ATOMIC_FENCE:               "atomic.fence",
+	I32_ATOMIC_LOAD:            "i32.atomic.load",
+	I64_ATOMIC_LOAD:            "i64.atomic.load",
+	I32_ATOMIC_LOAD8_U:         "i32.atomic.load8_u",
+	I32_ATOMIC_LOAD16_U:        "i32.atomic.load16_u",
+	I64_ATOMIC_LOAD8_U:         "i64.atomic.load8_u",
+	I64_ATOMIC_LOAD16_U:        "i64.atomic.load16_u",
+	I64_ATOMIC_LOAD32_U:        "i64.atomic.load32_u",
+	I32_ATOMIC_STORE:           "i32.atomic.store",
+	I64_ATOMIC_STORE:           "i64.atomic.store",
+	I32_ATOMIC_STORE8:          "i32.atomic.store8",
+	I32_ATOMIC_STORE16:         "i32.atomic.store16",
+	I64_ATOMIC_STORE8:          "i64.atomic.store8",
+	I64_ATOMIC_STORE16:         "i64.atomic.store16",
+	I64_ATOMIC_STORE32:         "i64.atomic.store32",
+	I32_ATOMIC_RMW_ADD:         "i32.atomic.rmw.add",
+	I64_ATOMIC_RMW_ADD:         "i64.atomic.rmw.add",
+	I32_ATOMIC_RMW8_ADD_U:      "i32.atomic.rmw8.add_u",
+	I32_ATOMIC_RMW16_ADD_U:     "i32.atomic.rmw16.add_u",
+	I64_ATOMIC_RMW8_ADD_U:      "i64.atomic.rmw8.add_u",
+	I64_ATOMIC_RMW16_ADD_U:     "i64.atomic.rmw16.add_u",
+	I64_ATOMIC_RMW32_ADD_U:     "i64.atomic.rmw32.add_u",
+	I32_ATOMIC_RMW_SUB:         "i32.atomic.rmw.sub",
+	I64_ATOMIC_RMW_SUB:         "i64.atomic.rmw.sub",
+	I32_ATOMIC_RMW8_SUB_U:      "i32.atomic.rmw8.sub_u",
+	I32_ATOMIC_RMW16_SUB_U:     "i32.atomic.rmw16.sub_u",
+	I64_ATOMIC_RMW8_SUB_U:      "i64.atomic.rmw8.sub_u",
+	I64_ATOMIC_RMW16_SUB_U:     "i64.atomic.rmw16.sub_u",
+	I64_ATOMIC_RMW32_SUB_U:     "i64.atomic.rmw32.sub_u",
+	I32_ATOMIC_RMW_AND:         "i32.atomic.rmw.and",
+	I64_ATOMIC_RMW_AND:         "i64.atomic.rmw.and",
+	I32_ATOMIC_RMW8_AND_U:      "i32.atomic.rmw8.and_u",
+	I32_ATOMIC_RMW16_AND_U:     "i32.atomic.rmw16.and_u",
+	I64_ATOMIC_RMW8_AND_U:      "i64.atomic.rmw8.and_u",
+	I64_ATOMIC_RMW16_AND_U:     "i64.atomic.rmw16.and_u",
+	I64_ATOMIC_RMW32_AND_U:     "i64.atomic.rmw32.and_u",
+	I32_ATOMIC_RMW_OR:          "i32.atomic.rmw.or",
+	I64_ATOMIC_RMW_OR:          "i64.atomic.rmw.or",
+	I32_ATOMIC_RMW8_OR_U:       "i32.atomic.rmw8.or_u",
+	I32_ATOMIC_RMW16_OR_U:      "i32.atomic.rmw16.or_u",
+	I64_ATOMIC_RMW8_OR_U:       "i64.atomic.rmw8.or_u",
+	I64_ATOMIC_RMW16_OR_U:      "i64.atomic.rmw16.or_u",
+	I64_ATOMIC_RMW32_OR_U:      "i64.atomic.rmw32.or_u",
+	I32_ATOMIC_RMW_XOR:         "i32.atomic.rmw.xor",
+	I64_ATOMIC_RMW_XOR:         "i64.atomic.rmw.xor",
+	I32_ATOMIC_RMW8_XOR_U:      "i32.atomic.rmw8.xor_u",
+	I32_ATOMIC_RMW16_XOR_U:     "i32.atomic.rmw16.xor_u",
+	I64_ATOMIC_RMW8_XOR_U:      "i64.atomic.rmw8.xor_u",
+	I64_ATOMIC_RMW16_XOR_U:     "i64.atomic.rmw16.xor_u",
+	I64_ATOMIC_RMW32_XOR_U:     "i64.atomic.rmw32.xor_u",
+	I32_ATOMIC_RMW_XCHG:        "i32.atomic.rmw.xchg",
+	I64_ATOMIC_RMW_XCHG:        "i64.atomic.rmw.xchg",
+	I32_ATOMIC_RMW8_XCHG_U:     "i32.atomic.rmw8.xchg_u",
+	I32_ATOMIC_RMW16_XCHG_U:    "i32.atomic.rmw16.xchg_u",
+	I64_ATOMIC_RMW8_XCHG_U:     "i64.atomic.rmw8.xchg_u",
+	I64_ATOMIC_RMW16_XCHG_U:    "i64.atomic.rmw16.xchg_u",
+	I64_ATOMIC_RMW32_XCHG_U:    "i64.atomic.rmw32.xchg_u",
+	I32_ATOMIC_RMW_CMPXCHG:     "i32.atomic.rmw.cmpxchg",
+	I64_ATOMIC_RMW_CMPXCHG:     "i64.atomic.rmw.cmpxchg",
+	I32_ATOMIC_RMW8_CMPXCHG_U:  "i32.atomic.rmw8.cmpxchg_u",
+	I32_ATOMIC_RMW16_CMPXCHG_U: "i32.atomic.rmw16.cmpxchg_u",
+	I64_ATOMIC_RMW8_CMPXCHG_U:  "i64.atomic.rmw8.cmpxchg_u",
+	I64_ATOMIC_RMW16_CMPXCHG_U: "i64.atomic.rmw16.cmpxchg_u",
+	I64_ATOMIC_RMW32_CMPXCHG_U: "i64.atomic.rmw32.cmpxchg_u",
+}
+
+// Atomic is an atomic memory access instruction of the threads proposal.
+// All of them share the prefix 0xfe and are distinguished by Op.
+type Atomic struct {
+	Op  AtomicOpcode
+	Imm MemoryImm // zero for atomic.fence
+}
+
+func (*Atomic) Opcode() Opcode {
+	return ATOMIC
+}
+
+func (a *Atomic) imm() any {
+	return a.Imm
+}
+
+func (a *Atomic) String() string {
+	return a.Op.String()
+}
+
+func (a *Atomic) ImmString() string {
+	if a.Op == ATOMIC_FENCE {
+		return ""
+	}
+	return fmt.Sprintf("0x%x", a.Imm.Offset)
+}
+
+func (op AtomicOpcode) String() string {
+	if name, ok := atomicOpcodeNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("atomic[0x%x]", uint32(op))
+}
+
+func decodeAtomic(buf *bytes.Buffer) (Instruction, error) {
+	code, _, err := types.DecodeVarUint32(buf)
+	if err != nil {
+		return nil, fmt.Errorf("Instruction(atomic) decode: %w", err)
+	}
+	op := AtomicOpcode(code)
+	if _, ok := atomicOpcodeNames[op]; !ok {
+		return nil, fmt.Errorf("%w: 0x%x 0x%x", InvalidOpcode, ATOMIC, code)
+	}
+	a := &Atomic{Op: op}
+	if op == ATOMIC_FENCE {
+		// reserved byte
+		if b, err := buf.ReadByte(); err != nil || b != 0x00 {
+			return nil, fmt.Errorf("Instruction(%s): invalid reserved byte", op)
+		}
+		return a, nil
+	}
+	imm, err := newMemImm(buf)
+	if err != nil {
+		return nil, fmt.Errorf("Instruction(%s): %w", op, err)
+	}
+	a.Imm = *imm
+	return a, nil
+}
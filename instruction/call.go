@@ -27,8 +27,9 @@ type CallIndirect struct {
 }
 
 type CallIndirectImm struct {
-	TypeIndex uint32
-	reserved  bool
+	TypeIndex  uint32
+	TableIndex uint32
+	reserved   bool
 }
 
 func (*CallIndirect) Opcode() Opcode {
@@ -46,3 +47,46 @@ func (*CallIndirect) String() string {
 func (c *CallIndirect) ImmString() string {
 	return fmt.Sprintf("%v", c.Imm.TypeIndex)
 }
+
+// ReturnCall is call which replaces the frame of the caller.
+// https://github.com/WebAssembly/tail-call/blob/main/proposals/tail-call/Overview.md
+type ReturnCall struct {
+	Imm uint32
+}
+
+func (*ReturnCall) Opcode() Opcode {
+	return RETURN_CALL
+}
+
+func (rc *ReturnCall) imm() any {
+	return rc.Imm
+}
+
+func (*ReturnCall) String() string {
+	return "return_call"
+}
+
+func (rc *ReturnCall) ImmString() string {
+	return fmt.Sprintf("%v", rc.Imm)
+}
+
+// ReturnCallIndirect is call_indirect which replaces the frame of the caller.
+type ReturnCallIndirect struct {
+	Imm CallIndirectImm
+}
+
+func (*ReturnCallIndirect) Opcode() Opcode {
+	return RETURN_CALL_INDIRECT
+}
+
+func (rci *ReturnCallIndirect) imm() any {
+	return rci.Imm
+}
+
+func (*ReturnCallIndirect) String() string {
+	return "return_call_indirect"
+}
+
+func (rci *ReturnCallIndirect) ImmString() string {
+	return fmt.Sprintf("%v", rci.Imm.TypeIndex)
+}
@@ -0,0 +1,128 @@
+package instruction
+
+import (
+	"fmt"
+
+	"github.com/terassyi/gowi/types"
+)
+
+// Instructions of the exception handling proposal.
+// https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+
+type Try struct {
+	Imm types.BlockType
+}
+
+func (*Try) Opcode() Opcode {
+	return TRY
+}
+
+func (t *Try) imm() any {
+	return t.Imm
+}
+
+func (*Try) String() string {
+	return "try"
+}
+
+func (t *Try) ImmString() string {
+	return fmt.Sprintf("%s", types.ValueType(t.Imm))
+}
+
+type Catch struct {
+	Imm uint32 // tagidx
+}
+
+func (*Catch) Opcode() Opcode {
+	return CATCH
+}
+
+func (c *Catch) imm() any {
+	return c.Imm
+}
+
+func (*Catch) String() string {
+	return "catch"
+}
+
+func (c *Catch) ImmString() string {
+	return fmt.Sprintf("%d", c.Imm)
+}
+
+type Throw struct {
+	Imm uint32 // tagidx
+}
+
+func (*Throw) Opcode() Opcode {
+	return THROW
+}
+
+func (t *Throw) imm() any {
+	return t.Imm
+}
+
+func (*Throw) String() string {
+	return "throw"
+}
+
+func (t *Throw) ImmString() string {
+	return fmt.Sprintf("%d", t.Imm)
+}
+
+type Rethrow struct {
+	Imm uint32 // labelidx
+}
+
+func (*Rethrow) Opcode() Opcode {
+	return RETHROW
+}
+
+func (r *Rethrow) imm() any {
+	return r.Imm
+}
+
+func (*Rethrow) String() string {
+	return "rethrow"
+}
+
+func (r *Rethrow) ImmString() string {
+	return fmt.Sprintf("%d", r.Imm)
+}
+
+type Delegate struct {
+	Imm uint32 // labelidx
+}
+
+func (*Delegate) Opcode() Opcode {
+	return DELEGATE
+}
+
+func (d *Delegate) imm() any {
+	return d.Imm
+}
+
+func (*Delegate) String() string {
+	return "delegate"
+}
+
+func (d *Delegate) ImmString() string {
+	return fmt.Sprintf("%d", d.Imm)
+}
+
+type CatchAll struct{}
+
+func (*CatchAll) Opcode() Opcode {
+	return CATCH_ALL
+}
+
+func (*CatchAll) imm() any {
+	return NoImm
+}
+
+func (*CatchAll) String() string {
+	return "catch_all"
+}
+
+func (*CatchAll) ImmString() string {
+	return ""
+}
@@ -0,0 +1,57 @@
+package instruction
+
+import "github.com/terassyi/gowi/types"
+
+// RequiredFeatures returns the proposals which the instruction belongs to.
+// It returns FeaturesNone for the instructions of the MVP.
+func RequiredFeatures(instr Instruction) types.Features {
+	switch instr.Opcode() {
+	case BLOCK, LOOP, IF, TRY:
+		fs := types.FeaturesNone
+		if instr.Opcode() == TRY {
+			fs |= types.FeatureExceptions
+		}
+		// a block type which is not a value type refers to a function type
+		if types.ValueType(Imm[types.BlockType](instr)) < types.EMPTY {
+			fs |= types.FeatureMultiValue
+		}
+		return fs
+	case CATCH, THROW, RETHROW, DELEGATE, CATCH_ALL:
+		return types.FeatureExceptions
+	case CALL_INDIRECT:
+		if Imm[CallIndirectImm](instr).TableIndex != 0 {
+			return types.FeatureReferenceTypes
+		}
+		return types.FeaturesNone
+	case RETURN_CALL:
+		return types.FeatureTailCall
+	case RETURN_CALL_INDIRECT:
+		fs := types.FeatureTailCall
+		if Imm[CallIndirectImm](instr).TableIndex != 0 {
+			fs |= types.FeatureReferenceTypes
+		}
+		return fs
+	case I32_EXTEND8_S, I32_EXTEND16_S, I64_EXTEND8_S, I64_EXTEND16_S, I64_EXTEND32_S:
+		return types.FeatureSignExt
+	case SIMD:
+		return types.FeatureSIMD
+	case ATOMIC:
+		return types.FeatureThreads
+	default:
+		return types.FeaturesNone
+	}
+}
+
+// UsesMemory returns true when the instruction accesses the memory.
+func UsesMemory(instr Instruction) bool {
+	switch op := instr.Opcode(); {
+	case I32_LOAD <= op && op <= GROW_MEMORY:
+		return true
+	case op == SIMD:
+		return instr.(*Vector).Op.hasMemoryImm()
+	case op == ATOMIC:
+		return instr.(*Atomic).Op != ATOMIC_FENCE
+	default:
+		return false
+	}
+}
@@ -12,6 +12,7 @@ var (
 	InvalidOpcode       error = errors.New("Invalid opcode")
 	NotImplemented      error = errors.New("Not implemented")
 	NotConstInstruction error = errors.New("Not const instruction")
+	InvalidLaneIndex    error = errors.New("Invalid lane index")
 )
 
 type Instruction interface {
@@ -59,6 +60,38 @@ func Decode(buf *bytes.Buffer) (Instruction, error) {
 		return &If{Imm: types.BlockType(imm)}, nil
 	case ELSE:
 		return &Else{}, nil
+	case TRY:
+		imm, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(try) decode: %w", err)
+		}
+		return &Try{Imm: types.BlockType(imm)}, nil
+	case CATCH:
+		imm, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(catch) decode: %w", err)
+		}
+		return &Catch{Imm: uint32(imm)}, nil
+	case THROW:
+		imm, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(throw) decode: %w", err)
+		}
+		return &Throw{Imm: uint32(imm)}, nil
+	case RETHROW:
+		imm, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(rethrow) decode: %w", err)
+		}
+		return &Rethrow{Imm: uint32(imm)}, nil
+	case DELEGATE:
+		imm, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(delegate) decode: %w", err)
+		}
+		return &Delegate{Imm: uint32(imm)}, nil
+	case CATCH_ALL:
+		return &CatchAll{}, nil
 	case END:
 		return &End{}, nil
 	case BR:
@@ -119,8 +152,30 @@ func Decode(buf *bytes.Buffer) (Instruction, error) {
 		}
 		return &CallIndirect{
 			Imm: CallIndirectImm{
-				TypeIndex: uint32(index),
-				reserved:  reserved,
+				TypeIndex:  uint32(index),
+				TableIndex: uint32(r),
+				reserved:   reserved,
+			},
+		}, nil
+	case RETURN_CALL:
+		imm, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(return_call) decode: %w", err)
+		}
+		return &ReturnCall{Imm: uint32(imm)}, nil
+	case RETURN_CALL_INDIRECT:
+		index, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(return_call_indirect) decode: %w", err)
+		}
+		table, _, err := types.DecodeVarUint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(return_call_indirect) decode: %w", err)
+		}
+		return &ReturnCallIndirect{
+			Imm: CallIndirectImm{
+				TypeIndex:  uint32(index),
+				TableIndex: uint32(table),
 			},
 		}, nil
 	case DROP:
@@ -275,8 +330,18 @@ func Decode(buf *bytes.Buffer) (Instruction, error) {
 			return nil, fmt.Errorf("Instruction(i64.store32): %w", err)
 		}
 		return &I64Store32{Imm: *imm}, nil
-	// case CURRENT_MEMORY:
-	// case GROW_MEMORY:
+	case CURRENT_MEMORY:
+		imm, err := buf.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(current_memory): %w", err)
+		}
+		return &CurrentMemory{Imm: imm}, nil
+	case GROW_MEMORY:
+		imm, err := buf.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(grow_memory): %w", err)
+		}
+		return &GrowMemory{Imm: imm}, nil
 	case I32_CONST:
 		imm, _, err := types.DecodeVarInt32(buf)
 		if err != nil {
@@ -479,6 +544,20 @@ func Decode(buf *bytes.Buffer) (Instruction, error) {
 	// case F64_CONVERT_U_I64:
 	// case F64_PROMOTE_F32:
 	// case TRUNC_SAT:
+	case I32_EXTEND8_S:
+		return &I32Extend8S{}, nil
+	case I32_EXTEND16_S:
+		return &I32Extend16S{}, nil
+	case I64_EXTEND8_S:
+		return &I64Extend8S{}, nil
+	case I64_EXTEND16_S:
+		return &I64Extend16S{}, nil
+	case I64_EXTEND32_S:
+		return &I64Extend32S{}, nil
+	case SIMD:
+		return decodeVector(buf)
+	case ATOMIC:
+		return decodeAtomic(buf)
 	// case I32_REINTERPRET_F32:
 	// case I64_REINTERPRET_F64:
 	// case F32_REINTERPRET_I32:
@@ -1,9 +1,11 @@
 package instruction
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestImm_None(t *testing.T) {
@@ -60,3 +62,118 @@ func TestImm_BrTableImm(t *testing.T) {
 	res := Imm[BrTableImm](instr)
 	assert.Equal(t, imm, res)
 }
+
+func TestDecode_Vector(t *testing.T) {
+	for _, d := range []struct {
+		buf []byte
+		exp *Vector
+		str string
+	}{
+		{buf: []byte{0xfd, 0x00, 0x04, 0x10}, exp: &Vector{Op: V128_LOAD, Imm: VectorImm{Memory: MemoryImm{Flags: 4, Offset: 0x10}}}, str: "0x10"},
+		{buf: []byte{0xfd, 0x57, 0x03, 0x08, 0x01}, exp: &Vector{Op: V128_LOAD64_LANE, Imm: VectorImm{Memory: MemoryImm{Flags: 3, Offset: 8}, Lane: 1}}, str: "0x8 1"},
+		{buf: []byte{0xfd, 0x1b, 0x03}, exp: &Vector{Op: I32X4_EXTRACT_LANE, Imm: VectorImm{Lane: 3}}, str: "3"},
+		{buf: []byte{0xfd, 0xae, 0x01}, exp: &Vector{Op: I32X4_ADD}, str: ""},
+		{
+			buf: []byte{0xfd, 0x0c, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0},
+			exp: &Vector{Op: V128_CONST, Imm: VectorImm{Bytes: [16]byte{1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0}}},
+			str: "i32x4 0x00000001 0x00000002 0x00000003 0x00000004",
+		},
+		{
+			buf: []byte{0xfd, 0x0d, 0, 1, 2, 3, 4, 5, 6, 7, 16, 17, 18, 19, 20, 21, 22, 23},
+			exp: &Vector{Op: I8X16_SHUFFLE, Imm: VectorImm{Bytes: [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 16, 17, 18, 19, 20, 21, 22, 23}}},
+			str: "0 1 2 3 4 5 6 7 16 17 18 19 20 21 22 23",
+		},
+	} {
+		instr, err := Decode(bytes.NewBuffer(d.buf))
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, instr)
+		assert.Equal(t, d.str, instr.ImmString())
+	}
+	for _, buf := range [][]byte{
+		{0xfd, 0xaf, 0x01},    // unknown opcode
+		{0xfd, 0x0c, 1, 2, 3}, // short v128.const
+		{0xfd, 0x15},          // extract_lane without lane
+	} {
+		_, err := Decode(bytes.NewBuffer(buf))
+		assert.Error(t, err)
+	}
+	_, err := Decode(bytes.NewBuffer([]byte{0xfd, 0x0d, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 32}))
+	assert.ErrorIs(t, err, InvalidLaneIndex)
+}
+
+func TestDecode_Atomic(t *testing.T) {
+	for _, d := range []struct {
+		buf []byte
+		exp *Atomic
+		str string
+	}{
+		{buf: []byte{0xfe, 0x00, 0x02, 0x08}, exp: &Atomic{Op: MEMORY_ATOMIC_NOTIFY, Imm: MemoryImm{Flags: 2, Offset: 8}}, str: "memory.atomic.notify"},
+		{buf: []byte{0xfe, 0x03, 0x00}, exp: &Atomic{Op: ATOMIC_FENCE}, str: "atomic.fence"},
+		{buf: []byte{0xfe, 0x1e, 0x02, 0x00}, exp: &Atomic{Op: I32_ATOMIC_RMW_ADD, Imm: MemoryImm{Flags: 2}}, str: "i32.atomic.rmw.add"},
+		{buf: []byte{0xfe, 0x4e, 0x02, 0x04}, exp: &Atomic{Op: I64_ATOMIC_RMW32_CMPXCHG_U, Imm: MemoryImm{Flags: 2, Offset: 4}}, str: "i64.atomic.rmw32.cmpxchg_u"},
+	} {
+		instr, err := Decode(bytes.NewBuffer(d.buf))
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, instr)
+		assert.Equal(t, d.str, instr.String())
+	}
+	for _, buf := range [][]byte{
+		{0xfe, 0x04, 0x00, 0x00}, // unknown opcode
+		{0xfe, 0x03, 0x01},       // fence with a non zero reserved byte
+		{0xfe, 0x10, 0x02},       // load without offset
+	} {
+		_, err := Decode(bytes.NewBuffer(buf))
+		assert.Error(t, err)
+	}
+}
+
+func TestDecode_Exception(t *testing.T) {
+	for _, d := range []struct {
+		buf []byte
+		exp Instruction
+	}{
+		{buf: []byte{0x06, 0x40}, exp: &Try{Imm: 0x40}},
+		{buf: []byte{0x07, 0x01}, exp: &Catch{Imm: 1}},
+		{buf: []byte{0x08, 0x00}, exp: &Throw{Imm: 0}},
+		{buf: []byte{0x09, 0x02}, exp: &Rethrow{Imm: 2}},
+		{buf: []byte{0x18, 0x00}, exp: &Delegate{Imm: 0}},
+		{buf: []byte{0x19}, exp: &CatchAll{}},
+	} {
+		instr, err := Decode(bytes.NewBuffer(d.buf))
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, instr)
+	}
+}
+
+func TestDecode_TailCall(t *testing.T) {
+	for _, d := range []struct {
+		buf []byte
+		exp Instruction
+	}{
+		{buf: []byte{0x12, 0x03}, exp: &ReturnCall{Imm: 3}},
+		{buf: []byte{0x13, 0x01, 0x00}, exp: &ReturnCallIndirect{Imm: CallIndirectImm{TypeIndex: 1}}},
+		{buf: []byte{0x13, 0x02, 0x01}, exp: &ReturnCallIndirect{Imm: CallIndirectImm{TypeIndex: 2, TableIndex: 1}}},
+	} {
+		instr, err := Decode(bytes.NewBuffer(d.buf))
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, instr)
+	}
+}
+
+func TestDecode_SignExtension(t *testing.T) {
+	for _, d := range []struct {
+		buf []byte
+		str string
+	}{
+		{buf: []byte{0xc0}, str: "i32.extend8_s"},
+		{buf: []byte{0xc1}, str: "i32.extend16_s"},
+		{buf: []byte{0xc2}, str: "i64.extend8_s"},
+		{buf: []byte{0xc3}, str: "i64.extend16_s"},
+		{buf: []byte{0xc4}, str: "i64.extend32_s"},
+	} {
+		instr, err := Decode(bytes.NewBuffer(d.buf))
+		require.NoError(t, err)
+		assert.Equal(t, Opcode(d.buf[0]), instr.Opcode())
+		assert.Equal(t, d.str, instr.String())
+	}
+}
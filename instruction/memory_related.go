@@ -365,3 +365,39 @@ func newMemImm(buf *bytes.Buffer) (*MemoryImm, error) {
 	}
 	return &MemoryImm{Flags: uint32(flags), Offset: uint32(offset)}, nil
 }
+
+type CurrentMemory struct{ Imm uint8 } // reserved
+
+func (*CurrentMemory) Opcode() Opcode {
+	return CURRENT_MEMORY
+}
+
+func (i *CurrentMemory) imm() any {
+	return i.Imm
+}
+
+func (*CurrentMemory) String() string {
+	return "current_memory"
+}
+
+func (i *CurrentMemory) ImmString() string {
+	return fmt.Sprintf("%d", i.Imm)
+}
+
+type GrowMemory struct{ Imm uint8 } // reserved
+
+func (*GrowMemory) Opcode() Opcode {
+	return GROW_MEMORY
+}
+
+func (i *GrowMemory) imm() any {
+	return i.Imm
+}
+
+func (*GrowMemory) String() string {
+	return "grow_memory"
+}
+
+func (i *GrowMemory) ImmString() string {
+	return fmt.Sprintf("%d", i.Imm)
+}
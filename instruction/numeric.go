@@ -1043,3 +1043,93 @@ func (*I64Popcnt) String() string {
 func (*I64Popcnt) ImmString() string {
 	return ""
 }
+
+type I32Extend8S struct{}
+
+func (*I32Extend8S) Opcode() Opcode {
+	return I32_EXTEND8_S
+}
+
+func (*I32Extend8S) imm() any {
+	return NoImm
+}
+
+func (*I32Extend8S) String() string {
+	return "i32.extend8_s"
+}
+
+func (*I32Extend8S) ImmString() string {
+	return ""
+}
+
+type I32Extend16S struct{}
+
+func (*I32Extend16S) Opcode() Opcode {
+	return I32_EXTEND16_S
+}
+
+func (*I32Extend16S) imm() any {
+	return NoImm
+}
+
+func (*I32Extend16S) String() string {
+	return "i32.extend16_s"
+}
+
+func (*I32Extend16S) ImmString() string {
+	return ""
+}
+
+type I64Extend8S struct{}
+
+func (*I64Extend8S) Opcode() Opcode {
+	return I64_EXTEND8_S
+}
+
+func (*I64Extend8S) imm() any {
+	return NoImm
+}
+
+func (*I64Extend8S) String() string {
+	return "i64.extend8_s"
+}
+
+func (*I64Extend8S) ImmString() string {
+	return ""
+}
+
+type I64Extend16S struct{}
+
+func (*I64Extend16S) Opcode() Opcode {
+	return I64_EXTEND16_S
+}
+
+func (*I64Extend16S) imm() any {
+	return NoImm
+}
+
+func (*I64Extend16S) String() string {
+	return "i64.extend16_s"
+}
+
+func (*I64Extend16S) ImmString() string {
+	return ""
+}
+
+type I64Extend32S struct{}
+
+func (*I64Extend32S) Opcode() Opcode {
+	return I64_EXTEND32_S
+}
+
+func (*I64Extend32S) imm() any {
+	return NoImm
+}
+
+func (*I64Extend32S) String() string {
+	return "i64.extend32_s"
+}
+
+func (*I64Extend32S) ImmString() string {
+	return ""
+}
@@ -10,16 +10,26 @@ const (
 	LOOP        Opcode = 0x03
 	IF          Opcode = 0x04
 	ELSE        Opcode = 0x05
+	TRY         Opcode = 0x06
+	CATCH       Opcode = 0x07
+	THROW       Opcode = 0x08
+	RETHROW     Opcode = 0x09
 	END         Opcode = 0x0b
 	BR          Opcode = 0x0c
 	BR_IF       Opcode = 0x0d
 	BR_TABLE    Opcode = 0x0e
 	RETURN      Opcode = 0x0f
+	DELEGATE    Opcode = 0x18
+	CATCH_ALL   Opcode = 0x19
 
 	// Call operators
 	CALL          Opcode = 0x10
 	CALL_INDIRECT Opcode = 0x11
 
+	// Tail call operators
+	RETURN_CALL          Opcode = 0x12
+	RETURN_CALL_INDIRECT Opcode = 0x13
+
 	// Parametic operators
 	DROP   Opcode = 0x1a
 	SELECT Opcode = 0x1b
@@ -189,6 +199,8 @@ const (
 	F64_CONVERT_U_I64 Opcode = 0xba
 	F64_PROMOTE_F32   Opcode = 0xbb
 	TRUNC_SAT         Opcode = 0xfc
+	SIMD              Opcode = 0xfd // followed by VectorOpcode
+	ATOMIC            Opcode = 0xfe // followed by AtomicOpcode
 	// I32_TRUNC_SAT_F32_S Opcode = 0xfc // 0x00
 	// I32_TRUNC_SAT_F32_U Opcode = 0xfc // 0x01
 	// I32_TRUNC_SAT_F64_S Opcode = 0xfc // 0x02
@@ -203,6 +215,13 @@ const (
 	I64_REINTERPRET_F64 Opcode = 0xbd
 	F32_REINTERPRET_I32 Opcode = 0xbe
 	F64_REINTERPRET_I64 Opcode = 0xbf
+
+	// Sign-extension operators
+	I32_EXTEND8_S  Opcode = 0xc0
+	I32_EXTEND16_S Opcode = 0xc1
+	I64_EXTEND8_S  Opcode = 0xc2
+	I64_EXTEND16_S Opcode = 0xc3
+	I64_EXTEND32_S Opcode = 0xc4
 )
 
 const (
@@ -0,0 +1,114 @@
+package instruction
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/terassyi/gowi/types"
+)
+
+// Vector is a SIMD instruction. All of them share the prefix 0xfd and are distinguished by Op.
+type Vector struct {
+	Op  VectorOpcode
+	Imm VectorImm
+}
+
+// VectorImm is the immediate of a SIMD instruction. Only the fields used by Op are set.
+type VectorImm struct {
+	Memory MemoryImm // loads and stores
+	Lane   uint8     // lane index of extract_lane, replace_lane, load_lane and store_lane
+	Bytes  [16]byte  // value of v128.const or lane indices of i8x16.shuffle
+}
+
+func (*Vector) Opcode() Opcode {
+	return SIMD
+}
+
+func (v *Vector) imm() any {
+	return v.Imm
+}
+
+func (v *Vector) String() string {
+	return v.Op.String()
+}
+
+func (v *Vector) ImmString() string {
+	switch {
+	case v.Op.hasMemoryImm() && v.Op.hasLaneImm():
+		return fmt.Sprintf("0x%x %d", v.Imm.Memory.Offset, v.Imm.Lane)
+	case v.Op.hasMemoryImm():
+		return fmt.Sprintf("0x%x", v.Imm.Memory.Offset)
+	case v.Op.hasLaneImm():
+		return fmt.Sprintf("%d", v.Imm.Lane)
+	case v.Op == V128_CONST:
+		lanes := make([]string, 0, 4)
+		for i := 0; i < 16; i += 4 {
+			lanes = append(lanes, fmt.Sprintf("0x%08x", binary.LittleEndian.Uint32(v.Imm.Bytes[i:])))
+		}
+		return "i32x4 " + strings.Join(lanes, " ")
+	case v.Op == I8X16_SHUFFLE:
+		lanes := make([]string, 0, 16)
+		for _, l := range v.Imm.Bytes {
+			lanes = append(lanes, fmt.Sprintf("%d", l))
+		}
+		return strings.Join(lanes, " ")
+	default:
+		return ""
+	}
+}
+
+func (op VectorOpcode) String() string {
+	if name, ok := vectorOpcodeNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("simd[0x%x]", uint32(op))
+}
+
+func (op VectorOpcode) hasMemoryImm() bool {
+	return op <= V128_STORE || (V128_LOAD8_LANE <= op && op <= V128_LOAD64_ZERO)
+}
+
+func (op VectorOpcode) hasLaneImm() bool {
+	return (I8X16_EXTRACT_LANE_S <= op && op <= F64X2_REPLACE_LANE) || (V128_LOAD8_LANE <= op && op <= V128_STORE64_LANE)
+}
+
+func decodeVector(buf *bytes.Buffer) (Instruction, error) {
+	code, _, err := types.DecodeVarUint32(buf)
+	if err != nil {
+		return nil, fmt.Errorf("Instruction(simd) decode: %w", err)
+	}
+	op := VectorOpcode(code)
+	if _, ok := vectorOpcodeNames[op]; !ok {
+		return nil, fmt.Errorf("%w: 0x%x 0x%x", InvalidOpcode, SIMD, code)
+	}
+	v := &Vector{Op: op}
+	if op.hasMemoryImm() {
+		imm, err := newMemImm(buf)
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(%s): %w", op, err)
+		}
+		v.Imm.Memory = *imm
+	}
+	if op.hasLaneImm() {
+		lane, err := buf.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("Instruction(%s): %w", op, err)
+		}
+		v.Imm.Lane = lane
+	}
+	if op == V128_CONST || op == I8X16_SHUFFLE {
+		if n, _ := buf.Read(v.Imm.Bytes[:]); n != 16 {
+			return nil, fmt.Errorf("Instruction(%s): immediate is too short", op)
+		}
+	}
+	if op == I8X16_SHUFFLE {
+		for _, l := range v.Imm.Bytes {
+			if l >= 32 {
+				return nil, fmt.Errorf("Instruction(%s): %w: %d", op, InvalidLaneIndex, l)
+			}
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,483 @@
+package instruction
+
+// VectorOpcode is the opcode of a SIMD instruction following the prefix 0xfd.
+// https://webassembly.github.io/spec/core/binary/instructions.html#vector-instructions
+type VectorOpcode uint32
+
+const (
+	V128_LOAD                     VectorOpcode = 0x00
+	V128_LOAD8X8_S                VectorOpcode = 0x01
+	V128_LOAD8X8_U                VectorOpcode = 0x02
+	V128_LOAD16X4_S               VectorOpcode = 0x03
+	V128_LOAD16X4_U               VectorOpcode = 0x04
+	V128_LOAD32X2_S               VectorOpcode = 0x05
+	V128_LOAD32X2_U               VectorOpcode = 0x06
+	V128_LOAD8_SPLAT              VectorOpcode = 0x07
+	V128_LOAD16_SPLAT             VectorOpcode = 0x08
+	V128_LOAD32_SPLAT             VectorOpcode = 0x09
+	V128_LOAD64_SPLAT             VectorOpcode = 0x0a
+	V128_STORE                    VectorOpcode = 0x0b
+	V128_CONST                    VectorOpcode = 0x0c
+	I8X16_SHUFFLE                 VectorOpcode = 0x0d
+	I8X16_SWIZZLE                 VectorOpcode = 0x0e
+	I8X16_SPLAT                   VectorOpcode = 0x0f
+	I16X8_SPLAT                   VectorOpcode = 0x10
+	I32X4_SPLAT                   VectorOpcode = 0x11
+	I64X2_SPLAT                   VectorOpcode = 0x12
+	F32X4_SPLAT                   VectorOpcode = 0x13
+	F64X2_SPLAT                   VectorOpcode = 0x14
+	I8X16_EXTRACT_LANE_S          VectorOpcode = 0x15
+	I8X16_EXTRACT_LANE_U          VectorOpcode = 0x16
+	I8X16_REPLACE_LANE            VectorOpcode = 0x17
+	I16X8_EXTRACT_LANE_S          VectorOpcode = 0x18
+	I16X8_EXTRACT_LANE_U          VectorOpcode = 0x19
+	I16X8_REPLACE_LANE            VectorOpcode = 0x1a
+	I32X4_EXTRACT_LANE            VectorOpcode = 0x1b
+	I32X4_REPLACE_LANE            VectorOpcode = 0x1c
+	I64X2_EXTRACT_LANE            VectorOpcode = 0x1d
+	I64X2_REPLACE_LANE            VectorOpcode = 0x1e
+	F32X4_EXTRACT_LANE            VectorOpcode = 0x1f
+	F32X4_REPLACE_LANE            VectorOpcode = 0x20
+	F64X2_EXTRACT_LANE            VectorOpcode = 0x21
+	F64X2_REPLACE_LANE            VectorOpcode = 0x22
+	I8X16_EQ                      VectorOpcode = 0x23
+	I8X16_NE                      VectorOpcode = 0x24
+	I8X16_LT_S                    VectorOpcode = 0x25
+	I8X16_LT_U                    VectorOpcode = 0x26
+	I8X16_GT_S                    VectorOpcode = 0x27
+	I8X16_GT_U                    VectorOpcode = 0x28
+	I8X16_LE_S                    VectorOpcode = 0x29
+	I8X16_LE_U                    VectorOpcode = 0x2a
+	I8X16_GE_S                    VectorOpcode = 0x2b
+	I8X16_GE_U                    VectorOpcode = 0x2c
+	I16X8_EQ                      VectorOpcode = 0x2d
+	I16X8_NE                      VectorOpcode = 0x2e
+	I16X8_LT_S                    VectorOpcode = 0x2f
+	I16X8_LT_U                    VectorOpcode = 0x30
+	I16X8_GT_S                    VectorOpcode = 0x31
+	I16X8_GT_U                    VectorOpcode = 0x32
+	I16X8_LE_S                    VectorOpcode = 0x33
+	I16X8_LE_U                    VectorOpcode = 0x34
+	I16X8_GE_S                    VectorOpcode = 0x35
+	I16X8_GE_U                    VectorOpcode = 0x36
+	I32X4_EQ                      VectorOpcode = 0x37
+	I32X4_NE                      VectorOpcode = 0x38
+	I32X4_LT_S                    VectorOpcode = 0x39
+	I32X4_LT_U                    VectorOpcode = 0x3a
+	I32X4_GT_S                    VectorOpcode = 0x3b
+	I32X4_GT_U                    VectorOpcode = 0x3c
+	I32X4_LE_S                    VectorOpcode = 0x3d
+	I32X4_LE_U                    VectorOpcode = 0x3e
+	I32X4_GE_S                    VectorOpcode = 0x3f
+	I32X4_GE_U                    VectorOpcode = 0x40
+	F32X4_EQ                      VectorOpcode = 0x41
+	F32X4_NE                      VectorOpcode = 0x42
+	F32X4_LT                      VectorOpcode = 0x43
+	F32X4_GT                      VectorOpcode = 0x44
+	F32X4_LE                      VectorOpcode = 0x45
+	F32X4_GE                      VectorOpcode = 0x46
+	F64X2_EQ                      VectorOpcode = 0x47
+	F64X2_NE                      VectorOpcode = 0x48
+	F64X2_LT                      VectorOpcode = 0x49
+	F64X2_GT                      VectorOpcode = 0x4a
+	F64X2_LE                      VectorOpcode = 0x4b
+	F64X2_GE                      VectorOpcode = 0x4c
+	V128_NOT                      VectorOpcode = 0x4d
+	V128_AND                      VectorOpcode = 0x4e
+	V128_ANDNOT                   VectorOpcode = 0x4f
+	V128_OR                       VectorOpcode = 0x50
+	V128_XOR                      VectorOpcode = 0x51
+	V128_BITSELECT                VectorOpcode = 0x52
+	V128_ANY_TRUE                 VectorOpcode = 0x53
+	V128_LOAD8_LANE               VectorOpcode = 0x54
+	V128_LOAD16_LANE              VectorOpcode = 0x55
+	V128_LOAD32_LANE              VectorOpcode = 0x56
+	V128_LOAD64_LANE              VectorOpcode = 0x57
+	V128_STORE8_LANE              VectorOpcode = 0x58
+	V128_STORE16_LANE             VectorOpcode = 0x59
+	V128_STORE32_LANE             VectorOpcode = 0x5a
+	V128_STORE64_LANE             VectorOpcode = 0x5b
+	V128_LOAD32_ZERO              VectorOpcode = 0x5c
+	V128_LOAD64_ZERO              VectorOpcode = 0x5d
+	F32X4_DEMOTE_F64X2_ZERO       VectorOpcode = 0x5e
+	F64X2_PROMOTE_LOW_F32X4       VectorOpcode = 0x5f
+	I8X16_ABS                     VectorOpcode = 0x60
+	I8X16_NEG                     VectorOpcode = 0x61
+	I8X16_POPCNT                  VectorOpcode = 0x62
+	I8X16_ALL_TRUE                VectorOpcode = 0x63
+	I8X16_BITMASK                 VectorOpcode = 0x64
+	I8X16_NARROW_I16X8_S          VectorOpcode = 0x65
+	I8X16_NARROW_I16X8_U          VectorOpcode = 0x66
+	F32X4_CEIL                    VectorOpcode = 0x67
+	F32X4_FLOOR                   VectorOpcode = 0x68
+	F32X4_TRUNC                   VectorOpcode = 0x69
+	F32X4_NEAREST                 VectorOpcode = 0x6a
+	I8X16_SHL                     VectorOpcode = 0x6b
+	I8X16_SHR_S                   VectorOpcode = 0x6c
+	I8X16_SHR_U                   VectorOpcode = 0x6d
+	I8X16_ADD                     VectorOpcode = 0x6e
+	I8X16_ADD_SAT_S               VectorOpcode = 0x6f
+	I8X16_ADD_SAT_U               VectorOpcode = 0x70
+	I8X16_SUB                     VectorOpcode = 0x71
+	I8X16_SUB_SAT_S               VectorOpcode = 0x72
+	I8X16_SUB_SAT_U               VectorOpcode = 0x73
+	F64X2_CEIL                    VectorOpcode = 0x74
+	F64X2_FLOOR                   VectorOpcode = 0x75
+	I8X16_MIN_S                   VectorOpcode = 0x76
+	I8X16_MIN_U                   VectorOpcode = 0x77
+	I8X16_MAX_S                   VectorOpcode = 0x78
+	I8X16_MAX_U                   VectorOpcode = 0x79
+	F64X2_TRUNC                   VectorOpcode = 0x7a
+	I8X16_AVGR_U                  VectorOpcode = 0x7b
+	I16X8_EXTADD_PAIRWISE_I8X16_S VectorOpcode = 0x7c
+	I16X8_EXTADD_PAIRWISE_I8X16_U VectorOpcode = 0x7d
+	I32X4_EXTADD_PAIRWISE_I16X8_S VectorOpcode = 0x7e
+	I32X4_EXTADD_PAIRWISE_I16X8_U VectorOpcode = 0x7f
+	I16X8_ABS                     VectorOpcode = 0x80
+	I16X8_NEG                     VectorOpcode = 0x81
+	I16X8_Q15MULR_SAT_S           VectorOpcode = 0x82
+	I16X8_ALL_TRUE                VectorOpcode = 0x83
+	I16X8_BITMASK                 VectorOpcode = 0x84
+	I16X8_NARROW_I32X4_S          VectorOpcode = 0x85
+	I16X8_NARROW_I32X4_U          VectorOpcode = 0x86
+	I16X8_EXTEND_LOW_I8X16_S      VectorOpcode = 0x87
+	I16X8_EXTEND_HIGH_I8X16_S     VectorOpcode = 0x88
+	I16X8_EXTEND_LOW_I8X16_U      VectorOpcode = 0x89
+	I16X8_EXTEND_HIGH_I8X16_U     VectorOpcode = 0x8a
+	I16X8_SHL                     VectorOpcode = 0x8b
+	I16X8_SHR_S                   VectorOpcode = 0x8c
+	I16X8_SHR_U                   VectorOpcode = 0x8d
+	I16X8_ADD                     VectorOpcode = 0x8e
+	I16X8_ADD_SAT_S               VectorOpcode = 0x8f
+	I16X8_ADD_SAT_U               VectorOpcode = 0x90
+	I16X8_SUB                     VectorOpcode = 0x91
+	I16X8_SUB_SAT_S               VectorOpcode = 0x92
+	I16X8_SUB_SAT_U               VectorOpcode = 0x93
+	F64X2_NEAREST                 VectorOpcode = 0x94
+	I16X8_MUL                     VectorOpcode = 0x95
+	I16X8_MIN_S                   VectorOpcode = 0x96
+	I16X8_MIN_U                   VectorOpcode = 0x97
+	I16X8_MAX_S                   VectorOpcode = 0x98
+	I16X8_MAX_U                   VectorOpcode = 0x99
+	I16X8_AVGR_U                  VectorOpcode = 0x9b
+	I16X8_EXTMUL_LOW_I8X16_S      VectorOpcode = 0x9c
+	I16X8_EXTMUL_HIGH_I8X16_S     VectorOpcode = 0x9d
+	I16X8_EXTMUL_LOW_I8X16_U      VectorOpcode = 0x9e
+	I16X8_EXTMUL_HIGH_I8X16_U     VectorOpcode = 0x9f
+	I32X4_ABS                     VectorOpcode = 0xa0
+	I32X4_NEG                     VectorOpcode = 0xa1
+	I32X4_ALL_TRUE                VectorOpcode = 0xa3
+	I32X4_BITMASK                 VectorOpcode = 0xa4
+	I32X4_EXTEND_LOW_I16X8_S      VectorOpcode = 0xa7
+	I32X4_EXTEND_HIGH_I16X8_S     VectorOpcode = 0xa8
+	I32X4_EXTEND_LOW_I16X8_U      VectorOpcode = 0xa9
+	I32X4_EXTEND_HIGH_I16X8_U     VectorOpcode = 0xaa
+	I32X4_SHL                     VectorOpcode = 0xab
+	I32X4_SHR_S                   VectorOpcode = 0xac
+	I32X4_SHR_U                   VectorOpcode = 0xad
+	I32X4_ADD                     VectorOpcode = 0xae
+	I32X4_SUB                     VectorOpcode = 0xb1
+	I32X4_MUL                     VectorOpcode = 0xb5
+	I32X4_MIN_S                   VectorOpcode = 0xb6
+	I32X4_MIN_U                   VectorOpcode = 0xb7
+	I32X4_MAX_S                   VectorOpcode = 0xb8
+	I32X4_MAX_U                   VectorOpcode = 0xb9
+	I32X4_DOT_I16X8_S             VectorOpcode = 0xba
+	I32X4_EXTMUL_LOW_I16X8_S      VectorOpcode = 0xbc
+	I32X4_EXTMUL_HIGH_I16X8_S     VectorOpcode = 0xbd
+	I32X4_EXTMUL_LOW_I16X8_U      VectorOpcode = 0xbe
+	I32X4_EXTMUL_HIGH_I16X8_U     VectorOpcode = 0xbf
+	I64X2_ABS                     VectorOpcode = 0xc0
+	I64X2_NEG                     VectorOpcode = 0xc1
+	I64X2_ALL_TRUE                VectorOpcode = 0xc3
+	I64X2_BITMASK                 VectorOpcode = 0xc4
+	I64X2_EXTEND_LOW_I32X4_S      VectorOpcode = 0xc7
+	I64X2_EXTEND_HIGH_I32X4_S     VectorOpcode = 0xc8
+	I64X2_EXTEND_LOW_I32X4_U      VectorOpcode = 0xc9
+	I64X2_EXTEND_HIGH_I32X4_U     VectorOpcode = 0xca
+	I64X2_SHL                     VectorOpcode = 0xcb
+	I64X2_SHR_S                   VectorOpcode = 0xcc
+	I64X2_SHR_U                   VectorOpcode = 0xcd
+	I64X2_ADD                     VectorOpcode = 0xce
+	I64X2_SUB                     VectorOpcode = 0xd1
+	I64X2_MUL                     VectorOpcode = 0xd5
+	I64X2_EQ                      VectorOpcode = 0xd6
+	I64X2_NE                      VectorOpcode = 0xd7
+	I64X2_LT_S                    VectorOpcode = 0xd8
+	I64X2_GT_S                    VectorOpcode = 0xd9
+	I64X2_LE_S                    VectorOpcode = 0xda
+	I64X2_GE_S                    VectorOpcode = 0xdb
+	I64X2_EXTMUL_LOW_I32X4_S      VectorOpcode = 0xdc
+	I64X2_EXTMUL_HIGH_I32X4_S     VectorOpcode = 0xdd
+	I64X2_EXTMUL_LOW_I32X4_U      VectorOpcode = 0xde
+	I64X2_EXTMUL_HIGH_I32X4_U     VectorOpcode = 0xdf
+	F32X4_ABS                     VectorOpcode = 0xe0
+	F32X4_NEG                     VectorOpcode = 0xe1
+	F32X4_SQRT                    VectorOpcode = 0xe3
+	F32X4_ADD                     VectorOpcode = 0xe4
+	F32X4_SUB                     VectorOpcode = 0xe5
+	F32X4_MUL                     VectorOpcode = 0xe6
+	F32X4_DIV                     VectorOpcode = 0xe7
+	F32X4_MIN                     VectorOpcode = 0xe8
+	F32X4_MAX                     VectorOpcode = 0xe9
+	F32X4_PMIN                    VectorOpcode = 0xea
+	F32X4_PMAX                    VectorOpcode = 0xeb
+	F64X2_ABS                     VectorOpcode = 0xec
+	F64X2_NEG                     VectorOpcode = 0xed
+	F64X2_SQRT                    VectorOpcode = 0xef
+	F64X2_ADD                     VectorOpcode = 0xf0
+	F64X2_SUB                     VectorOpcode = 0xf1
+	F64X2_MUL                     VectorOpcode = 0xf2
+	F64X2_DIV                     VectorOpcode = 0xf3
+	F64X2_MIN                     VectorOpcode = 0xf4
+	F64X2_MAX                     VectorOpcode = 0xf5
+	F64X2_PMIN                    VectorOpcode = 0xf6
+	F64X2_PMAX                    VectorOpcode = 0xf7
+	I32X4_TRUNC_SAT_F32X4_S       VectorOpcode = 0xf8
+	I32X4_TRUNC_SAT_F32X4_U       VectorOpcode = 0xf9
+	F32X4_CONVERT_I32X4_S         VectorOpcode = 0xfa
+	F32X4_CONVERT_I32X4_U         VectorOpcode = 0xfb
+	I32X4_TRUNC_SAT_F64X2_S_ZERO  VectorOpcode = 0xfc
+	I32X4_TRUNC_SAT_F64X2_U_ZERO  VectorOpcode = 0xfd
+	F64X2_CONVERT_LOW_I32X4_S     VectorOpcode = 0xfe
+	F64X2_CONVERT_LOW_I32X4_U     VectorOpcode = 0xff
+)
+
+var vectorOpcodeNames = map[VectorOpcode]string{
+	V128_LOAD:                     "v128.load",
+	V128_LOAD8X8_S:                "v128.load8x8_s",
+	V128_LOAD8X8_U:                "v128.load8x8_u",
+	V128_LOAD16X4_S:               "v128.load16x4_s",
+	V128_LOAD16X4_U:               "v128.load16x4_u",
+	V128_LOAD32X2_S:               "v128.load32x2_s",
+	V128_LOAD32X2_U:               "v128.load32x2_u",
+	V128_LOAD8_SPLAT:              "v128.load8_splat",
+	V128_LOAD16_SPLAT:             "v128.load16_splat",
+	V128_LOAD32_SPLAT:             "v128.load32_splat",
+	V128_LOAD64_SPLAT:             "v128.load64_splat",
+	V128_STORE:                    "v128.store",
+	V128_CONST:                    "v128.const",
+	I8X16_SHUFFLE:                 "i8x16.shuffle",
+	I8X16_SWIZZLE:                 "i8x16.swizzle",
+	I8X16_SPLAT:                   "i8x16.splat",
+	I16X8_SPLAT:                   "i16x8.splat",
+	I32X4_SPLAT:                   "i32x4.splat",
+	I64X2_SPLAT:                   "i64x2.splat",
+	F32X4_SPLAT:                   "f32x4.splat",
+	F64X2_SPLAT:                   "f64x2.splat",
+	I8X16_EXTRACT_LANE_S:          "i8x16.extract_lane_s",
+	I8X16_EXTRACT_LANE_U:          "i8x16.extract_lane_u",
+	I8X16_REPLACE_LANE:            "i8x16.replace_lane",
+	I16X8_EXTRACT_LANE_S:          "i16x8.extract_lane_s",
+	I16X8_EXTRACT_LANE_U:          "i16x8.extract_lane_u",
+	I16X8_REPLACE_LANE:            "i16x8.replace_lane",
+	I32X4_EXTRACT_LANE:            "i32x4.extract_lane",
+	I32X4_REPLACE_LANE:            "i32x4.replace_lane",
+	I64X2_EXTRACT_LANE:            "i64x2.extract_lane",
+	I64X2_REPLACE_LANE:            "i64x2.replace_lane",
+	F32X4_EXTRACT_LANE:            "f32x4.extract_lane",
+	F32X4_REPLACE_LANE:            "f32x4.replace_lane",
+	F64X2_EXTRACT_LANE:            "f64x2.extract_lane",
+	F64X2_REPLACE_LANE:            "f64x2.replace_lane",
+	I8X16_EQ:                      "i8x16.eq",
+	I8X16_NE:                      "i8x16.ne",
+	I8X16_LT_S:                    "i8x16.lt_s",
+	I8X16_LT_U:                    "i8x16.lt_u",
+	I8X16_GT_S:                    "i8x16.gt_s",
+	I8X16_GT_U:                    "i8x16.gt_u",
+	I8X16_LE_S:                    "i8x16.le_s",
+	I8X16_LE_U:                    "i8x16.le_u",
+	I8X16_GE_S:                    "i8x16.ge_s",
+	I8X16_GE_U:                    "i8x16.ge_u",
+	I16X8_EQ:                      "i16x8.eq",
+	I16X8_NE:                      "i16x8.ne",
+	I16X8_LT_S:                    "i16x8.lt_s",
+	I16X8_LT_U:                    "i16x8.lt_u",
+	I16X8_GT_S:                    "i16x8.gt_s",
+	I16X8_GT_U:                    "i16x8.gt_u",
+	I16X8_LE_S:                    "i16x8.le_s",
+	I16X8_LE_U:                    "i16x8.le_u",
+	I16X8_GE_S:                    "i16x8.ge_s",
+	I16X8_GE_U:                    "i16x8.ge_u",
+	I32X4_EQ:                      "i32x4.eq",
+	I32X4_NE:                      "i32x4.ne",
+	I32X4_LT_S:                    "i32x4.lt_s",
+	I32X4_LT_U:                    "i32x4.lt_u",
+	I32X4_GT_S:                    "i32x4.gt_s",
+	I32X4_GT_U:                    "i32x4.gt_u",
+	I32X4_LE_S:                    "i32x4.le_s",
+	I32X4_LE_U:                    "i32x4.le_u",
+	I32X4_GE_S:                    "i32x4.ge_s",
+	I32X4_GE_U:                    "i32x4.ge_u",
+	F32X4_EQ:                      "f32x4.eq",
+	F32X4_NE:                      "f32x4.ne",
+	F32X4_LT:                      "f32x4.lt",
+	F32X4_GT:                      "f32x4.gt",
+	F32X4_LE:                      "f32x4.le",
+	F32X4_GE:                      "f32x4.ge",
+	F64X2_EQ:                      "f64x2.eq",
+	F64X2_NE:                      "f64x2.ne",
+	F64X2_LT:                      "f64x2.lt",
+	F64X2_GT:                      "f64x2.gt",
+	F64X2_LE:                      "f64x2.le",
+	F64X2_GE:                      "f64x2.ge",
+	V128_NOT:                      "v128.not",
+	V128_AND:                      "v128.and",
+	V128_ANDNOT:                   "v128.andnot",
+	V128_OR:                       "v128.or",
+	V128_XOR:                      "v128.xor",
+	V128_BITSELECT:                "v128.bitselect",
+	V128_ANY_TRUE:                 "v128.any_true",
+	V128_LOAD8_LANE:               "v128.load8_lane",
+	V128_LOAD16_LANE:              "v128.load16_lane",
+	V128_LOAD32_LANE:              "v128.load32_lane",
+	V128_LOAD64_LANE:              "v128.load64_lane",
+	V128_STORE8_LANE:              "v128.store8_lane",
+	V128_STORE16_LANE:             "v128.store16_lane",
+	V128_STORE32_LANE:             "v128.store32_lane",
+	V128_STORE64_LANE:             "v128.store64_lane",
+	V128_LOAD32_ZERO:              "v128.load32_zero",
+	V128_LOAD64_ZERO:              "v128.load64_zero",
+	F32X4_DEMOTE_F64X2_ZERO:       "f32x4.demote_f64x2_zero",
+	F64X2_PROMOTE_LOW_F32X4:       "f64x2.promote_low_f32x4",
+	I8X16_ABS:                     "i8x16.abs",
+	I8X16_NEG:                     "i8x16.neg",
+	I8X16_POPCNT:                  "i8x16.popcnt",
+	I8X16_ALL_TRUE:                "i8x16.all_true",
+	I8X16_BITMASK:                 "i8x16.bitmask",
+	I8X16_NARROW_I16X8_S:          "i8x16.narrow_i16x8_s",
+	I8X16_NARROW_I16X8_U:          "i8x16.narrow_i16x8_u",
+	F32X4_CEIL:                    "f32x4.ceil",
+	F32X4_FLOOR:                   "f32x4.floor",
+	F32X4_TRUNC:                   "f32x4.trunc",
+	F32X4_NEAREST:                 "f32x4.nearest",
+	I8X16_SHL:                     "i8x16.shl",
+	I8X16_SHR_S:                   "i8x16.shr_s",
+	I8X16_SHR_U:                   "i8x16.shr_u",
+	I8X16_ADD:                     "i8x16.add",
+	I8X16_ADD_SAT_S:               "i8x16.add_sat_s",
+	I8X16_ADD_SAT_U:               "i8x16.add_sat_u",
+	I8X16_SUB:                     "i8x16.sub",
+	I8X16_SUB_SAT_S:               "i8x16.sub_sat_s",
+	I8X16_SUB_SAT_U:               "i8x16.sub_sat_u",
+	F64X2_CEIL:                    "f64x2.ceil",
+	F64X2_FLOOR:                   "f64x2.floor",
+	I8X16_MIN_S:                   "i8x16.min_s",
+	I8X16_MIN_U:                   "i8x16.min_u",
+	I8X16_MAX_S:                   "i8x16.max_s",
+	I8X16_MAX_U:                   "i8x16.max_u",
+	F64X2_TRUNC:                   "f64x2.trunc",
+	I8X16_AVGR_U:                  "i8x16.avgr_u",
+	I16X8_EXTADD_PAIRWISE_I8X16_S: "i16x8.extadd_pairwise_i8x16_s",
+	I16X8_EXTADD_PAIRWISE_I8X16_U: "i16x8.extadd_pairwise_i8x16_u",
+	I32X4_EXTADD_PAIRWISE_I16X8_S: "i32x4.extadd_pairwise_i16x8_s",
+	I32X4_EXTADD_PAIRWISE_I16X8_U: "i32x4.extadd_pairwise_i16x8_u",
+	I16X8_ABS:                     "i16x8.abs",
+	I16X8_NEG:                     "i16x8.neg",
+	I16X8_Q15MULR_SAT_S:           "i16x8.q15mulr_sat_s",
+	I16X8_ALL_TRUE:                "i16x8.all_true",
+	I16X8_BITMASK:                 "i16x8.bitmask",
+	I16X8_NARROW_I32X4_S:          "i16x8.narrow_i32x4_s",
+	I16X8_NARROW_I32X4_U:          "i16x8.narrow_i32x4_u",
+	I16X8_EXTEND_LOW_I8X16_S:      "i16x8.extend_low_i8x16_s",
+	I16X8_EXTEND_HIGH_I8X16_S:     "i16x8.extend_high_i8x16_s",
+	I16X8_EXTEND_LOW_I8X16_U:      "i16x8.extend_low_i8x16_u",
+	I16X8_EXTEND_HIGH_I8X16_U:     "i16x8.extend_high_i8x16_u",
+	I16X8_SHL:                     "i16x8.shl",
+	I16X8_SHR_S:                   "i16x8.shr_s",
+	I16X8_SHR_U:                   "i16x8.shr_u",
+	I16X8_ADD:                     "i16x8.add",
+	I16X8_ADD_SAT_S:               "i16x8.add_sat_s",
+	I16X8_ADD_SAT_U:               "i16x8.add_sat_u",
+	I16X8_SUB:                     "i16x8.sub",
+	I16X8_SUB_SAT_S:               "i16x8.sub_sat_s",
+	I16X8_SUB_SAT_U:               "i16x8.sub_sat_u",
+	F64X2_NEAREST:                 "f64x2.nearest",
+	I16X8_MUL:                     "i16x8.mul",
+	I16X8_MIN_S:                   "i16x8.min_s",
+	I16X8_MIN_U:                   "i16x8.min_u",
+	I16X8_MAX_S:                   "i16x8.max_s",
+	I16X8_MAX_U:                   "i16x8.max_u",
+	I16X8_AVGR_U:                  "i16x8.avgr_u",
+	I16X8_EXTMUL_LOW_I8X16_S:      "i16x8.extmul_low_i8x16_s",
+	I16X8_EXTMUL_HIGH_I8X16_S:     "i16x8.extmul_high_i8x16_s",
+	I16X8_EXTMUL_LOW_I8X16_U:      "i16x8.extmul_low_i8x16_u",
+	I16X8_EXTMUL_HIGH_I8X16_U:     "i16x8.extmul_high_i8x16_u",
+	I32X4_ABS:                     "i32x4.abs",
+	I32X4_NEG:                     "i32x4.neg",
+	I32X4_ALL_TRUE:                "i32x4.all_true",
+	I32X4_BITMASK:                 "i32x4.bitmask",
+	I32X4_EXTEND_LOW_I16X8_S:      "i32x4.extend_low_i16x8_s",
+	I32X4_EXTEND_HIGH_I16X8_S:     "i32x4.extend_high_i16x8_s",
+	I32X4_EXTEND_LOW_I16X8_U:      "i32x4.extend_low_i16x8_u",
+	I32X4_EXTEND_HIGH_I16X8_U:     "i32x4.extend_high_i16x8_u",
+	I32X4_SHL:                     "i32x4.shl",
+	I32X4_SHR_S:                   "i32x4.shr_s",
+	I32X4_SHR_U:                   "i32x4.shr_u",
+	I32X4_ADD:                     "i32x4.add",
+	I32X4_SUB:                     "i32x4.sub",
+	I32X4_MUL:                     "i32x4.mul",
+	I32X4_MIN_S:                   "i32x4.min_s",
+	I32X4_MIN_U:                   "i32x4.min_u",
+	I32X4_MAX_S:                   "i32x4.max_s",
+	I32X4_MAX_U:                   "i32x4.max_u",
+	I32X4_DOT_I16X8_S:             "i32x4.dot_i16x8_s",
+	I32X4_EXTMUL_LOW_I16X8_S:      "i32x4.extmul_low_i16x8_s",
+	I32X4_EXTMUL_HIGH_I16X8_S:     "i32x4.extmul_high_i16x8_s",
+	I32X4_EXTMUL_LOW_I16X8_U:      "i32x4.extmul_low_i16x8_u",
+	I32X4_EXTMUL_HIGH_I16X8_U:     "i32x4.extmul_high_i16x8_u",
+	I64X2_ABS:                     "i64x2.abs",
+	I64X2_NEG:                     "i64x2.neg",
+	I64X2_ALL_TRUE:                "i64x2.all_true",
+	I64X2_BITMASK:                 "i64x2.bitmask",
+	I64X2_EXTEND_LOW_I32X4_S:      "i64x2.extend_low_i32x4_s",
+	I64X2_EXTEND_HIGH_I32X4_S:     "i64x2.extend_high_i32x4_s",
+	I64X2_EXTEND_LOW_I32X4_U:      "i64x2.extend_low_i32x4_u",
+	I64X2_EXTEND_HIGH_I32X4_U:     "i64x2.extend_high_i32x4_u",
+	I64X2_SHL:                     "i64x2.shl",
+	I64X2_SHR_S:                   "i64x2.shr_s",
+	I64X2_SHR_U:                   "i64x2.shr_u",
+	I64X2_ADD:                     "i64x2.add",
+	I64X2_SUB:                     "i64x2.sub",
+	I64X2_MUL:                     "i64x2.mul",
+	I64X2_EQ:                      "i64x2.eq",
+	I64X2_NE:                      "i64x2.ne",
+	I64X2_LT_S:                    "i64x2.lt_s",
+	I64X2_GT_S:                    "i64x2.gt_s",
+	I64X2_LE_S:                    "i64x2.le_s",
+	I64X2_GE_S:                    "i64x2.ge_s",
+	I64X2_EXTMUL_LOW_I32X4_S:      "i64x2.extmul_low_i32x4_s",
+	I64X2_EXTMUL_HIGH_I32X4_S:     "i64x2.extmul_high_i32x4_s",
+	I64X2_EXTMUL_LOW_I32X4_U:      "i64x2.extmul_low_i32x4_u",
+	I64X2_EXTMUL_HIGH_I32X4_U:     "i64x2.extmul_high_i32x4_u",
+	F32X4_ABS:                     "f32x4.abs",
+	F32X4_NEG:                     "f32x4.neg",
+	F32X4_SQRT:                    "f32x4.sqrt",
+	F32X4_ADD:                     "f32x4.add",
+	F32X4_SUB:                     "f32x4.sub",
+	F32X4_MUL:                     "f32x4.mul",
+	F32X4_DIV:                     "f32x4.div",
+	F32X4_MIN:                     "f32x4.min",
+	F32X4_MAX:                     "f32x4.max",
+	F32X4_PMIN:                    "f32x4.pmin",
+	F32X4_PMAX:                    "f32x4.pmax",
+	F64X2_ABS:                     "f64x2.abs",
+	F64X2_NEG:                     "f64x2.neg",
+	F64X2_SQRT:                    "f64x2.sqrt",
+	F64X2_ADD:                     "f64x2.add",
+	F64X2_SUB:                     "f64x2.sub",
+	F64X2_MUL:                     "f64x2.mul",
+	F64X2_DIV:                     "f64x2.div",
+	F64X2_MIN:                     "f64x2.min",
+	F64X2_MAX:                     "f64x2.max",
+	F64X2_PMIN:                    "f64x2.pmin",
+	F64X2_PMAX:                    "f64x2.pmax",
+	I32X4_TRUNC_SAT_F32X4_S:       "i32x4.trunc_sat_f32x4_s",
+	I32X4_TRUNC_SAT_F32X4_U:       "i32x4.trunc_sat_f32x4_u",
+	F32X4_CONVERT_I32X4_S:         "f32x4.convert_i32x4_s",
+	F32X4_CONVERT_I32X4_U:         "f32x4.convert_i32x4_u",
+	I32X4_TRUNC_SAT_F64X2_S_ZERO:  "i32x4.trunc_sat_f64x2_s_zero",
+	I32X4_TRUNC_SAT_F64X2_U_ZERO:  "i32x4.trunc_sat_f64x2_u_zero",
+	F64X2_CONVERT_LOW_I32X4_S:     "f64x2.convert_low_i32x4_s",
+	F64X2_CONVERT_LOW_I32X4_U:     "f64x2.convert_low_i32x4_u",
+}
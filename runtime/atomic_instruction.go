@@ -0,0 +1,226 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/types"
+)
+
+// execAtomic executes an atomic instruction of the threads proposal.
+// Atomic accesses are serialized by the memory, so they are safe for invocations running on other goroutines.
+// https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+func (i *interpreter) execAtomic(instr instruction.Instruction) (instructionResult, error) {
+	a := instr.(*instruction.Atomic)
+	if a.Op == instruction.ATOMIC_FENCE {
+		// accesses are sequentially consistent
+		return instructionResultRunNext, nil
+	}
+	mem, err := i.memory(a)
+	if err != nil {
+		return instructionResultTrap, err
+	}
+	var exec func(mem *instance.Memory, a *instruction.Atomic) error
+	switch {
+	case a.Op == instruction.MEMORY_ATOMIC_NOTIFY:
+		exec = i.atomicNotify
+	case a.Op == instruction.MEMORY_ATOMIC_WAIT32 || a.Op == instruction.MEMORY_ATOMIC_WAIT64:
+		exec = i.atomicWait
+	case a.Op <= instruction.I64_ATOMIC_LOAD32_U:
+		exec = i.atomicLoad
+	case a.Op <= instruction.I64_ATOMIC_STORE32:
+		exec = i.atomicStore
+	case a.Op < instruction.I32_ATOMIC_RMW_CMPXCHG:
+		exec = i.atomicRMW
+	default:
+		exec = i.atomicCmpxchg
+	}
+	if err := exec(mem, a); err != nil {
+		if errors.Is(err, instance.MemoryOutOfBounds) {
+			// the bounds are checked by the memory under its lock
+			err = fmt.Errorf("%w: %w", MemoryDoesNotHaveEnoughLength, err)
+		}
+		return instructionResultTrap, fmt.Errorf("%s: %w", a, err)
+	}
+	return instructionResultRunNext, nil
+}
+
+// atomicAddress pops the base address and returns the effective address of the access.
+// The bounds and the alignment are checked by the memory.
+func (i *interpreter) atomicAddress(mem *instance.Memory, a *instruction.Atomic) (uint64, error) {
+	base, err := i.stack.PopI32()
+	if err != nil {
+		return 0, err
+	}
+	return effectiveAddress(uint32(base), a.Imm), nil
+}
+
+func (i *interpreter) atomicLoad(mem *instance.Memory, a *instruction.Atomic) error {
+	ea, err := i.atomicAddress(mem, a)
+	if err != nil {
+		return err
+	}
+	v, err := mem.AtomicLoad(ea, atomicWidth(a.Op))
+	if err != nil {
+		return err
+	}
+	return i.stack.PushRaw(v, atomicType(a.Op))
+}
+
+func (i *interpreter) atomicStore(mem *instance.Memory, a *instruction.Atomic) error {
+	v, err := i.stack.PopRaw(atomicType(a.Op))
+	if err != nil {
+		return err
+	}
+	ea, err := i.atomicAddress(mem, a)
+	if err != nil {
+		return err
+	}
+	return mem.AtomicStore(ea, atomicWidth(a.Op), v)
+}
+
+func (i *interpreter) atomicRMW(mem *instance.Memory, a *instruction.Atomic) error {
+	t := atomicType(a.Op)
+	v, err := i.stack.PopRaw(t)
+	if err != nil {
+		return err
+	}
+	ea, err := i.atomicAddress(mem, a)
+	if err != nil {
+		return err
+	}
+	// the operations are grouped by 7 opcodes of i32, i64, i32 8, i32 16, i64 8, i64 16 and i64 32
+	var f func(old uint64) uint64
+	switch (a.Op - instruction.I32_ATOMIC_RMW_ADD) / 7 {
+	case 0:
+		f = func(old uint64) uint64 { return old + v }
+	case 1:
+		f = func(old uint64) uint64 { return old - v }
+	case 2:
+		f = func(old uint64) uint64 { return old & v }
+	case 3:
+		f = func(old uint64) uint64 { return old | v }
+	case 4:
+		f = func(old uint64) uint64 { return old ^ v }
+	default:
+		f = func(uint64) uint64 { return v }
+	}
+	old, err := mem.AtomicRMW(ea, atomicWidth(a.Op), f)
+	if err != nil {
+		return err
+	}
+	return i.stack.PushRaw(old, t)
+}
+
+func (i *interpreter) atomicCmpxchg(mem *instance.Memory, a *instruction.Atomic) error {
+	t := atomicType(a.Op)
+	replacement, err := i.stack.PopRaw(t)
+	if err != nil {
+		return err
+	}
+	expected, err := i.stack.PopRaw(t)
+	if err != nil {
+		return err
+	}
+	ea, err := i.atomicAddress(mem, a)
+	if err != nil {
+		return err
+	}
+	width := atomicWidth(a.Op)
+	// the expected value is wrapped to the width
+	if width < 8 {
+		expected &= 1<<(8*width) - 1
+	}
+	old, err := mem.AtomicRMW(ea, width, func(old uint64) uint64 {
+		if old == expected {
+			return replacement
+		}
+		return old
+	})
+	if err != nil {
+		return err
+	}
+	return i.stack.PushRaw(old, t)
+}
+
+func (i *interpreter) atomicWait(mem *instance.Memory, a *instruction.Atomic) error {
+	timeout, err := i.stack.PopRaw(types.I64)
+	if err != nil {
+		return err
+	}
+	t := types.I32
+	if a.Op == instruction.MEMORY_ATOMIC_WAIT64 {
+		t = types.I64
+	}
+	expected, err := i.stack.PopRaw(t)
+	if err != nil {
+		return err
+	}
+	ea, err := i.atomicAddress(mem, a)
+	if err != nil {
+		return err
+	}
+	// a negative timeout in nanoseconds waits forever
+	res, err := mem.Wait(ea, atomicWidth(a.Op), expected, time.Duration(int64(timeout)))
+	if err != nil {
+		return err
+	}
+	return i.stack.PushRaw(uint64(res), types.I32)
+}
+
+func (i *interpreter) atomicNotify(mem *instance.Memory, a *instruction.Atomic) error {
+	count, err := i.stack.PopI32()
+	if err != nil {
+		return err
+	}
+	ea, err := i.atomicAddress(mem, a)
+	if err != nil {
+		return err
+	}
+	n, err := mem.Notify(ea, uint32(count))
+	if err != nil {
+		return err
+	}
+	return i.stack.PushRaw(uint64(n), types.I32)
+}
+
+// atomicWidth returns the number of bytes accessed by an atomic instruction.
+func atomicWidth(op instruction.AtomicOpcode) uint32 {
+	switch op {
+	case instruction.MEMORY_ATOMIC_NOTIFY, instruction.MEMORY_ATOMIC_WAIT32:
+		return 4
+	case instruction.MEMORY_ATOMIC_WAIT64:
+		return 8
+	case instruction.I32_ATOMIC_LOAD8_U, instruction.I64_ATOMIC_LOAD8_U, instruction.I32_ATOMIC_STORE8, instruction.I64_ATOMIC_STORE8:
+		return 1
+	case instruction.I32_ATOMIC_LOAD16_U, instruction.I64_ATOMIC_LOAD16_U, instruction.I32_ATOMIC_STORE16, instruction.I64_ATOMIC_STORE16:
+		return 2
+	case instruction.I32_ATOMIC_LOAD, instruction.I64_ATOMIC_LOAD32_U, instruction.I32_ATOMIC_STORE, instruction.I64_ATOMIC_STORE32:
+		return 4
+	case instruction.I64_ATOMIC_LOAD, instruction.I64_ATOMIC_STORE:
+		return 8
+	}
+	// read-modify-write instructions
+	return [...]uint32{4, 8, 1, 2, 1, 2, 4}[(op-instruction.I32_ATOMIC_RMW_ADD)%7]
+}
+
+// atomicType returns the value type of the operand and the result of a load, store or read-modify-write instruction.
+func atomicType(op instruction.AtomicOpcode) types.ValueType {
+	switch op {
+	case instruction.I32_ATOMIC_LOAD, instruction.I32_ATOMIC_LOAD8_U, instruction.I32_ATOMIC_LOAD16_U,
+		instruction.I32_ATOMIC_STORE, instruction.I32_ATOMIC_STORE8, instruction.I32_ATOMIC_STORE16:
+		return types.I32
+	case instruction.I64_ATOMIC_LOAD, instruction.I64_ATOMIC_LOAD8_U, instruction.I64_ATOMIC_LOAD16_U, instruction.I64_ATOMIC_LOAD32_U,
+		instruction.I64_ATOMIC_STORE, instruction.I64_ATOMIC_STORE8, instruction.I64_ATOMIC_STORE16, instruction.I64_ATOMIC_STORE32:
+		return types.I64
+	}
+	switch (op - instruction.I32_ATOMIC_RMW_ADD) % 7 {
+	case 0, 2, 3:
+		return types.I32
+	default:
+		return types.I64
+	}
+}
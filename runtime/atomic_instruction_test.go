@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/debugger"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/types"
+)
+
+func newSharedMemory() *instance.Memory {
+	return instance.NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1, Max: 1}, Shared: true})
+}
+
+func newAtomicInterpreter(mem *instance.Memory) *interpreter {
+	m := &instance.Module{MemAddrs: []*instance.Memory{mem}}
+	return &interpreter{stack: stack.New(), cur: &current{frame: &stack.Frame{Module: m}}}
+}
+
+func TestExecAtomic(t *testing.T) {
+	for _, d := range []struct {
+		op       instruction.AtomicOpcode
+		operands []value.Value
+		exp      value.Value
+		mem      uint64 // 8 bytes from 8 after the instruction
+	}{
+		{op: instruction.I32_ATOMIC_LOAD, operands: []value.Value{value.I32(8)}, exp: value.I32(0x04030201), mem: 0x0807060504030201},
+		{op: instruction.I64_ATOMIC_LOAD16_U, operands: []value.Value{value.I32(8)}, exp: value.I64(0x0201), mem: 0x0807060504030201},
+		{op: instruction.I64_ATOMIC_STORE8, operands: []value.Value{value.I32(8), value.I64(0xffff)}, mem: 0x08070605040302ff},
+		{op: instruction.I32_ATOMIC_RMW_ADD, operands: []value.Value{value.I32(8), value.I32(0xffffffff)}, exp: value.I32(0x04030201), mem: 0x0807060504030200},
+		{op: instruction.I32_ATOMIC_RMW8_SUB_U, operands: []value.Value{value.I32(8), value.I32(2)}, exp: value.I32(0x01), mem: 0x08070605040302ff},
+		{op: instruction.I64_ATOMIC_RMW_AND, operands: []value.Value{value.I32(8), value.I64(0xff)}, exp: value.I64(0x0807060504030201), mem: 0x01},
+		{op: instruction.I64_ATOMIC_RMW32_OR_U, operands: []value.Value{value.I32(8), value.I64(0xf0f0f0f0)}, exp: value.I64(0x04030201), mem: 0x08070605f4f3f2f1},
+		{op: instruction.I32_ATOMIC_RMW16_XOR_U, operands: []value.Value{value.I32(10), value.I32(0xffff)}, exp: value.I32(0x0403), mem: 0x08070605fbfc0201},
+		{op: instruction.I64_ATOMIC_RMW_XCHG, operands: []value.Value{value.I32(8), value.I64(1)}, exp: value.I64(0x0807060504030201), mem: 0x01},
+		{op: instruction.I32_ATOMIC_RMW_CMPXCHG, operands: []value.Value{value.I32(8), value.I32(0x04030201), value.I32(7)}, exp: value.I32(0x04030201), mem: 0x0807060500000007},
+		{op: instruction.I32_ATOMIC_RMW_CMPXCHG, operands: []value.Value{value.I32(8), value.I32(1), value.I32(7)}, exp: value.I32(0x04030201), mem: 0x0807060504030201},
+		// the expected value is wrapped to 8 bits
+		{op: instruction.I64_ATOMIC_RMW8_CMPXCHG_U, operands: []value.Value{value.I32(8), value.I64(0x101), value.I64(0x1ff)}, exp: value.I64(0x01), mem: 0x08070605040302ff},
+		{op: instruction.MEMORY_ATOMIC_WAIT32, operands: []value.Value{value.I32(8), value.I32(0), value.I64(0)}, exp: value.I32(instance.WaitResultNotEqual), mem: 0x0807060504030201},
+		{op: instruction.MEMORY_ATOMIC_WAIT64, operands: []value.Value{value.I32(8), value.I64(0x0807060504030201), value.I64(1000)}, exp: value.I32(instance.WaitResultTimedOut), mem: 0x0807060504030201},
+		{op: instruction.MEMORY_ATOMIC_NOTIFY, operands: []value.Value{value.I32(8), value.I32(1)}, exp: value.I32(0), mem: 0x0807060504030201},
+	} {
+		mem := newSharedMemory()
+		require.NoError(t, mem.WriteUint64LE(8, 0x0807060504030201))
+		i := newAtomicInterpreter(mem)
+		for _, o := range d.operands {
+			require.NoError(t, i.stack.PushValue(o))
+		}
+		_, err := i.execAtomic(&instruction.Atomic{Op: d.op})
+		require.NoError(t, err, d.op.String())
+		if d.exp != nil {
+			res, err := i.stack.PopValue()
+			require.NoError(t, err)
+			assert.Equal(t, d.exp, res, d.op.String())
+		}
+		assert.Equal(t, 0, len(i.stack.Values()), d.op.String())
+		v, err := mem.ReadUint64LE(8)
+		require.NoError(t, err)
+		assert.Equal(t, d.mem, v, d.op.String())
+	}
+}
+
+func TestExecAtomic_Trap(t *testing.T) {
+	for _, d := range []struct {
+		op       instruction.AtomicOpcode
+		offset   uint32
+		operands []value.Value
+		shared   bool
+		kind     string
+	}{
+		{op: instruction.I32_ATOMIC_LOAD, operands: []value.Value{value.I32(2)}, shared: true, kind: "unaligned atomic"},
+		{op: instruction.I64_ATOMIC_STORE, offset: 4, operands: []value.Value{value.I32(0), value.I64(1)}, shared: true, kind: "unaligned atomic"},
+		{op: instruction.I32_ATOMIC_RMW16_ADD_U, operands: []value.Value{value.I32(instance.PAGE_SIZE - 1), value.I32(1)}, shared: true, kind: "out of bounds memory access"},
+		{op: instruction.I32_ATOMIC_LOAD, offset: 0xffffffff, operands: []value.Value{value.I32(1)}, shared: true, kind: "out of bounds memory access"},
+		{op: instruction.MEMORY_ATOMIC_NOTIFY, operands: []value.Value{value.I32(1), value.I32(1)}, shared: true, kind: "unaligned atomic"},
+		{op: instruction.MEMORY_ATOMIC_WAIT32, operands: []value.Value{value.I32(0), value.I32(0), value.I64(0)}, kind: "expected shared memory"},
+	} {
+		mem := newSharedMemory()
+		if !d.shared {
+			mem = instance.NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1}})
+		}
+		i := newAtomicInterpreter(mem)
+		for _, o := range d.operands {
+			require.NoError(t, i.stack.PushValue(o))
+		}
+		_, err := i.execAtomic(&instruction.Atomic{Op: d.op, Imm: instruction.MemoryImm{Offset: d.offset}})
+		require.Error(t, err, d.op.String())
+		assert.Equal(t, d.kind, TrapKind(err), d.op.String())
+		assert.Equal(t, make([]byte, instance.PAGE_SIZE), mem.Data, d.op.String())
+	}
+}
+
+func newSharedInterpreters(t *testing.T, mem *instance.Memory, n int) []Interpreter {
+	dec, err := decoder.New("../examples/atomic.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	interpreters := make([]Interpreter, 0, n)
+	for j := 0; j < n; j++ {
+		i, err := New(mod, []instance.ExternalValue{mem}, debugger.DebugLevelNoLog)
+		require.NoError(t, err)
+		interpreters = append(interpreters, i)
+	}
+	return interpreters
+}
+
+func TestInvoke_SharedMemory(t *testing.T) {
+	mem := newSharedMemory()
+	interpreters := newSharedInterpreters(t, mem, 2)
+	wg := sync.WaitGroup{}
+	for j := 0; j < 8; j++ {
+		wg.Add(1)
+		go func(i Interpreter) {
+			defer wg.Done()
+			_, err := i.Invoke("increment", []value.Value{value.I32(500)})
+			assert.NoError(t, err)
+		}(interpreters[j%2])
+	}
+	wg.Wait()
+	for _, i := range interpreters {
+		res, err := i.Invoke("load", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []value.Value{value.I32(4000)}, res)
+	}
+}
+
+func TestInvoke_WaitNotify(t *testing.T) {
+	interpreters := newSharedInterpreters(t, newSharedMemory(), 2)
+	waited := make(chan []value.Value)
+	go func() {
+		res, err := interpreters[0].Invoke("wait", []value.Value{value.I32(0)})
+		assert.NoError(t, err)
+		waited <- res
+	}()
+	res, err := interpreters[1].Invoke("notify", nil)
+	require.NoError(t, err)
+	// the waiter doesn't wait when it starts after the flag is set
+	if assert.Equal(t, 1, len(res)) && res[0] == value.I32(1) {
+		assert.Equal(t, []value.Value{value.I32(instance.WaitResultOk)}, <-waited)
+	} else {
+		assert.Equal(t, []value.Value{value.I32(instance.WaitResultNotEqual)}, <-waited)
+	}
+}
+
+func TestNew_ImportedMemoryNotMatched(t *testing.T) {
+	dec, err := decoder.New("../examples/atomic.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	for _, externalvals := range [][]instance.ExternalValue{
+		{instance.NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1, Max: 1}})},
+		{instance.NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1, Max: 2}, Shared: true})},
+		{newSharedMemory(), newSharedMemory()},
+	} {
+		_, err := New(mod, externalvals, debugger.DebugLevelNoLog)
+		assert.ErrorIs(t, err, instance.ExternalValuesNotMatched)
+	}
+}
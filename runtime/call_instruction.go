@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/instance"
+)
+
+var (
+	TrapUndefinedElement         error = errors.New("trap: undefined element")
+	TrapUninitializedElement     error = errors.New("trap: uninitialized element")
+	TrapIndirectCallTypeMismatch error = errors.New("trap: indirect call type mismatch")
+)
+
+func (i *interpreter) execCallIndirect(instr instruction.Instruction) (instructionResult, error) {
+	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-control-mathsf-call-indirect-x-y
+	f, err := i.indirectFunction(instruction.Imm[instruction.CallIndirectImm](instr))
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("call_indirect: %w", err)
+	}
+	i.f = f
+	return instructionResultCallFunc, nil
+}
+
+func (i *interpreter) execReturnCall(instr instruction.Instruction) (instructionResult, error) {
+	// https://github.com/WebAssembly/tail-call/blob/main/proposals/tail-call/Overview.md#execution
+	index := instruction.Imm[uint32](instr)
+	funcs := i.cur.frame.Module.FuncAddrs
+	if int(index) >= len(funcs) {
+		return instructionResultTrap, fmt.Errorf("return_call: func[%d] is not found", index)
+	}
+	res, err := i.tailCall(funcs[index])
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("return_call: %w", err)
+	}
+	return res, nil
+}
+
+func (i *interpreter) execReturnCallIndirect(instr instruction.Instruction) (instructionResult, error) {
+	f, err := i.indirectFunction(instruction.Imm[instruction.CallIndirectImm](instr))
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("return_call_indirect: %w", err)
+	}
+	res, err := i.tailCall(f)
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("return_call_indirect: %w", err)
+	}
+	return res, nil
+}
+
+// indirectFunction pops an element index and returns the function at the index of the table.
+// The function must have the type of the immediate.
+func (i *interpreter) indirectFunction(imm instruction.CallIndirectImm) (*instance.Function, error) {
+	mod := i.cur.frame.Module
+	if int(imm.TableIndex) >= len(mod.TableAddrs) {
+		return nil, fmt.Errorf("table[%d] is not found", imm.TableIndex)
+	}
+	if int(imm.TypeIndex) >= len(mod.Types) {
+		return nil, fmt.Errorf("type[%d] is not found", imm.TypeIndex)
+	}
+	idx, err := i.stack.PopI32()
+	if err != nil {
+		return nil, err
+	}
+	table := mod.TableAddrs[imm.TableIndex]
+	if int(idx) >= len(table.Elems) {
+		return nil, fmt.Errorf("%w: index=%d size=%d", TrapUndefinedElement, idx, len(table.Elems))
+	}
+	f, ok := table.Elems[idx].(*instance.Function)
+	if !ok || f == nil {
+		return nil, fmt.Errorf("%w: index=%d", TrapUninitializedElement, idx)
+	}
+	t := mod.Types[imm.TypeIndex]
+	if !f.Type.Params.Equal(t.Params) || !f.Type.Returns.Equal(t.Returns) {
+		return nil, fmt.Errorf("%w: index=%d", TrapIndirectCallTypeMismatch, idx)
+	}
+	return f, nil
+}
+
+// tailCall drops the labels and the frame of the executing function and calls f with the arguments on the top of the value stack,
+// so that the frame stack doesn't grow.
+func (i *interpreter) tailCall(f *instance.Function) (instructionResult, error) {
+	if !f.Type.Returns.Equal(i.cur.frame.Function.Type.Returns) {
+		return instructionResultTrap, fmt.Errorf("%w: results of the callee are %v", ExecutionErrorTypeNotMatched, f.Type.Returns)
+	}
+	if err := i.stack.ValidateValue(f.Type.Params); err != nil {
+		return instructionResultTrap, err
+	}
+	branch := i.cur.code.branches[i.pc()]
+	if branch == nil {
+		return instructionResultTrap, fmt.Errorf("branch target is not resolved at %d", i.pc())
+	}
+	// the function label is the outermost label of the frame
+	label, err := i.stack.RefLabel(branch.labels - 1)
+	if err != nil {
+		return instructionResultTrap, err
+	}
+	if err := i.stack.Unwind(label.Height, len(f.Type.Params)); err != nil {
+		return instructionResultTrap, err
+	}
+	if err := i.stack.PopLabels(branch.labels); err != nil {
+		return instructionResultTrap, err
+	}
+	if _, err := i.stack.PopFrame(); err != nil {
+		return instructionResultTrap, err
+	}
+	i.f = f
+	return instructionResultCallFunc, nil
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/debugger"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestInvoke_TailCall(t *testing.T) {
+	i := newInterpreterFromFile(t, "../examples/tail_call.wasm")
+	for _, d := range []struct {
+		name string
+		n    int32
+		exp  value.I32
+	}{
+		{name: "is_even", n: 0, exp: 1},
+		{name: "is_even", n: 7, exp: 0},
+		{name: "is_odd", n: 7, exp: 1},
+		// deeper than the frame stack limit
+		{name: "is_even", n: stack.FRAME_STACK_LIMIT * 2, exp: 1},
+		{name: "is_odd", n: stack.FRAME_STACK_LIMIT*2 + 1, exp: 1},
+	} {
+		res, err := i.Invoke(d.name, []value.Value{value.I32(d.n)})
+		require.NoError(t, err, "%s(%d)", d.name, d.n)
+		assert.Equal(t, []value.Value{d.exp}, res, "%s(%d)", d.name, d.n)
+	}
+}
+
+func TestExecCallIndirect_Trap(t *testing.T) {
+	ft := &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{types.I32}}
+	other := &types.FuncType{Params: types.ResultType{types.I32}, Returns: types.ResultType{types.I32}}
+	mod := &structure.Module{
+		Types:  []*types.FuncType{ft, other},
+		Tables: []*structure.Table{{Type: &types.TableType{ElementType: types.ElemTypeFuncref, Limits: &types.Limits{Min: 3, Max: 3}}}},
+		Elements: []*structure.Element{
+			{Type: types.ElemTypeFuncref, Offset: &instruction.I32Const{Imm: 0}, Init: []uint32{0, 1}},
+		},
+		Functions: []*structure.Function{
+			{Type: 0, Body: []instruction.Instruction{&instruction.I32Const{Imm: 1}, &instruction.End{}}},
+			{Type: 1, Body: []instruction.Instruction{&instruction.GetLocal{Imm: 0}, &instruction.End{}}},
+			{Type: 1, Body: []instruction.Instruction{
+				&instruction.GetLocal{Imm: 0},
+				&instruction.CallIndirect{Imm: instruction.CallIndirectImm{TypeIndex: 0}},
+				&instruction.End{},
+			}},
+			{Type: 1, Body: []instruction.Instruction{
+				&instruction.GetLocal{Imm: 0},
+				&instruction.ReturnCallIndirect{Imm: instruction.CallIndirectImm{TypeIndex: 0}},
+				&instruction.End{},
+			}},
+		},
+		Exports: []*structure.Export{
+			{Name: "call", Desc: &structure.ExportDesc{Type: structure.DescTypeFunc, Val: 2}},
+			{Name: "return_call", Desc: &structure.ExportDesc{Type: structure.DescTypeFunc, Val: 3}},
+		},
+	}
+	i, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	for _, name := range []string{"call", "return_call"} {
+		res, err := i.Invoke(name, []value.Value{value.I32(0)})
+		require.NoError(t, err)
+		assert.Equal(t, []value.Value{value.I32(1)}, res)
+		for _, d := range []struct {
+			idx int32
+			err error
+		}{
+			{idx: 1, err: TrapIndirectCallTypeMismatch},
+			{idx: 2, err: TrapUninitializedElement},
+			{idx: 3, err: TrapUndefinedElement},
+		} {
+			_, err := i.Invoke(name, []value.Value{value.I32(d.idx)})
+			assert.ErrorIs(t, err, d.err, "%s(%d)", name, d.idx)
+		}
+	}
+}
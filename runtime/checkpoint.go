@@ -0,0 +1,203 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+const CHECKPOINT_VERSION = 1
+
+var (
+	Suspended                 error = errors.New("Invocation is suspended")
+	CheckpointVersionNotMatch error = errors.New("Checkpoint version is not supported")
+)
+
+// Checkpoint is the state of an invocation between two instructions.
+// It is encoded as JSON by WriteCheckpoint and resumed by Resume with an interpreter of the same module.
+type Checkpoint struct {
+	Version  int             `json:"version"`
+	Function string          `json:"function"` // invoked export
+	Args     []string        `json:"args"`
+	Steps    uint64          `json:"steps"` // number of executed instructions
+	Instance *instance.State `json:"instance"`
+	Stack    *stack.State    `json:"stack"`
+	Caught   []CaughtState   `json:"caught,omitempty"` // exceptions which handlers on the stack can rethrow
+}
+
+// CaughtState is an exception caught by the handler of a catch label.
+// The tag is referred to by its index in the module instance and the values by their literals.
+type CaughtState struct {
+	Label  int      `json:"label"` // index of the catch label in the label stack
+	Tag    int      `json:"tag"`
+	Values []string `json:"values"`
+}
+
+// CheckpointOptions configures when checkpoints are taken.
+type CheckpointOptions struct {
+	// Interval is the number of instructions between checkpoints. 0 disables periodic checkpoints.
+	Interval uint64
+	// Stop suspends the invocation with a checkpoint when it is closed.
+	Stop <-chan struct{}
+	// Save is called with each checkpoint. The invocation fails when it returns an error.
+	Save func(*Checkpoint) error
+}
+
+// checkpointer takes checkpoints of an invocation.
+type checkpointer struct {
+	opts     CheckpointOptions
+	function string
+	args     []string
+	steps    uint64
+}
+
+// InvokeWithCheckpoint invokes the exported function like Invoke while taking checkpoints.
+// When opts.Stop is closed, the invocation is suspended and returns Suspended after saving the checkpoint.
+func InvokeWithCheckpoint(i Interpreter, name string, locals []value.Value, opts CheckpointOptions) ([]value.Value, error) {
+	r, ok := i.(*runner)
+	if !ok {
+		return nil, InterpreterNotSupported
+	}
+	return r.invoke(func(i *interpreter) ([]value.Value, error) {
+		args := make([]string, 0, len(locals))
+		for _, l := range locals {
+			args = append(args, fmt.Sprint(l))
+		}
+		i.checkpoint = &checkpointer{opts: opts, function: name, args: args}
+		return i.Invoke(name, locals)
+	})
+}
+
+// Resume restores the instance and the stack of the interpreter from the checkpoint and continues the invocation.
+// Memories, tables and globals of the instance are overwritten.
+func Resume(i Interpreter, c *Checkpoint, opts CheckpointOptions) ([]value.Value, error) {
+	r, ok := i.(*runner)
+	if !ok {
+		return nil, InterpreterNotSupported
+	}
+	if c.Version != CHECKPOINT_VERSION {
+		return nil, fmt.Errorf("Resume: %w: %d", CheckpointVersionNotMatch, c.Version)
+	}
+	return r.invoke(func(i *interpreter) ([]value.Value, error) {
+		i.checkpoint = &checkpointer{opts: opts, function: c.Function, args: c.Args, steps: c.Steps}
+		return i.resume(c)
+	})
+}
+
+// WriteCheckpoint encodes the checkpoint to w.
+func WriteCheckpoint(w io.Writer, c *Checkpoint) error {
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ReadCheckpoint decodes a checkpoint written by WriteCheckpoint.
+func ReadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	c := &Checkpoint{}
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+	if c.Version != CHECKPOINT_VERSION {
+		return nil, fmt.Errorf("read checkpoint: %w: %d", CheckpointVersionNotMatch, c.Version)
+	}
+	if c.Instance == nil || c.Stack == nil {
+		return nil, fmt.Errorf("read checkpoint: state is missing")
+	}
+	return c, nil
+}
+
+// resume continues the invocation from the checkpoint on the stack of the context.
+func (i *interpreter) resume(c *Checkpoint) ([]value.Value, error) {
+	defer i.reset()
+	if i.debubber != nil {
+		i.debubber.ShowInfo(c.Function)
+	}
+	f, err := i.exportedFunction(c.Function)
+	if err != nil {
+		return nil, fmt.Errorf("Resume: \n\t%w", err)
+	}
+	if err := i.stack.Restore(c.Stack, i.instance); err != nil {
+		return nil, fmt.Errorf("Resume: \n\t%w", err)
+	}
+	if i.stack.LenFrame() == 0 || i.stack.LenLabel() == 0 {
+		return nil, fmt.Errorf("Resume: \n\t%w: no frame", stack.StateNotMatched)
+	}
+	caught, err := i.restoreCaught(c.Caught)
+	if err != nil {
+		return nil, fmt.Errorf("Resume: \n\t%w", err)
+	}
+	if err := i.instance.Load(c.Instance); err != nil {
+		return nil, fmt.Errorf("Resume: \n\t%w", err)
+	}
+	i.caught = caught
+	if !i.isInvocationFinished() {
+		if err := i.updateCurrent(); err != nil {
+			return nil, fmt.Errorf("Resume: \n\t%w", err)
+		}
+	}
+	if err := i.execute(); err != nil {
+		return nil, fmt.Errorf("Resume: \n\t%w", err)
+	}
+	res, err := i.finishInvoke(f)
+	if err != nil {
+		return nil, fmt.Errorf("Resume: \n\t%w", err)
+	}
+	return res, nil
+}
+
+// check is called before each instruction. It takes a checkpoint when the interval has passed
+// and suspends the invocation when the stop channel is closed.
+func (c *checkpointer) check(i *interpreter) error {
+	select {
+	case <-c.opts.Stop:
+		if err := c.save(i); err != nil {
+			return err
+		}
+		return Suspended
+	default:
+	}
+	if c.opts.Interval != 0 && c.steps != 0 && c.steps%c.opts.Interval == 0 {
+		if err := c.save(i); err != nil {
+			return err
+		}
+	}
+	c.steps++
+	return nil
+}
+
+func (c *checkpointer) save(i *interpreter) error {
+	if c.opts.Save == nil {
+		return nil
+	}
+	inst, err := i.instance.State()
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	s, err := i.stack.State(i.instance)
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	caught, err := i.caughtState()
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	cp := &Checkpoint{
+		Version:  CHECKPOINT_VERSION,
+		Function: c.function,
+		Args:     c.args,
+		Steps:    c.steps,
+		Instance: inst,
+		Stack:    s,
+		Caught:   caught,
+	}
+	if err := c.opts.Save(cp); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
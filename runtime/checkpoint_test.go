@@ -0,0 +1,130 @@
+package runtime
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/runtime/debugger"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+func newInterpreterFromFile(t *testing.T, path string) Interpreter {
+	dec, err := decoder.New(path)
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	i, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	return i
+}
+
+func TestResume(t *testing.T) {
+	for _, d := range []struct {
+		path     string
+		name     string
+		args     []value.Value
+		interval uint64
+		exp      []value.Value
+	}{
+		{path: "../examples/fibonacci.wasm", name: "fib_recursive", args: []value.Value{value.I32(10)}, interval: 50, exp: []value.Value{value.I32(34)}},
+		{path: "../examples/fibonacci.wasm", name: "fib_iterative", args: []value.Value{value.I32(10)}, interval: 7, exp: []value.Value{value.I32(34)}},
+		// the stored value is only in the memory of the checkpoints after the store
+		{path: "../examples/memory.wasm", name: "i32_load16_u", args: []value.Value{value.I32(0xbeef)}, interval: 1, exp: []value.Value{value.I32(0xbeef)}},
+		// the memory of the checkpoint after memory.grow is larger than the fresh instance
+		{path: "../examples/grow.wasm", name: "grow", args: []value.Value{value.I32(1)}, interval: 1, exp: []value.Value{value.I32(1)}},
+	} {
+		checkpoints := make([]*bytes.Buffer, 0)
+		res, err := InvokeWithCheckpoint(newInterpreterFromFile(t, d.path), d.name, d.args, CheckpointOptions{
+			Interval: d.interval,
+			Save: func(c *Checkpoint) error {
+				buf := &bytes.Buffer{}
+				checkpoints = append(checkpoints, buf)
+				return WriteCheckpoint(buf, c)
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, res)
+		require.NotEmpty(t, checkpoints)
+		for n, buf := range checkpoints {
+			c, err := ReadCheckpoint(buf)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(n+1)*d.interval, c.Steps)
+			res, err := Resume(newInterpreterFromFile(t, d.path), c, CheckpointOptions{})
+			require.NoError(t, err, "%s: checkpoint %d", d.name, n)
+			assert.Equal(t, d.exp, res, "%s: checkpoint %d", d.name, n)
+		}
+	}
+}
+
+func TestResume_Suspended(t *testing.T) {
+	stop := make(chan struct{})
+	var checkpoint *Checkpoint
+	opts := CheckpointOptions{
+		Stop: stop,
+		Save: func(c *Checkpoint) error {
+			checkpoint = c
+			return nil
+		},
+	}
+	close(stop)
+	i := newInterpreterFromFile(t, "../examples/factorial.wasm")
+	_, err := InvokeWithCheckpoint(i, "factorial", []value.Value{value.I32(5)}, opts)
+	require.ErrorIs(t, err, Suspended)
+	assert.Equal(t, "suspended", TrapKind(err))
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, uint64(0), checkpoint.Steps)
+
+	res, err := Resume(i, checkpoint, CheckpointOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.I32(120)}, res)
+}
+
+func TestResume_NotMatched(t *testing.T) {
+	var checkpoint *Checkpoint
+	_, err := InvokeWithCheckpoint(newInterpreterFromFile(t, "../examples/fibonacci.wasm"), "fib_recursive", []value.Value{value.I32(5)}, CheckpointOptions{
+		Interval: 10,
+		Save: func(c *Checkpoint) error {
+			checkpoint = c
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	// factorial.wasm doesn't export fib_recursive
+	_, err = Resume(newInterpreterFromFile(t, "../examples/factorial.wasm"), checkpoint, CheckpointOptions{})
+	assert.Error(t, err)
+
+	checkpoint.Version = CHECKPOINT_VERSION + 1
+	_, err = Resume(newInterpreterFromFile(t, "../examples/fibonacci.wasm"), checkpoint, CheckpointOptions{})
+	assert.ErrorIs(t, err, CheckpointVersionNotMatch)
+}
+
+func TestResume_Rethrow(t *testing.T) {
+	checkpoints := make([]*bytes.Buffer, 0)
+	_, err := InvokeWithCheckpoint(newInterpreterFromFile(t, "../examples/exception.wasm"), "rethrow", []value.Value{value.I32(5)}, CheckpointOptions{
+		Interval: 1,
+		Save: func(c *Checkpoint) error {
+			buf := &bytes.Buffer{}
+			checkpoints = append(checkpoints, buf)
+			return WriteCheckpoint(buf, c)
+		},
+	})
+	var exc *Exception
+	require.ErrorAs(t, err, &exc)
+	caught := 0
+	for n, buf := range checkpoints {
+		c, err := ReadCheckpoint(buf)
+		require.NoError(t, err)
+		if len(c.Caught) > 0 {
+			caught++
+		}
+		// checkpoints in the handler resume with the caught exception
+		_, err = Resume(newInterpreterFromFile(t, "../examples/exception.wasm"), c, CheckpointOptions{})
+		require.ErrorAs(t, err, &exc, "checkpoint %d", n)
+		assert.Equal(t, []value.Value{value.I32(5)}, exc.Values, "checkpoint %d", n)
+	}
+	assert.NotZero(t, caught)
+}
@@ -0,0 +1,291 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+	"github.com/terassyi/gowi/validator"
+)
+
+var (
+	CompileErrorUnmatchedEnd      error = errors.New("Compile error: unmatched end")
+	CompileErrorUnmatchedElse     error = errors.New("Compile error: else without if")
+	CompileErrorInvalidLabel      error = errors.New("Compile error: invalid label index")
+	CompileErrorUnmatchedCatch    error = errors.New("Compile error: catch without try")
+	CompileErrorUnmatchedDelegate error = errors.New("Compile error: delegate without try")
+)
+
+// compiledFunction is a function body flattened into a single instruction sequence.
+// Targets of structured instructions and branches are resolved at instantiation,
+// so that entering a block or taking a branch is a jump to a known position.
+type compiledFunction struct {
+	body     []instruction.Instruction
+	blocks   []*blockTarget       // indexed by the position of block, loop, if and try
+	branches []*branchTarget      // indexed by the position of br, br_if, else, catch, catch_all, return and tail calls
+	tries    map[int]*blockTarget // try blocks keyed by the position where a branch to the label continues
+	locals   []types.ValueType    // params followed by the declared locals
+}
+
+// blockTarget is the resolved block, loop, if or try instruction.
+type blockTarget struct {
+	label    stack.Label // Height is filled when the label is pushed
+	params   int
+	els      int           // position of else, -1 when the if block doesn't have else
+	end      int           // position of the matching end, or delegate
+	catches  []catchClause // handlers of try in order
+	delegate int           // label index delegate rethrows to, -1 when try doesn't end with delegate
+}
+
+// catchClause is a catch or catch_all of try.
+type catchClause struct {
+	all bool   // catch_all
+	tag uint32 // tagidx caught by catch
+	pc  int    // position of catch or catch_all
+}
+
+// branchTarget is the resolved br, br_if, else, return or tail call instruction.
+// The value stack height to unwind to is taken from the target label at runtime.
+type branchTarget struct {
+	pc     int   // position to continue
+	arity  uint8 // number of values carried by the branch
+	labels int   // number of labels to pop
+	ret    bool  // the branch returns from the function
+}
+
+// control is a structured instruction which is not closed yet while compiling.
+type control struct {
+	block   *blockTarget
+	pending []int // positions of forward branches resolved when the end is found
+}
+
+// codeCache holds compiled functions keyed by the function in the module structure,
+// so that instances restored from a snapshot share them.
+// It is safe for concurrent use by multiple goroutines.
+type codeCache struct {
+	mu       sync.RWMutex
+	codes    map[*structure.Function]*compiledFunction
+	features types.Features // allowed in lazily decoded functions
+}
+
+func newCodeCache(features types.Features) *codeCache {
+	return &codeCache{codes: make(map[*structure.Function]*compiledFunction), features: features}
+}
+
+// get returns the compiled body of f.
+// A function which is not compiled yet, such as a lazily decoded one, is decoded, validated and compiled.
+func (c *codeCache) get(f *instance.Function) (*compiledFunction, error) {
+	c.mu.RLock()
+	code, ok := c.codes[f.Code]
+	c.mu.RUnlock()
+	if ok {
+		return code, nil
+	}
+	if err := validator.ValidateFunction(f.Code, c.features); err != nil {
+		return nil, err
+	}
+	code, err := compileFunction(f)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if compiled, ok := c.codes[f.Code]; ok {
+		// compiled by another invocation meanwhile
+		return compiled, nil
+	}
+	c.codes[f.Code] = code
+	return code, nil
+}
+
+// compileModule compiles all decoded functions of the module.
+// Lazily decoded functions are validated with the features and compiled on their first call.
+func compileModule(mod *instance.Module, features types.Features) (*codeCache, error) {
+	c := newCodeCache(features)
+	for idx, f := range mod.FuncAddrs {
+		if !f.Code.Decoded() {
+			continue
+		}
+		code, err := compileFunction(f)
+		if err != nil {
+			return nil, fmt.Errorf("compile func[%d]: %w", idx, err)
+		}
+		c.codes[f.Code] = code
+	}
+	return c, nil
+}
+
+func compileFunction(f *instance.Function) (*compiledFunction, error) {
+	body, err := f.Code.Instructions()
+	if err != nil {
+		return nil, fmt.Errorf("compile: %w", err)
+	}
+	code := &compiledFunction{
+		body:     body,
+		blocks:   make([]*blockTarget, len(body)),
+		branches: make([]*branchTarget, len(body)),
+		tries:    make(map[int]*blockTarget),
+		locals:   append(append([]types.ValueType{}, f.Type.Params...), f.Code.Locals...),
+	}
+	// the function itself is the outermost label
+	controls := []*control{{
+		block: &blockTarget{
+			label: stack.Label{Type: stack.LabelTypeFunction, N: uint8(len(f.Type.Returns)), Pc: len(body)},
+			els:   -1,
+			end:   len(body) - 1,
+		},
+	}}
+	terminated := false
+	for pc, instr := range body {
+		switch instr.Opcode() {
+		case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+			ft, err := expand(f.Module, instruction.Imm[types.BlockType](instr))
+			if err != nil {
+				return nil, fmt.Errorf("compile: %w", err)
+			}
+			labelType, err := stack.NewLabelType(instr)
+			if err != nil {
+				return nil, fmt.Errorf("compile: %w", err)
+			}
+			block := &blockTarget{
+				label:  stack.Label{Type: labelType, N: uint8(len(ft.Returns))},
+				params: len(ft.Params),
+				els:    -1,
+			}
+			if labelType == stack.LabelTypeTry {
+				block.delegate = -1
+			}
+			if labelType == stack.LabelTypeLoop {
+				// branching to a loop jumps back to the head of its body with its parameters
+				block.label.N = uint8(len(ft.Params))
+				block.label.Pc = pc + 1
+			}
+			code.blocks[pc] = block
+			controls = append(controls, &control{block: block})
+		case instruction.ELSE:
+			c := controls[len(controls)-1]
+			if c.block.label.Type != stack.LabelTypeIf || c.block.els != -1 {
+				return nil, fmt.Errorf("compile: %w: at %d", CompileErrorUnmatchedElse, pc)
+			}
+			c.block.els = pc
+			// the end of the then branch jumps to the end of if, which pops the label
+			code.branches[pc] = &branchTarget{}
+		case instruction.CATCH, instruction.CATCH_ALL:
+			c := controls[len(controls)-1]
+			catches := c.block.catches
+			if c.block.label.Type != stack.LabelTypeTry || (len(catches) > 0 && catches[len(catches)-1].all) {
+				return nil, fmt.Errorf("compile: %w: at %d", CompileErrorUnmatchedCatch, pc)
+			}
+			clause := catchClause{all: instr.Opcode() == instruction.CATCH_ALL, pc: pc}
+			if !clause.all {
+				clause.tag = instruction.Imm[uint32](instr)
+			}
+			c.block.catches = append(catches, clause)
+			// the end of the try body or the previous handler jumps to the end of try
+			code.branches[pc] = &branchTarget{}
+		case instruction.DELEGATE:
+			c := controls[len(controls)-1]
+			if len(controls) == 1 || c.block.label.Type != stack.LabelTypeTry || len(c.block.catches) > 0 {
+				return nil, fmt.Errorf("compile: %w: at %d", CompileErrorUnmatchedDelegate, pc)
+			}
+			// the label index is counted from the label outside of try
+			depth := int(instruction.Imm[uint32](instr))
+			if depth >= len(controls)-1 {
+				return nil, fmt.Errorf("compile: %w: %d at %d", CompileErrorInvalidLabel, depth, pc)
+			}
+			c.block.delegate = depth
+			c.block.end = pc
+			c.block.label.Pc = pc + 1
+			code.tries[c.block.label.Pc] = c.block
+			c.patch(code)
+			controls = controls[:len(controls)-1]
+		case instruction.END:
+			c := controls[len(controls)-1]
+			if len(controls) == 1 {
+				if pc != len(body)-1 {
+					return nil, fmt.Errorf("compile: %w: at %d", CompileErrorUnmatchedEnd, pc)
+				}
+				terminated = true
+				continue
+			}
+			c.block.end = pc
+			if c.block.els != -1 {
+				code.branches[c.block.els].pc = pc
+			}
+			for _, h := range c.block.catches {
+				code.branches[h.pc].pc = pc
+			}
+			if c.block.label.Type != stack.LabelTypeLoop {
+				c.block.label.Pc = pc + 1
+			}
+			if c.block.label.Type == stack.LabelTypeTry {
+				code.tries[c.block.label.Pc] = c.block
+			}
+			c.patch(code)
+			controls = controls[:len(controls)-1]
+		case instruction.BR, instruction.BR_IF:
+			depth := int(instruction.Imm[uint32](instr))
+			if depth >= len(controls) {
+				return nil, fmt.Errorf("compile: %w: %d at %d", CompileErrorInvalidLabel, depth, pc)
+			}
+			c := controls[len(controls)-1-depth]
+			target := c.block
+			branch := &branchTarget{pc: target.label.Pc, arity: target.label.N, labels: depth + 1}
+			switch target.label.Type {
+			case stack.LabelTypeFunction:
+				branch.ret = true
+			case stack.LabelTypeLoop:
+				// the loop label stays on the label stack
+				branch.labels = depth
+			case stack.LabelTypeBlock, stack.LabelTypeIf, stack.LabelTypeTry:
+				// the end of the target is not known yet
+				c.pending = append(c.pending, pc)
+			}
+			code.branches[pc] = branch
+		case instruction.RETURN, instruction.RETURN_CALL, instruction.RETURN_CALL_INDIRECT:
+			// a tail call unwinds the labels of the function as return does
+			code.branches[pc] = &branchTarget{pc: len(body), arity: uint8(len(f.Type.Returns)), labels: len(controls), ret: true}
+		}
+	}
+	if !terminated {
+		return nil, fmt.Errorf("compile: %w: %d blocks are not closed", CompileErrorUnmatchedEnd, len(controls))
+	}
+	return code, nil
+}
+
+// handler returns the first catch clause of try which catches an exception of the tag.
+// Tag indices are resolved in the instance of the function.
+func (b *blockTarget) handler(tag *instance.Tag, mod *instance.Module) *catchClause {
+	for idx, c := range b.catches {
+		if c.all || (int(c.tag) < len(mod.TagAddrs) && mod.TagAddrs[c.tag] == tag) {
+			return &b.catches[idx]
+		}
+	}
+	return nil
+}
+
+// patch resolves the position of the branch after the end of the target is found.
+func (c *control) patch(code *compiledFunction) {
+	for _, pc := range c.pending {
+		code.branches[pc].pc = c.block.label.Pc
+	}
+}
+
+func expand(mod *instance.Module, block types.BlockType) (*types.FuncType, error) {
+	switch types.ValueType(block) {
+	case types.I32, types.I64, types.F32, types.F64, types.V128:
+		return &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{types.ValueType(block)}}, nil
+	case types.BLOCKTYPE:
+		return &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{}}, nil
+	default:
+		if mod == nil || int(block) >= len(mod.Types) {
+			return nil, fmt.Errorf("expand: function type is not found")
+		}
+		return mod.Types[int(block)], nil
+	}
+}
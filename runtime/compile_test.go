@@ -0,0 +1,235 @@
+package runtime
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/debugger"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+	"github.com/terassyi/gowi/validator"
+)
+
+func TestCompileFunction(t *testing.T) {
+	for _, d := range []struct {
+		name        string
+		body        []instruction.Instruction
+		returns     types.ResultType
+		expBlocks   map[int]*blockTarget
+		expBranches map[int]*branchTarget
+	}{
+		{
+			name: "nested block",
+			body: []instruction.Instruction{
+				&instruction.Block{Imm: types.BlockType(types.I32)},
+				&instruction.Block{Imm: types.BlockType(types.I32)},
+				&instruction.I32Const{Imm: 0},
+				&instruction.End{},
+				&instruction.End{},
+				&instruction.End{},
+			},
+			returns: types.ResultType{types.I32},
+			expBlocks: map[int]*blockTarget{
+				0: {label: stack.Label{Type: stack.LabelTypeBlock, N: 1, Pc: 5}, els: -1, end: 4},
+				1: {label: stack.Label{Type: stack.LabelTypeBlock, N: 1, Pc: 4}, els: -1, end: 3},
+			},
+			expBranches: map[int]*branchTarget{},
+		},
+		{
+			name: "if else",
+			body: []instruction.Instruction{
+				&instruction.I32Const{Imm: 1},
+				&instruction.If{Imm: types.BlockType(types.I32)},
+				&instruction.I32Const{Imm: 2},
+				&instruction.Else{},
+				&instruction.I32Const{Imm: 3},
+				&instruction.End{},
+				&instruction.End{},
+			},
+			returns: types.ResultType{types.I32},
+			expBlocks: map[int]*blockTarget{
+				1: {label: stack.Label{Type: stack.LabelTypeIf, N: 1, Pc: 6}, els: 3, end: 5},
+			},
+			expBranches: map[int]*branchTarget{
+				3: {pc: 5},
+			},
+		},
+		{
+			name: "loop with br_if and br",
+			body: []instruction.Instruction{
+				&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
+				&instruction.Loop{Imm: types.BlockType(types.BLOCKTYPE)},
+				&instruction.I32Const{Imm: 1},
+				&instruction.BrIf{Imm: 1},
+				&instruction.Br{Imm: 0},
+				&instruction.End{},
+				&instruction.End{},
+				&instruction.Br{Imm: 0},
+				&instruction.End{},
+			},
+			expBlocks: map[int]*blockTarget{
+				0: {label: stack.Label{Type: stack.LabelTypeBlock, N: 0, Pc: 7}, els: -1, end: 6},
+				1: {label: stack.Label{Type: stack.LabelTypeLoop, N: 0, Pc: 2}, els: -1, end: 5},
+			},
+			expBranches: map[int]*branchTarget{
+				3: {pc: 7, labels: 2},
+				4: {pc: 2, labels: 0},
+				7: {pc: 9, labels: 1, ret: true},
+			},
+		},
+		{
+			name: "return in block",
+			body: []instruction.Instruction{
+				&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
+				&instruction.I32Const{Imm: 1},
+				&instruction.Return{},
+				&instruction.End{},
+				&instruction.I32Const{Imm: 2},
+				&instruction.End{},
+			},
+			returns: types.ResultType{types.I32},
+			expBlocks: map[int]*blockTarget{
+				0: {label: stack.Label{Type: stack.LabelTypeBlock, N: 0, Pc: 4}, els: -1, end: 3},
+			},
+			expBranches: map[int]*branchTarget{
+				2: {pc: 6, arity: 1, labels: 2, ret: true},
+			},
+		},
+	} {
+		t.Run(d.name, func(t *testing.T) {
+			f := &instance.Function{
+				Type: &types.FuncType{Params: types.ResultType{}, Returns: d.returns},
+				Code: &structure.Function{Body: d.body},
+			}
+			code, err := compileFunction(f)
+			require.NoError(t, err)
+			for pc := range d.body {
+				assert.Equal(t, d.expBlocks[pc], code.blocks[pc], "block at %d", pc)
+				assert.Equal(t, d.expBranches[pc], code.branches[pc], "branch at %d", pc)
+			}
+		})
+	}
+}
+
+func TestCompileFunction_Err(t *testing.T) {
+	for _, d := range []struct {
+		name string
+		body []instruction.Instruction
+		err  error
+	}{
+		{
+			name: "block is not closed",
+			body: []instruction.Instruction{&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)}, &instruction.End{}},
+			err:  CompileErrorUnmatchedEnd,
+		},
+		{
+			name: "else without if",
+			body: []instruction.Instruction{&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)}, &instruction.Else{}, &instruction.End{}, &instruction.End{}},
+			err:  CompileErrorUnmatchedElse,
+		},
+		{
+			name: "invalid label",
+			body: []instruction.Instruction{&instruction.Br{Imm: 1}, &instruction.End{}},
+			err:  CompileErrorInvalidLabel,
+		},
+		{
+			name: "catch without try",
+			body: []instruction.Instruction{&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)}, &instruction.Catch{}, &instruction.End{}, &instruction.End{}},
+			err:  CompileErrorUnmatchedCatch,
+		},
+		{
+			name: "catch after catch_all",
+			body: []instruction.Instruction{&instruction.Try{Imm: types.BlockType(types.BLOCKTYPE)}, &instruction.CatchAll{}, &instruction.Catch{}, &instruction.End{}, &instruction.End{}},
+			err:  CompileErrorUnmatchedCatch,
+		},
+		{
+			name: "delegate after catch",
+			body: []instruction.Instruction{&instruction.Try{Imm: types.BlockType(types.BLOCKTYPE)}, &instruction.Catch{}, &instruction.Delegate{}, &instruction.End{}},
+			err:  CompileErrorUnmatchedDelegate,
+		},
+		{
+			name: "invalid delegate label",
+			body: []instruction.Instruction{&instruction.Try{Imm: types.BlockType(types.BLOCKTYPE)}, &instruction.Delegate{Imm: 1}, &instruction.End{}},
+			err:  CompileErrorInvalidLabel,
+		},
+	} {
+		t.Run(d.name, func(t *testing.T) {
+			f := &instance.Function{
+				Type: &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{}},
+				Code: &structure.Function{Body: d.body},
+			}
+			_, err := compileFunction(f)
+			require.ErrorIs(t, err, d.err)
+		})
+	}
+}
+
+func TestNew_Lazy(t *testing.T) {
+	dec, err := decoder.NewWithMode("../examples/tail_call.wasm", decoder.ModeLazy)
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	r, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	for _, f := range mod.Functions {
+		assert.False(t, f.Decoded())
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			res, err := r.Invoke("is_even", []value.Value{value.I32(int32(g))})
+			assert.NoError(t, err)
+			assert.Equal(t, []value.Value{value.I32(int32(1 - g%2))}, res)
+		}(g)
+	}
+	wg.Wait()
+	for _, f := range mod.Functions {
+		assert.True(t, f.Decoded())
+	}
+}
+
+func TestNew_LazyInvalidBody(t *testing.T) {
+	broken := errors.New("broken body")
+	mod := &structure.Module{
+		Types: []*types.FuncType{{Params: []types.ValueType{}, Returns: []types.ValueType{}}},
+		Functions: []*structure.Function{
+			structure.NewLazyFunction(0, nil, func() ([]instruction.Instruction, error) { return nil, broken }),
+		},
+		Exports: []*structure.Export{{Name: "f", Desc: &structure.ExportDesc{Type: structure.DescTypeFunc, Val: 0}}},
+	}
+	// the body is not decoded until the function is called
+	r, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	_, err = r.Invoke("f", []value.Value{})
+	assert.ErrorIs(t, err, broken)
+}
+
+func TestNewWithFeatures(t *testing.T) {
+	features := types.FeaturesAll &^ types.FeatureTailCall
+	for _, mode := range []decoder.Mode{decoder.ModeEager, decoder.ModeLazy} {
+		dec, err := decoder.NewWithMode("../examples/tail_call.wasm", mode)
+		require.NoError(t, err)
+		mod, err := dec.Decode()
+		require.NoError(t, err)
+		r, err := NewWithFeatures(mod, nil, nil, features)
+		if mode == decoder.ModeEager {
+			assert.ErrorIs(t, err, validator.FeatureNotEnabled)
+			continue
+		}
+		// lazily decoded functions are rejected on their first call
+		require.NoError(t, err)
+		_, err = r.Invoke("is_even", []value.Value{value.I32(2)})
+		assert.ErrorIs(t, err, validator.FeatureNotEnabled)
+	}
+}
@@ -0,0 +1,121 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+type traceRecord struct {
+	Step     uint64       `json:"step"`
+	Function int          `json:"func"`
+	Name     string       `json:"name"`
+	Offset   int          `json:"offset"`
+	Depth    int          `json:"depth"`
+	Instr    string       `json:"instr"`
+	Imm      string       `json:"imm,omitempty"`
+	Stack    []traceValue `json:"stack"`
+	Locals   []traceValue `json:"locals"`
+	Labels   []traceLabel `json:"labels"`
+}
+
+type traceValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type traceLabel struct {
+	Kind  string `json:"kind"`
+	Arity uint8  `json:"arity"`
+	Pc    int    `json:"pc"`
+}
+
+// showContext prints the top of the value stack, locals and labels of the current frame.
+func (d *Debugger) showContext(stck *stack.Stack, indent string) {
+	values := topValues(stck, d.contextDepth)
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		strs = append(strs, valueString(v))
+	}
+	fmt.Fprintf(d.writer, "%s; stack: [%s]\n", indent, strings.Join(strs, ", "))
+	frame, err := stck.TopFrame()
+	if err == nil {
+		strs = make([]string, 0, frame.Locals.Len())
+		for i, l := range frame.Locals.Values() {
+			strs = append(strs, fmt.Sprintf("$%d=%s", i, valueString(l)))
+		}
+		fmt.Fprintf(d.writer, "%s; locals: [%s]\n", indent, strings.Join(strs, ", "))
+	}
+	labels := frameLabels(stck)
+	strs = make([]string, 0, len(labels))
+	for _, l := range labels {
+		strs = append(strs, fmt.Sprintf("%s(%d)", l.Type, l.N))
+	}
+	fmt.Fprintf(d.writer, "%s; labels: %s\n", indent, strings.Join(strs, " > "))
+}
+
+func (d *Debugger) writeTrace(stck *stack.Stack, instr instruction.Instruction) error {
+	pos, err := newPosition(stck)
+	if err != nil {
+		return fmt.Errorf("trace: %w", err)
+	}
+	record := &traceRecord{
+		Step:     d.steps,
+		Function: pos.index,
+		Name:     pos.name,
+		Offset:   pos.offset,
+		Depth:    pos.depth,
+		Instr:    instr.String(),
+		Imm:      instr.ImmString(),
+		Stack:    make([]traceValue, 0, d.contextDepth),
+		Locals:   make([]traceValue, 0, pos.frame.Locals.Len()),
+		Labels:   make([]traceLabel, 0),
+	}
+	for _, v := range topValues(stck, d.contextDepth) {
+		record.Stack = append(record.Stack, newTraceValue(v))
+	}
+	for _, l := range pos.frame.Locals.Values() {
+		record.Locals = append(record.Locals, newTraceValue(l))
+	}
+	for _, l := range frameLabels(stck) {
+		record.Labels = append(record.Labels, traceLabel{Kind: l.Type.String(), Arity: l.N, Pc: l.Pc})
+	}
+	if err := d.trace.Encode(record); err != nil {
+		return fmt.Errorf("trace: %w", err)
+	}
+	return nil
+}
+
+func newTraceValue(v value.Value) traceValue {
+	switch val := v.(type) {
+	case value.Number:
+		return traceValue{Type: val.NumType().String(), Value: fmt.Sprintf("%v", val)}
+	case value.Vector:
+		return traceValue{Type: "v128", Value: fmt.Sprintf("%x", [16]byte(val))}
+	default:
+		return traceValue{Type: "unknown", Value: fmt.Sprintf("%v", v)}
+	}
+}
+
+// topValues returns at most n values from the bottom to the top of the value stack.
+func topValues(stck *stack.Stack, n int) []value.Value {
+	values := stck.Values()
+	if len(values) > n {
+		return values[len(values)-n:]
+	}
+	return values
+}
+
+// frameLabels returns labels in the current frame from the function label to the innermost label.
+func frameLabels(stck *stack.Stack) []stack.Label {
+	labels := stck.Labels()
+	for i := len(labels) - 1; i >= 0; i-- {
+		if labels[i].IsFunction() {
+			return labels[i:]
+		}
+	}
+	return labels
+}
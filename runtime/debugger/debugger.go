@@ -1,11 +1,14 @@
 package debugger
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/profiler"
 	"github.com/terassyi/gowi/runtime/stack"
 	"github.com/terassyi/gowi/runtime/value"
 )
@@ -20,27 +23,85 @@ const (
 	DebugLevelInterrupt     DebugLevel = 4
 )
 
+const (
+	DEFAULT_CONTEXT_DEPTH int = 8
+)
+
 type Debugger struct {
-	level  DebugLevel
-	writer io.Writer
+	level        DebugLevel
+	writer       io.Writer
+	reader       *bufio.Reader
+	mode         runMode
+	depth        int // frame depth when the debugger stopped last time
+	breakpoints  []*Breakpoint
+	lastCommand  string
+	contextDepth int
+	trace        *json.Encoder
+	steps        uint64
+	profiler     *profiler.Profiler
 }
 
 func New(level DebugLevel) *Debugger {
 	switch level {
-	case DebugLevelLogOnly:
-		return &Debugger{level: level, writer: os.Stderr}
+	case DebugLevelLogOnly, DebugLevelShowContext:
+		return WithIO(level, nil, os.Stderr)
 	case DebugLevelLogOnlyStdout:
-		return &Debugger{level: level, writer: os.Stdout}
+		return WithIO(level, nil, os.Stdout)
+	case DebugLevelInterrupt:
+		return WithIO(level, os.Stdin, os.Stdout)
 	default:
-		return &Debugger{level: level, writer: io.Discard}
+		return WithIO(level, nil, io.Discard)
+	}
+}
+
+// WithIO creates a debugger reading commands from r and writing to w.
+// r is only used at DebugLevelInterrupt.
+func WithIO(level DebugLevel, r io.Reader, w io.Writer) *Debugger {
+	d := &Debugger{
+		level:        level,
+		writer:       w,
+		mode:         runModeStep,
+		breakpoints:  make([]*Breakpoint, 0),
+		contextDepth: DEFAULT_CONTEXT_DEPTH,
 	}
+	if r != nil {
+		d.reader = bufio.NewReader(r)
+	}
+	return d
+}
+
+// SetContextDepth sets the number of values shown from the top of the value stack
+// at DebugLevelShowContext and in the trace.
+func (d *Debugger) SetContextDepth(n int) {
+	d.contextDepth = n
+}
+
+// SetTrace writes a JSON object per executed instruction to w.
+func (d *Debugger) SetTrace(w io.Writer) {
+	d.trace = json.NewEncoder(w)
+}
+
+// SetProfiler records every executed instruction to p.
+func (d *Debugger) SetProfiler(p *profiler.Profiler) {
+	d.profiler = p
+}
+
+// Steps returns the number of instructions executed so far.
+func (d *Debugger) Steps() uint64 {
+	return d.steps
 }
 
 func (d *Debugger) ShowInfo(name string) {
+	if d.profiler != nil {
+		d.profiler.Start()
+	}
 	fmt.Fprintf(d.writer, "\n\nInvoke %s\n--------------------\n", name)
 }
 
 func (d *Debugger) ShowResult(results []value.Value) {
+	if d.profiler != nil {
+		d.profiler.Stop()
+	}
 	fmt.Fprintf(d.writer, "Execution Result = (")
 	for i, res := range results {
 		fmt.Fprintf(d.writer, "%v", res)
@@ -51,7 +112,22 @@ func (d *Debugger) ShowResult(results []value.Value) {
 	fmt.Fprintf(d.writer, ")\n")
 }
 
-func (d *Debugger) PrintInstr(stck *stack.Stack, instr instruction.Instruction) {
+func (d *Debugger) PrintInstr(stck *stack.Stack, instr instruction.Instruction) error {
+	d.steps++
+	if d.profiler != nil {
+		d.profiler.Step(stck, instr)
+	}
+	if d.trace != nil {
+		if err := d.writeTrace(stck, instr); err != nil {
+			return err
+		}
+	}
+	if d.level == DebugLevelInterrupt {
+		return d.interrupt(stck, instr)
+	}
+	if d.level == DebugLevelNoLog {
+		return nil
+	}
 	nestTab := ""
 	for i := 0; i < stck.LenLabel(); i++ {
 		nestTab += "  "
@@ -60,4 +136,8 @@ func (d *Debugger) PrintInstr(stck *stack.Stack, instr instruction.Instruction)
 		nestTab = nestTab[:len(nestTab)-2]
 	}
 	fmt.Fprintf(d.writer, "%s%s %s\n", nestTab, instr, instr.ImmString())
+	if d.level == DebugLevelShowContext {
+		d.showContext(stck, nestTab+"  ")
+	}
+	return nil
 }
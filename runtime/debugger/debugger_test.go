@@ -0,0 +1,63 @@
+package debugger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+func TestPrintInstr_ShowContext(t *testing.T) {
+	locals, err := stack.NewLocals(value.I32(10), value.I64(20))
+	require.NoError(t, err)
+	s, err := stack.WithValue(
+		[]value.Value{value.I32(1), value.I64(2), value.I32(3)},
+		[]stack.Frame{{Locals: locals}},
+		[]stack.Label{{Type: stack.LabelTypeFunction, N: 1}, {Type: stack.LabelTypeLoop, Pc: 4}},
+	)
+	require.NoError(t, err)
+	out := &bytes.Buffer{}
+	d := WithIO(DebugLevelShowContext, nil, out)
+	d.SetContextDepth(2)
+	require.NoError(t, d.PrintInstr(s, &instruction.I32Add{}))
+	assert.Equal(t, "    i32.add \n"+
+		"      ; stack: [i64:2, i32:3]\n"+
+		"      ; locals: [$0=i32:10, $1=i64:20]\n"+
+		"      ; labels: function(1) > loop(0)\n", out.String())
+}
+
+func TestPrintInstr_Trace(t *testing.T) {
+	locals, err := stack.NewLocals(value.I32(10))
+	require.NoError(t, err)
+	s, err := stack.WithValue(
+		[]value.Value{value.I32(1), value.I32(3)},
+		[]stack.Frame{{Locals: locals, Pc: 5}},
+		[]stack.Label{{Type: stack.LabelTypeFunction, N: 1, Pc: 12}},
+	)
+	require.NoError(t, err)
+	trace := &bytes.Buffer{}
+	d := WithIO(DebugLevelNoLog, nil, &bytes.Buffer{})
+	d.SetTrace(trace)
+	require.NoError(t, d.PrintInstr(s, &instruction.I32Add{}))
+	require.NoError(t, d.PrintInstr(s, &instruction.GetLocal{Imm: 0}))
+	lines := bytes.Split(bytes.TrimSpace(trace.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	record := &traceRecord{}
+	require.NoError(t, json.Unmarshal(lines[1], record))
+	assert.Equal(t, &traceRecord{
+		Step:     2,
+		Function: -1,
+		Offset:   5,
+		Depth:    1,
+		Instr:    "get_local",
+		Imm:      "$0",
+		Stack:    []traceValue{{Type: "i32", Value: "1"}, {Type: "i32", Value: "3"}},
+		Locals:   []traceValue{{Type: "i32", Value: "10"}},
+		Labels:   []traceLabel{{Kind: "function", Arity: 1, Pc: 12}},
+	}, record)
+}
@@ -0,0 +1,335 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+var (
+	ExecutionAborted   error = errors.New("execution is aborted by the debugger")
+	InvalidCommand     error = errors.New("invalid debugger command")
+	BreakpointNotFound error = errors.New("breakpoint is not found")
+)
+
+const (
+	defaultMemoryDumpLength int = 64
+	memoryDumpWidth         int = 16
+)
+
+type runMode uint8
+
+const (
+	runModeStep     runMode = iota // stop at the next instruction
+	runModeNext     runMode = iota // stop at the next instruction in the same or outer function
+	runModeFinish   runMode = iota // stop after returning from the current function
+	runModeContinue runMode = iota // stop only at breakpoints
+)
+
+// Breakpoint stops the execution before the instruction at Offset in the function.
+// Function is an exported name or a function index.
+type Breakpoint struct {
+	Function string
+	Offset   int
+}
+
+func (b *Breakpoint) match(pos *position) bool {
+	if b.Offset != pos.offset {
+		return false
+	}
+	return b.Function == pos.name || b.Function == strconv.Itoa(pos.index)
+}
+
+func (b *Breakpoint) String() string {
+	return fmt.Sprintf("%s+%d", b.Function, b.Offset)
+}
+
+// position is where the interpreter is going to execute next.
+type position struct {
+	index  int
+	name   string
+	offset int
+	depth  int
+	frame  *stack.Frame
+}
+
+func newPosition(stck *stack.Stack) (*position, error) {
+	frame, err := stck.TopFrame()
+	if err != nil {
+		return nil, fmt.Errorf("position: %w", err)
+	}
+	pos := &position{index: -1, offset: frame.Pc, depth: stck.LenFrame(), frame: frame}
+	if frame.Module == nil || frame.Function == nil {
+		return pos, nil
+	}
+	pos.index = frame.Module.FuncIndex(frame.Function)
+	pos.name = frame.Module.FuncName(frame.Function)
+	return pos, nil
+}
+
+func (p *position) String() string {
+	return fmt.Sprintf("%s+%d", p.name, p.offset)
+}
+
+// AddBreakpoint registers a breakpoint before starting the execution.
+func (d *Debugger) AddBreakpoint(function string, offset int) {
+	d.breakpoints = append(d.breakpoints, &Breakpoint{Function: function, Offset: offset})
+}
+
+func (d *Debugger) shouldStop(pos *position) bool {
+	for _, b := range d.breakpoints {
+		if b.match(pos) {
+			return true
+		}
+	}
+	switch d.mode {
+	case runModeStep:
+		return true
+	case runModeNext:
+		return pos.depth <= d.depth
+	case runModeFinish:
+		return pos.depth < d.depth
+	default:
+		return false
+	}
+}
+
+func (d *Debugger) interrupt(stck *stack.Stack, instr instruction.Instruction) error {
+	pos, err := newPosition(stck)
+	if err != nil {
+		return fmt.Errorf("interrupt: %w", err)
+	}
+	if !d.shouldStop(pos) {
+		return nil
+	}
+	d.depth = pos.depth
+	fmt.Fprintf(d.writer, "%s: %s %s\n", pos, instr, instr.ImmString())
+	for {
+		fmt.Fprintf(d.writer, "(gowi) ")
+		line, err := d.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("interrupt: %w", err)
+		}
+		if err == io.EOF && strings.TrimSpace(line) == "" {
+			// nobody can answer anymore, run to the end
+			fmt.Fprintln(d.writer)
+			d.mode = runModeContinue
+			d.breakpoints = nil
+			return nil
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = d.lastCommand
+		}
+		d.lastCommand = line
+		resume, err := d.command(stck, pos, instr, strings.Fields(line))
+		if err != nil {
+			if errors.Is(err, ExecutionAborted) {
+				return err
+			}
+			fmt.Fprintf(d.writer, "%v\n", err)
+			continue
+		}
+		if resume {
+			return nil
+		}
+	}
+}
+
+// command runs a debugger command and reports whether the execution should be resumed.
+func (d *Debugger) command(stck *stack.Stack, pos *position, instr instruction.Instruction, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	switch args[0] {
+	case "s", "step":
+		d.mode = runModeStep
+		return true, nil
+	case "n", "next":
+		d.mode = runModeNext
+		return true, nil
+	case "f", "finish":
+		d.mode = runModeFinish
+		return true, nil
+	case "c", "continue":
+		d.mode = runModeContinue
+		return true, nil
+	case "b", "break":
+		return false, d.commandBreak(pos, args[1:])
+	case "d", "delete":
+		return false, d.commandDelete(args[1:])
+	case "i", "info":
+		d.showBreakpoints()
+	case "w", "where":
+		fmt.Fprintf(d.writer, "%s: %s %s (depth=%d)\n", pos, instr, instr.ImmString(), pos.depth)
+	case "l", "locals":
+		d.showLocals(pos.frame)
+	case "st", "stack":
+		return false, d.showStack(stck, args[1:])
+	case "la", "labels":
+		d.showLabels(stck)
+	case "m", "mem":
+		return false, d.showMemory(pos.frame, args[1:])
+	case "q", "quit":
+		return false, ExecutionAborted
+	case "h", "help":
+		d.showHelp()
+	default:
+		return false, fmt.Errorf("%w: %s", InvalidCommand, args[0])
+	}
+	return false, nil
+}
+
+func (d *Debugger) commandBreak(pos *position, args []string) error {
+	if len(args) == 0 {
+		d.AddBreakpoint(pos.name, pos.offset)
+		fmt.Fprintf(d.writer, "breakpoint %d at %s\n", len(d.breakpoints)-1, pos)
+		return nil
+	}
+	offset := 0
+	if len(args) > 1 {
+		o, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("break: %w", err)
+		}
+		offset = o
+	}
+	d.AddBreakpoint(args[0], offset)
+	fmt.Fprintf(d.writer, "breakpoint %d at %s\n", len(d.breakpoints)-1, d.breakpoints[len(d.breakpoints)-1])
+	return nil
+}
+
+func (d *Debugger) commandDelete(args []string) error {
+	if len(args) == 0 {
+		d.breakpoints = make([]*Breakpoint, 0)
+		return nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if n < 0 || n >= len(d.breakpoints) {
+		return fmt.Errorf("delete: %w: %d", BreakpointNotFound, n)
+	}
+	d.breakpoints = append(d.breakpoints[:n], d.breakpoints[n+1:]...)
+	return nil
+}
+
+func (d *Debugger) showBreakpoints() {
+	if len(d.breakpoints) == 0 {
+		fmt.Fprintln(d.writer, "no breakpoints")
+		return
+	}
+	for i, b := range d.breakpoints {
+		fmt.Fprintf(d.writer, "  %d: %s\n", i, b)
+	}
+}
+
+func (d *Debugger) showLocals(frame *stack.Frame) {
+	for i, l := range frame.Locals.Values() {
+		fmt.Fprintf(d.writer, "  $%d = %s\n", i, valueString(l))
+	}
+}
+
+func (d *Debugger) showStack(stck *stack.Stack, args []string) error {
+	values := stck.Values()
+	n := len(values)
+	if len(args) > 0 {
+		m, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("stack: %w", err)
+		}
+		if m < n {
+			n = m
+		}
+	}
+	if len(values) == 0 {
+		fmt.Fprintln(d.writer, "  (empty)")
+		return nil
+	}
+	// from top to bottom
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(d.writer, "  [%d] %s\n", i, valueString(values[len(values)-1-i]))
+	}
+	return nil
+}
+
+func (d *Debugger) showLabels(stck *stack.Stack) {
+	labels := frameLabels(stck)
+	for i := len(labels) - 1; i >= 0; i-- {
+		l := labels[i]
+		fmt.Fprintf(d.writer, "  [%d] %s arity=%d pc=%d\n", len(labels)-1-i, l.Type, l.N, l.Pc)
+	}
+}
+
+func (d *Debugger) showMemory(frame *stack.Frame, args []string) error {
+	if frame.Module == nil || len(frame.Module.MemAddrs) == 0 {
+		return fmt.Errorf("mem: memory instance is not exist")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("mem: %w: address is required", InvalidCommand)
+	}
+	addr, err := strconv.ParseUint(args[0], 0, 32)
+	if err != nil {
+		return fmt.Errorf("mem: %w", err)
+	}
+	length := uint64(defaultMemoryDumpLength)
+	if len(args) > 1 {
+		length, err = strconv.ParseUint(args[1], 0, 32)
+		if err != nil {
+			return fmt.Errorf("mem: %w", err)
+		}
+	}
+	data, err := frame.Module.MemAddrs[0].ReadBytes(uint32(addr), uint32(length))
+	if err != nil {
+		return fmt.Errorf("mem: %w", err)
+	}
+	for i := uint64(0); i < length; i += uint64(memoryDumpWidth) {
+		end := i + uint64(memoryDumpWidth)
+		if end > length {
+			end = length
+		}
+		fmt.Fprintf(d.writer, "  %08x ", addr+i)
+		for _, b := range data[i:end] {
+			fmt.Fprintf(d.writer, " %02x", b)
+		}
+		fmt.Fprintln(d.writer)
+	}
+	return nil
+}
+
+func (d *Debugger) showHelp() {
+	fmt.Fprint(d.writer, `commands:
+  s, step                  execute the next instruction
+  n, next                  execute the next instruction, stepping over calls
+  f, finish                run until the current function returns
+  c, continue              run until a breakpoint
+  b, break [func [offset]] set a breakpoint by function name or index and instruction offset
+                           (the index of the instruction in the function, not the byte offset of dump --disassemble)
+  d, delete [n]            delete the n-th breakpoint or all breakpoints
+  i, info                  list breakpoints
+  w, where                 show the current position
+  l, locals                show locals of the current frame
+  st, stack [n]            show top n values of the value stack
+  la, labels               show labels of the current frame
+  m, mem addr [len]        dump the memory range
+  q, quit                  abort the execution
+`)
+}
+
+func valueString(v value.Value) string {
+	switch val := v.(type) {
+	case value.Number:
+		return fmt.Sprintf("%s:%v", val.NumType(), val)
+	case value.Vector:
+		return fmt.Sprintf("v128:%x", [16]byte(val))
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,199 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+var (
+	UncaughtException error = errors.New("Uncaught exception")
+	NoCaughtException error = errors.New("No caught exception to rethrow")
+)
+
+// Exception is an exception thrown by throw.
+// Invoke returns it when no handler in the invocation catches it,
+// so that the host can find the tag and the values with errors.As.
+// https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+type Exception struct {
+	Tag    *instance.Tag
+	Values []value.Value
+}
+
+func (e *Exception) Error() string {
+	return fmt.Sprintf("%s: values=%v", UncaughtException, e.Values)
+}
+
+func (e *Exception) Unwrap() error {
+	return UncaughtException
+}
+
+// caughtException is an exception caught by the handler of the catch label at the index of the label stack.
+type caughtException struct {
+	label int
+	exc   *Exception
+}
+
+func (i *interpreter) execThrow(instr instruction.Instruction) (instructionResult, error) {
+	idx := instruction.Imm[uint32](instr)
+	tags := i.cur.frame.Module.TagAddrs
+	if int(idx) >= len(tags) {
+		return instructionResultTrap, fmt.Errorf("throw: tag[%d] is not found", idx)
+	}
+	tag := tags[idx]
+	if err := i.stack.ValidateValue(tag.Type.Params); err != nil {
+		return instructionResultTrap, fmt.Errorf("throw: %w", err)
+	}
+	values, err := i.stack.PopValuesRev(len(tag.Type.Params))
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("throw: %w", err)
+	}
+	return i.throw(&Exception{Tag: tag, Values: values})
+}
+
+func (i *interpreter) execRethrow(instr instruction.Instruction) (instructionResult, error) {
+	depth := int(instruction.Imm[uint32](instr))
+	label, err := i.stack.RefLabel(depth)
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("rethrow: %w", err)
+	}
+	if label.Type == stack.LabelTypeCatch {
+		idx := i.stack.LenLabel() - 1 - depth
+		for j := len(i.caught) - 1; j >= 0; j-- {
+			if i.caught[j].label == idx {
+				return i.throw(i.caught[j].exc)
+			}
+		}
+	}
+	return instructionResultTrap, fmt.Errorf("rethrow: %w: label %d", NoCaughtException, depth)
+}
+
+func (i *interpreter) execCatch(instr instruction.Instruction) (instructionResult, error) {
+	// the try body or the previous handler is finished, go to the end of try
+	branch := i.cur.code.branches[i.pc()]
+	if branch == nil {
+		return instructionResultTrap, fmt.Errorf("%s: branch target is not resolved at %d", instr, i.pc())
+	}
+	i.cur.frame.Pc = branch.pc
+	return instructionResultRunNext, nil
+}
+
+// throw unwinds labels and frames until a try label has a handler for the exception.
+// The exception is returned as an error when it reaches the frame of Invoke.
+func (i *interpreter) throw(exc *Exception) (instructionResult, error) {
+	for {
+		label, err := i.stack.TopLabel()
+		if err != nil {
+			return instructionResultTrap, fmt.Errorf("throw: %w", err)
+		}
+		switch label.Type {
+		case stack.LabelTypeFunction:
+			if i.cur.frame.Function == nil {
+				// the dummy frame pushed by Invoke
+				return instructionResultTrap, exc
+			}
+			if _, err := i.restoreStack(); err != nil {
+				return instructionResultTrap, fmt.Errorf("throw: %w", err)
+			}
+			continue
+		case stack.LabelTypeTry:
+			block := i.cur.code.tries[label.Pc]
+			if block == nil {
+				return instructionResultTrap, fmt.Errorf("throw: try is not resolved for label at %d", label.Pc)
+			}
+			if block.delegate >= 0 {
+				// handlers of the target label are searched next
+				if err := i.stack.PopLabels(block.delegate + 1); err != nil {
+					return instructionResultTrap, fmt.Errorf("throw: %w", err)
+				}
+				continue
+			}
+			if h := block.handler(exc.Tag, i.cur.frame.Module); h != nil {
+				return i.catch(label, h, exc)
+			}
+		}
+		if err := i.stack.PopLabels(1); err != nil {
+			return instructionResultTrap, fmt.Errorf("throw: %w", err)
+		}
+	}
+}
+
+// catch runs the handler of the try label on the top of the label stack.
+// The label stays until the end of try, so that a branch in the handler and rethrow can refer to it.
+func (i *interpreter) catch(label *stack.Label, h *catchClause, exc *Exception) (instructionResult, error) {
+	if err := i.stack.Unwind(label.Height, 0); err != nil {
+		return instructionResultTrap, fmt.Errorf("catch: %w", err)
+	}
+	if !h.all {
+		for _, v := range exc.Values {
+			if err := i.stack.PushValue(v); err != nil {
+				return instructionResultTrap, fmt.Errorf("catch: %w", err)
+			}
+		}
+	}
+	label.Type = stack.LabelTypeCatch
+	idx := i.stack.LenLabel() - 1
+	// exceptions caught by labels which are already popped are dropped
+	n := len(i.caught)
+	for n > 0 && i.caught[n-1].label >= idx {
+		n--
+	}
+	i.caught = append(i.caught[:n], caughtException{label: idx, exc: exc})
+	i.cur.frame.Pc = h.pc + 1
+	if err := i.cur.updateLabel(i.stack); err != nil {
+		return instructionResultTrap, fmt.Errorf("catch: %w", err)
+	}
+	return instructionResultRunNext, nil
+}
+
+// caughtState copies the caught exceptions. Their tags must belong to the instance.
+func (i *interpreter) caughtState() ([]CaughtState, error) {
+	if len(i.caught) == 0 {
+		return nil, nil
+	}
+	states := make([]CaughtState, 0, len(i.caught))
+	for _, c := range i.caught {
+		tag := i.instance.TagIndex(c.exc.Tag)
+		if tag < 0 {
+			return nil, fmt.Errorf("caught exception: %w: tag is not in the module", instance.StateNotMatched)
+		}
+		values := make([]string, 0, len(c.exc.Values))
+		for _, v := range c.exc.Values {
+			values = append(values, fmt.Sprint(v))
+		}
+		states = append(states, CaughtState{Label: c.label, Tag: tag, Values: values})
+	}
+	return states, nil
+}
+
+// restoreCaught binds the caught exceptions to tags of the instance.
+// It must be called after the stack is restored since the labels of the handlers are checked.
+func (i *interpreter) restoreCaught(states []CaughtState) ([]caughtException, error) {
+	caught := make([]caughtException, 0, len(states))
+	for n, c := range states {
+		if c.Label < 0 || c.Label >= i.stack.LenLabel() {
+			return nil, fmt.Errorf("caught exception[%d]: %w: label=%d", n, instance.StateNotMatched, c.Label)
+		}
+		if c.Tag < 0 || c.Tag >= len(i.instance.TagAddrs) {
+			return nil, fmt.Errorf("caught exception[%d]: %w: tag=%d", n, instance.StateNotMatched, c.Tag)
+		}
+		tag := i.instance.TagAddrs[c.Tag]
+		if len(c.Values) != len(tag.Type.Params) {
+			return nil, fmt.Errorf("caught exception[%d]: %w: values=%d", n, instance.StateNotMatched, len(c.Values))
+		}
+		exc := &Exception{Tag: tag, Values: make([]value.Value, 0, len(c.Values))}
+		for j, v := range c.Values {
+			val, err := value.FromString(v, tag.Type.Params[j])
+			if err != nil {
+				return nil, fmt.Errorf("caught exception[%d]: %w", n, err)
+			}
+			exc.Values = append(exc.Values, val)
+		}
+		caught = append(caught, caughtException{label: c.Label, exc: exc})
+	}
+	return caught, nil
+}
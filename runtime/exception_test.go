@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/debugger"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestInvoke_Exception(t *testing.T) {
+	i := newInterpreterFromFile(t, "../examples/exception.wasm")
+	for _, d := range []struct {
+		name string
+		args []value.Value
+		exp  []value.Value
+	}{
+		{name: "catch", args: []value.Value{value.I32(5)}, exp: []value.Value{value.I32(6)}},
+		{name: "catch_all", args: []value.Value{value.I32(5)}, exp: []value.Value{value.I32(42)}},
+		{name: "delegate", args: []value.Value{value.I32(5)}, exp: []value.Value{value.I32(5)}},
+		{name: "no_throw", args: []value.Value{}, exp: []value.Value{value.I32(7)}},
+	} {
+		res, err := i.Invoke(d.name, d.args)
+		require.NoError(t, err, d.name)
+		assert.Equal(t, d.exp, res, d.name)
+	}
+}
+
+func TestInvoke_UncaughtException(t *testing.T) {
+	i := newInterpreterFromFile(t, "../examples/exception.wasm")
+	_, err := i.Invoke("rethrow", []value.Value{value.I32(5)})
+	require.ErrorIs(t, err, UncaughtException)
+	var exc *Exception
+	require.True(t, errors.As(err, &exc))
+	assert.Equal(t, []value.Value{value.I32(5)}, exc.Values)
+	tag, err := i.(*runner).instance.GetExport("tag")
+	require.NoError(t, err)
+	assert.Same(t, tag, exc.Tag)
+
+	// the stack is cleared after the exception
+	res, err := i.Invoke("catch", []value.Value{value.I32(1)})
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.I32(2)}, res)
+}
+
+func TestInvoke_ExceptionInHandler(t *testing.T) {
+	tag := &types.FuncType{Params: types.ResultType{types.I32}, Returns: types.ResultType{}}
+	ret := &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{types.I32}}
+	mod := &structure.Module{
+		Types: []*types.FuncType{tag, ret},
+		Tags:  []*structure.Tag{{Type: 0}},
+		Functions: []*structure.Function{{
+			Type: 1,
+			// the exception thrown in the handler of the inner try is caught by the outer try,
+			// and rethrow in the outer handler refers to the exception caught by it
+			Body: []instruction.Instruction{
+				&instruction.Try{Imm: types.BlockType(types.I32)},
+				&instruction.Try{Imm: types.BlockType(types.I32)},
+				&instruction.I32Const{Imm: 1},
+				&instruction.Throw{Imm: 0},
+				&instruction.Catch{Imm: 0},
+				&instruction.I32Const{Imm: 10},
+				&instruction.I32Add{},
+				&instruction.Throw{Imm: 0},
+				&instruction.End{},
+				&instruction.Catch{Imm: 0},
+				&instruction.I32Const{Imm: 100},
+				&instruction.I32Add{},
+				&instruction.End{},
+				&instruction.End{},
+			},
+		}},
+		Exports: []*structure.Export{{Name: "f", Desc: &structure.ExportDesc{Type: structure.DescTypeFunc, Val: 0}}},
+	}
+	i, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	res, err := i.Invoke("f", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.I32(111)}, res)
+
+	// rethrow of a label which is not catching
+	mod.Functions[0].Body = []instruction.Instruction{
+		&instruction.Try{Imm: types.BlockType(types.BLOCKTYPE)},
+		&instruction.Rethrow{Imm: 0},
+		&instruction.End{},
+		&instruction.I32Const{Imm: 0},
+		&instruction.End{},
+	}
+	i, err = New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	_, err = i.Invoke("f", nil)
+	assert.ErrorIs(t, err, NoCaughtException)
+}
+
+func TestNew_ImportedTag(t *testing.T) {
+	dec, err := decoder.New("../examples/exception.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	mod.Imports = []*structure.Import{{Module: "env", Name: "tag", Desc: &structure.ImportDesc{Type: structure.DescTypeTag, Tag: 0}}}
+
+	imported := instance.NewTag(mod.Types[0])
+	i, err := New(mod, []instance.ExternalValue{imported}, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	inst := i.(*runner).instance
+	require.Len(t, inst.TagAddrs, 3)
+	assert.Same(t, imported, inst.TagAddrs[0])
+
+	_, err = New(mod, []instance.ExternalValue{instance.NewTag(mod.Types[2])}, debugger.DebugLevelNoLog)
+	assert.ErrorIs(t, err, instance.ExternalValuesNotMatched)
+}
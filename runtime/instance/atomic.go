@@ -0,0 +1,168 @@
+package instance
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	MemoryUnalignedAtomic error = errors.New("Unaligned atomic memory access")
+	MemoryNotShared       error = errors.New("Memory is not shared")
+)
+
+// WaitResult is the result of Wait pushed by memory.atomic.wait.
+type WaitResult uint32
+
+const (
+	WaitResultOk       WaitResult = 0 // woken by Notify
+	WaitResultNotEqual WaitResult = 1
+	WaitResultTimedOut WaitResult = 2
+)
+
+// checkAtomic returns an error unless width bytes from offset are in the memory and naturally aligned.
+// It must be called with mu held.
+func (m *Memory) checkAtomic(offset uint64, width uint32) error {
+	if offset+uint64(width) > uint64(len(m.Data)) {
+		return fmt.Errorf("%w: offset=%d length=%d size=%d", MemoryOutOfBounds, offset, width, len(m.Data))
+	}
+	if offset%uint64(width) != 0 {
+		return fmt.Errorf("%w: offset=%d width=%d", MemoryUnalignedAtomic, offset, width)
+	}
+	return nil
+}
+
+func (m *Memory) readAtomic(offset uint64, width uint32) uint64 {
+	switch width {
+	case 1:
+		return uint64(m.Data[offset])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(m.Data[offset:]))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(m.Data[offset:]))
+	default:
+		return binary.LittleEndian.Uint64(m.Data[offset:])
+	}
+}
+
+func (m *Memory) writeAtomic(offset uint64, width uint32, v uint64) {
+	switch width {
+	case 1:
+		m.Data[offset] = uint8(v)
+	case 2:
+		binary.LittleEndian.PutUint16(m.Data[offset:], uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(m.Data[offset:], uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(m.Data[offset:], v)
+	}
+	m.markDirty(uint32(offset), width)
+}
+
+// AtomicLoad reads width bytes from offset in little endian as an atomic access.
+func (m *Memory) AtomicLoad(offset uint64, width uint32) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkAtomic(offset, width); err != nil {
+		return 0, err
+	}
+	return m.readAtomic(offset, width), nil
+}
+
+// AtomicStore writes the lower width bytes of v to offset in little endian as an atomic access.
+func (m *Memory) AtomicStore(offset uint64, width uint32, v uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkAtomic(offset, width); err != nil {
+		return err
+	}
+	m.writeAtomic(offset, width, v)
+	return nil
+}
+
+// AtomicRMW replaces width bytes at offset with f of the old value and returns the old value.
+// The result of f is truncated to width bytes.
+func (m *Memory) AtomicRMW(offset uint64, width uint32, f func(old uint64) uint64) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkAtomic(offset, width); err != nil {
+		return 0, err
+	}
+	old := m.readAtomic(offset, width)
+	m.writeAtomic(offset, width, f(old))
+	return old, nil
+}
+
+// Wait blocks until Notify is called for offset or the timeout passes when width bytes at offset equal expected.
+// A negative timeout waits forever. Only a shared memory can be waited on.
+func (m *Memory) Wait(offset uint64, width uint32, expected uint64, timeout time.Duration) (WaitResult, error) {
+	m.mu.Lock()
+	if err := m.checkAtomic(offset, width); err != nil {
+		m.mu.Unlock()
+		return 0, err
+	}
+	if !m.Shared() {
+		m.mu.Unlock()
+		return 0, MemoryNotShared
+	}
+	if m.readAtomic(offset, width) != expected {
+		m.mu.Unlock()
+		return WaitResultNotEqual, nil
+	}
+	ch := make(chan struct{})
+	if m.waiters == nil {
+		m.waiters = make(map[uint64][]chan struct{})
+	}
+	m.waiters[offset] = append(m.waiters[offset], ch)
+	m.mu.Unlock()
+
+	if timeout < 0 {
+		<-ch
+		return WaitResultOk, nil
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return WaitResultOk, nil
+	case <-timer.C:
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, w := range m.waiters[offset] {
+		if w == ch {
+			m.waiters[offset] = append(m.waiters[offset][:i], m.waiters[offset][i+1:]...)
+			if len(m.waiters[offset]) == 0 {
+				delete(m.waiters, offset)
+			}
+			return WaitResultTimedOut, nil
+		}
+	}
+	// notified while timing out
+	return WaitResultOk, nil
+}
+
+// Notify wakes up to count waiters on offset in the order they started waiting
+// and returns the number of woken waiters. Nobody waits on an unshared memory.
+func (m *Memory) Notify(offset uint64, count uint32) (uint32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkAtomic(offset, 4); err != nil {
+		return 0, err
+	}
+	waiters := m.waiters[offset]
+	n := len(waiters)
+	if uint64(count) < uint64(n) {
+		n = int(count)
+	}
+	for _, w := range waiters[:n] {
+		close(w)
+	}
+	if n == len(waiters) {
+		delete(m.waiters, offset)
+	} else {
+		m.waiters[offset] = waiters[n:]
+	}
+	return uint32(n), nil
+}
@@ -0,0 +1,71 @@
+package instance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestMemoryWaitNotify(t *testing.T) {
+	mem := NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1, Max: 2}, Shared: true})
+	results := make(chan WaitResult)
+	for j := 0; j < 3; j++ {
+		go func() {
+			res, err := mem.Wait(8, 4, 0, -1)
+			assert.NoError(t, err)
+			results <- res
+		}()
+	}
+	// wait until all of them are waiting
+	for {
+		mem.mu.Lock()
+		n := len(mem.waiters[8])
+		mem.mu.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	n, err := mem.Notify(8, 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), n)
+	assert.Equal(t, WaitResultOk, <-results)
+	assert.Equal(t, WaitResultOk, <-results)
+	n, err = mem.Notify(8, 0xffffffff)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), n)
+	assert.Equal(t, WaitResultOk, <-results)
+	assert.Empty(t, mem.waiters)
+
+	res, err := mem.Wait(8, 4, 0, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, WaitResultTimedOut, res)
+	assert.Empty(t, mem.waiters)
+
+	require.NoError(t, mem.AtomicStore(8, 4, 1))
+	res, err = mem.Wait(8, 4, 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, WaitResultNotEqual, res)
+
+	_, err = NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1}}).Wait(8, 4, 0, -1)
+	assert.ErrorIs(t, err, MemoryNotShared)
+}
+
+func TestMemoryGrow_Shared(t *testing.T) {
+	mem := NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1, Max: 3}, Shared: true})
+	// the maximum is not reserved up front
+	assert.Equal(t, int(PAGE_SIZE), cap(mem.Data))
+	require.NoError(t, mem.WriteUint32LE(PAGE_SIZE-4, 0xdeadbeef))
+	prev, err := mem.Grow(2)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), prev)
+	assert.Equal(t, uint64(3*PAGE_SIZE), mem.Size())
+	v, err := mem.ReadUint32LE(PAGE_SIZE - 4)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0xdeadbeef), v)
+	_, err = mem.Grow(1)
+	assert.ErrorIs(t, err, MemoryGrowLimit)
+}
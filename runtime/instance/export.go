@@ -11,22 +11,28 @@ type Export struct {
 	Value ExternalValue
 }
 
-func newExports(mod *structure.Module, funcs []*Function, tables []*Table, memories []*Memory, globals []*Global) ([]*Export, error) {
+func newExports(mod *structure.Module, funcs []*Function, tables []*Table, memories []*Memory, globals []*Global, tags []*Tag) ([]*Export, error) {
 	exports := make([]*Export, 0, len(mod.Exports))
 	for _, e := range mod.Exports {
 		var val ExternalValue
+		var err error
 		switch e.Desc.Type {
 		case structure.DescTypeFunc:
-			val = funcs[e.Desc.Val]
+			val, err = exportValue(funcs, e.Desc.Val)
 		case structure.DescTypeTable:
-			val = tables[e.Desc.Val]
+			val, err = exportValue(tables, e.Desc.Val)
 		case structure.DescTypeMemory:
-			val = memories[e.Desc.Val]
+			val, err = exportValue(memories, e.Desc.Val)
 		case structure.DescTypeGlobal:
-			val = globals[e.Desc.Val]
+			val, err = exportValue(globals, e.Desc.Val)
+		case structure.DescTypeTag:
+			val, err = exportValue(tags, e.Desc.Val)
 		default:
 			return nil, fmt.Errorf("new export instance: %w", structure.InvalidDesType)
 		}
+		if err != nil {
+			return nil, fmt.Errorf("new export instance: %s: %w", e.Name, err)
+		}
 		exports = append(exports, &Export{
 			Name:  e.Name,
 			Value: val,
@@ -34,3 +40,10 @@ func newExports(mod *structure.Module, funcs []*Function, tables []*Table, memor
 	}
 	return exports, nil
 }
+
+func exportValue[T ExternalValue](vals []T, idx uint32) (ExternalValue, error) {
+	if int(idx) >= len(vals) {
+		return nil, fmt.Errorf("%w: %d", structure.InvalidExportIndex, idx)
+	}
+	return vals[idx], nil
+}
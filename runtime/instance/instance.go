@@ -17,6 +17,12 @@ func evaluateConstInstr(instr instruction.Instruction) (value.Value, error) {
 		return value.F32(value.Float32FromUint32(instruction.Imm[uint32](instr))), nil
 	case instruction.F64_CONST:
 		return value.F64(value.Float64FromUint64(instruction.Imm[uint64](instr))), nil
+	case instruction.SIMD:
+		imm := instruction.Imm[instruction.VectorImm](instr)
+		if instr.(*instruction.Vector).Op == instruction.V128_CONST {
+			return value.Vector(imm.Bytes), nil
+		}
+		return nil, fmt.Errorf("evaluateConstInstr: %w: %s", instruction.NotConstInstruction, instr)
 	default:
 		return nil, fmt.Errorf("evaluateConstInstr: %w: opcode=%x", instruction.NotConstInstruction, instr.Opcode())
 	}
@@ -45,6 +51,7 @@ const (
 	ExternalValueTypeTable  ExternalValueType = 1
 	ExternalValueTypeMem    ExternalValueType = 2
 	ExternalValueTypeGlobal ExternalValueType = 3
+	ExternalValueTypeTag    ExternalValueType = 4
 )
 
 type ExternalValue interface {
@@ -52,7 +59,7 @@ type ExternalValue interface {
 }
 
 type ExternalValueTypeSet interface {
-	*Function | *Table | *Memory | *Global
+	*Function | *Table | *Memory | *Global | *Tag
 }
 
 func GetExternVal[T ExternalValueTypeSet](v ExternalValue) T {
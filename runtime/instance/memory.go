@@ -1,7 +1,13 @@
 package instance
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
 
 	"github.com/terassyi/gowi/structure"
 	"github.com/terassyi/gowi/types"
@@ -9,24 +15,52 @@ import (
 
 const (
 	PAGE_SIZE uint32 = 65536 // 64KB
+	MAX_PAGES uint32 = 65536 // 4GB
+
+	// DIRTY_PAGE_SIZE is the granularity of tracking written memory.
+	DIRTY_PAGE_SIZE uint32 = 4096
+)
+
+var (
+	MemoryOutOfBounds     error = errors.New("Out of bounds memory access")
+	MemoryGrowLimit       error = errors.New("Memory can't grow over the limit")
+	MemoryStringNotClosed error = errors.New("String is not terminated by NUL")
 )
 
+// Memory is a linear memory instance.
+// Data is replaced when the memory grows, so hosts should access the memory through the methods or views
+// instead of keeping Data. The methods hold the read lock while they access Data,
+// so they are safe for concurrent use with the execution of the instance and growing the memory.
+// A shared memory can be used by instances running on multiple goroutines. It is allocated at its current size
+// and copied under the lock when it grows, like an unshared one. Atomic accesses to it are serialized by the memory.
 type Memory struct {
-	Type *types.MemoryType
-	Data []byte
+	Type    *types.MemoryType
+	Data    []byte
+	dirty   []uint64 // bitmap of written pages, nil when writes are not tracked
+	hooks   []func(prev, pages uint32)
+	mu      sync.RWMutex // read locked by accesses, locked by atomic accesses and growth
+	waiters map[uint64][]chan struct{}
+}
+
+// NewMemory creates a memory of the type with the minimum size.
+// A shared memory is passed to multiple instances as an imported external value.
+func NewMemory(t *types.MemoryType) *Memory {
+	return &Memory{Type: t, Data: make([]byte, uint64(PAGE_SIZE)*uint64(t.Limits.Min))}
 }
 
 func newMemories(mod *structure.Module) []*Memory {
 	mems := make([]*Memory, 0, len(mod.Memories))
 	for _, m := range mod.Memories {
-		mems = append(mems, &Memory{
-			Type: m.Type,
-			Data: make([]byte, PAGE_SIZE*m.Type.Limits.Min),
-		})
+		mems = append(mems, NewMemory(m.Type))
 	}
 	return mems
 }
 
+// Shared reports whether the memory is a shared memory of the threads proposal.
+func (m *Memory) Shared() bool {
+	return m.Type != nil && m.Type.Shared
+}
+
 func (*Memory) ExternalValueType() ExternalValueType {
 	return ExternalValueTypeMem
 }
@@ -41,3 +75,394 @@ func (m *Memory) initData(offset int32, data []byte) error {
 	}
 	return nil
 }
+
+// MarkDirty records that length bytes from offset are written.
+// It does nothing unless the memory belongs to an instance restored from a snapshot.
+func (m *Memory) MarkDirty(offset, length uint32) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.markDirty(offset, length)
+}
+
+// markDirty must be called with mu held. Writes under the read lock mark pages concurrently,
+// so the bits are set atomically.
+func (m *Memory) markDirty(offset, length uint32) {
+	if m.dirty == nil || length == 0 {
+		return
+	}
+	for p := uint64(offset) / uint64(DIRTY_PAGE_SIZE); p <= (uint64(offset)+uint64(length)-1)/uint64(DIRTY_PAGE_SIZE); p++ {
+		if int(p/64) < len(m.dirty) {
+			setBits(&m.dirty[p/64], 1<<(p%64))
+		}
+	}
+}
+
+// markAllDirty records that the whole memory is written.
+func (m *Memory) markAllDirty() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.dirty == nil {
+		return
+	}
+	pages := (uint64(len(m.Data)) + uint64(DIRTY_PAGE_SIZE) - 1) / uint64(DIRTY_PAGE_SIZE)
+	for p := uint64(0); p < pages; p++ {
+		setBits(&m.dirty[p/64], 1<<(p%64))
+	}
+}
+
+// setBits sets the bits of mask in *addr with a CAS loop.
+func setBits(addr *uint64, mask uint64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		if old&mask == mask || atomic.CompareAndSwapUint64(addr, old, old|mask) {
+			return
+		}
+	}
+}
+
+func (m *Memory) trackDirty() {
+	pages := (uint64(len(m.Data)) + uint64(DIRTY_PAGE_SIZE) - 1) / uint64(DIRTY_PAGE_SIZE)
+	m.dirty = make([]uint64, (pages+63)/64)
+}
+
+// reset copies the written pages back from data.
+func (m *Memory) reset(data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Data) != len(data) {
+		m.Data = make([]byte, len(data))
+		copy(m.Data, data)
+		m.trackDirty()
+		return
+	}
+	for i, dirty := range m.dirty {
+		for dirty != 0 {
+			p := uint64(i*64 + bits.TrailingZeros64(dirty))
+			dirty &= dirty - 1
+			start := p * uint64(DIRTY_PAGE_SIZE)
+			end := start + uint64(DIRTY_PAGE_SIZE)
+			if end > uint64(len(data)) {
+				end = uint64(len(data))
+			}
+			copy(m.Data[start:end], data[start:end])
+		}
+		m.dirty[i] = 0
+	}
+}
+
+// Size returns the size of the memory in bytes.
+// It is uint64 because a memory of MAX_PAGES is 4GiB.
+func (m *Memory) Size() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return uint64(len(m.Data))
+}
+
+// Pages returns the size of the memory in pages.
+func (m *Memory) Pages() uint32 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pages()
+}
+
+func (m *Memory) pages() uint32 {
+	return uint32(uint64(len(m.Data)) / uint64(PAGE_SIZE))
+}
+
+// maxPages returns the maximum size of the memory in pages.
+func (m *Memory) maxPages() uint64 {
+	if m.Type != nil && m.Type.Limits != nil && m.Type.Limits.Max != 0 {
+		return uint64(m.Type.Limits.Max)
+	}
+	return uint64(MAX_PAGES)
+}
+
+// Grow grows the memory by delta pages and returns the previous size in pages.
+// Hooks registered by OnGrow are called after the memory grows.
+// https://webassembly.github.io/spec/core/exec/modules.html#growing-memories
+func (m *Memory) Grow(delta uint32) (uint32, error) {
+	prev, pages, hooks, err := m.grow(delta)
+	if err != nil || delta == 0 {
+		return prev, err
+	}
+	// hooks are called without the lock, so that they can access the memory
+	for _, hook := range hooks {
+		hook(prev, pages)
+	}
+	return prev, nil
+}
+
+// grow resizes the data under the lock and returns the hooks to be called.
+func (m *Memory) grow(delta uint32) (uint32, uint32, []func(prev, pages uint32), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := m.pages()
+	pages := uint64(prev) + uint64(delta)
+	if max := m.maxPages(); pages > max {
+		return prev, prev, nil, fmt.Errorf("%w: pages=%d max=%d", MemoryGrowLimit, pages, max)
+	}
+	if delta == 0 {
+		return prev, prev, nil, nil
+	}
+	if uint64(cap(m.Data)) >= pages*uint64(PAGE_SIZE) {
+		m.Data = m.Data[:pages*uint64(PAGE_SIZE)]
+	} else {
+		data := make([]byte, pages*uint64(PAGE_SIZE))
+		copy(data, m.Data)
+		m.Data = data
+	}
+	if m.dirty != nil {
+		dirty := m.dirty
+		m.trackDirty()
+		copy(m.dirty, dirty)
+	}
+	return prev, uint32(pages), append([]func(prev, pages uint32){}, m.hooks...), nil
+}
+
+// OnGrow registers a hook called with the previous and the new size in pages when the memory grows.
+// Slices taken from Data or View.Bytes are stale after the memory grows.
+func (m *Memory) OnGrow(hook func(prev, pages uint32)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Load copies len(b) bytes from offset to b.
+func (m *Memory) Load(offset uint64, b []byte) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.checkRange(offset, uint64(len(b))); err != nil {
+		return err
+	}
+	copy(b, m.Data[offset:])
+	return nil
+}
+
+// Store copies b to the memory from offset. Nothing is written when b doesn't fit.
+func (m *Memory) Store(offset uint64, b []byte) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.checkRange(offset, uint64(len(b))); err != nil {
+		return err
+	}
+	copy(m.Data[offset:], b)
+	m.markDirty(uint32(offset), uint32(len(b)))
+	return nil
+}
+
+// checkRange returns an error unless length bytes from offset are in the memory.
+// It must be called with mu held.
+func (m *Memory) checkRange(offset, length uint64) error {
+	if offset+length > uint64(len(m.Data)) {
+		return fmt.Errorf("%w: offset=%d length=%d size=%d", MemoryOutOfBounds, offset, length, len(m.Data))
+	}
+	return nil
+}
+
+// check returns an error unless length bytes from offset are in the memory.
+// It must be called with mu held.
+func (m *Memory) check(offset, length uint32) error {
+	return m.checkRange(uint64(offset), uint64(length))
+}
+
+func (m *Memory) ReadUint8(offset uint32) (uint8, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, 1); err != nil {
+		return 0, err
+	}
+	return m.Data[offset], nil
+}
+
+func (m *Memory) ReadUint16LE(offset uint32) (uint16, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, 2); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(m.Data[offset:]), nil
+}
+
+func (m *Memory) ReadUint32LE(offset uint32) (uint32, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, 4); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(m.Data[offset:]), nil
+}
+
+func (m *Memory) ReadUint64LE(offset uint32) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, 8); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(m.Data[offset:]), nil
+}
+
+func (m *Memory) WriteUint8(offset uint32, v uint8) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, 1); err != nil {
+		return err
+	}
+	m.Data[offset] = v
+	m.markDirty(offset, 1)
+	return nil
+}
+
+func (m *Memory) WriteUint16LE(offset uint32, v uint16) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, 2); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint16(m.Data[offset:], v)
+	m.markDirty(offset, 2)
+	return nil
+}
+
+func (m *Memory) WriteUint32LE(offset uint32, v uint32) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, 4); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(m.Data[offset:], v)
+	m.markDirty(offset, 4)
+	return nil
+}
+
+func (m *Memory) WriteUint64LE(offset uint32, v uint64) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, 8); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint64(m.Data[offset:], v)
+	m.markDirty(offset, 8)
+	return nil
+}
+
+// ReadBytes returns a copy of length bytes from offset.
+func (m *Memory) ReadBytes(offset, length uint32) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, length); err != nil {
+		return nil, err
+	}
+	b := make([]byte, length)
+	copy(b, m.Data[offset:])
+	return b, nil
+}
+
+// WriteBytes copies b to the memory from offset. Nothing is written when b doesn't fit.
+func (m *Memory) WriteBytes(offset uint32, b []byte) error {
+	if uint64(len(b)) > uint64(MAX_PAGES)*uint64(PAGE_SIZE) {
+		return fmt.Errorf("%w: length=%d", MemoryOutOfBounds, len(b))
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, uint32(len(b))); err != nil {
+		return err
+	}
+	copy(m.Data[offset:], b)
+	m.markDirty(offset, uint32(len(b)))
+	return nil
+}
+
+// ReadString returns the string of length bytes from ptr.
+func (m *Memory) ReadString(ptr, length uint32) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(ptr, length); err != nil {
+		return "", err
+	}
+	return string(m.Data[ptr : ptr+length]), nil
+}
+
+// ReadCString returns the string from ptr to the first NUL byte, which is not included.
+func (m *Memory) ReadCString(ptr uint32) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(ptr, 0); err != nil {
+		return "", err
+	}
+	for i, b := range m.Data[ptr:] {
+		if b == 0 {
+			return string(m.Data[ptr : int(ptr)+i]), nil
+		}
+	}
+	return "", fmt.Errorf("%w: ptr=%d", MemoryStringNotClosed, ptr)
+}
+
+// View returns the view of length bytes from offset.
+func (m *Memory) View(offset, length uint32) (*MemoryView, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.check(offset, length); err != nil {
+		return nil, err
+	}
+	return &MemoryView{mem: m, offset: offset, length: length}, nil
+}
+
+// MemoryView is a range of a memory which is checked on every access,
+// so it stays valid after the memory grows. It implements io.ReaderAt and io.WriterAt.
+type MemoryView struct {
+	mem    *Memory
+	offset uint32
+	length uint32
+}
+
+func (v *MemoryView) Len() int {
+	return int(v.length)
+}
+
+// Bytes returns the range of the memory without copying.
+// The slice must not be used after the memory grows.
+func (v *MemoryView) Bytes() ([]byte, error) {
+	v.mem.mu.RLock()
+	defer v.mem.mu.RUnlock()
+	return v.bytes()
+}
+
+// bytes must be called with the lock of the memory held.
+func (v *MemoryView) bytes() ([]byte, error) {
+	if err := v.mem.check(v.offset, v.length); err != nil {
+		return nil, err
+	}
+	return v.mem.Data[v.offset : v.offset+v.length], nil
+}
+
+func (v *MemoryView) ReadAt(p []byte, off int64) (int, error) {
+	v.mem.mu.RLock()
+	defer v.mem.mu.RUnlock()
+	b, err := v.bytes()
+	if err != nil {
+		return 0, err
+	}
+	if off < 0 || off > int64(len(b)) {
+		return 0, fmt.Errorf("%w: view offset=%d", MemoryOutOfBounds, off)
+	}
+	n := copy(p, b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (v *MemoryView) WriteAt(p []byte, off int64) (int, error) {
+	v.mem.mu.RLock()
+	defer v.mem.mu.RUnlock()
+	b, err := v.bytes()
+	if err != nil {
+		return 0, err
+	}
+	if off < 0 || off+int64(len(p)) > int64(len(b)) {
+		return 0, fmt.Errorf("%w: view offset=%d length=%d", MemoryOutOfBounds, off, len(p))
+	}
+	n := copy(b[off:], p)
+	v.mem.markDirty(v.offset+uint32(off), uint32(n))
+	return n, nil
+}
@@ -1,6 +1,7 @@
 package instance
 
 import (
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -96,3 +97,107 @@ func TestMemoryInitData_Panic(t *testing.T) {
 		}
 	}
 }
+
+func newTestMemory(min, max uint32) *Memory {
+	return &Memory{Type: &types.MemoryType{Limits: &types.Limits{Min: min, Max: max}}, Data: make([]byte, PAGE_SIZE*min)}
+}
+
+func TestMemoryReadWrite(t *testing.T) {
+	mem := newTestMemory(1, 0)
+	require.NoError(t, mem.WriteUint32LE(4, 0xdeadbeef))
+	require.NoError(t, mem.WriteUint64LE(8, 0x0102030405060708))
+	require.NoError(t, mem.WriteUint16LE(16, 0xcafe))
+	require.NoError(t, mem.WriteUint8(18, 0xff))
+	require.NoError(t, mem.WriteBytes(20, []byte("hello\x00world")))
+
+	v32, err := mem.ReadUint32LE(4)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0xdeadbeef), v32)
+	v64, err := mem.ReadUint64LE(8)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x0102030405060708), v64)
+	v16, err := mem.ReadUint16LE(16)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0xcafe), v16)
+	v8, err := mem.ReadUint8(18)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0xff), v8)
+	assert.Equal(t, []byte{0xef, 0xbe, 0xad, 0xde}, mem.Data[4:8])
+
+	s, err := mem.ReadString(20, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", s)
+	s, err = mem.ReadCString(20)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", s)
+	s, err = mem.ReadCString(26)
+	require.NoError(t, err)
+	assert.Equal(t, "world", s)
+	b, err := mem.ReadBytes(20, 3)
+	require.NoError(t, err)
+	b[0] = 'j'
+	assert.Equal(t, byte('h'), mem.Data[20])
+}
+
+func TestMemoryReadWrite_OutOfBounds(t *testing.T) {
+	mem := newTestMemory(1, 0)
+	last := PAGE_SIZE - 1
+	_, err := mem.ReadUint32LE(last - 2)
+	assert.ErrorIs(t, err, MemoryOutOfBounds)
+	_, err = mem.ReadUint64LE(0xffffffff)
+	assert.ErrorIs(t, err, MemoryOutOfBounds)
+	assert.ErrorIs(t, mem.WriteUint16LE(last, 1), MemoryOutOfBounds)
+	assert.ErrorIs(t, mem.WriteBytes(last, []byte("ab")), MemoryOutOfBounds)
+	assert.Equal(t, byte(0), mem.Data[last])
+	_, err = mem.ReadString(0xfffffff0, 0x20)
+	assert.ErrorIs(t, err, MemoryOutOfBounds)
+	_, err = mem.ReadCString(PAGE_SIZE + 1)
+	assert.ErrorIs(t, err, MemoryOutOfBounds)
+	mem.Data[last] = 'a'
+	_, err = mem.ReadCString(last)
+	assert.ErrorIs(t, err, MemoryStringNotClosed)
+	_, err = mem.View(last, 2)
+	assert.ErrorIs(t, err, MemoryOutOfBounds)
+}
+
+func TestMemoryGrow(t *testing.T) {
+	mem := newTestMemory(1, 3)
+	require.NoError(t, mem.WriteBytes(10, []byte("abc")))
+	view, err := mem.View(10, 3)
+	require.NoError(t, err)
+	grown := [][2]uint32{}
+	mem.OnGrow(func(prev, pages uint32) {
+		grown = append(grown, [2]uint32{prev, pages})
+	})
+	// a hook can access the memory
+	mem.OnGrow(func(_, pages uint32) {
+		_, err := mem.AtomicLoad(uint64(pages)*uint64(PAGE_SIZE)-4, 4)
+		assert.NoError(t, err)
+	})
+
+	prev, err := mem.Grow(2)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), prev)
+	assert.Equal(t, uint32(3), mem.Pages())
+	assert.Equal(t, uint64(3*PAGE_SIZE), mem.Size())
+	assert.Equal(t, [][2]uint32{{1, 3}}, grown)
+
+	// the view reads the grown memory
+	_, err = view.WriteAt([]byte("x"), 1)
+	require.NoError(t, err)
+	b, err := view.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("axc"), b)
+	buf := make([]byte, 4)
+	n, err := view.ReadAt(buf, 1)
+	assert.Equal(t, 2, n)
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = view.WriteAt([]byte("xyz"), 1)
+	assert.ErrorIs(t, err, MemoryOutOfBounds)
+
+	_, err = mem.Grow(1)
+	assert.ErrorIs(t, err, MemoryGrowLimit)
+	assert.Equal(t, uint32(3), mem.Pages())
+	assert.Len(t, grown, 1)
+	require.NoError(t, mem.WriteUint32LE(3*PAGE_SIZE-4, 1))
+}
@@ -1,6 +1,7 @@
 package instance
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/terassyi/gowi/runtime/value"
@@ -8,25 +9,59 @@ import (
 	"github.com/terassyi/gowi/types"
 )
 
+var (
+	ExternalValuesNotMatched error = errors.New("External values don't match imports")
+	ImportNotResolved        error = errors.New("Import is not resolved")
+)
+
 type Module struct {
 	Types      []*types.FuncType
 	FuncAddrs  []*Function
 	TableAddrs []*Table
 	MemAddrs   []*Memory
 	GlobalAddr []*Global
+	TagAddrs   []*Tag
 	// ElemAddrs  []*Element
 	// DataAddrs  []*Data
 	Exports []*Export
+
+	importedMems int // number of imported memories at the head of MemAddrs
 }
 
 func New(mod *structure.Module) (*Module, error) {
+	return NewWithExternalValues(mod, nil)
+}
+
+// NewWithExternalValues instantiates the module with the external values given in the order of the imports.
+// Only imported memories and tags are resolved for now, so that a shared memory can be used by multiple instances
+// and an exception thrown in an instance can be caught in another.
+// Imports are ignored when no external value is given, except that imported tags get new tag instances.
+func NewWithExternalValues(mod *structure.Module, externalvals []ExternalValue) (*Module, error) {
 	m := &Module{}
 	m.Types = mod.Types
 	funcs := newFunctions(mod)
 	m.FuncAddrs = funcs
 	m.TableAddrs = newTables(mod)
-	m.MemAddrs = newMemories(mod)
+	mems, err := importMemories(mod, externalvals)
+	if err != nil {
+		return nil, fmt.Errorf("New module instance: %w", err)
+	}
+	m.importedMems = len(mems)
+	m.MemAddrs = append(mems, newMemories(mod)...)
+	tags, err := newTags(mod, externalvals)
+	if err != nil {
+		return nil, fmt.Errorf("New module instance: %w", err)
+	}
+	m.TagAddrs = tags
+	globals, err := newGlobals(mod)
+	if err != nil {
+		return nil, fmt.Errorf("New module instance: %w", err)
+	}
+	m.GlobalAddr = globals
 	for _, e := range mod.Elements {
+		if int(e.TableIndex) >= len(m.TableAddrs) {
+			return nil, fmt.Errorf("New module instance: %w: table %d", ImportNotResolved, e.TableIndex)
+		}
 		table := m.TableAddrs[e.TableIndex]
 		offset, err := evaluateConstInstr(e.Offset)
 		if err != nil {
@@ -37,6 +72,9 @@ func New(mod *structure.Module) (*Module, error) {
 		}
 	}
 	for _, d := range mod.Datas {
+		if int(d.MemoryIndex) >= len(m.MemAddrs) {
+			return nil, fmt.Errorf("New module instance: %w: memory %d", ImportNotResolved, d.MemoryIndex)
+		}
 		mem := m.MemAddrs[d.MemoryIndex]
 		offset, err := evaluateConstInstr(d.Offset)
 		if err != nil {
@@ -46,7 +84,7 @@ func New(mod *structure.Module) (*Module, error) {
 			return nil, fmt.Errorf("New module instance: %w", err)
 		}
 	}
-	exports, err := newExports(mod, m.FuncAddrs, m.TableAddrs, m.MemAddrs, m.GlobalAddr)
+	exports, err := newExports(mod, m.FuncAddrs, m.TableAddrs, m.MemAddrs, m.GlobalAddr, m.TagAddrs)
 	if err != nil {
 		return nil, fmt.Errorf("New module instance: %w", err)
 	}
@@ -57,6 +95,42 @@ func New(mod *structure.Module) (*Module, error) {
 	return m, nil
 }
 
+// importMemories returns the memories given for the imported memories.
+// https://webassembly.github.io/spec/core/valid/types.html#import-subtyping
+func importMemories(mod *structure.Module, externalvals []ExternalValue) ([]*Memory, error) {
+	if len(externalvals) == 0 {
+		return nil, nil
+	}
+	if len(externalvals) != len(mod.Imports) {
+		return nil, fmt.Errorf("%w: imports=%d external values=%d", ExternalValuesNotMatched, len(mod.Imports), len(externalvals))
+	}
+	mems := make([]*Memory, 0)
+	for i, imp := range mod.Imports {
+		if imp.Desc.Type != structure.DescTypeMemory {
+			continue
+		}
+		mem, ok := externalvals[i].(*Memory)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.%s is not a memory", ExternalValuesNotMatched, imp.Module, imp.Name)
+		}
+		t := imp.Desc.Mem
+		max := uint32(0)
+		if mem.Type != nil && mem.Type.Limits != nil {
+			max = mem.Type.Limits.Max
+		}
+		switch {
+		case mem.Shared() != t.Shared:
+			return nil, fmt.Errorf("%w: %s.%s shared=%v", ExternalValuesNotMatched, imp.Module, imp.Name, mem.Shared())
+		case mem.Pages() < t.Limits.Min:
+			return nil, fmt.Errorf("%w: %s.%s pages=%d min=%d", ExternalValuesNotMatched, imp.Module, imp.Name, mem.Pages(), t.Limits.Min)
+		case t.Limits.Max != 0 && (max == 0 || max > t.Limits.Max):
+			return nil, fmt.Errorf("%w: %s.%s max=%d", ExternalValuesNotMatched, imp.Module, imp.Name, max)
+		}
+		mems = append(mems, mem)
+	}
+	return mems, nil
+}
+
 func (m *Module) GetExports() []ExternalValue {
 	externalValues := make([]ExternalValue, 0, len(m.Exports))
 	for _, e := range m.Exports {
@@ -73,3 +147,34 @@ func (m *Module) GetExport(name string) (ExternalValue, error) {
 	}
 	return nil, fmt.Errorf("Not found exported isntance")
 }
+
+// FuncIndex returns the index of the function instance in the module or -1.
+func (m *Module) FuncIndex(f *Function) int {
+	for i, addr := range m.FuncAddrs {
+		if addr == f {
+			return i
+		}
+	}
+	return -1
+}
+
+// TagIndex returns the index of the tag instance in the module or -1.
+func (m *Module) TagIndex(t *Tag) int {
+	for i, addr := range m.TagAddrs {
+		if addr == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// FuncName returns the exported name of the function instance.
+// If the function is not exported, it returns func[index].
+func (m *Module) FuncName(f *Function) string {
+	for _, e := range m.Exports {
+		if e.Value == ExternalValue(f) {
+			return e.Name
+		}
+	}
+	return fmt.Sprintf("func[%d]", m.FuncIndex(f))
+}
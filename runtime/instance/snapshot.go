@@ -0,0 +1,179 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+var SnapshotNotMatched error = errors.New("Module instance is not restored from the snapshot")
+
+// Snapshot is a copy of the mutable state of a module instance: memories, tables and globals.
+// Types and function codes are shared with the source instance.
+// Imported and shared memories are not copied, restored instances refer to the same memories as the source.
+// A snapshot is immutable, so it can be restored from multiple goroutines.
+type Snapshot struct {
+	source   *Module
+	memories [][]byte // nil for a memory kept by reference
+	tables   [][]int  // function index of each element, -1 for null
+	globals  []value.Value
+}
+
+// Snapshot copies the current state of the instance.
+// It must not be called while the instance is executing.
+func (m *Module) Snapshot() (*Snapshot, error) {
+	s := &Snapshot{
+		source:   m,
+		memories: make([][]byte, 0, len(m.MemAddrs)),
+		tables:   make([][]int, 0, len(m.TableAddrs)),
+		globals:  make([]value.Value, 0, len(m.GlobalAddr)),
+	}
+	for idx, mem := range m.MemAddrs {
+		if m.sharesMemory(idx) {
+			s.memories = append(s.memories, nil)
+			continue
+		}
+		data := make([]byte, len(mem.Data))
+		copy(data, mem.Data)
+		s.memories = append(s.memories, data)
+	}
+	for idx, t := range m.TableAddrs {
+		elems := make([]int, 0, len(t.Elems))
+		for _, e := range t.Elems {
+			if e == nil {
+				elems = append(elems, -1)
+				continue
+			}
+			f, ok := e.(*Function)
+			if !ok {
+				return nil, fmt.Errorf("snapshot: table[%d]: unsupported reference", idx)
+			}
+			fidx := m.FuncIndex(f)
+			if fidx < 0 {
+				return nil, fmt.Errorf("snapshot: table[%d]: function is not in the module", idx)
+			}
+			elems = append(elems, fidx)
+		}
+		s.tables = append(s.tables, elems)
+	}
+	for _, g := range m.GlobalAddr {
+		s.globals = append(s.globals, g.Value)
+	}
+	return s, nil
+}
+
+// Module returns the instance the snapshot is taken from.
+func (s *Snapshot) Module() *Module {
+	return s.source
+}
+
+// Restore creates a new instance in the state of the snapshot without evaluating segments again.
+// Memories of the restored instance track written pages, so that Reset only copies them back.
+func (s *Snapshot) Restore() *Module {
+	m := &Module{
+		Types:      s.source.Types,
+		FuncAddrs:  make([]*Function, 0, len(s.source.FuncAddrs)),
+		TableAddrs: make([]*Table, 0, len(s.tables)),
+		MemAddrs:   make([]*Memory, 0, len(s.memories)),
+		GlobalAddr: make([]*Global, 0, len(s.globals)),
+		TagAddrs:   s.source.TagAddrs, // tags are immutable, so exceptions match across restored instances
+		Exports:    make([]*Export, 0, len(s.source.Exports)),
+
+		importedMems: s.source.importedMems,
+	}
+	for _, f := range s.source.FuncAddrs {
+		m.FuncAddrs = append(m.FuncAddrs, &Function{Type: f.Type, Module: m, Code: f.Code})
+	}
+	for i, t := range s.source.TableAddrs {
+		m.TableAddrs = append(m.TableAddrs, &Table{Type: t.Type, Elems: s.tableElems(i, m)})
+	}
+	for i, mem := range s.source.MemAddrs {
+		if s.memories[i] == nil {
+			m.MemAddrs = append(m.MemAddrs, mem)
+			continue
+		}
+		data := make([]byte, len(s.memories[i]))
+		copy(data, s.memories[i])
+		restored := &Memory{Type: mem.Type, Data: data}
+		restored.trackDirty()
+		m.MemAddrs = append(m.MemAddrs, restored)
+	}
+	for i, g := range s.source.GlobalAddr {
+		m.GlobalAddr = append(m.GlobalAddr, &Global{Type: g.Type, Value: s.globals[i]})
+	}
+	for _, e := range s.source.Exports {
+		m.Exports = append(m.Exports, &Export{Name: e.Name, Value: s.source.mapExternalValue(e.Value, m)})
+	}
+	return m
+}
+
+// Reset returns the instance restored from the snapshot to the state of the snapshot.
+// Only the memory pages written since the last Restore or Reset are copied.
+// Memories kept by reference are left as they are, because other instances use them.
+func (s *Snapshot) Reset(m *Module) error {
+	if len(m.FuncAddrs) != len(s.source.FuncAddrs) || len(m.MemAddrs) != len(s.memories) ||
+		len(m.TableAddrs) != len(s.tables) || len(m.GlobalAddr) != len(s.globals) {
+		return SnapshotNotMatched
+	}
+	for i, mem := range m.MemAddrs {
+		if s.memories[i] == nil && mem != s.source.MemAddrs[i] {
+			return fmt.Errorf("%w: memory[%d] is not the memory of the snapshot", SnapshotNotMatched, i)
+		}
+	}
+	for i, mem := range m.MemAddrs {
+		if s.memories[i] != nil {
+			mem.reset(s.memories[i])
+		}
+	}
+	for i, t := range m.TableAddrs {
+		t.Elems = s.tableElems(i, m)
+	}
+	for i, g := range m.GlobalAddr {
+		g.Value = s.globals[i]
+	}
+	return nil
+}
+
+// sharesMemory reports whether the memory at idx is kept by reference in restored instances.
+// An imported memory belongs to another instance and a shared memory is meant to be shared.
+func (m *Module) sharesMemory(idx int) bool {
+	return idx < m.importedMems || m.MemAddrs[idx].Shared()
+}
+
+func (s *Snapshot) tableElems(idx int, m *Module) []value.Reference {
+	elems := make([]value.Reference, len(s.tables[idx]))
+	for i, fidx := range s.tables[idx] {
+		if fidx >= 0 {
+			elems[i] = m.FuncAddrs[fidx]
+		}
+	}
+	return elems
+}
+
+// mapExternalValue returns the value in dst at the same index as v in m.
+func (m *Module) mapExternalValue(v ExternalValue, dst *Module) ExternalValue {
+	switch val := v.(type) {
+	case *Function:
+		return dst.FuncAddrs[m.FuncIndex(val)]
+	case *Table:
+		for i, t := range m.TableAddrs {
+			if t == val {
+				return dst.TableAddrs[i]
+			}
+		}
+	case *Memory:
+		for i, mem := range m.MemAddrs {
+			if mem == val {
+				return dst.MemAddrs[i]
+			}
+		}
+	case *Global:
+		for i, g := range m.GlobalAddr {
+			if g == val {
+				return dst.GlobalAddr[i]
+			}
+		}
+	}
+	return v
+}
@@ -0,0 +1,167 @@
+package instance
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/types"
+)
+
+func newModuleFromFile(t *testing.T, path string) *Module {
+	dec, err := decoder.New(path)
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	m, err := New(mod)
+	require.NoError(t, err)
+	return m
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	m := newModuleFromFile(t, "../../examples/memory.wasm")
+	s, err := m.Snapshot()
+	require.NoError(t, err)
+	// the snapshot doesn't change with the source
+	m.MemAddrs[0].Data[0] = 'Z'
+
+	r := s.Restore()
+	require.Len(t, r.MemAddrs, 1)
+	assert.Equal(t, []byte("ABC\xa7D"), r.MemAddrs[0].Data[:5])
+	assert.Equal(t, []byte("WASM"), r.MemAddrs[0].Data[20:24])
+	for _, f := range r.FuncAddrs {
+		assert.Equal(t, r, f.Module)
+	}
+	for _, e := range r.Exports {
+		f := GetExternVal[*Function](e.Value)
+		assert.GreaterOrEqual(t, r.FuncIndex(f), 0)
+	}
+	// restored instances don't share memories
+	r2 := s.Restore()
+	r.MemAddrs[0].Data[1] = 'X'
+	assert.Equal(t, byte('B'), r2.MemAddrs[0].Data[1])
+}
+
+func TestSnapshotRestore_ImportedMemory(t *testing.T) {
+	for _, d := range []struct {
+		path string
+		mem  *Memory
+		vals func(mem *Memory) []ExternalValue
+	}{
+		{path: "../../examples/mem1.wasm", mem: NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1}}), vals: func(mem *Memory) []ExternalValue { return []ExternalValue{nil, mem} }},
+		{path: "../../examples/atomic.wasm", mem: NewMemory(&types.MemoryType{Limits: &types.Limits{Min: 1, Max: 1}, Shared: true}), vals: func(mem *Memory) []ExternalValue { return []ExternalValue{mem} }},
+	} {
+		dec, err := decoder.New(d.path)
+		require.NoError(t, err)
+		mod, err := dec.Decode()
+		require.NoError(t, err)
+		m, err := NewWithExternalValues(mod, d.vals(d.mem))
+		require.NoError(t, err)
+		s, err := m.Snapshot()
+		require.NoError(t, err)
+
+		r := s.Restore()
+		require.Len(t, r.MemAddrs, 1)
+		assert.Same(t, d.mem, r.MemAddrs[0], d.path)
+		// a write through the restored instance is seen by the exporter and not undone by Reset
+		require.NoError(t, r.MemAddrs[0].WriteUint32LE(4, 42))
+		require.NoError(t, s.Reset(r))
+		v, err := d.mem.ReadUint32LE(4)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(42), v, d.path)
+
+		other, err := NewWithExternalValues(mod, d.vals(NewMemory(d.mem.Type)))
+		require.NoError(t, err)
+		assert.ErrorIs(t, s.Reset(other), SnapshotNotMatched, d.path)
+	}
+}
+
+func TestSnapshotRestore_Table(t *testing.T) {
+	m := newModuleFromFile(t, "../../examples/table.wasm")
+	s, err := m.Snapshot()
+	require.NoError(t, err)
+	r := s.Restore()
+	require.Len(t, r.TableAddrs, 1)
+	require.Len(t, r.TableAddrs[0].Elems, 2)
+	assert.Equal(t, r.FuncAddrs[0], r.TableAddrs[0].Elems[0])
+	assert.Equal(t, r.FuncAddrs[1], r.TableAddrs[0].Elems[1])
+
+	r.TableAddrs[0].Elems[0] = nil
+	require.NoError(t, s.Reset(r))
+	assert.Equal(t, r.FuncAddrs[0], r.TableAddrs[0].Elems[0])
+}
+
+func TestSnapshotReset(t *testing.T) {
+	m := newModuleFromFile(t, "../../examples/memory.wasm")
+	s, err := m.Snapshot()
+	require.NoError(t, err)
+	r := s.Restore()
+	mem := r.MemAddrs[0]
+	for _, offset := range []uint32{0, DIRTY_PAGE_SIZE - 1, DIRTY_PAGE_SIZE * 3} {
+		mem.Data[offset] = 0xff
+		mem.Data[offset+1] = 0xff
+		mem.MarkDirty(offset, 2)
+	}
+	// a write which is not tracked isn't restored
+	mem.Data[DIRTY_PAGE_SIZE*5] = 0xee
+
+	require.NoError(t, s.Reset(r))
+	assert.Equal(t, byte('A'), mem.Data[0])
+	assert.Equal(t, byte('B'), mem.Data[1])
+	assert.Equal(t, byte(0), mem.Data[DIRTY_PAGE_SIZE-1])
+	assert.Equal(t, byte(0), mem.Data[DIRTY_PAGE_SIZE])
+	assert.Equal(t, byte(0), mem.Data[DIRTY_PAGE_SIZE*3])
+	assert.Equal(t, byte(0xee), mem.Data[DIRTY_PAGE_SIZE*5])
+	for _, d := range mem.dirty {
+		assert.Zero(t, d)
+	}
+
+	other := newModuleFromFile(t, "../../examples/table.wasm")
+	assert.ErrorIs(t, s.Reset(other), SnapshotNotMatched)
+}
+
+func TestSnapshotReset_ConcurrentStores(t *testing.T) {
+	m := newModuleFromFile(t, "../../examples/memory.wasm")
+	s, err := m.Snapshot()
+	require.NoError(t, err)
+	r := s.Restore()
+	mem := r.MemAddrs[0]
+	// the pages share a word of the dirty bitmap
+	var wg sync.WaitGroup
+	for p := uint32(0); p < 16; p++ {
+		wg.Add(1)
+		go func(p uint32) {
+			defer wg.Done()
+			assert.NoError(t, mem.Store(uint64(p*DIRTY_PAGE_SIZE+100), []byte{0xff}))
+		}(p)
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(0xffff), mem.dirty[0])
+
+	require.NoError(t, s.Reset(r))
+	for p := uint32(0); p < 16; p++ {
+		assert.Equal(t, byte(0), mem.Data[p*DIRTY_PAGE_SIZE+100])
+	}
+}
+
+func TestLoad_Grown(t *testing.T) {
+	m := newModuleFromFile(t, "../../examples/grow.wasm")
+	_, err := m.MemAddrs[0].Grow(1)
+	require.NoError(t, err)
+	m.MemAddrs[0].Data[PAGE_SIZE] = 0xff
+	state, err := m.State()
+	require.NoError(t, err)
+
+	fresh := newModuleFromFile(t, "../../examples/grow.wasm")
+	require.NoError(t, fresh.Load(state))
+	assert.Equal(t, uint32(2), fresh.MemAddrs[0].Pages())
+	assert.Equal(t, byte(0xff), fresh.MemAddrs[0].Data[PAGE_SIZE])
+
+	// grow.wasm has at most 3 pages
+	fresh = newModuleFromFile(t, "../../examples/grow.wasm")
+	state.Memories[0] = make([]byte, 4*PAGE_SIZE)
+	assert.ErrorIs(t, fresh.Load(state), StateNotMatched)
+	assert.Equal(t, uint32(1), fresh.MemAddrs[0].Pages())
+}
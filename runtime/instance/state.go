@@ -0,0 +1,88 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+var StateNotMatched error = errors.New("State doesn't match the module instance")
+
+// State is the mutable state of an instance in a form which can be encoded.
+// Table elements are recorded by function indices and globals by their literals.
+type State struct {
+	Memories [][]byte `json:"memories"`
+	Tables   [][]int  `json:"tables"` // function index of each element, -1 for null
+	Globals  []string `json:"globals"`
+}
+
+// State copies memories, tables and globals of the instance.
+// It must not be called while the instance is executing.
+func (m *Module) State() (*State, error) {
+	s, err := m.Snapshot()
+	if err != nil {
+		return nil, fmt.Errorf("state: %w", err)
+	}
+	state := &State{
+		Memories: s.memories,
+		Tables:   s.tables,
+		Globals:  make([]string, 0, len(s.globals)),
+	}
+	for _, g := range s.globals {
+		state.Globals = append(state.Globals, fmt.Sprint(g))
+	}
+	return state, nil
+}
+
+// Load overwrites memories, tables and globals of the instance with the state.
+// Memories are grown to the size in the state since they may have grown after the instance was created.
+// The instance is not modified when the state doesn't match it.
+func (m *Module) Load(state *State) error {
+	if len(state.Memories) != len(m.MemAddrs) || len(state.Tables) != len(m.TableAddrs) || len(state.Globals) != len(m.GlobalAddr) {
+		return fmt.Errorf("load: %w", StateNotMatched)
+	}
+	for i, mem := range m.MemAddrs {
+		size := uint64(len(state.Memories[i]))
+		if size < uint64(len(mem.Data)) || size%uint64(PAGE_SIZE) != 0 || size/uint64(PAGE_SIZE) > mem.maxPages() {
+			return fmt.Errorf("load: %w: memory[%d] size=%d", StateNotMatched, i, size)
+		}
+	}
+	tables := make([][]value.Reference, 0, len(state.Tables))
+	for i, elems := range state.Tables {
+		refs := make([]value.Reference, len(elems))
+		for j, fidx := range elems {
+			if fidx >= len(m.FuncAddrs) {
+				return fmt.Errorf("load: %w: table[%d] function index=%d", StateNotMatched, i, fidx)
+			}
+			if fidx >= 0 {
+				refs[j] = m.FuncAddrs[fidx]
+			}
+		}
+		tables = append(tables, refs)
+	}
+	globals := make([]value.Value, 0, len(state.Globals))
+	for i, g := range m.GlobalAddr {
+		v, err := value.FromString(state.Globals[i], g.Type)
+		if err != nil {
+			return fmt.Errorf("load: global[%d]: %w", i, err)
+		}
+		globals = append(globals, v)
+	}
+	for i, mem := range m.MemAddrs {
+		if delta := uint32(uint64(len(state.Memories[i]))/uint64(PAGE_SIZE)) - mem.Pages(); delta > 0 {
+			if _, err := mem.Grow(delta); err != nil {
+				return fmt.Errorf("load: memory[%d]: %w", i, err)
+			}
+		}
+		copy(mem.Data, state.Memories[i])
+		mem.markAllDirty()
+	}
+	for i, t := range m.TableAddrs {
+		t.Elems = tables[i]
+	}
+	for i, g := range m.GlobalAddr {
+		g.Value = globals[i]
+	}
+	return nil
+}
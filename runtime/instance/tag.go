@@ -0,0 +1,65 @@
+package instance
+
+import (
+	"fmt"
+
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+// Tag is a tag instance. An exception is caught by a handler of the same tag instance,
+// so that a tag shared by instances through imports matches in all of them.
+// https://webassembly.github.io/exception-handling/core/exec/runtime.html#tag-instances
+type Tag struct {
+	Type *types.FuncType // params are the values carried by an exception
+}
+
+func NewTag(t *types.FuncType) *Tag {
+	return &Tag{Type: t}
+}
+
+func (*Tag) ExternalValueType() ExternalValueType {
+	return ExternalValueTypeTag
+}
+
+// newTags returns the imported tags followed by the tags defined in the module.
+// A new tag is created for an import when no external value is given.
+func newTags(mod *structure.Module, externalvals []ExternalValue) ([]*Tag, error) {
+	tags := make([]*Tag, 0, len(mod.Tags))
+	for i, imp := range mod.Imports {
+		if imp.Desc.Type != structure.DescTypeTag {
+			continue
+		}
+		t, err := tagType(mod, imp.Desc.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("new tags: %w", err)
+		}
+		if len(externalvals) == 0 {
+			tags = append(tags, NewTag(t))
+			continue
+		}
+		tag, ok := externalvals[i].(*Tag)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.%s is not a tag", ExternalValuesNotMatched, imp.Module, imp.Name)
+		}
+		if !tag.Type.Params.Equal(t.Params) {
+			return nil, fmt.Errorf("%w: %s.%s type=%v", ExternalValuesNotMatched, imp.Module, imp.Name, tag.Type.Params)
+		}
+		tags = append(tags, tag)
+	}
+	for _, tag := range mod.Tags {
+		t, err := tagType(mod, tag.Type)
+		if err != nil {
+			return nil, fmt.Errorf("new tags: %w", err)
+		}
+		tags = append(tags, NewTag(t))
+	}
+	return tags, nil
+}
+
+func tagType(mod *structure.Module, idx uint32) (*types.FuncType, error) {
+	if int(idx) >= len(mod.Types) {
+		return nil, fmt.Errorf("type[%d] is not found", idx)
+	}
+	return mod.Types[idx], nil
+}
@@ -1,13 +1,12 @@
 package runtime
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"math/bits"
 
 	"github.com/terassyi/gowi/instruction"
-	"github.com/terassyi/gowi/runtime/instance"
 	"github.com/terassyi/gowi/runtime/stack"
 	"github.com/terassyi/gowi/runtime/value"
 	"github.com/terassyi/gowi/types"
@@ -44,100 +43,9 @@ const (
 	instructionResultTrap       instructionResult = iota
 )
 
-type blockStack struct {
-	inner []block
-}
-
-func newBlockStack() *blockStack {
-	return &blockStack{
-		inner: make([]block, 0, 1024),
-	}
-}
-
-func (b *blockStack) push(op instruction.Opcode) error {
-	switch op {
-	case instruction.BLOCK:
-		b.inner = append(b.inner, &blockBlock{})
-	case instruction.LOOP:
-		b.inner = append(b.inner, &blockLoop{})
-	case instruction.IF:
-		b.inner = append(b.inner, &blockIf{})
-	default:
-		return fmt.Errorf("Instruction is not structured instruction")
-	}
-	return nil
-}
-
-func (b *blockStack) pop() (block, error) {
-	if b.len() == 0 {
-		return nil, fmt.Errorf("block stack is empty")
-	}
-	val := b.inner[len(b.inner)-1]
-	b.inner = b.inner[:len(b.inner)-1]
-	return val, nil
-}
-
-func (b *blockStack) top() block {
-	if b.isEmpty() {
-		return nil
-	}
-	return b.inner[len(b.inner)-1]
-}
-
-func (b *blockStack) len() int {
-	return len(b.inner)
-}
-
-func (b *blockStack) isEmpty() bool {
-	if b.len() == 0 {
-		return true
-	}
-	return false
-}
-
-type blockType uint8
-
-const (
-	blockTypeBlock blockType = iota
-	blockTypeLoop  blockType = iota
-	blockTypeIf    blockType = iota
-)
-
-type block interface {
-	typ() blockType
-}
-
-type blockBlock struct{}
-
-type blockLoop struct{}
-
-type blockIf struct {
-	els bool
-}
-
-func (*blockBlock) typ() blockType {
-	return blockTypeBlock
-}
-
-func (*blockLoop) typ() blockType {
-	return blockTypeLoop
-}
-
-func (*blockIf) typ() blockType {
-	return blockTypeIf
-}
-
 func (i *interpreter) execBlock(instr instruction.Instruction) (instructionResult, error) {
-	funcType, err := i.expand(instruction.Imm[types.BlockType](instr))
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("block: %w", err)
-	}
-	label, l, err := i.labelBlock(instr, funcType)
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("block: %w", err)
-	}
-	i.cur.label.Sp += l
-	if err := i.stack.PushLabel(*label); err != nil {
+	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-control-mathsf-block-xref-syntax-instructions-syntax-blocktype-mathit-blocktype-xref-syntax-instructions-syntax-instr-mathit-instr-ast-xref-syntax-instructions-syntax-instr-control-mathsf-end
+	if err := i.enterBlock(); err != nil {
 		return instructionResultTrap, fmt.Errorf("block: %w", err)
 	}
 	return instructionResultEnterBlock, nil
@@ -145,16 +53,7 @@ func (i *interpreter) execBlock(instr instruction.Instruction) (instructionResul
 
 func (i *interpreter) execLoop(instr instruction.Instruction) (instructionResult, error) {
 	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-control-mathsf-loop-xref-syntax-instructions-syntax-blocktype-mathit-blocktype-xref-syntax-instructions-syntax-instr-mathit-instr-ast-xref-syntax-instructions-syntax-instr-control-mathsf-end
-	funcType, err := i.expand(instruction.Imm[types.BlockType](instr))
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("loop: %w", err)
-	}
-	label, l, err := i.labelBlock(instr, funcType)
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("loop: %w", err)
-	}
-	i.cur.label.Sp += l
-	if err := i.stack.PushLabel(*label); err != nil {
+	if err := i.enterBlock(); err != nil {
 		return instructionResultTrap, fmt.Errorf("loop: %w", err)
 	}
 	return instructionResultEnterBlock, nil
@@ -162,35 +61,50 @@ func (i *interpreter) execLoop(instr instruction.Instruction) (instructionResult
 
 func (i *interpreter) execIf(instr instruction.Instruction) (instructionResult, error) {
 	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-control-mathsf-if-xref-syntax-instructions-syntax-blocktype-mathit-blocktype-xref-syntax-instructions-syntax-instr-mathit-instr-1-ast-xref-syntax-instructions-syntax-instr-control-mathsf-else-xref-syntax-instructions-syntax-instr-mathit-instr-2-ast-xref-syntax-instructions-syntax-instr-control-mathsf-end
-	funcType, err := i.expand(instruction.Imm[types.BlockType](instr))
+	cond, err := i.stack.PopI32()
 	if err != nil {
 		return instructionResultTrap, fmt.Errorf("if: %w", err)
 	}
-	label, l, err := i.labelBlock(instr, funcType)
-	if err := i.stack.ValidateValue([]types.ValueType{types.I32}); err != nil {
+	block := i.cur.code.blocks[i.pc()]
+	if err := i.enterBlock(); err != nil {
 		return instructionResultTrap, fmt.Errorf("if: %w", err)
 	}
-	i.cur.label.Sp += l
-	val, err := i.stack.PopValue()
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("if: %w", err)
+	if cond == 0 {
+		// run the else branch, or the end of if pops the label when else doesn't exist
+		if block.els != -1 {
+			i.cur.frame.Pc = block.els + 1
+		} else {
+			i.cur.frame.Pc = block.end
+		}
 	}
-	if val.(value.Number).NumType() != value.NumTypeI32 {
-		return instructionResultTrap, fmt.Errorf("if: i32 is expected, got %s", val.(value.Number))
+	return instructionResultEnterBlock, nil
+}
+
+// enterBlock pushes the label resolved for the executing block, loop, if or try.
+func (i *interpreter) enterBlock() error {
+	block := i.cur.code.blocks[i.pc()]
+	if block == nil {
+		return fmt.Errorf("block target is not resolved at %d", i.pc())
 	}
-	if i.stack.Len() < len(funcType.Params) {
-		return instructionResultTrap, fmt.Errorf("if: %d values is requied on the value stack", len(funcType.Params))
+	if i.stack.Len() < block.params {
+		return fmt.Errorf("%d values is requied on the value stack", block.params)
 	}
-	// branch
-	condLabel, err := ifElseLabel(label, instance.GetVal[value.I32](val))
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("if: %w", err)
+	label := block.label
+	label.Height = i.stack.Len() - block.params
+	if err := i.stack.PushLabel(label); err != nil {
+		return err
 	}
-	// push to label stack
-	if err := i.stack.PushLabel(*condLabel); err != nil {
-		return instructionResultTrap, fmt.Errorf("loop: %w", err)
+	return i.cur.updateLabel(i.stack)
+}
+
+func (i *interpreter) execElse(instr instruction.Instruction) (instructionResult, error) {
+	// the then branch is finished, go to the end of if
+	branch := i.cur.code.branches[i.pc()]
+	if branch == nil {
+		return instructionResultTrap, fmt.Errorf("else: branch target is not resolved at %d", i.pc())
 	}
-	return instructionResultEnterBlock, nil
+	i.cur.frame.Pc = branch.pc
+	return instructionResultRunNext, nil
 }
 
 func (i *interpreter) execBr(instr instruction.Instruction) (instructionResult, error) {
@@ -199,197 +113,85 @@ func (i *interpreter) execBr(instr instruction.Instruction) (instructionResult,
 	if i.stack.LenLabel() <= int(labelIndex) {
 		return instructionResultTrap, fmt.Errorf("br: the label stack must contain at least %d labels", labelIndex+1)
 	}
-	target, err := i.stack.RefLabel(int(labelIndex))
+	res, err := i.branch(int(labelIndex))
 	if err != nil {
 		return instructionResultTrap, fmt.Errorf("br: %w", err)
 	}
-	values, err := i.stack.PopValuesRev(int(target.N))
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("br: %w", err)
-	}
-	popedLabel := 0
-	for {
-		if popedLabel == int(labelIndex)+1 {
-			break
-		}
-		v, err := i.stack.Top()
-		if err != nil {
-			return instructionResultTrap, fmt.Errorf("br: %w", err)
-		}
-		switch v.ValType() {
-		case value.ValTypeLabel:
-			if target.IsLoop() && popedLabel == int(labelIndex) {
-				popedLabel++
-				continue
-			}
-			if _, err := i.stack.PopLabel(); err != nil {
-				return instructionResultTrap, fmt.Errorf("br: %w", err)
-			}
-			popedLabel++
-		case value.ValTypeNum, value.ValTypeVec:
-			if _, err := i.stack.PopValue(); err != nil {
-				return instructionResultTrap, fmt.Errorf("br: %w", err)
-			}
-		}
-	}
-	for _, v := range values {
-		if err := i.stack.PushValue(v); err != nil {
-			return instructionResultTrap, fmt.Errorf("br: %w", err)
-		}
-	}
-	if err := i.cur.update(i.stack); err != nil {
-		return instructionResultTrap, fmt.Errorf("br: %w", err)
-	}
-	if target.IsLoop() && labelIndex == 0 {
-		i.cur.label.Sp = -1
-	} else if target.IsLoop() {
-		i.cur.label.Sp = 0
-	}
-	return instructionResultLabelEnd, nil
+	return res, nil
 }
 
 func (i *interpreter) execBrIf(instr instruction.Instruction) (instructionResult, error) {
 	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-control-mathsf-br-l
-	if err := i.stack.ValidateValue([]types.ValueType{types.I32}); err != nil {
-		return instructionResultTrap, fmt.Errorf("br_if: %w", err)
-	}
-	cond, err := i.stack.PopValue()
+	cond, err := i.stack.PopI32()
 	if err != nil {
 		return instructionResultTrap, fmt.Errorf("br_if: %w", err)
 	}
-	switch cond {
-	case value.I32(0):
+	if cond == 0 {
 		return instructionResultRunNext, nil
-	default:
-		return i.execBr(instr)
 	}
+	return i.execBr(instr)
 }
 
 func (i *interpreter) execReturn(instr instruction.Instruction) (instructionResult, error) {
 	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-control-mathsf-return
-	if i.stack.Len() < int(i.cur.label.N) {
-		return instructionResultTrap, fmt.Errorf("return: the value stack must have at least %d values", i.cur.label.N)
-	}
-	if i.stack.IsFrameEmpty() {
-		return instructionResultTrap, fmt.Errorf("return: the frame stack must have at least one vlaue")
-	}
-	if _, err := i.stack.PopFrame(); err != nil {
-		return instructionResultTrap, fmt.Errorf("return: %w", err)
-	}
-	for {
-		label, err := i.stack.PopLabel()
-		if err != nil {
-			return instructionResultTrap, fmt.Errorf("return: %w", err)
-		}
-		if label.IsFunction() {
-			break
-		}
+	branch := i.cur.code.branches[i.pc()]
+	if branch == nil {
+		return instructionResultTrap, fmt.Errorf("return: branch target is not resolved at %d", i.pc())
 	}
-	if err := i.cur.update(i.stack); err != nil {
+	// the function label is the outermost label of the frame
+	res, err := i.branch(branch.labels - 1)
+	if err != nil {
 		return instructionResultTrap, fmt.Errorf("return: %w", err)
 	}
-	return instructionResultReturn, nil
+	return res, nil
 }
 
-func (i *interpreter) labelBlock(instr instruction.Instruction, funcType *types.FuncType) (*stack.Label, int, error) {
-	instrs := make([]instruction.Instruction, 0)
-	bs := newBlockStack()
-	bs.push(i.cur.label.Instructions[i.cur.label.Sp].Opcode())
-	for sp := i.cur.label.Sp + 1; sp < len(i.cur.label.Instructions); sp++ {
-		instrs = append(instrs, i.cur.label.Instructions[sp])
-		if i.cur.label.Instructions[sp].Opcode() == instruction.BLOCK ||
-			i.cur.label.Instructions[sp].Opcode() == instruction.LOOP ||
-			i.cur.label.Instructions[sp].Opcode() == instruction.IF {
-			bs.push(i.cur.label.Instructions[sp].Opcode())
-		}
-		if i.cur.label.Instructions[sp].Opcode() == instruction.END {
-			_, err := bs.pop()
-			if err != nil {
-				return nil, 0, fmt.Errorf("label block: %w", err)
-			}
-			if bs.isEmpty() {
-				break
-			}
-		}
+// branch jumps to the resolved target of the executing instruction.
+// labelIndex is the index of the target label from the top of the label stack.
+func (i *interpreter) branch(labelIndex int) (instructionResult, error) {
+	branch := i.cur.code.branches[i.pc()]
+	if branch == nil {
+		return instructionResultTrap, fmt.Errorf("branch target is not resolved at %d", i.pc())
 	}
-	labelType, err := stack.NewLabelType(instr)
+	target, err := i.stack.RefLabel(labelIndex)
 	if err != nil {
-		return nil, 0, fmt.Errorf("label block: %w", err)
+		return instructionResultTrap, err
 	}
-	var arity uint8
-	switch labelType {
-	case stack.LabelTypeLoop:
-		arity = uint8(len(funcType.Params))
-	default:
-		arity = uint8(len(funcType.Returns))
+	if err := i.stack.Unwind(target.Height, int(branch.arity)); err != nil {
+		return instructionResultTrap, err
 	}
-	return &stack.Label{Instructions: instrs, N: arity, Sp: 0, Type: labelType}, len(instrs), nil
-}
-
-func ifElseLabel(label *stack.Label, cond value.I32) (*stack.Label, error) {
-	ifBlock := make([]instruction.Instruction, 0, len(label.Instructions))
-	elseBlock := make([]instruction.Instruction, 0, len(label.Instructions))
-	bs := newBlockStack()
-	bs.push(instruction.IF)
-	splitIndex := -1
-	for j, instr := range label.Instructions {
-		if instr.Opcode() == instruction.BLOCK ||
-			instr.Opcode() == instruction.LOOP ||
-			instr.Opcode() == instruction.IF {
-			if err := bs.push(instr.Opcode()); err != nil {
-				return nil, fmt.Errorf("if_else label: %w", err)
-			}
-		}
-		if instr.Opcode() == instruction.ELSE {
-			if bs.top() == nil || bs.top().typ() != blockTypeIf {
-				return nil, fmt.Errorf("if_else label: invalie else")
-			}
-			if bs.len() == 1 {
-				splitIndex = j
-			}
-		}
-		if instr.Opcode() == instruction.END {
-			_, err := bs.pop()
-			if err != nil {
-				return nil, fmt.Errorf("if_else label: %w", err)
-			}
-			if bs.isEmpty() {
-				break
-			}
-		}
+	if err := i.stack.PopLabels(branch.labels); err != nil {
+		return instructionResultTrap, err
 	}
-	if splitIndex != -1 {
-		ifBlock = append(ifBlock, label.Instructions[:splitIndex]...)
-		ifBlock = append(ifBlock, &instruction.End{})
-		elseBlock = append(elseBlock, label.Instructions[splitIndex+1:]...)
-		if len(elseBlock) == 0 {
-			elseBlock = append(elseBlock, &instruction.End{})
-		}
-	} else {
-		ifBlock = append(ifBlock, label.Instructions...)
-		elseBlock = append(elseBlock, &instruction.End{})
+	if branch.ret {
+		if _, err := i.stack.PopFrame(); err != nil {
+			return instructionResultTrap, err
+		}
+		if err := i.updateCurrent(); err != nil {
+			return instructionResultTrap, err
+		}
+		return instructionResultReturn, nil
 	}
-	condLabel := &stack.Label{N: label.N, Sp: label.Sp, Type: stack.LabelTypeIf}
-	if cond != value.I32(0) {
-		condLabel.Instructions = ifBlock
-	} else {
-		condLabel.Instructions = elseBlock
+	i.cur.frame.Pc = branch.pc
+	if err := i.cur.updateLabel(i.stack); err != nil {
+		return instructionResultTrap, err
 	}
-	return condLabel, nil
+	return instructionResultLabelEnd, nil
 }
 
 func (i *interpreter) execLabelEnd(instr instruction.Instruction) (instructionResult, error) {
-	if err := i.restoreStack(); err != nil {
+	label, err := i.restoreStack()
+	if err != nil {
 		return instructionResultTrap, fmt.Errorf("label end: %w", err)
 	}
-	if err := i.cur.update(i.stack); err != nil {
-		return instructionResultTrap, fmt.Errorf("label end: %w", err)
+	if label.IsFunction() {
+		return instructionResultReturn, nil
 	}
 	return instructionResultLabelEnd, nil
 }
 
 func (i *interpreter) execDrop(instr instruction.Instruction) (instructionResult, error) {
-	if _, err := i.stack.PopValue(); err != nil {
+	if err := i.stack.Drop(); err != nil {
 		return instructionResultTrap, fmt.Errorf("drop: %w", err)
 	}
 	return instructionResultRunNext, nil
@@ -405,29 +207,8 @@ func (i *interpreter) execUnreachable(instr instruction.Instruction) (instructio
 }
 
 func (i *interpreter) execSelect(instr instruction.Instruction) (instructionResult, error) {
-	if err := i.stack.ValidateValue([]types.ValueType{types.I32}); err != nil {
-		return instructionResultTrap, fmt.Errorf("select :%w", err)
-	}
-	c, err := i.stack.PopValue()
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("select: %w", err)
-	}
-	val2, err := i.stack.PopValue()
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("select: %w", err)
-	}
-	val1, err := i.stack.PopValue()
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("select: %w", err)
-	}
-	if instance.GetVal[value.I32](c) != value.I32(0) {
-		if err := i.stack.PushValue(val1); err != nil {
-			return instructionResultTrap, fmt.Errorf("select: %w", err)
-		}
-	} else {
-		if err := i.stack.PushValue(val2); err != nil {
-			return instructionResultTrap, fmt.Errorf("select: %w", err)
-		}
+	if err := i.stack.Select(); err != nil {
+		return instructionResultTrap, err
 	}
 	return instructionResultRunNext, nil
 }
@@ -444,25 +225,25 @@ func (i *interpreter) execConst(instr instruction.Instruction) (instructionResul
 	switch instr.Opcode() {
 	case instruction.I32_CONST:
 		imm := instruction.Imm[int32](instr)
-		if err := i.stack.PushValue(value.I32(imm)); err != nil {
+		if err := i.stack.PushI32(value.I32(imm)); err != nil {
 			return instructionResultTrap, fmt.Errorf("const: %w", err)
 		}
 		return instructionResultRunNext, nil
 	case instruction.I64_CONST:
 		imm := instruction.Imm[int64](instr)
-		if err := i.stack.PushValue(value.I64(imm)); err != nil {
+		if err := i.stack.PushRaw(uint64(imm), types.I64); err != nil {
 			return instructionResultTrap, fmt.Errorf("const: %w", err)
 		}
 		return instructionResultRunNext, nil
 	case instruction.F32_CONST:
 		imm := instruction.Imm[uint32](instr)
-		if err := i.stack.PushValue(value.F32(imm)); err != nil {
+		if err := i.stack.PushRaw(uint64(imm), types.F32); err != nil {
 			return instructionResultTrap, fmt.Errorf("const: %w", err)
 		}
 		return instructionResultRunNext, nil
 	case instruction.F64_CONST:
 		imm := instruction.Imm[uint64](instr)
-		if err := i.stack.PushValue(value.F64(imm)); err != nil {
+		if err := i.stack.PushRaw(imm, types.F64); err != nil {
 			return instructionResultTrap, fmt.Errorf("const: %w", err)
 		}
 		return instructionResultRunNext, nil
@@ -493,78 +274,80 @@ func (i *interpreter) execLocal(instr instruction.Instruction, frame *stack.Fram
 
 func (i *interpreter) getLocal(index uint32, frame *stack.Frame) error {
 	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-variable-mathsf-local-get-x
-	if int(index) >= len(frame.Locals) {
+	if int(index) >= frame.Locals.Len() {
 		return ExecutionErrorLocalNotExist
 	}
-	if err := i.stack.PushValue(frame.Locals[index]); err != nil {
-		return err
-	}
-	return nil
+	return i.stack.GetLocal(&frame.Locals, int(index))
 }
 
 func (i *interpreter) setLocal(index uint32, frame *stack.Frame) error {
 	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-variable-mathsf-local-set-x
-	if int(index) >= len(frame.Locals) {
+	if int(index) >= frame.Locals.Len() {
 		return ExecutionErrorLocalNotExist
 	}
-	val, err := i.stack.PopValue()
-	if err != nil {
-		return fmt.Errorf("set_local: %w", err)
-	}
-	frame.Locals[index] = val
-	return nil
+	return i.stack.SetLocal(&frame.Locals, int(index))
 }
 
 func (i *interpreter) teeLocal(index uint32, frame *stack.Frame) error {
-	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-variable-mathsf-local-set-x
-	if int(index) >= len(frame.Locals) {
+	// https://webassembly.github.io/spec/core/exec/instructions.html#xref-syntax-instructions-syntax-instr-variable-mathsf-local-tee-x
+	if int(index) >= frame.Locals.Len() {
 		return ExecutionErrorLocalNotExist
 	}
-	val, err := i.stack.PopValue()
-	if err != nil {
-		return fmt.Errorf("tee_local: %w", err)
-	}
-	if err := i.stack.PushValue(val); err != nil {
-		return fmt.Errorf("tee_local: %w", err)
-	}
-	if err := i.stack.PushValue(val); err != nil {
-		return fmt.Errorf("tee_local: %w", err)
-	}
-	return i.setLocal(index, frame)
+	return i.stack.TeeLocal(&frame.Locals, int(index))
 }
 
 func (i *interpreter) execUnop(instr instruction.Instruction) (instructionResult, error) {
 	switch instr.Opcode() {
 	case instruction.I32_EQZ:
-		if err := i.unop(value.NumTypeI32, eqz); err != nil {
+		if err := testop(i, types.I32, eqz[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_EQZ:
-		if err := i.unop(value.NumTypeI64, eqz); err != nil {
+		if err := testop(i, types.I64, eqz[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_CLZ:
-		if err := i.unop(value.NumTypeI32, clz); err != nil {
+		if err := unop(i, types.I32, clz[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_CLZ:
-		if err := i.unop(value.NumTypeI64, clz); err != nil {
+		if err := unop(i, types.I64, clz[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_CTZ:
-		if err := i.unop(value.NumTypeI32, ctz); err != nil {
+		if err := unop(i, types.I32, ctz[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_CTZ:
-		if err := i.unop(value.NumTypeI64, ctz); err != nil {
+		if err := unop(i, types.I64, ctz[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_POPCNT:
-		if err := i.unop(value.NumTypeI32, popcnt); err != nil {
+		if err := unop(i, types.I32, popcnt[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_POPCNT:
-		if err := i.unop(value.NumTypeI64, popcnt); err != nil {
+		if err := unop(i, types.I64, popcnt[uint64]); err != nil {
+			return instructionResultTrap, err
+		}
+	case instruction.I32_EXTEND8_S:
+		if err := unop(i, types.I32, extend8s[uint32]); err != nil {
+			return instructionResultTrap, err
+		}
+	case instruction.I64_EXTEND8_S:
+		if err := unop(i, types.I64, extend8s[uint64]); err != nil {
+			return instructionResultTrap, err
+		}
+	case instruction.I32_EXTEND16_S:
+		if err := unop(i, types.I32, extend16s[uint32]); err != nil {
+			return instructionResultTrap, err
+		}
+	case instruction.I64_EXTEND16_S:
+		if err := unop(i, types.I64, extend16s[uint64]); err != nil {
+			return instructionResultTrap, err
+		}
+	case instruction.I64_EXTEND32_S:
+		if err := unop(i, types.I64, extend32s[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	default:
@@ -573,29 +356,31 @@ func (i *interpreter) execUnop(instr instruction.Instruction) (instructionResult
 	return instructionResultRunNext, nil
 }
 
-type unopFunc func(value.Number) (value.Number, error)
+// integer is the raw bits of i32 or i64. Numeric instructions work on the bits popped from the value stack
+// without boxing them into value.Number.
+type integer interface {
+	~uint32 | ~uint64
+}
 
-func (i *interpreter) unop(valType value.NumberType, f unopFunc) error {
-	v, err := i.stack.TopValue()
+func unop[T integer](i *interpreter, t types.ValueType, f func(T) T) error {
+	val, err := i.stack.PopRaw(t)
 	if err != nil {
-		return fmt.Errorf("unop: %w", err)
-	}
-	if v.ValType() != value.ValTypeNum {
-		return fmt.Errorf("unop: %w", ExecutionErrorTypeNotMatched)
+		return fmt.Errorf("unop: %w: %w", ExecutionErrorArgumentTypeNotMatch, err)
 	}
-	if v.(value.Number).NumType() != valType {
-		return fmt.Errorf("unop: %w", ExecutionErrorArgumentTypeNotMatch)
-	}
-	val, err := i.stack.PopValue()
-	if err != nil {
+	if err := i.stack.PushRaw(uint64(f(T(val))), t); err != nil {
 		return fmt.Errorf("unop: %w", err)
 	}
-	res, err := f(val.(value.Number))
+	return nil
+}
+
+// testop pushes the result of the test as i32.
+func testop[T integer](i *interpreter, t types.ValueType, f func(T) bool) error {
+	val, err := i.stack.PopRaw(t)
 	if err != nil {
-		return fmt.Errorf("unop: %w", err)
+		return fmt.Errorf("testop: %w: %w", ExecutionErrorArgumentTypeNotMatch, err)
 	}
-	if err := i.stack.PushValue(res.ToValue()); err != nil {
-		return fmt.Errorf("unop: %w", err)
+	if err := i.stack.PushI32(boolI32(f(T(val)))); err != nil {
+		return fmt.Errorf("testop: %w", err)
 	}
 	return nil
 }
@@ -603,203 +388,203 @@ func (i *interpreter) unop(valType value.NumberType, f unopFunc) error {
 func (i *interpreter) execBinop(instr instruction.Instruction) (instructionResult, error) {
 	switch instr.Opcode() {
 	case instruction.I32_ADD:
-		if err := i.binop(value.NumTypeI32, add); err != nil {
+		if err := binop(i, types.I32, add[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_ADD:
-		if err := i.binop(value.NumTypeI64, add); err != nil {
+		if err := binop(i, types.I64, add[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_SUB:
-		if err := i.binop(value.NumTypeI32, sub); err != nil {
+		if err := binop(i, types.I32, sub[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_SUB:
-		if err := i.binop(value.NumTypeI64, sub); err != nil {
+		if err := binop(i, types.I64, sub[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_MUL:
-		if err := i.binop(value.NumTypeI32, mul); err != nil {
+		if err := binop(i, types.I32, mul[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_MUL:
-		if err := i.binop(value.NumTypeI64, mul); err != nil {
+		if err := binop(i, types.I64, mul[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_DIV_S:
-		if err := i.binop(value.NumTypeI32, divs); err != nil {
+		if err := binop(i, types.I32, divs[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_DIV_S:
-		if err := i.binop(value.NumTypeI64, divs); err != nil {
+		if err := binop(i, types.I64, divs[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_DIV_U:
-		if err := i.binop(value.NumTypeI32, divu); err != nil {
+		if err := binop(i, types.I32, divu[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_DIV_U:
-		if err := i.binop(value.NumTypeI64, divu); err != nil {
+		if err := binop(i, types.I64, divu[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_REM_S:
-		if err := i.binop(value.NumTypeI32, rems); err != nil {
+		if err := binop(i, types.I32, rems[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_REM_S:
-		if err := i.binop(value.NumTypeI64, rems); err != nil {
+		if err := binop(i, types.I64, rems[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_REM_U:
-		if err := i.binop(value.NumTypeI32, remu); err != nil {
+		if err := binop(i, types.I32, remu[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_REM_U:
-		if err := i.binop(value.NumTypeI64, remu); err != nil {
+		if err := binop(i, types.I64, remu[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_AND:
-		if err := i.binop(value.NumTypeI32, and); err != nil {
+		if err := binop(i, types.I32, and[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_AND:
-		if err := i.binop(value.NumTypeI64, and); err != nil {
+		if err := binop(i, types.I64, and[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_OR:
-		if err := i.binop(value.NumTypeI32, or); err != nil {
+		if err := binop(i, types.I32, or[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_OR:
-		if err := i.binop(value.NumTypeI64, or); err != nil {
+		if err := binop(i, types.I64, or[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_XOR:
-		if err := i.binop(value.NumTypeI32, xor); err != nil {
+		if err := binop(i, types.I32, xor[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_XOR:
-		if err := i.binop(value.NumTypeI64, xor); err != nil {
+		if err := binop(i, types.I64, xor[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_SHL:
-		if err := i.binop(value.NumTypeI32, shl); err != nil {
+		if err := binop(i, types.I32, shl[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_SHL:
-		if err := i.binop(value.NumTypeI64, shl); err != nil {
+		if err := binop(i, types.I64, shl[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_SHR_U:
-		if err := i.binop(value.NumTypeI32, shru); err != nil {
+		if err := binop(i, types.I32, shru[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_SHR_U:
-		if err := i.binop(value.NumTypeI64, shru); err != nil {
+		if err := binop(i, types.I64, shru[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_SHR_S:
-		if err := i.binop(value.NumTypeI32, shrs); err != nil {
+		if err := binop(i, types.I32, shrs[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_SHR_S:
-		if err := i.binop(value.NumTypeI64, shrs); err != nil {
+		if err := binop(i, types.I64, shrs[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_ROTL:
-		if err := i.binop(value.NumTypeI32, rotl); err != nil {
+		if err := binop(i, types.I32, rotl[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_ROTL:
-		if err := i.binop(value.NumTypeI64, rotl); err != nil {
+		if err := binop(i, types.I64, rotl[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_ROTR:
-		if err := i.binop(value.NumTypeI32, rotr); err != nil {
+		if err := binop(i, types.I32, rotr[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_ROTR:
-		if err := i.binop(value.NumTypeI64, rotr); err != nil {
+		if err := binop(i, types.I64, rotr[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_EQ:
-		if err := i.binop(value.NumTypeI32, eq); err != nil {
+		if err := relop(i, types.I32, eq[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_EQ:
-		if err := i.binop(value.NumTypeI64, eq); err != nil {
+		if err := relop(i, types.I64, eq[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_NE:
-		if err := i.binop(value.NumTypeI32, ne); err != nil {
+		if err := relop(i, types.I32, ne[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_NE:
-		if err := i.binop(value.NumTypeI64, ne); err != nil {
+		if err := relop(i, types.I64, ne[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_LE_S:
-		if err := i.binop(value.NumTypeI32, les); err != nil {
+		if err := relop(i, types.I32, les[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_LE_S:
-		if err := i.binop(value.NumTypeI64, les); err != nil {
+		if err := relop(i, types.I64, les[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_LE_U:
-		if err := i.binop(value.NumTypeI32, leu); err != nil {
+		if err := relop(i, types.I32, leu[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_LE_U:
-		if err := i.binop(value.NumTypeI64, leu); err != nil {
+		if err := relop(i, types.I64, leu[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_GE_S:
-		if err := i.binop(value.NumTypeI32, ges); err != nil {
+		if err := relop(i, types.I32, ges[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_GE_S:
-		if err := i.binop(value.NumTypeI64, ges); err != nil {
+		if err := relop(i, types.I64, ges[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_GE_U:
-		if err := i.binop(value.NumTypeI32, geu); err != nil {
+		if err := relop(i, types.I32, geu[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_GE_U:
-		if err := i.binop(value.NumTypeI64, geu); err != nil {
+		if err := relop(i, types.I64, geu[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_LT_S:
-		if err := i.binop(value.NumTypeI32, lts); err != nil {
+		if err := relop(i, types.I32, lts[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_LT_S:
-		if err := i.binop(value.NumTypeI64, lts); err != nil {
+		if err := relop(i, types.I64, lts[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_LT_U:
-		if err := i.binop(value.NumTypeI32, ltu); err != nil {
+		if err := relop(i, types.I32, ltu[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_LT_U:
-		if err := i.binop(value.NumTypeI64, ltu); err != nil {
+		if err := relop(i, types.I64, ltu[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_GT_S:
-		if err := i.binop(value.NumTypeI32, gts); err != nil {
+		if err := relop(i, types.I32, gts[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_GT_S:
-		if err := i.binop(value.NumTypeI64, gts); err != nil {
+		if err := relop(i, types.I64, gts[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I32_GT_U:
-		if err := i.binop(value.NumTypeI32, gtu); err != nil {
+		if err := relop(i, types.I32, gtu[uint32]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.I64_GT_U:
-		if err := i.binop(value.NumTypeI64, gtu); err != nil {
+		if err := relop(i, types.I64, gtu[uint64]); err != nil {
 			return instructionResultTrap, err
 		}
 	case instruction.F32_ADD:
@@ -810,692 +595,219 @@ func (i *interpreter) execBinop(instr instruction.Instruction) (instructionResul
 	return instructionResultRunNext, nil
 }
 
-type binopFunc func(value.Number, value.Number) (value.Number, error)
-
 // https://webassembly.github.io/spec/core/exec/instructions.html#t-mathsf-xref-syntax-instructions-syntax-binop-mathit-binop
-func (i *interpreter) binop(valType value.NumberType, f binopFunc) error {
-	vallds, err := i.stack.RefNValueRev(2)
+func binop[T integer](i *interpreter, t types.ValueType, f func(a, b T) (T, error)) error {
+	c2, err := i.stack.PopRaw(t)
 	if err != nil {
-		return fmt.Errorf("binop: %w", err)
+		return fmt.Errorf("binop: %w: %w", ExecutionErrorArgumentTypeNotMatch, err)
 	}
-	for _, v := range vallds {
-		if v.ValType() != value.ValTypeNum {
-			return fmt.Errorf("binop: %w", ExecutionErrorTypeNotMatched)
-		}
-		if v.(value.Number).NumType() != valType || v.(value.Number).NumType() != valType {
-			return fmt.Errorf("binop: %w", ExecutionErrorArgumentTypeNotMatch)
-		}
-	}
-	values, err := i.stack.PopValuesRev(2)
+	c1, err := i.stack.PopRaw(t)
 	if err != nil {
-		return fmt.Errorf("binop: %w", err)
+		return fmt.Errorf("binop: %w: %w", ExecutionErrorArgumentTypeNotMatch, err)
 	}
-	res, err := f(values[0].(value.Number), values[1].(value.Number))
+	res, err := f(T(c1), T(c2))
 	if err != nil {
 		return fmt.Errorf("binop: %w", err)
 	}
-	if err := i.stack.PushValue(res.ToValue()); err != nil {
+	if err := i.stack.PushRaw(uint64(res), t); err != nil {
 		return fmt.Errorf("binop: %w", err)
 	}
 	return nil
 }
 
-func add(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
+// relop pushes the result of the comparison as i32.
+// https://webassembly.github.io/spec/core/exec/instructions.html#t-mathsf-xref-syntax-instructions-syntax-relop-mathit-relop
+func relop[T integer](i *interpreter, t types.ValueType, f func(a, b T) bool) error {
+	c2, err := i.stack.PopRaw(t)
+	if err != nil {
+		return fmt.Errorf("relop: %w: %w", ExecutionErrorArgumentTypeNotMatch, err)
 	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := uint64(value.GetNum[value.I32](a).Unsigned())
-		i2 := uint64(value.GetNum[value.I32](b).Unsigned())
-		return value.I32(i1 + i2), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		return value.I64(i1 + i2), nil
+	c1, err := i.stack.PopRaw(t)
+	if err != nil {
+		return fmt.Errorf("relop: %w: %w", ExecutionErrorArgumentTypeNotMatch, err)
+	}
+	if err := i.stack.PushI32(boolI32(f(T(c1), T(c2)))); err != nil {
+		return fmt.Errorf("relop: %w", err)
 	}
-	return nil, nil
+	return nil
 }
 
-func sub(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		return value.I32(i1 - i2), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		return value.I64(i1 - i2), nil
+func boolI32(b bool) value.I32 {
+	if b {
+		return value.I32(1)
 	}
-	return nil, nil
+	return value.I32(0)
 }
 
-func mul(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
+// width returns the bit width of T.
+func width[T integer]() T {
+	if uint64(^T(0)) == math.MaxUint32 {
+		return 32
 	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		return value.I32(i1 * i2), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		return value.I64(i1 * i2), nil
-	}
-	return nil, nil
+	return 64
 }
 
-func divs(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		if value.GetNum[value.I32](b) == value.I32(0) {
-			return nil, ExecutionErrorDivideByZero
-		}
-		i1 := value.GetNum[value.I32](a).Signed()
-		i2 := value.GetNum[value.I32](b).Signed()
-		return value.I32(i1 / i2), nil
-	case value.NumTypeI64:
-		if value.GetNum[value.I64](b) == value.I64(0) {
-			return nil, ExecutionErrorDivideByZero
-		}
-		i1 := value.GetNum[value.I64](a).Signed()
-		i2 := value.GetNum[value.I64](b).Signed()
-		return value.I64(i1 / i2), nil
+// signed interprets the bits of T as a signed integer.
+func signed[T integer](v T) int64 {
+	if width[T]() == 32 {
+		return int64(int32(v))
 	}
-	return nil, nil
+	return int64(v)
 }
 
-func divu(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		if value.GetNum[value.I32](b) == value.I32(0) {
-			return nil, ExecutionErrorDivideByZero
-		}
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		return value.I32(i1 / i2), nil
-	case value.NumTypeI64:
-		if value.GetNum[value.I64](b) == value.I64(0) {
-			return nil, ExecutionErrorDivideByZero
-		}
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		return value.I64(i1 / i2), nil
-	}
-	return nil, nil
+func add[T integer](a, b T) (T, error) {
+	return a + b, nil
 }
 
-func rems(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		if value.GetNum[value.I32](b) == value.I32(0) {
-			return nil, ExecutionErrorDivideByZero
-		}
-		i1 := value.GetNum[value.I32](a).Signed()
-		i2 := value.GetNum[value.I32](b).Signed()
-		return value.I32(i1 % i2), nil
-	case value.NumTypeI64:
-		if value.GetNum[value.I64](b) == value.I64(0) {
-			return nil, ExecutionErrorDivideByZero
-		}
-		i1 := value.GetNum[value.I64](a).Signed()
-		i2 := value.GetNum[value.I64](b).Signed()
-		return value.I64(i1 % i2), nil
-	}
-	return nil, nil
+func sub[T integer](a, b T) (T, error) {
+	return a - b, nil
 }
 
-func remu(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		if value.GetNum[value.I32](b) == value.I32(0) {
-			return nil, ExecutionErrorDivideByZero
-		}
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		return value.I32(i1 % i2), nil
-
-	case value.NumTypeI64:
-		if value.GetNum[value.I64](b) == value.I64(0) {
-			return nil, ExecutionErrorDivideByZero
-		}
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		return value.I64(i1 % i2), nil
-	}
-	return nil, nil
+func mul[T integer](a, b T) (T, error) {
+	return a * b, nil
 }
 
-func and(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		return value.I32(i1 & i2), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		return value.I64(i1 & i2), nil
-	default:
-		return nil, ExecutionErrorOperation
+func divs[T integer](a, b T) (T, error) {
+	if b == 0 {
+		return 0, ExecutionErrorDivideByZero
 	}
+	return T(signed(a) / signed(b)), nil
 }
 
-func or(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		return value.I32(i1 | i2), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		return value.I64(i1 | i2), nil
-	default:
-		return nil, ExecutionErrorOperation
+func divu[T integer](a, b T) (T, error) {
+	if b == 0 {
+		return 0, ExecutionErrorDivideByZero
 	}
+	return a / b, nil
 }
 
-func xor(a, b value.Number) (value.Number, error) {
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
+func rems[T integer](a, b T) (T, error) {
+	if b == 0 {
+		return 0, ExecutionErrorDivideByZero
 	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		return value.I32(i1 ^ i2), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		return value.I64(i1 ^ i2), nil
-	default:
-		return nil, ExecutionErrorOperation
+	return T(signed(a) % signed(b)), nil
+}
+
+func remu[T integer](a, b T) (T, error) {
+	if b == 0 {
+		return 0, ExecutionErrorDivideByZero
 	}
+	return a % b, nil
+}
+
+func and[T integer](a, b T) (T, error) {
+	return a & b, nil
 }
 
-func shl(a, b value.Number) (value.Number, error) {
+func or[T integer](a, b T) (T, error) {
+	return a | b, nil
+}
+
+func xor[T integer](a, b T) (T, error) {
+	return a ^ b, nil
+}
+
+func shl[T integer](a, b T) (T, error) {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ishl-mathrm-ishl-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		k := value.GetNum[value.I32](b).Unsigned()
-		v := value.GetNum[value.I32](a).Unsigned()
-		return value.I32(v << (k % 32)), nil
-	case value.NumTypeI64:
-		k := value.GetNum[value.I64](b).Unsigned()
-		v := value.GetNum[value.I64](a).Unsigned()
-		return value.I64(v << (k % 64)), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	return a << (b % width[T]()), nil
 }
 
-func shru(a, b value.Number) (value.Number, error) {
+func shru[T integer](a, b T) (T, error) {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ishr-u-mathrm-ishr-u-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		k := value.GetNum[value.I32](b).Unsigned()
-		v := value.GetNum[value.I32](a).Unsigned()
-		return value.I32(v >> (k % 32)), nil
-	case value.NumTypeI64:
-		k := value.GetNum[value.I64](b).Unsigned()
-		v := value.GetNum[value.I64](a).Unsigned()
-		return value.I64(v >> (k % 64)), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	return a >> (b % width[T]()), nil
 }
 
-func shrs(a, b value.Number) (value.Number, error) {
+func shrs[T integer](a, b T) (T, error) {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ishr-s-mathrm-ishr-s-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		k := value.GetNum[value.I32](b).Unsigned()
-		v := value.GetNum[value.I32](a).Signed()
-		return value.I32(v >> (k % 32)), nil
-	case value.NumTypeI64:
-		k := value.GetNum[value.I64](b).Unsigned()
-		v := value.GetNum[value.I64](a).Signed()
-		return value.I64(v >> (k % 64)), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	return T(signed(a) >> (b % width[T]())), nil
 }
 
-func rotl(a, b value.Number) (value.Number, error) {
+func rotl[T integer](a, b T) (T, error) {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-irotl-mathrm-irotl-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned() % 32
-		return value.I32((i1 << i2) | (i1 >> (32 - i2))), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned() % 64
-		return value.I64((i1 << i2) | (i1 >> (64 - i2))), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	k := b % width[T]()
+	return (a << k) | (a >> (width[T]() - k)), nil
 }
 
-func rotr(a, b value.Number) (value.Number, error) {
+func rotr[T integer](a, b T) (T, error) {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-irotr-mathrm-irotr-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned() % 32
-		return value.I32((i1 >> i2) | (i1 << (32 - i2))), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned() % 64
-		return value.I64((i1 >> i2) | (i1 << (64 - i2))), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	k := b % width[T]()
+	return (a >> k) | (a << (width[T]() - k)), nil
 }
 
-func eq(a, b value.Number) (value.Number, error) {
+func eq[T integer](a, b T) bool {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ieq-mathrm-ieq-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		if i1 == i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		if i1 == i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	return a == b
 }
 
-func ne(a, b value.Number) (value.Number, error) {
-	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ieq-mathrm-ieq-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		if i1 == i2 {
-			return value.I32(0), nil
-		}
-		return value.I32(1), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		if i1 == i2 {
-			return value.I32(0), nil
-		}
-		return value.I32(1), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+func ne[T integer](a, b T) bool {
+	return a != b
 }
 
-func ltu(a, b value.Number) (value.Number, error) {
-	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ilt-u-mathrm-ilt-u-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		if i1 < i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		if i1 < i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+func ltu[T integer](a, b T) bool {
+	return a < b
 }
 
-func lts(a, b value.Number) (value.Number, error) {
+func lts[T integer](a, b T) bool {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ilt-s-mathrm-ilt-s-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Signed()
-		i2 := value.GetNum[value.I32](b).Signed()
-		if i1 < i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Signed()
-		i2 := value.GetNum[value.I64](b).Signed()
-		if i1 < i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	return signed(a) < signed(b)
 }
 
-func gtu(a, b value.Number) (value.Number, error) {
-	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-igt-u-mathrm-igt-u-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		if i1 > i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		if i1 > i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+func gtu[T integer](a, b T) bool {
+	return a > b
 }
 
-func gts(a, b value.Number) (value.Number, error) {
-	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-igt-s-mathrm-igt-s-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Signed()
-		i2 := value.GetNum[value.I32](b).Signed()
-		if i1 > i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Signed()
-		i2 := value.GetNum[value.I64](b).Signed()
-		if i1 > i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+func gts[T integer](a, b T) bool {
+	return signed(a) > signed(b)
 }
 
-func leu(a, b value.Number) (value.Number, error) {
-	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ile-u-mathrm-ile-u-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		if i1 <= i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		if i1 <= i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+func leu[T integer](a, b T) bool {
+	return a <= b
 }
 
-func les(a, b value.Number) (value.Number, error) {
-	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ile-s-mathrm-ile-s-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Signed()
-		i2 := value.GetNum[value.I32](b).Signed()
-		if i1 <= i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Signed()
-		i2 := value.GetNum[value.I64](b).Signed()
-		if i1 <= i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+func les[T integer](a, b T) bool {
+	return signed(a) <= signed(b)
 }
 
-func geu(a, b value.Number) (value.Number, error) {
-	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ige-u-mathrm-ige-u-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Unsigned()
-		i2 := value.GetNum[value.I32](b).Unsigned()
-		if i1 >= i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Unsigned()
-		i2 := value.GetNum[value.I64](b).Unsigned()
-		if i1 >= i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+func geu[T integer](a, b T) bool {
+	return a >= b
 }
 
-func ges(a, b value.Number) (value.Number, error) {
-	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ige-s-mathrm-ige-s-n-i-1-i-2
-	if a.NumType() != b.NumType() {
-		return nil, ExecutionErrorArgumentTypeNotMatch
-	}
-	switch a.NumType() {
-	case value.NumTypeI32:
-		i1 := value.GetNum[value.I32](a).Signed()
-		i2 := value.GetNum[value.I32](b).Signed()
-		if i1 >= i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		i1 := value.GetNum[value.I64](a).Signed()
-		i2 := value.GetNum[value.I64](b).Signed()
-		if i1 >= i2 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+func ges[T integer](a, b T) bool {
+	return signed(a) >= signed(b)
 }
 
-func eqz(a value.Number) (value.Number, error) {
+func eqz[T integer](a T) bool {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ieqz-mathrm-ieqz-n-i
-	switch a.NumType() {
-	case value.NumTypeI32:
-		if value.GetNum[value.I32](a).Unsigned() == 0 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	case value.NumTypeI64:
-		if value.GetNum[value.I64](a).Unsigned() == 0 {
-			return value.I32(1), nil
-		}
-		return value.I32(0), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	return a == 0
 }
 
-func clz(a value.Number) (value.Number, error) {
+func clz[T integer](a T) T {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-iclz-mathrm-iclz-n-i
-	switch a.NumType() {
-	case value.NumTypeI32:
-		for i := 31; i >= 0; i-- {
-			if bits(value.GetNum[value.I32](a).Unsigned(), i) {
-				return value.I32(uint32(31 - i)), nil
-			}
-		}
-		return value.I32(32), nil
-	case value.NumTypeI64:
-		for i := 63; i >= 0; i-- {
-			if bits(value.GetNum[value.I64](a).Unsigned(), i) {
-				return value.I64(uint64(63 - i)), nil
-			}
-		}
-		return value.I64(64), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
+	return T(bits.LeadingZeros64(uint64(a))) - (64 - width[T]())
 }
 
-func ctz(a value.Number) (value.Number, error) {
+func ctz[T integer](a T) T {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ictz-mathrm-ictz-n-i
-	switch a.NumType() {
-	case value.NumTypeI32:
-		for i := 0; i < 32; i++ {
-			if bits(value.GetNum[value.I32](a).Unsigned(), i) {
-				return value.I32(i), nil
-			}
-		}
-		return value.I32(32), nil
-	case value.NumTypeI64:
-		for i := 0; i < 64; i++ {
-			if bits(value.GetNum[value.I64](a).Unsigned(), i) {
-				return value.I64(i), nil
-			}
-		}
-		return value.I64(64), nil
-	default:
-		return nil, ExecutionErrorOperation
+	if a == 0 {
+		return width[T]()
 	}
+	return T(bits.TrailingZeros64(uint64(a)))
 }
 
-func popcnt(a value.Number) (value.Number, error) {
+func popcnt[T integer](a T) T {
 	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-ipopcnt-mathrm-ipopcnt-n-i
-	switch a.NumType() {
-	case value.NumTypeI32:
-		cnt := 0
-		for i := 0; i < 32; i++ {
-			if bits(value.GetNum[value.I32](a).Unsigned(), i) {
-				cnt++
-			}
-		}
-		return value.I32(cnt), nil
-	case value.NumTypeI64:
-		cnt := 0
-		for i := 0; i < 64; i++ {
-			if bits(value.GetNum[value.I64](a).Unsigned(), i) {
-				cnt++
-			}
-		}
-		return value.I64(cnt), nil
-	default:
-		return nil, ExecutionErrorOperation
-	}
-}
-
-func bits[T ~uint32 | ~uint64](v T, n int) bool {
-	var mask T = 1 << n
-	if v&mask == 0 {
-		return false
-	}
-	return true
-}
-
-func extendu[T, V uint8 | uint16 | uint32 | uint64](i T) V {
-	return V(i)
+	return T(bits.OnesCount64(uint64(a)))
 }
 
-func extends[T, V int8 | int16 | int32 | int64](i T) V {
-	return V(i)
+func extend8s[T integer](a T) T {
+	// https://webassembly.github.io/spec/core/exec/numerics.html#xref-exec-numerics-op-iextendn-s-mathrm-iextend-m-mathrm-s-n-i
+	return T(int8(a))
 }
 
-func signed[T uint8 | uint16 | uint32 | uint64, V int8 | int16 | int32 | int64](i T) V {
-	var v V
-	b := bytes.NewBuffer(make([]byte, 0, 8))
-	binary.Write(b, binary.BigEndian, i)
-	binary.Read(b, binary.BigEndian, &v)
-	return v
+func extend16s[T integer](a T) T {
+	return T(int16(a))
 }
 
-func bytesToVal[T uint8 | uint16 | uint32 | uint64 | int8 | int16 | int32 | int64](b []byte) T {
-	var v T
-	buff := bytes.NewBuffer(b)
-	binary.Read(buff, binary.LittleEndian, &v)
-	return v
+func extend32s[T integer](a T) T {
+	return T(int32(a))
 }
@@ -14,17 +14,35 @@ import (
 func TestBinop(t *testing.T) {
 	for _, d := range []struct {
 		interpreter *interpreter
-		t           value.NumberType
-		f           binopFunc
+		t           types.ValueType
+		f           func(a, b uint32) (uint32, error)
 		exp         *stack.Stack
+		err         error
 	}{
 		{
 			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{value.I32(1), value.I32(1)}, nil, []stack.Label{})},
-			f:           add,
+			t:           types.I32,
+			f:           add[uint32],
 			exp:         stackWithValueIgnoreError([]value.Value{value.I32(2)}, nil, []stack.Label{}),
 		},
+		{
+			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{value.I32(1), value.I32(0)}, nil, []stack.Label{})},
+			t:           types.I32,
+			f:           divu[uint32],
+			err:         ExecutionErrorDivideByZero,
+		},
+		{
+			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{value.I64(1), value.I64(1)}, nil, []stack.Label{})},
+			t:           types.I32,
+			f:           add[uint32],
+			err:         ExecutionErrorArgumentTypeNotMatch,
+		},
 	} {
-		err := d.interpreter.binop(d.t, d.f)
+		err := binop(d.interpreter, d.t, d.f)
+		if d.err != nil {
+			assert.ErrorIs(t, err, d.err)
+			continue
+		}
 		require.NoError(t, err)
 		assert.Equal(t, d.exp, d.interpreter.stack)
 	}
@@ -32,12 +50,13 @@ func TestBinop(t *testing.T) {
 
 func TestAdd_I32(t *testing.T) {
 	for _, d := range []struct {
-		a   value.I32
-		b   value.I32
-		exp value.I32
+		a   uint32
+		b   uint32
+		exp uint32
 	}{
-		{a: value.I32(0), b: value.I32(1), exp: value.I32(1)},
-		{a: value.I32(0x0f), b: value.I32(0x1f), exp: value.I32(0x2e)},
+		{a: 0, b: 1, exp: 1},
+		{a: 0x0f, b: 0x1f, exp: 0x2e},
+		{a: 0xffffffff, b: 2, exp: 1},
 	} {
 		res, err := add(d.a, d.b)
 		require.NoError(t, err)
@@ -45,513 +64,47 @@ func TestAdd_I32(t *testing.T) {
 	}
 }
 
-func TestBlockStackPush(t *testing.T) {
+func TestExecNumeric(t *testing.T) {
 	for _, d := range []struct {
-		bs  *blockStack
-		op  instruction.Opcode
-		exp *blockStack
+		instr  instruction.Instruction
+		values []value.Value
+		exp    value.Value
 	}{
-		{
-			bs:  newBlockStack(),
-			op:  instruction.BLOCK,
-			exp: &blockStack{inner: []block{&blockBlock{}}},
-		},
-		{
-			bs:  newBlockStack(),
-			op:  instruction.LOOP,
-			exp: &blockStack{inner: []block{&blockLoop{}}},
-		},
-		{
-			bs:  newBlockStack(),
-			op:  instruction.IF,
-			exp: &blockStack{inner: []block{&blockIf{}}},
-		},
-		{
-			bs:  &blockStack{inner: []block{&blockIf{}}},
-			op:  instruction.BLOCK,
-			exp: &blockStack{inner: []block{&blockIf{}, &blockBlock{}}},
-		},
+		{instr: &instruction.I32Sub{}, values: []value.Value{value.I32(1), value.I32(2)}, exp: value.I32(0xffffffff)},
+		{instr: &instruction.I32DivS{}, values: []value.Value{signedI32(-7), value.I32(2)}, exp: signedI32(-3)},
+		{instr: &instruction.I64DivS{}, values: []value.Value{signedI64(-7), value.I64(2)}, exp: signedI64(-3)},
+		{instr: &instruction.I32RemS{}, values: []value.Value{signedI32(-7), value.I32(2)}, exp: signedI32(-1)},
+		{instr: &instruction.I32ShrS{}, values: []value.Value{signedI32(-8), value.I32(33)}, exp: signedI32(-4)},
+		{instr: &instruction.I64ShrU{}, values: []value.Value{signedI64(-8), value.I64(60)}, exp: value.I64(0xf)},
+		{instr: &instruction.I32RotL{}, values: []value.Value{value.I32(0x80000001), value.I32(1)}, exp: value.I32(3)},
+		{instr: &instruction.I64RotR{}, values: []value.Value{value.I64(1), value.I64(0)}, exp: value.I64(1)},
+		{instr: &instruction.I32LtS{}, values: []value.Value{signedI32(-1), value.I32(0)}, exp: value.I32(1)},
+		{instr: &instruction.I32LtU{}, values: []value.Value{signedI32(-1), value.I32(0)}, exp: value.I32(0)},
+		{instr: &instruction.I64GeS{}, values: []value.Value{signedI64(-1), value.I64(0)}, exp: value.I32(0)},
+		{instr: &instruction.I64Eqz{}, values: []value.Value{value.I64(0)}, exp: value.I32(1)},
+		{instr: &instruction.I32Clz{}, values: []value.Value{value.I32(1)}, exp: value.I32(31)},
+		{instr: &instruction.I64Clz{}, values: []value.Value{value.I64(0)}, exp: value.I64(64)},
+		{instr: &instruction.I32Ctz{}, values: []value.Value{value.I32(0)}, exp: value.I32(32)},
+		{instr: &instruction.I64Popcnt{}, values: []value.Value{signedI64(-1)}, exp: value.I64(64)},
+		{instr: &instruction.I32Extend8S{}, values: []value.Value{value.I32(0x80)}, exp: signedI32(-128)},
+		{instr: &instruction.I64Extend32S{}, values: []value.Value{value.I64(0x80000000)}, exp: signedI64(-0x80000000)},
 	} {
-		err := d.bs.push(d.op)
-		require.NoError(t, err)
-		assert.Equal(t, d.exp, d.bs)
+		i := &interpreter{stack: stackWithValueIgnoreError(d.values, nil, []stack.Label{})}
+		var err error
+		if len(d.values) == 1 {
+			_, err = i.execUnop(d.instr)
+		} else {
+			_, err = i.execBinop(d.instr)
+		}
+		require.NoError(t, err, d.instr.String())
+		assert.Equal(t, []value.Value{d.exp}, i.stack.Values(), d.instr.String())
 	}
 }
 
-func TestBlockStackPop(t *testing.T) {
-	for _, d := range []struct {
-		bs   *blockStack
-		exp  *blockStack
-		expT blockType
-	}{
-		{
-			bs:   &blockStack{inner: []block{&blockBlock{}}},
-			exp:  &blockStack{inner: []block{}},
-			expT: blockTypeBlock,
-		},
-		{
-			bs:   &blockStack{inner: []block{&blockIf{}, &blockBlock{}}},
-			exp:  &blockStack{inner: []block{&blockIf{}}},
-			expT: blockTypeBlock,
-		},
-	} {
-		v, err := d.bs.pop()
-		require.NoError(t, err)
-		assert.Equal(t, d.exp, d.bs)
-		assert.Equal(t, d.expT, v.typ())
-	}
-}
-func TestInterpreterLabelBlock(t *testing.T) {
-	for _, d := range []struct {
-		interpreter *interpreter
-		// op          instruction.Opcode
-		instr instruction.Instruction
-		ft    *types.FuncType
-		exp   *stack.Label
-	}{
-		{
-			interpreter: &interpreter{cur: &current{
-				frame: nil,
-				label: &stack.Label{
-					Instructions: []instruction.Instruction{
-						&instruction.Block{Imm: types.BlockType(0x40)},
-						&instruction.Nop{},
-						&instruction.End{},
-					},
-					N:  0,
-					Sp: 0,
-				},
-			}},
-			instr: &instruction.Block{},
-			ft:    &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{}},
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeBlock,
-			},
-		},
-		{
-			interpreter: &interpreter{cur: &current{
-				frame: nil,
-				label: &stack.Label{
-					Instructions: []instruction.Instruction{
-						&instruction.Block{Imm: types.BlockType(types.I32)},
-						&instruction.Nop{},
-						&instruction.I32Const{Imm: 0},
-						&instruction.End{},
-					},
-					N:  0,
-					Sp: 0,
-				},
-			}},
-			instr: &instruction.Block{},
-			ft:    &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{types.I32}},
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.I32Const{Imm: 0},
-					&instruction.End{},
-				},
-				N:    1,
-				Sp:   0,
-				Type: stack.LabelTypeBlock,
-			},
-		},
-		{
-			interpreter: &interpreter{cur: &current{
-				frame: nil,
-				label: &stack.Label{
-					Instructions: []instruction.Instruction{
-						&instruction.Block{Imm: types.BlockType(types.I32)},
-						&instruction.Nop{},
-						&instruction.End{},
-						&instruction.Block{Imm: types.BlockType(types.I32)},
-						&instruction.I32Const{Imm: 0},
-						&instruction.End{},
-						&instruction.End{},
-					},
-					N:  0,
-					Sp: 3,
-				},
-			}},
-			instr: &instruction.Block{},
-			ft:    &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{types.I32}},
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.I32Const{Imm: 0},
-					&instruction.End{},
-				},
-				N:    1,
-				Sp:   0,
-				Type: stack.LabelTypeBlock,
-			},
-		},
-		{
-			interpreter: &interpreter{cur: &current{
-				frame: nil,
-				label: &stack.Label{
-					Instructions: []instruction.Instruction{
-						&instruction.Block{Imm: types.BlockType(types.I32)},
-						&instruction.Block{Imm: types.BlockType(types.I32)},
-						&instruction.I32Const{Imm: 0},
-						&instruction.End{},
-						&instruction.End{},
-						&instruction.End{},
-					},
-					N:  1,
-					Sp: 0,
-				},
-			}},
-			instr: &instruction.Block{},
-			ft:    &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{types.I32}},
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Block{Imm: types.BlockType(types.I32)},
-					&instruction.I32Const{Imm: 0},
-					&instruction.End{},
-					&instruction.End{},
-				},
-				N:    1,
-				Sp:   0,
-				Type: stack.LabelTypeBlock,
-			},
-		},
-		{
-			interpreter: &interpreter{cur: &current{
-				frame: nil,
-				label: &stack.Label{
-					Instructions: []instruction.Instruction{
-						&instruction.If{Imm: types.BlockType(0x40)},
-						&instruction.Nop{},
-						&instruction.End{},
-					},
-					N:  0,
-					Sp: 0,
-				},
-			}},
-			instr: &instruction.Block{},
-			ft:    &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{}},
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeBlock,
-			},
-		},
-		{
-			interpreter: &interpreter{cur: &current{
-				frame: nil,
-				label: &stack.Label{
-					Instructions: []instruction.Instruction{
-						&instruction.If{Imm: types.BlockType(0x40)},
-						&instruction.Nop{},
-						&instruction.Else{},
-						&instruction.End{},
-					},
-					N:  0,
-					Sp: 0,
-				},
-			}},
-			instr: &instruction.Block{},
-			ft:    &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{}},
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.Else{},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeBlock,
-			},
-		},
-		{
-			interpreter: &interpreter{cur: &current{
-				frame: nil,
-				label: &stack.Label{
-					Instructions: []instruction.Instruction{
-						&instruction.If{Imm: types.BlockType(0x40)},
-						&instruction.Nop{},
-						&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)},
-						&instruction.End{},
-						&instruction.Else{},
-						&instruction.End{},
-					},
-					N:  0,
-					Sp: 0,
-				},
-			}},
-			instr: &instruction.Block{},
-			ft:    &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{}},
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.End{},
-					&instruction.Else{},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeBlock,
-			},
-		},
-		{
-			interpreter: &interpreter{cur: &current{
-				frame: nil,
-				label: &stack.Label{
-					Instructions: []instruction.Instruction{
-						&instruction.If{Imm: types.BlockType(0x40)},
-						&instruction.Nop{},
-						&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)},
-						&instruction.End{},
-						&instruction.Else{},
-						&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
-						&instruction.Nop{},
-						&instruction.End{},
-						&instruction.End{},
-						&instruction.End{},
-					},
-					N:  0,
-					Sp: 0,
-				},
-			}},
-			instr: &instruction.Block{},
-			ft:    &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{}},
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.End{},
-					&instruction.Else{},
-					&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.Nop{},
-					&instruction.End{},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeBlock,
-			},
-		},
-	} {
-		label, _, err := d.interpreter.labelBlock(d.instr, d.ft)
-		require.NoError(t, err)
-		assert.Equal(t, d.exp, label)
-	}
+func signedI32(v int32) value.I32 {
+	return value.I32(v)
 }
 
-func TestIfElseLabelBlock(t *testing.T) {
-	for _, d := range []struct {
-		label *stack.Label
-		cond  value.I32
-		exp   *stack.Label
-	}{
-		{
-			label: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.End{},
-				},
-				N:  0,
-				Sp: 0,
-			},
-			cond: value.I32(1),
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeIf,
-			},
-		},
-		{
-			label: &stack.Label{
-				Instructions: []instruction.Instruction{},
-				N:            0,
-				Sp:           0,
-			},
-			cond: value.I32(0),
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeIf,
-			},
-		},
-		{
-			label: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.End{},
-				},
-				N:  0,
-				Sp: 0,
-			},
-			cond: value.I32(0),
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeIf,
-			},
-		},
-		{
-			label: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.End{},
-				},
-				N:  0,
-				Sp: 0,
-			},
-			cond: value.I32(1),
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Nop{},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeIf,
-			},
-		},
-		{
-			label: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.I32Const{Imm: 7},
-					&instruction.Else{},
-					&instruction.I32Const{Imm: 7},
-					&instruction.End{},
-				},
-				N:  0,
-				Sp: 0,
-			},
-			cond: value.I32(1),
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.I32Const{Imm: 7},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeIf,
-			},
-		},
-		{
-			label: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.I32Const{Imm: 7},
-					&instruction.Else{},
-					&instruction.I32Const{Imm: 8},
-					&instruction.End{},
-				},
-				N:  0,
-				Sp: 0,
-			},
-			cond: value.I32(0),
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.I32Const{Imm: 8},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeIf,
-			},
-		},
-		{
-			label: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.GetLocal{Imm: 1},
-					&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.Call{Imm: 0},
-					&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.End{},
-					&instruction.Nop{},
-					&instruction.End{},
-					&instruction.Else{},
-					&instruction.Call{Imm: 0},
-					&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.End{},
-					&instruction.Nop{},
-					&instruction.End{},
-				},
-				N:  0,
-				Sp: 0,
-			},
-			cond: value.I32(1),
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.GetLocal{Imm: 1},
-					&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.Call{Imm: 0},
-					&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.End{},
-					&instruction.Nop{},
-					&instruction.End{},
-					&instruction.End{},
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeIf,
-			},
-		},
-		{
-			label: &stack.Label{
-				Instructions: []instruction.Instruction{
-					// if0
-					&instruction.GetLocal{Imm: 1},
-					&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)}, // if1
-					&instruction.Call{Imm: 0},
-					&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
-					&instruction.End{}, // end of block
-					&instruction.Nop{},
-					&instruction.End{},  // end of if1
-					&instruction.Else{}, // else of if0
-					&instruction.Call{Imm: 0},
-					&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)}, // end of if2
-					&instruction.Nop{},
-					&instruction.Else{}, // else of if2
-					&instruction.Call{Imm: 0},
-					&instruction.Call{Imm: 0},
-					&instruction.End{}, // end of if2
-					&instruction.End{}, // end of if0
-				},
-				N:  0,
-				Sp: 0,
-			},
-			cond: value.I32(0), //e lse
-			exp: &stack.Label{
-				Instructions: []instruction.Instruction{
-					&instruction.Call{Imm: 0},
-					&instruction.If{Imm: types.BlockType(types.BLOCKTYPE)}, // end of if2
-					&instruction.Nop{},
-					&instruction.Else{}, // else of if2
-					&instruction.Call{Imm: 0},
-					&instruction.Call{Imm: 0},
-					&instruction.End{}, // end of if2
-					&instruction.End{}, // end of if0
-				},
-				N:    0,
-				Sp:   0,
-				Type: stack.LabelTypeIf,
-			},
-		},
-	} {
-		condLabel, err := ifElseLabel(d.label, d.cond)
-		require.NoError(t, err)
-		assert.Equal(t, d.exp, condLabel)
-	}
+func signedI64(v int64) value.I64 {
+	return value.I64(v)
 }
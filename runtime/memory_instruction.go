@@ -1,7 +1,6 @@
 package runtime
 
 import (
-	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -16,205 +15,146 @@ var (
 	MemoryDoesNotHaveEnoughLength error = errors.New("memory doesn't have enough length")
 )
 
+// https://webassembly.github.io/spec/core/exec/instructions.html#exec-load
 func (i *interpreter) execLoad(instr instruction.Instruction) (instructionResult, error) {
-	imm := instruction.Imm[instruction.MemoryImm](instr)
-	if len(i.cur.frame.Module.MemAddrs) == 0 || i.cur.frame.Module.MemAddrs == nil {
-		return instructionResultTrap, fmt.Errorf("load: memory instance is not exist")
-	}
-	mem := i.cur.frame.Module.MemAddrs[0]
-	if err := i.stack.ValidateValue([]types.ValueType{types.I32}); err != nil {
-		return instructionResultTrap, fmt.Errorf("load: %w", err)
+	mem, err := i.memory(instr)
+	if err != nil {
+		return instructionResultTrap, err
 	}
-	v, err := i.stack.PopValue()
+	base, err := i.stack.PopI32()
 	if err != nil {
-		return instructionResultTrap, fmt.Errorf("load: %w", err)
+		return instructionResultTrap, fmt.Errorf("%s: %w", instr, err)
+	}
+	var buf [8]byte
+	if err := load(mem, uint32(base), instruction.Imm[instruction.MemoryImm](instr), buf[:accessWidth(instr.Opcode())]); err != nil {
+		return instructionResultTrap, fmt.Errorf("%s: %w", instr, err)
 	}
-	ea := instance.GetVal[value.I32](v).Unsigned() + imm.Offset
+	raw := binary.LittleEndian.Uint64(buf[:])
 	switch instr.Opcode() {
-	case instruction.I32_LOAD:
-		if int(ea+4) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i32.load: memory doesn't have enough length")
-		}
-		val := binary.LittleEndian.Uint32(load(mem, ea, 4))
-		if err := i.stack.PushValue(value.I32(val)); err != nil {
-			return instructionResultTrap, fmt.Errorf("i32.load: %w", err)
-		}
-	case instruction.I64_LOAD:
-		if int(ea+8) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.load: memory doesn't have enough length")
-		}
-		val := binary.LittleEndian.Uint64(load(mem, ea, 8))
-		if err := i.stack.PushValue(value.I64(val)); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.load: %w", err)
-		}
+	case instruction.I32_LOAD, instruction.I32_LOAD8_U, instruction.I32_LOAD16_U:
+		err = i.stack.PushRaw(raw, types.I32)
 	case instruction.I32_LOAD8_S:
-		if int(ea+1) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i32.load8_s: %w", err)
-		}
-		val := bytesToVal[int8](load(mem, ea, 1))
-		if err := i.stack.PushValue(value.NewI32(int32(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i32.load8_s: %w", err)
-		}
-	case instruction.I64_LOAD8_S:
-		if int(ea+1) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.load8_s: %w", err)
-		}
-		val := bytesToVal[int8](load(mem, ea, 1))
-		if err := i.stack.PushValue(value.NewI64(int64(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.load8_s: %w", err)
-		}
-	case instruction.I32_LOAD8_U:
-		if int(ea+1) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i32.load8_u: %w", err)
-		}
-		val := bytesToVal[uint8](load(mem, ea, 1))
-		if err := i.stack.PushValue(value.I32(uint32(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i32.load8_u: %w", err)
-		}
-	case instruction.I64_LOAD8_U:
-		if int(ea+1) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.load8_u: %w", err)
-		}
-		val := bytesToVal[uint8](load(mem, ea, 1))
-		if err := i.stack.PushValue(value.I64(uint64(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.load8_u: %w", err)
-		}
+		err = i.stack.PushRaw(uint64(uint32(int8(raw))), types.I32)
 	case instruction.I32_LOAD16_S:
-		if int(ea+2) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i32.load16_s: %w", err)
-		}
-		val := bytesToVal[int16](load(mem, ea, 2))
-		if err := i.stack.PushValue(value.NewI32(int32(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i32.load16_s: %w", err)
-		}
+		err = i.stack.PushRaw(uint64(uint32(int16(raw))), types.I32)
+	case instruction.I64_LOAD, instruction.I64_LOAD8_U, instruction.I64_LOAD16_U, instruction.I64_LOAD32_U:
+		err = i.stack.PushRaw(raw, types.I64)
+	case instruction.I64_LOAD8_S:
+		err = i.stack.PushRaw(uint64(int8(raw)), types.I64)
 	case instruction.I64_LOAD16_S:
-		if int(ea+2) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.load16_s: %w", err)
-		}
-		val := bytesToVal[int16](load(mem, ea, 2))
-		if err := i.stack.PushValue(value.NewI64(int64(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.load16_s: %w", err)
-		}
-	case instruction.I32_LOAD16_U:
-		if int(ea+2) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i32.load16_u: %w", err)
-		}
-		val := bytesToVal[uint16](load(mem, ea, 2))
-		if err := i.stack.PushValue(value.I32(uint32(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i32.load16_u: %w", err)
-		}
-	case instruction.I64_LOAD16_U:
-		if int(ea+2) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.load16_u: %w", err)
-		}
-		val := bytesToVal[uint16](load(mem, ea, 2))
-		if err := i.stack.PushValue(value.I64(uint64(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.load16_u: %w", err)
-		}
+		err = i.stack.PushRaw(uint64(int16(raw)), types.I64)
 	case instruction.I64_LOAD32_S:
-		if int(ea+4) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.load32_s: %w", err)
-		}
-		val := bytesToVal[int32](load(mem, ea, 4))
-		if err := i.stack.PushValue(value.NewI64(int64(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.load32_s: %w", err)
-		}
-	case instruction.I64_LOAD32_U:
-		if int(ea+4) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.load32_u: %w", err)
-		}
-		val := bytesToVal[uint32](load(mem, ea, 4))
-		if err := i.stack.PushValue(value.I64(uint64(val))); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.load32_u: %w", err)
-		}
+		err = i.stack.PushRaw(uint64(int32(raw)), types.I64)
 	default:
 		return instructionResultTrap, instruction.NotImplemented
 	}
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("%s: %w", instr, err)
+	}
 	return instructionResultRunNext, nil
 }
 
-func load(mem *instance.Memory, offset, length uint32) []byte {
-	return mem.Data[offset : offset+length]
-}
-
+// https://webassembly.github.io/spec/core/exec/instructions.html#exec-store
 func (i *interpreter) execStore(instr instruction.Instruction) (instructionResult, error) {
-	imm := instruction.Imm[instruction.MemoryImm](instr)
-	if len(i.cur.frame.Module.MemAddrs) == 0 || i.cur.frame.Module.MemAddrs == nil {
-		return instructionResultTrap, fmt.Errorf("store: memory instance is not exist")
-	}
-	mem := i.cur.frame.Module.MemAddrs[0]
-	v, err := i.stack.PopValue()
+	mem, err := i.memory(instr)
 	if err != nil {
-		return instructionResultTrap, fmt.Errorf("store: %w", err)
+		return instructionResultTrap, err
 	}
-	e, err := i.stack.PopValue()
-	if err != nil {
-		return instructionResultTrap, fmt.Errorf("store: %w", err)
-	}
-	ea := instance.GetVal[value.I32](e).Unsigned() + imm.Offset
+	var raw uint64
 	switch instr.Opcode() {
-	case instruction.I32_STORE:
-		if int(ea+4) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i32.store: memory doesn't have enough length")
-		}
-		if err := store(mem, instance.GetVal[value.I32](v).Unsigned(), ea, 4); err != nil {
-			return instructionResultTrap, fmt.Errorf("i32.store: %w", err)
-		}
-	case instruction.I64_STORE:
-		if int(ea+8) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.store: memory doesn't have enough length")
-		}
-		if err := store(mem, instance.GetVal[value.I64](v).Unsigned(), ea, 8); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.store: %w", err)
-		}
-	case instruction.I32_STORE8:
-		if int(ea+1) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i32.store8: memory doesn't have enough length")
-		}
-		if err := store(mem, instance.GetVal[value.I32](v).Unsigned(), ea, 1); err != nil {
-			return instructionResultTrap, fmt.Errorf("i32.store8: %w", err)
-		}
-	case instruction.I64_STORE8:
-		if int(ea+1) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.store8: memory doesn't have enough length")
-		}
-		if err := store(mem, instance.GetVal[value.I64](v).Unsigned(), ea, 1); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.store8: %w", err)
-		}
-	case instruction.I32_STORE16:
-		if int(ea+2) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i32.store16: memory doesn't have enough length")
-		}
-		if err := store(mem, instance.GetVal[value.I32](v).Unsigned(), ea, 2); err != nil {
-			return instructionResultTrap, fmt.Errorf("i32.store16: %w", err)
-		}
-	case instruction.I64_STORE16:
-		if int(ea+2) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.store16: memory doesn't have enough length")
-		}
-		if err := store(mem, instance.GetVal[value.I64](v).Unsigned(), ea, 2); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.store16: %w", err)
-		}
-	case instruction.I64_STORE32:
-		if int(ea+4) > len(mem.Data) {
-			return instructionResultTrap, fmt.Errorf("i64.store32: memory doesn't have enough length")
-		}
-		if err := store(mem, instance.GetVal[value.I64](v).Unsigned(), ea, 4); err != nil {
-			return instructionResultTrap, fmt.Errorf("i64.store32: %w", err)
-		}
+	case instruction.I32_STORE, instruction.I32_STORE8, instruction.I32_STORE16:
+		raw, err = i.stack.PopRaw(types.I32)
+	case instruction.I64_STORE, instruction.I64_STORE8, instruction.I64_STORE16, instruction.I64_STORE32:
+		raw, err = i.stack.PopRaw(types.I64)
 	default:
 		return instructionResultTrap, instruction.NotImplemented
 	}
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("%s: %w", instr, err)
+	}
+	base, err := i.stack.PopI32()
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("%s: %w", instr, err)
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], raw)
+	if err := store(mem, uint32(base), instruction.Imm[instruction.MemoryImm](instr), buf[:accessWidth(instr.Opcode())]); err != nil {
+		return instructionResultTrap, fmt.Errorf("%s: %w", instr, err)
+	}
 	return instructionResultRunNext, nil
 }
 
-func store[T uint32 | uint64](mem *instance.Memory, val T, offset, length uint32) error {
-	buf := bytes.NewBuffer(make([]byte, 0, 8))
-	if err := binary.Write(buf, binary.LittleEndian, val); err != nil {
-		return err
+// memory returns the memory accessed by the instruction.
+func (i *interpreter) memory(instr instruction.Instruction) (*instance.Memory, error) {
+	if len(i.cur.frame.Module.MemAddrs) == 0 {
+		return nil, fmt.Errorf("%s: memory instance is not exist", instr)
 	}
-	for i, b := range buf.Bytes() {
-		mem.Data[int(offset)+i] = b
+	return i.cur.frame.Module.MemAddrs[0], nil
+}
+
+// effectiveAddress computes the address accessed by a load or store in 64 bit,
+// so that base + offset doesn't wrap around.
+func effectiveAddress(base uint32, imm instruction.MemoryImm) uint64 {
+	return uint64(base) + uint64(imm.Offset)
+}
+
+// load reads len(b) bytes at the effective address. The access traps unless all bytes are in the memory.
+// The memory is accessed through its lock, because it may grow on another goroutine.
+func load(mem *instance.Memory, base uint32, imm instruction.MemoryImm, b []byte) error {
+	if err := mem.Load(effectiveAddress(base, imm), b); err != nil {
+		return fmt.Errorf("%w: %w", MemoryDoesNotHaveEnoughLength, err)
 	}
 	return nil
 }
+
+// store writes b at the effective address. The access traps unless all bytes are in the memory.
+func store(mem *instance.Memory, base uint32, imm instruction.MemoryImm, b []byte) error {
+	if err := mem.Store(effectiveAddress(base, imm), b); err != nil {
+		return fmt.Errorf("%w: %w", MemoryDoesNotHaveEnoughLength, err)
+	}
+	return nil
+}
+
+// accessWidth returns the number of bytes accessed by a load or store.
+func accessWidth(op instruction.Opcode) uint32 {
+	switch op {
+	case instruction.I32_LOAD8_S, instruction.I32_LOAD8_U, instruction.I64_LOAD8_S, instruction.I64_LOAD8_U,
+		instruction.I32_STORE8, instruction.I64_STORE8:
+		return 1
+	case instruction.I32_LOAD16_S, instruction.I32_LOAD16_U, instruction.I64_LOAD16_S, instruction.I64_LOAD16_U,
+		instruction.I32_STORE16, instruction.I64_STORE16:
+		return 2
+	case instruction.I32_LOAD, instruction.I64_LOAD32_S, instruction.I64_LOAD32_U,
+		instruction.I32_STORE, instruction.I64_STORE32:
+		return 4
+	default:
+		return 8
+	}
+}
+
+func (i *interpreter) execMemory(instr instruction.Instruction) (instructionResult, error) {
+	mem, err := i.memory(instr)
+	if err != nil {
+		return instructionResultTrap, err
+	}
+	switch instr.Opcode() {
+	case instruction.CURRENT_MEMORY:
+		if err := i.stack.PushI32(value.I32(mem.Pages())); err != nil {
+			return instructionResultTrap, fmt.Errorf("current_memory: %w", err)
+		}
+	case instruction.GROW_MEMORY:
+		delta, err := i.stack.PopI32()
+		if err != nil {
+			return instructionResultTrap, fmt.Errorf("grow_memory: %w", err)
+		}
+		prev, err := mem.Grow(uint32(delta))
+		if err != nil {
+			// failing to grow is not a trap
+			prev = 0xffffffff
+		}
+		if err := i.stack.PushI32(value.I32(prev)); err != nil {
+			return instructionResultTrap, fmt.Errorf("grow_memory: %w", err)
+		}
+	}
+	return instructionResultRunNext, nil
+}
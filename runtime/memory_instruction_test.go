@@ -0,0 +1,180 @@
+package runtime
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/types"
+)
+
+func newMemoryInterpreter(pages uint32) (*interpreter, *instance.Memory) {
+	mem := &instance.Memory{Type: &types.MemoryType{Limits: &types.Limits{Min: pages}}, Data: make([]byte, instance.PAGE_SIZE*pages)}
+	m := &instance.Module{MemAddrs: []*instance.Memory{mem}}
+	return &interpreter{stack: stack.New(), cur: &current{frame: &stack.Frame{Module: m}}}, mem
+}
+
+var memoryAccessEdges = []struct {
+	name   string
+	base   func(size, width uint32) uint32
+	offset func(size, width uint32) uint32
+	trap   bool
+}{
+	{name: "first byte", base: func(_, _ uint32) uint32 { return 0 }, offset: func(_, _ uint32) uint32 { return 0 }},
+	{name: "last bytes by base", base: func(size, width uint32) uint32 { return size - width }, offset: func(_, _ uint32) uint32 { return 0 }},
+	{name: "last bytes by offset", base: func(_, _ uint32) uint32 { return 0 }, offset: func(size, width uint32) uint32 { return size - width }},
+	{name: "last bytes by both", base: func(size, width uint32) uint32 { return size/2 - width }, offset: func(size, _ uint32) uint32 { return size / 2 }},
+	{name: "over the end by base", base: func(size, width uint32) uint32 { return size - width + 1 }, offset: func(_, _ uint32) uint32 { return 0 }, trap: true},
+	{name: "over the end by offset", base: func(_, _ uint32) uint32 { return 0 }, offset: func(size, width uint32) uint32 { return size - width + 1 }, trap: true},
+	{name: "at the end", base: func(size, _ uint32) uint32 { return size }, offset: func(_, _ uint32) uint32 { return 0 }, trap: true},
+	{name: "base wraps to zero in 32 bit", base: func(_, _ uint32) uint32 { return 0xffffffff }, offset: func(_, _ uint32) uint32 { return 1 }, trap: true},
+	{name: "offset wraps to zero in 32 bit", base: func(_, _ uint32) uint32 { return 1 }, offset: func(_, _ uint32) uint32 { return 0xffffffff }, trap: true},
+	{name: "end wraps to zero in 32 bit", base: func(_, width uint32) uint32 { return 0xffffffff - width + 1 }, offset: func(_, _ uint32) uint32 { return 0 }, trap: true},
+	{name: "max address", base: func(_, _ uint32) uint32 { return 0xffffffff }, offset: func(_, _ uint32) uint32 { return 0xffffffff }, trap: true},
+}
+
+func TestExecLoad_Bounds(t *testing.T) {
+	for _, instr := range []instruction.Instruction{
+		&instruction.I32Load{}, &instruction.I64Load{},
+		&instruction.I32Load8S{}, &instruction.I32Load8U{}, &instruction.I32Load16S{}, &instruction.I32Load16U{},
+		&instruction.I64Load8S{}, &instruction.I64Load8U{}, &instruction.I64Load16S{}, &instruction.I64Load16U{},
+		&instruction.I64Load32S{}, &instruction.I64Load32U{},
+	} {
+		width := accessWidth(instr.Opcode())
+		for _, d := range memoryAccessEdges {
+			i, mem := newMemoryInterpreter(1)
+			imm := instruction.MemoryImm{Offset: d.offset(uint32(mem.Size()), width)}
+			instr := withMemoryImm(instr, imm)
+			require.NoError(t, i.stack.PushI32(value.I32(d.base(uint32(mem.Size()), width))))
+			_, err := i.execLoad(instr)
+			if d.trap {
+				assert.ErrorIs(t, err, MemoryDoesNotHaveEnoughLength, "%s: %s", instr, d.name)
+				assert.Equal(t, "out of bounds memory access", TrapKind(err))
+				continue
+			}
+			require.NoError(t, err, "%s: %s", instr, d.name)
+			assert.Equal(t, 1, len(i.stack.Values()), "%s: %s", instr, d.name)
+		}
+	}
+}
+
+func TestExecStore_Bounds(t *testing.T) {
+	for _, instr := range []instruction.Instruction{
+		&instruction.I32Store{}, &instruction.I64Store{},
+		&instruction.I32Store8{}, &instruction.I32Store16{},
+		&instruction.I64Store8{}, &instruction.I64Store16{}, &instruction.I64Store32{},
+	} {
+		width := accessWidth(instr.Opcode())
+		for _, d := range memoryAccessEdges {
+			i, mem := newMemoryInterpreter(1)
+			base := d.base(uint32(mem.Size()), width)
+			imm := instruction.MemoryImm{Offset: d.offset(uint32(mem.Size()), width)}
+			instr := withMemoryImm(instr, imm)
+			require.NoError(t, i.stack.PushI32(value.I32(base)))
+			switch instr.Opcode() {
+			case instruction.I32_STORE, instruction.I32_STORE8, instruction.I32_STORE16:
+				require.NoError(t, i.stack.PushValue(value.I32(0xffffffff)))
+			default:
+				require.NoError(t, i.stack.PushValue(value.I64(0xffffffffffffffff)))
+			}
+			_, err := i.execStore(instr)
+			if d.trap {
+				assert.ErrorIs(t, err, MemoryDoesNotHaveEnoughLength, "%s: %s", instr, d.name)
+				// a trapped store doesn't write any byte
+				assert.True(t, bytes.Equal(make([]byte, mem.Size()), mem.Data), "%s: %s", instr, d.name)
+				continue
+			}
+			require.NoError(t, err, "%s: %s", instr, d.name)
+			ea := int(base) + int(imm.Offset)
+			written := 0
+			for _, b := range mem.Data {
+				if b != 0 {
+					written++
+				}
+			}
+			assert.Equal(t, int(width), written, "%s: %s", instr, d.name)
+			assert.Equal(t, byte(0xff), mem.Data[ea+int(width)-1], "%s: %s", instr, d.name)
+		}
+	}
+}
+
+func TestExecLoad_Extend(t *testing.T) {
+	for _, d := range []struct {
+		instr instruction.Instruction
+		exp   value.Value
+	}{
+		{instr: &instruction.I32Load{}, exp: value.I32(0x83828180)},
+		{instr: &instruction.I32Load8S{}, exp: value.I32(0xffffff80)},
+		{instr: &instruction.I32Load8U{}, exp: value.I32(0x80)},
+		{instr: &instruction.I32Load16S{}, exp: value.I32(0xffff8180)},
+		{instr: &instruction.I32Load16U{}, exp: value.I32(0x8180)},
+		{instr: &instruction.I64Load{}, exp: value.I64(0x8786858483828180)},
+		{instr: &instruction.I64Load8S{}, exp: value.I64(0xffffffffffffff80)},
+		{instr: &instruction.I64Load8U{}, exp: value.I64(0x80)},
+		{instr: &instruction.I64Load16S{}, exp: value.I64(0xffffffffffff8180)},
+		{instr: &instruction.I64Load16U{}, exp: value.I64(0x8180)},
+		{instr: &instruction.I64Load32S{}, exp: value.I64(0xffffffff83828180)},
+		{instr: &instruction.I64Load32U{}, exp: value.I64(0x83828180)},
+	} {
+		i, mem := newMemoryInterpreter(1)
+		// the value is at the end of the memory
+		ea := uint32(mem.Size()) - accessWidth(d.instr.Opcode())
+		copy(mem.Data[ea:], []byte{0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87})
+		require.NoError(t, i.stack.PushI32(value.I32(ea)))
+		_, err := i.execLoad(d.instr)
+		require.NoError(t, err)
+		v, err := i.stack.PopValue()
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, v, d.instr.String())
+	}
+}
+
+func withMemoryImm(instr instruction.Instruction, imm instruction.MemoryImm) instruction.Instruction {
+	switch instr.(type) {
+	case *instruction.I32Load:
+		return &instruction.I32Load{Imm: imm}
+	case *instruction.I64Load:
+		return &instruction.I64Load{Imm: imm}
+	case *instruction.I32Load8S:
+		return &instruction.I32Load8S{Imm: imm}
+	case *instruction.I32Load8U:
+		return &instruction.I32Load8U{Imm: imm}
+	case *instruction.I32Load16S:
+		return &instruction.I32Load16S{Imm: imm}
+	case *instruction.I32Load16U:
+		return &instruction.I32Load16U{Imm: imm}
+	case *instruction.I64Load8S:
+		return &instruction.I64Load8S{Imm: imm}
+	case *instruction.I64Load8U:
+		return &instruction.I64Load8U{Imm: imm}
+	case *instruction.I64Load16S:
+		return &instruction.I64Load16S{Imm: imm}
+	case *instruction.I64Load16U:
+		return &instruction.I64Load16U{Imm: imm}
+	case *instruction.I64Load32S:
+		return &instruction.I64Load32S{Imm: imm}
+	case *instruction.I64Load32U:
+		return &instruction.I64Load32U{Imm: imm}
+	case *instruction.I32Store:
+		return &instruction.I32Store{Imm: imm}
+	case *instruction.I64Store:
+		return &instruction.I64Store{Imm: imm}
+	case *instruction.I32Store8:
+		return &instruction.I32Store8{Imm: imm}
+	case *instruction.I32Store16:
+		return &instruction.I32Store16{Imm: imm}
+	case *instruction.I64Store8:
+		return &instruction.I64Store8{Imm: imm}
+	case *instruction.I64Store16:
+		return &instruction.I64Store16{Imm: imm}
+	case *instruction.I64Store32:
+		return &instruction.I64Store32{Imm: imm}
+	default:
+		return instr
+	}
+}
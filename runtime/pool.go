@@ -0,0 +1,81 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/types"
+)
+
+var InterpreterNotSupported error = errors.New("Interpreter is not created by this package")
+
+// Snapshot copies memories, tables and globals of the instance of the interpreter.
+// Imported and shared memories are not copied, so sandboxes share them with the interpreter.
+// Invoke exports to initialize the instance before taking the snapshot.
+// It must not be called while the interpreter is executing.
+func Snapshot(i Interpreter) (*instance.Snapshot, error) {
+	r, ok := i.(*runner)
+	if !ok {
+		return nil, InterpreterNotSupported
+	}
+	return r.instance.Snapshot()
+}
+
+// Pool hands out sandboxes restored from a snapshot.
+// It is safe for concurrent use by multiple goroutines.
+type Pool struct {
+	snapshot  *instance.Snapshot
+	codes     *codeCache // shared by sandboxes
+	sandboxes sync.Pool
+}
+
+// Sandbox is an interpreter on its own copy of the module state.
+type Sandbox struct {
+	runner *runner
+}
+
+// NewPool creates a pool of sandboxes in the state of the snapshot.
+// Lazily decoded functions are validated with all features, since the snapshot is taken from a validated module.
+func NewPool(snapshot *instance.Snapshot) (*Pool, error) {
+	codes, err := compileModule(snapshot.Module(), types.FeaturesAll)
+	if err != nil {
+		return nil, fmt.Errorf("New pool: %w", err)
+	}
+	p := &Pool{
+		snapshot: snapshot,
+		codes:    codes,
+	}
+	p.sandboxes.New = func() any {
+		return &Sandbox{runner: newRunner(p.snapshot.Restore(), p.codes, nil)}
+	}
+	return p, nil
+}
+
+// Get returns a sandbox in the state of the snapshot.
+// A sandbox must not be used after it is returned by Put.
+func (p *Pool) Get() *Sandbox {
+	return p.sandboxes.Get().(*Sandbox)
+}
+
+// Put resets the sandbox to the state of the snapshot and returns it to the pool.
+// Only memory pages written in the sandbox are copied back.
+func (p *Pool) Put(s *Sandbox) error {
+	if err := p.snapshot.Reset(s.runner.instance); err != nil {
+		return fmt.Errorf("Put sandbox: %w", err)
+	}
+	p.sandboxes.Put(s)
+	return nil
+}
+
+// Invoke runs the exported function in the sandbox.
+func (s *Sandbox) Invoke(name string, locals []value.Value) ([]value.Value, error) {
+	return s.runner.Invoke(name, locals)
+}
+
+// Instance returns the module instance of the sandbox.
+func (s *Sandbox) Instance() *instance.Module {
+	return s.runner.instance
+}
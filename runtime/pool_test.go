@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/runtime/debugger"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+func TestPool(t *testing.T) {
+	dec, err := decoder.New("../examples/memory.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	r, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	snapshot, err := Snapshot(r)
+	require.NoError(t, err)
+	pool, err := NewPool(snapshot)
+	require.NoError(t, err)
+
+	sb := pool.Get()
+	res, err := sb.Invoke("i32_load16_u", []value.Value{value.I32(0xbeef)})
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.I32(0xbeef)}, res)
+	assert.Equal(t, []byte{0xef, 0xbe}, sb.Instance().MemAddrs[0].Data[8:10])
+	// the store in the sandbox doesn't reach the snapshot source
+	assert.Equal(t, []byte{0, 0}, snapshot.Module().MemAddrs[0].Data[8:10])
+
+	require.NoError(t, pool.Put(sb))
+	assert.Equal(t, []byte{0, 0}, sb.Instance().MemAddrs[0].Data[8:10])
+	res, err = sb.Invoke("data", []value.Value{})
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.I32(1)}, res)
+}
+
+func TestPool_Concurrent(t *testing.T) {
+	dec, err := decoder.New("../examples/memory.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	r, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+	snapshot, err := Snapshot(r)
+	require.NoError(t, err)
+	pool, err := NewPool(snapshot)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				sb := pool.Get()
+				res, err := sb.Invoke("data", []value.Value{})
+				assert.NoError(t, err)
+				assert.Equal(t, []value.Value{value.I32(1)}, res)
+				res, err = sb.Invoke("i32_load8_u", []value.Value{value.I32(g*10 + n)})
+				assert.NoError(t, err)
+				assert.Equal(t, []value.Value{value.I32(g*10 + n)}, res)
+				assert.NoError(t, pool.Put(sb))
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,209 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+const (
+	profileSampleType        uint64 = 1
+	profileSample            uint64 = 2
+	profileMapping           uint64 = 3
+	profileLocation          uint64 = 4
+	profileFunction          uint64 = 5
+	profileStringTable       uint64 = 6
+	profileTimeNanos         uint64 = 9
+	profileDurationNanos     uint64 = 10
+	profilePeriodType        uint64 = 11
+	profilePeriod            uint64 = 12
+	profileDefaultSampleType uint64 = 14
+
+	valueTypeType uint64 = 1
+	valueTypeUnit uint64 = 2
+
+	sampleLocationId uint64 = 1
+	sampleValue      uint64 = 2
+
+	mappingId           uint64 = 1
+	mappingMemoryStart  uint64 = 2
+	mappingMemoryLimit  uint64 = 3
+	mappingFilename     uint64 = 5
+	mappingHasFunctions uint64 = 7
+
+	locationId        uint64 = 1
+	locationMappingId uint64 = 2
+	locationAddress   uint64 = 3
+	locationLine      uint64 = 4
+
+	lineFunctionId uint64 = 1
+
+	functionId         uint64 = 1
+	functionName       uint64 = 2
+	functionSystemName uint64 = 3
+)
+
+const (
+	wireVarint uint64 = 0
+	wireBytes  uint64 = 2
+)
+
+// WriteProfile writes the profile in the gzipped pprof format.
+// Each wasm function is a location whose address is the function index.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	strs := newStringTable()
+	buf := &protoBuffer{}
+	buf.message(profileSampleType, func(b *protoBuffer) {
+		b.int64(valueTypeType, strs.index("instructions"))
+		b.int64(valueTypeUnit, strs.index("count"))
+	})
+	buf.message(profileSampleType, func(b *protoBuffer) {
+		b.int64(valueTypeType, strs.index("time"))
+		b.int64(valueTypeUnit, strs.index("nanoseconds"))
+	})
+	keys := make([]string, 0, len(p.samples))
+	for k := range p.samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := p.samples[k]
+		ids := make([]uint64, 0, len(s.stack))
+		for _, f := range s.stack {
+			ids = append(ids, f.id)
+		}
+		buf.message(profileSample, func(b *protoBuffer) {
+			b.packedUint64(sampleLocationId, ids)
+			b.packedInt64(sampleValue, []int64{int64(s.instructions), s.duration.Nanoseconds()})
+		})
+	}
+	funcs := make([]*function, 0, len(p.functions))
+	for _, f := range p.functions {
+		funcs = append(funcs, f)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].id < funcs[j].id })
+	buf.message(profileMapping, func(b *protoBuffer) {
+		b.uint64(mappingId, 1)
+		b.uint64(mappingMemoryStart, 0)
+		b.uint64(mappingMemoryLimit, uint64(len(funcs)+1))
+		b.int64(mappingFilename, strs.index("wasm"))
+		b.uint64(mappingHasFunctions, 1)
+	})
+	for _, f := range funcs {
+		buf.message(profileLocation, func(b *protoBuffer) {
+			b.uint64(locationId, f.id)
+			b.uint64(locationMappingId, 1)
+			b.uint64(locationAddress, uint64(f.index))
+			b.message(locationLine, func(l *protoBuffer) {
+				l.uint64(lineFunctionId, f.id)
+			})
+		})
+	}
+	for _, f := range funcs {
+		buf.message(profileFunction, func(b *protoBuffer) {
+			b.uint64(functionId, f.id)
+			b.int64(functionName, strs.index(f.name))
+			b.int64(functionSystemName, strs.index(fmt.Sprintf("func[%d]", f.index)))
+		})
+	}
+	buf.int64(profileTimeNanos, p.start.UnixNano())
+	buf.int64(profileDurationNanos, p.duration.Nanoseconds())
+	buf.message(profilePeriodType, func(b *protoBuffer) {
+		b.int64(valueTypeType, strs.index("instructions"))
+		b.int64(valueTypeUnit, strs.index("count"))
+	})
+	buf.int64(profilePeriod, 1)
+	buf.int64(profileDefaultSampleType, strs.index("instructions"))
+	// the string table must be written after all strings are registered
+	for _, s := range strs.strs {
+		buf.string(profileStringTable, s)
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(buf.data); err != nil {
+		return fmt.Errorf("write profile: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("write profile: %w", err)
+	}
+	return nil
+}
+
+type stringTable struct {
+	strs    []string
+	indexes map[string]int64
+}
+
+func newStringTable() *stringTable {
+	// the first entry must be an empty string
+	return &stringTable{strs: []string{""}, indexes: map[string]int64{"": 0}}
+}
+
+func (t *stringTable) index(s string) int64 {
+	if i, ok := t.indexes[s]; ok {
+		return i
+	}
+	t.indexes[s] = int64(len(t.strs))
+	t.strs = append(t.strs, s)
+	return t.indexes[s]
+}
+
+// protoBuffer is a minimal protocol buffers encoder.
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		b.data = append(b.data, byte(v)|0x80)
+		v >>= 7
+	}
+	b.data = append(b.data, byte(v))
+}
+
+func (b *protoBuffer) key(field, wire uint64) {
+	b.varint(field<<3 | wire)
+}
+
+func (b *protoBuffer) uint64(field, v uint64) {
+	b.key(field, wireVarint)
+	b.varint(v)
+}
+
+func (b *protoBuffer) int64(field uint64, v int64) {
+	b.uint64(field, uint64(v))
+}
+
+func (b *protoBuffer) bytes(field uint64, v []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(v)))
+	b.data = append(b.data, v...)
+}
+
+func (b *protoBuffer) string(field uint64, v string) {
+	b.bytes(field, []byte(v))
+}
+
+func (b *protoBuffer) packedUint64(field uint64, values []uint64) {
+	packed := &protoBuffer{}
+	for _, v := range values {
+		packed.varint(v)
+	}
+	b.bytes(field, packed.data)
+}
+
+func (b *protoBuffer) packedInt64(field uint64, values []int64) {
+	packed := &protoBuffer{}
+	for _, v := range values {
+		packed.varint(uint64(v))
+	}
+	b.bytes(field, packed.data)
+}
+
+func (b *protoBuffer) message(field uint64, f func(*protoBuffer)) {
+	m := &protoBuffer{}
+	f(m)
+	b.bytes(field, m.data)
+}
@@ -0,0 +1,214 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+)
+
+// Profiler records how many instructions and how much time are spent in each function.
+// Every executed instruction is recorded as a sample with the call stack at that time,
+// so that inclusive and exclusive costs can be derived like pprof does.
+type Profiler struct {
+	start     time.Time
+	last      time.Time
+	duration  time.Duration
+	current   *sample
+	depth     int
+	top       *instance.Function
+	functions map[*instance.Function]*function
+	samples   map[string]*sample
+	opcodes   map[string]uint64
+}
+
+type function struct {
+	id    uint64 // id in the pprof profile, starts from 1
+	index int
+	name  string
+	calls uint64
+}
+
+type sample struct {
+	stack        []*function // leaf first
+	instructions uint64
+	duration     time.Duration
+}
+
+type FunctionStat struct {
+	Index                 int
+	Name                  string
+	Calls                 uint64
+	Instructions          uint64 // executed in the function itself
+	InclusiveInstructions uint64 // executed in the function and its callees
+	Time                  time.Duration
+	InclusiveTime         time.Duration
+}
+
+type OpcodeStat struct {
+	Name  string
+	Count uint64
+}
+
+func New() *Profiler {
+	return &Profiler{
+		functions: make(map[*instance.Function]*function),
+		samples:   make(map[string]*sample),
+		opcodes:   make(map[string]uint64),
+	}
+}
+
+// Start is called when an invocation starts.
+func (p *Profiler) Start() {
+	now := time.Now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	p.last = now
+	p.current = nil
+	p.depth = 0
+	p.top = nil
+}
+
+// Stop is called when an invocation finishes.
+func (p *Profiler) Stop() {
+	now := time.Now()
+	if p.current != nil {
+		p.current.duration += now.Sub(p.last)
+	}
+	p.duration += now.Sub(p.last)
+	p.current = nil
+}
+
+// Step records the instruction which is going to be executed.
+func (p *Profiler) Step(stck *stack.Stack, instr instruction.Instruction) {
+	now := time.Now()
+	if p.current != nil {
+		p.current.duration += now.Sub(p.last)
+	}
+	p.duration += now.Sub(p.last)
+	p.last = now
+	frame, err := stck.TopFrame()
+	if err != nil || frame.Function == nil {
+		return
+	}
+	depth := stck.LenFrame()
+	if p.current == nil || depth != p.depth || frame.Function != p.top {
+		if p.current == nil || depth > p.depth || frame.Function != p.top && depth == p.depth {
+			p.function(frame).calls++
+		}
+		p.current = p.lookup(stck)
+		p.depth = depth
+		p.top = frame.Function
+	}
+	p.current.instructions++
+	p.opcodes[instr.String()]++
+}
+
+func (p *Profiler) function(frame *stack.Frame) *function {
+	if f, ok := p.functions[frame.Function]; ok {
+		return f
+	}
+	f := &function{id: uint64(len(p.functions) + 1), index: -1, name: "unknown"}
+	if frame.Module != nil {
+		f.index = frame.Module.FuncIndex(frame.Function)
+		f.name = frame.Module.FuncName(frame.Function)
+	}
+	p.functions[frame.Function] = f
+	return f
+}
+
+func (p *Profiler) lookup(stck *stack.Stack) *sample {
+	frames := stck.Frames()
+	funcs := make([]*function, 0, len(frames))
+	ids := make([]string, 0, len(frames))
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i].Function == nil {
+			continue
+		}
+		f := p.function(&frames[i])
+		funcs = append(funcs, f)
+		ids = append(ids, strconv.FormatUint(f.id, 10))
+	}
+	key := strings.Join(ids, ";")
+	s, ok := p.samples[key]
+	if !ok {
+		s = &sample{stack: funcs}
+		p.samples[key] = s
+	}
+	return s
+}
+
+// Functions returns statistics of called functions sorted by exclusive instruction counts.
+func (p *Profiler) Functions() []*FunctionStat {
+	stats := make(map[*function]*FunctionStat, len(p.functions))
+	for _, f := range p.functions {
+		stats[f] = &FunctionStat{Index: f.index, Name: f.name, Calls: f.calls}
+	}
+	for _, s := range p.samples {
+		if len(s.stack) == 0 {
+			continue
+		}
+		stats[s.stack[0]].Instructions += s.instructions
+		stats[s.stack[0]].Time += s.duration
+		// count once even if the function is recursive
+		seen := make(map[*function]bool, len(s.stack))
+		for _, f := range s.stack {
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+			stats[f].InclusiveInstructions += s.instructions
+			stats[f].InclusiveTime += s.duration
+		}
+	}
+	res := make([]*FunctionStat, 0, len(stats))
+	for _, stat := range stats {
+		res = append(res, stat)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Instructions != res[j].Instructions {
+			return res[i].Instructions > res[j].Instructions
+		}
+		return res[i].Index < res[j].Index
+	})
+	return res
+}
+
+// Opcodes returns the histogram of executed instructions sorted by counts.
+func (p *Profiler) Opcodes() []*OpcodeStat {
+	res := make([]*OpcodeStat, 0, len(p.opcodes))
+	for name, count := range p.opcodes {
+		res = append(res, &OpcodeStat{Name: name, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Report writes the human readable summary.
+func (p *Profiler) Report(w io.Writer) {
+	total := uint64(0)
+	for _, s := range p.samples {
+		total += s.instructions
+	}
+	fmt.Fprintf(w, "Profile: %d instructions in %s\n\n", total, p.duration)
+	fmt.Fprintf(w, "%8s %12s %12s %12s %12s  %s\n", "calls", "flat", "cum", "flat time", "cum time", "function")
+	for _, f := range p.Functions() {
+		fmt.Fprintf(w, "%8d %12d %12d %12s %12s  %s\n", f.Calls, f.Instructions, f.InclusiveInstructions, f.Time, f.InclusiveTime, f.Name)
+	}
+	fmt.Fprintf(w, "\n%12s  %s\n", "count", "instruction")
+	for _, o := range p.Opcodes() {
+		fmt.Fprintf(w, "%12d  %s\n", o.Count, o.Name)
+	}
+}
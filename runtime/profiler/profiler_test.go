@@ -0,0 +1,83 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+)
+
+func TestProfiler_Step(t *testing.T) {
+	caller := &instance.Function{}
+	callee := &instance.Function{}
+	mod := &instance.Module{
+		FuncAddrs: []*instance.Function{caller, callee},
+		Exports:   []*instance.Export{{Name: "main", Value: caller}},
+	}
+	callerFrame := stack.Frame{Module: mod, Function: caller}
+	calleeFrame := stack.Frame{Module: mod, Function: callee}
+	p := New()
+	p.Start()
+	// main: i32.const, call func[1]
+	s, err := stack.WithValue(nil, []stack.Frame{callerFrame}, nil)
+	require.NoError(t, err)
+	p.Step(s, &instruction.I32Const{})
+	p.Step(s, &instruction.Call{})
+	// func[1] is called twice and executes two instructions each time
+	for i := 0; i < 2; i++ {
+		s, err = stack.WithValue(nil, []stack.Frame{callerFrame, calleeFrame}, nil)
+		require.NoError(t, err)
+		p.Step(s, &instruction.I32Const{})
+		p.Step(s, &instruction.End{})
+		s, err = stack.WithValue(nil, []stack.Frame{callerFrame}, nil)
+		require.NoError(t, err)
+		p.Step(s, &instruction.Call{})
+	}
+	p.Stop()
+
+	stats := p.Functions()
+	require.Len(t, stats, 2)
+	// ties are sorted by the function index
+	assert.Equal(t, "main", stats[0].Name)
+	assert.Equal(t, uint64(1), stats[0].Calls)
+	assert.Equal(t, uint64(4), stats[0].Instructions)
+	assert.Equal(t, uint64(8), stats[0].InclusiveInstructions)
+	assert.Equal(t, "func[1]", stats[1].Name)
+	assert.Equal(t, uint64(2), stats[1].Calls)
+	assert.Equal(t, uint64(4), stats[1].Instructions)
+	assert.Equal(t, uint64(4), stats[1].InclusiveInstructions)
+	assert.Equal(t, []*OpcodeStat{
+		{Name: "call", Count: 3},
+		{Name: "i32.const", Count: 3},
+		{Name: "end", Count: 2},
+	}, p.Opcodes())
+}
+
+func TestProfiler_WriteProfile(t *testing.T) {
+	f := &instance.Function{}
+	mod := &instance.Module{
+		FuncAddrs: []*instance.Function{f},
+		Exports:   []*instance.Export{{Name: "main", Value: f}},
+	}
+	s, err := stack.WithValue(nil, []stack.Frame{{Module: mod, Function: f}}, nil)
+	require.NoError(t, err)
+	p := New()
+	p.Start()
+	p.Step(s, &instruction.Nop{})
+	p.Stop()
+	buf := &bytes.Buffer{}
+	require.NoError(t, p.WriteProfile(buf))
+	r, err := gzip.NewReader(buf)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "main")
+	assert.Contains(t, string(data), "func[0]")
+	assert.Contains(t, string(data), "instructions")
+}
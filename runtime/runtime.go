@@ -3,6 +3,7 @@ package runtime
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/terassyi/gowi/instruction"
 	"github.com/terassyi/gowi/runtime/debugger"
@@ -20,21 +21,40 @@ var (
 	FunctionParamTypesDoesntMatch error = errors.New("Function parameter type doesn't match")
 )
 
+// Interpreter invokes exported functions of an instantiated module.
+// Invoke is safe for concurrent use by multiple goroutines. Each invocation runs on its own stack,
+// while the memories, tables and globals of the instance are shared by all of them.
+// Loads and stores access a memory under its read lock, so the memory can grow during other invocations.
+// Invocations of an interpreter created with a debugger are serialized because a debugger observes one invocation at a time.
 type Interpreter interface {
 	Invoke(string, []value.Value) ([]value.Value, error)
 }
 
-type interpreter struct {
+// runner is an instantiated module which is shared by invocations.
+type runner struct {
 	instance *instance.Module
-	stack    *stack.Stack
-	debubber *debugger.Debugger
-	f        *instance.Function // next function
-	cur      *current
+	codes    *codeCache
+	debugger *debugger.Debugger
+	mu       sync.Mutex // held while the debugger observes an invocation
+	stacks   sync.Pool
+}
+
+// interpreter is the execution context of an invocation.
+type interpreter struct {
+	instance   *instance.Module
+	stack      *stack.Stack
+	debubber   *debugger.Debugger
+	f          *instance.Function // next function
+	cur        *current
+	codes      *codeCache
+	checkpoint *checkpointer // nil when checkpoints are not taken
+	caught     []caughtException
 }
 
 type current struct {
 	frame *stack.Frame
 	label *stack.Label
+	code  *compiledFunction
 }
 
 func (c *current) update(s *stack.Stack) error {
@@ -65,43 +85,93 @@ func (c *current) updateFrame(s *stack.Stack) error {
 // instanciate an interpreter
 // https://webassembly.github.io/spec/core/exec/modules.html#instantiation
 func New(mod *structure.Module, externalvals []instance.ExternalValue, debugLevel debugger.DebugLevel) (Interpreter, error) {
-	v, err := validator.New(mod)
+	if debugLevel == debugger.DebugLevelNoLog {
+		return NewWithDebugger(mod, externalvals, nil)
+	}
+	return NewWithDebugger(mod, externalvals, debugger.New(debugLevel))
+}
+
+// NewWithDebugger instanciates an interpreter with the configured debugger.
+// d may be nil to run without observing the execution.
+func NewWithDebugger(mod *structure.Module, externalvals []instance.ExternalValue, d *debugger.Debugger) (Interpreter, error) {
+	return NewWithFeatures(mod, externalvals, d, types.FeaturesAll)
+}
+
+// NewWithFeatures instanciates an interpreter which rejects modules using proposals not in the features.
+// Lazily decoded functions are checked on their first call.
+func NewWithFeatures(mod *structure.Module, externalvals []instance.ExternalValue, d *debugger.Debugger, features types.Features) (Interpreter, error) {
+	v, err := validator.NewWithFeatures(mod, features)
 	if err != nil {
 		return nil, fmt.Errorf("New interpreter: \n\t%w", err)
 	}
 	if _, err := v.Validate(); err != nil {
 		return nil, fmt.Errorf("New interpreter: \n\t%w", err)
 	}
-	inst, err := instance.New(mod)
+	inst, err := instance.NewWithExternalValues(mod, externalvals)
 	if err != nil {
 		return nil, fmt.Errorf("New interpreter: \n\t%w", err)
 	}
-	stack := stack.New()
-	return &interpreter{
+	codes, err := compileModule(inst, features)
+	if err != nil {
+		return nil, fmt.Errorf("New interpreter: \n\t%w", err)
+	}
+	return newRunner(inst, codes, d), nil
+}
+
+func newRunner(inst *instance.Module, codes *codeCache, d *debugger.Debugger) *runner {
+	return &runner{
 		instance: inst,
-		stack:    stack,
+		codes:    codes,
+		debugger: d,
+		stacks: sync.Pool{
+			New: func() any { return stack.New() },
+		},
+	}
+}
+
+// Invoke runs the exported function on a new execution context.
+func (r *runner) Invoke(name string, locals []value.Value) ([]value.Value, error) {
+	return r.invoke(func(i *interpreter) ([]value.Value, error) {
+		return i.Invoke(name, locals)
+	})
+}
+
+// invoke runs fn on a new execution context.
+func (r *runner) invoke(fn func(*interpreter) ([]value.Value, error)) ([]value.Value, error) {
+	if r.debugger != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+	}
+	s := r.stacks.Get().(*stack.Stack)
+	defer r.stacks.Put(s)
+	i := &interpreter{
+		instance: r.instance,
+		stack:    s,
 		cur:      &current{},
-		debubber: debugger.New(debugLevel),
-	}, nil
+		debubber: r.debugger,
+		codes:    r.codes,
+	}
+	return fn(i)
 }
 
+// Invoke runs the exported function on the stack of the context.
+// The stack is emptied when it returns, so the context can be reused even after a trap.
 func (i *interpreter) Invoke(name string, locals []value.Value) ([]value.Value, error) {
-	i.debubber.ShowInfo(name)
-	ext, err := i.instance.GetExport(name)
+	defer i.reset()
+	if i.debubber != nil {
+		i.debubber.ShowInfo(name)
+	}
+	f, err := i.exportedFunction(name)
 	if err != nil {
 		return nil, fmt.Errorf("Invoke: \n\t%w", err)
 	}
-	if ext.ExternalValueType() != instance.ExternalValueTypeFunc {
-		return nil, fmt.Errorf("Invoke: \n\t%w", FunctionIsRequired)
-	}
-	f := instance.GetExternVal[*instance.Function](ext)
 	if err := validateLocals(f, locals); err != nil {
 		return nil, fmt.Errorf("Invoke: \n\t%w", err)
 	}
-	if err := i.stack.PushFrame(stack.Frame{Module: nil, Locals: nil}); err != nil {
+	if err := i.stack.PushFrame(stack.Frame{Module: nil}); err != nil {
 		return nil, fmt.Errorf("Invoke: \n\t%w", err)
 	}
-	if err := i.stack.PushLabel(stack.Label{Instructions: nil, N: 0}); err != nil {
+	if err := i.stack.PushLabel(stack.Label{N: 0}); err != nil {
 		return nil, fmt.Errorf("Invoke: \n\t%w", err)
 	}
 	for _, v := range locals {
@@ -122,94 +192,112 @@ func (i *interpreter) Invoke(name string, locals []value.Value) ([]value.Value,
 	return res, nil
 }
 
+func (i *interpreter) exportedFunction(name string) (*instance.Function, error) {
+	ext, err := i.instance.GetExport(name)
+	if err != nil {
+		return nil, err
+	}
+	if ext.ExternalValueType() != instance.ExternalValueTypeFunc {
+		return nil, FunctionIsRequired
+	}
+	return instance.GetExternVal[*instance.Function](ext), nil
+}
+
 // https://webassembly.github.io/spec/core/exec/instructions.html#invocation-of-function-address-a
 func (i *interpreter) invokeFunction(f *instance.Function) error {
-	// valudate local arguments and values on the stack
-	if err := i.stack.ValidateValue(f.Type.Params); err != nil {
+	// a lazily decoded function is compiled on the first call
+	code, err := i.compiled(f)
+	if err != nil {
 		return fmt.Errorf("Invoke function: %w", err)
 	}
-	// get function arguments from the value stack
-	locals, err := i.stack.PopValuesRev(len(f.Type.Params))
+	// get function arguments from the value stack, they are validated against the params
+	locals, err := i.stack.PopLocals(code.locals, len(f.Type.Params))
 	if err != nil {
 		return fmt.Errorf("Invoke function: %w", err)
 	}
-	locals = append(locals, initLocalValues(f.Code.Locals)...)
-	if err := i.stack.PushFrame(stack.Frame{Module: f.Module, Locals: locals}); err != nil {
+	if err := i.stack.PushFrame(stack.Frame{Module: f.Module, Locals: locals, Function: f}); err != nil {
 		return fmt.Errorf("Invoke function: %w", err)
 	}
-	if err := i.stack.PushLabel(stack.Label{Instructions: f.Code.Body, N: uint8(len(f.Type.Returns)), Sp: 0, Type: stack.LabelTypeFunction}); err != nil {
+	if err := i.stack.PushLabel(stack.Label{N: uint8(len(f.Type.Returns)), Type: stack.LabelTypeFunction, Pc: len(code.body), Height: i.stack.Len()}); err != nil {
 		return fmt.Errorf("Invoke function: %w", err)
 	}
-	// sync current frame and label with top of the stack
-	if err := i.cur.update(i.stack); err != nil {
+	// sync current frame, label and code with top of the stack
+	if err := i.updateCurrent(); err != nil {
 		return fmt.Errorf("Invoke function: %w", err)
 	}
 	// execute function instruction
 	return nil
 }
 
-func initLocalValues(locals []types.ValueType) []value.Value {
-	values := make([]value.Value, 0, len(locals))
-	for _, l := range locals {
-		switch l {
-		case types.I32:
-			values = append(values, value.I32(0))
-		case types.I64:
-			values = append(values, value.I64(0))
-		case types.F32:
-			values = append(values, value.F32(0))
-		case types.F64:
-			values = append(values, value.F64(0))
-		}
-	}
-	return values
-}
-
 func (i *interpreter) execute() error {
-	for {
-		// frame := i.cur.frame
-		label := i.cur.label
-		contexSwitch := false
-		if i.stack.LenLabel() <= 1 {
-			return nil
+	for !i.isInvocationFinished() {
+		if i.checkpoint != nil {
+			if err := i.checkpoint.check(i); err != nil {
+				return fmt.Errorf("execute: %w", err)
+			}
+		}
+		frame := i.cur.frame
+		if frame.Pc >= len(i.cur.code.body) {
+			return fmt.Errorf("execute: pc %d is out of the function body", frame.Pc)
 		}
-		for sp := label.Sp; sp < len(label.Instructions); sp++ {
-			instr := label.Instructions[sp]
-			i.debubber.PrintInstr(i.stack, instr)
-			res, err := i.step(instr)
-			if err != nil {
+		instr := i.cur.code.body[frame.Pc]
+		if i.debubber != nil {
+			if err := i.debubber.PrintInstr(i.stack, instr); err != nil {
 				return fmt.Errorf("execute: %w", err)
 			}
-			label.Sp++
-			switch res {
-			case instructionResultTrap:
-				return Trap
-			case instructionResultCallFunc:
-				if i.f == nil {
-					return fmt.Errorf("execute: called function is not found")
-				}
-				if err := i.invokeFunction(i.f); err != nil {
-					return fmt.Errorf("execute: %w", err)
-				}
-				contexSwitch = true
-			case instructionResultEnterBlock:
-				if err := i.cur.update(i.stack); err != nil {
-					return fmt.Errorf("execute: %w", err)
-				}
-				contexSwitch = true
-			case instructionResultLabelEnd, instructionResultReturn:
-				if i.isInvocationFinished() {
-					return nil
-				}
-				contexSwitch = true
-			case instructionResultRunNext:
-				// go to next step
+		}
+		// branches overwrite the position of the next instruction
+		frame.Pc++
+		res, err := i.step(instr)
+		if err != nil {
+			return fmt.Errorf("execute: %w", err)
+		}
+		switch res {
+		case instructionResultTrap:
+			return Trap
+		case instructionResultCallFunc:
+			if i.f == nil {
+				return fmt.Errorf("execute: called function is not found")
 			}
-			if contexSwitch {
-				break
+			if err := i.invokeFunction(i.f); err != nil {
+				return fmt.Errorf("execute: %w", err)
 			}
+		case instructionResultRunNext, instructionResultEnterBlock, instructionResultLabelEnd, instructionResultReturn:
+			// go to next step
 		}
 	}
+	return nil
+}
+
+// updateCurrent syncs the current frame, label and code with the top of the stack.
+func (i *interpreter) updateCurrent() error {
+	if err := i.cur.update(i.stack); err != nil {
+		return err
+	}
+	if i.cur.frame.Function == nil {
+		// the dummy frame pushed by Invoke
+		i.cur.code = nil
+		return nil
+	}
+	code, err := i.compiled(i.cur.frame.Function)
+	if err != nil {
+		return err
+	}
+	i.cur.code = code
+	return nil
+}
+
+// compiled returns the compiled body of f. f is compiled when it is not compiled yet.
+func (i *interpreter) compiled(f *instance.Function) (*compiledFunction, error) {
+	if i.codes == nil {
+		i.codes = newCodeCache(types.FeaturesAll)
+	}
+	return i.codes.get(f)
+}
+
+// pc returns the position of the executing instruction.
+func (i *interpreter) pc() int {
+	return i.cur.frame.Pc - 1
 }
 
 // https://webassembly.github.io/spec/core/exec/instructions.html#returning-from-a-function
@@ -221,19 +309,23 @@ func (i *interpreter) finishInvoke(f *instance.Function) ([]value.Value, error)
 	if err != nil {
 		return nil, fmt.Errorf("finish: %w", err)
 	}
-	i.debubber.ShowResult(values)
+	if i.debubber != nil {
+		i.debubber.ShowResult(values)
+	}
 	return values, nil
 }
 
+// reset drops the state left by the invocation.
+func (i *interpreter) reset() {
+	i.stack.Reset()
+	i.f = nil
+	i.cur = &current{}
+	i.caught = nil
+}
+
 func (i *interpreter) isInvocationFinished() bool {
-	if i.stack.LenLabel() > 1 || i.stack.IsLabelEmpty() {
-		return false
-	}
-	if i.stack.LenFrame() == 1 {
-		// frame stack: dummy
-		return true
-	}
-	return false
+	// frame stack: dummy
+	return i.stack.LenFrame() <= 1
 }
 
 func (i *interpreter) step(instr instruction.Instruction) (instructionResult, error) {
@@ -252,6 +344,18 @@ func (i *interpreter) step(instr instruction.Instruction) (instructionResult, er
 		return i.execLoop(instr)
 	case instruction.IF:
 		return i.execIf(instr)
+	case instruction.ELSE:
+		return i.execElse(instr)
+	case instruction.TRY:
+		return i.execBlock(instr)
+	case instruction.CATCH, instruction.CATCH_ALL:
+		return i.execCatch(instr)
+	case instruction.THROW:
+		return i.execThrow(instr)
+	case instruction.RETHROW:
+		return i.execRethrow(instr)
+	case instruction.DELEGATE:
+		return i.execLabelEnd(instr)
 	case instruction.BR:
 		return i.execBr(instr)
 	case instruction.BR_IF:
@@ -289,10 +393,18 @@ func (i *interpreter) step(instr instruction.Instruction) (instructionResult, er
 	case instruction.I32_EQZ, instruction.I64_EQZ,
 		instruction.I32_CLZ, instruction.I64_CLZ,
 		instruction.I32_CTZ, instruction.I64_CTZ,
-		instruction.I32_POPCNT, instruction.I64_POPCNT:
+		instruction.I32_POPCNT, instruction.I64_POPCNT,
+		instruction.I32_EXTEND8_S, instruction.I32_EXTEND16_S,
+		instruction.I64_EXTEND8_S, instruction.I64_EXTEND16_S, instruction.I64_EXTEND32_S:
 		return i.execUnop(instr)
 	case instruction.CALL:
 		return i.execCall(instr)
+	case instruction.CALL_INDIRECT:
+		return i.execCallIndirect(instr)
+	case instruction.RETURN_CALL:
+		return i.execReturnCall(instr)
+	case instruction.RETURN_CALL_INDIRECT:
+		return i.execReturnCallIndirect(instr)
 	case instruction.END:
 		return i.execLabelEnd(instr)
 	case instruction.I32_LOAD, instruction.I64_LOAD,
@@ -307,37 +419,29 @@ func (i *interpreter) step(instr instruction.Instruction) (instructionResult, er
 		instruction.I32_STORE16, instruction.I64_STORE16,
 		instruction.I64_STORE32:
 		return i.execStore(instr)
+	case instruction.CURRENT_MEMORY, instruction.GROW_MEMORY:
+		return i.execMemory(instr)
+	case instruction.SIMD:
+		return i.execVector(instr)
+	case instruction.ATOMIC:
+		return i.execAtomic(instr)
 	default:
 		// return instruction.InvalidOpcode
 		return instructionResultTrap, nil
 	}
 }
 
-func (i *interpreter) restoreStack() error {
+func (i *interpreter) restoreStack() (stack.Label, error) {
 	label, err := i.stack.PopLabel()
 	if err != nil {
-		return fmt.Errorf("restore: %w", err)
+		return stack.Label{}, fmt.Errorf("restore: %w", err)
 	}
 	if label.IsFunction() {
 		if _, err := i.stack.PopFrame(); err != nil {
-			return fmt.Errorf("restore: %w", err)
+			return stack.Label{}, fmt.Errorf("restore: %w", err)
 		}
 	}
-	return i.cur.update(i.stack)
-}
-
-func (i *interpreter) expand(block types.BlockType) (*types.FuncType, error) {
-	switch types.ValueType(block) {
-	case types.I32, types.I64, types.F32, types.F64:
-		return &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{types.ValueType(block)}}, nil
-	case types.BLOCKTYPE:
-		return &types.FuncType{Params: types.ResultType{}, Returns: types.ResultType{}}, nil
-	default:
-		if int(block) >= len(i.cur.frame.Module.Types) {
-			return nil, fmt.Errorf("expand: function type is not found")
-		}
-		return i.cur.frame.Module.Types[int(block)], nil
-	}
+	return label, i.updateCurrent()
 }
 
 func validateLocals(f *instance.Function, locals []value.Value) error {
@@ -346,8 +450,16 @@ func validateLocals(f *instance.Function, locals []value.Value) error {
 		return fmt.Errorf("%w: expected=%d actual=%d", FunctionParamsDoesntMatch, len(params), len(locals))
 	}
 	for i, p := range params {
-		l := locals[i]
-		if l.ValType() != value.ValTypeNum || !p.IsNumber() {
+		switch l := locals[i].(type) {
+		case value.Number:
+			if !l.ValidateValueType(p) {
+				return fmt.Errorf("%w: param %d: expected=%s", FunctionParamTypesDoesntMatch, i, p)
+			}
+		case value.Vector:
+			if p != types.V128 {
+				return fmt.Errorf("%w: param %d: expected=%s", FunctionParamTypesDoesntMatch, i, p)
+			}
+		default:
 			return FunctionParamTypesDoesntMatch
 		}
 	}
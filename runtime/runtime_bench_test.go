@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/runtime/debugger"
+	"github.com/terassyi/gowi/runtime/value"
+)
+
+func benchmarkInvoke(b *testing.B, path, export string, args []value.Value) {
+	dec, err := decoder.New(path)
+	require.NoError(b, err)
+	mod, err := dec.Decode()
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		// instantiate every time to include the cost of allocating the stack
+		interpreter, err := New(mod, nil, debugger.DebugLevelNoLog)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := interpreter.Invoke(export, args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInvoke_FibonacciRecursive(b *testing.B) {
+	benchmarkInvoke(b, "../examples/fibonacci.wasm", "fib_recursive", []value.Value{value.I32(15)})
+}
+
+func BenchmarkInvoke_FibonacciIterative(b *testing.B) {
+	benchmarkInvoke(b, "../examples/fibonacci.wasm", "fib_iterative", []value.Value{value.I32(40)})
+}
+
+func BenchmarkInvoke_Factorial(b *testing.B) {
+	benchmarkInvoke(b, "../examples/factorial.wasm", "factorial", []value.Value{value.I32(10)})
+}
+
+func BenchmarkSandbox_New(b *testing.B) {
+	dec, err := decoder.New("../examples/memory.wasm")
+	require.NoError(b, err)
+	mod, err := dec.Decode()
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		interpreter, err := New(mod, nil, debugger.DebugLevelNoLog)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := interpreter.Invoke("i32_load8_u", []value.Value{value.I32(n)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSandbox_Pool(b *testing.B) {
+	dec, err := decoder.New("../examples/memory.wasm")
+	require.NoError(b, err)
+	mod, err := dec.Decode()
+	require.NoError(b, err)
+	interpreter, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(b, err)
+	snapshot, err := Snapshot(interpreter)
+	require.NoError(b, err)
+	pool, err := NewPool(snapshot)
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		sb := pool.Get()
+		if _, err := sb.Invoke("i32_load8_u", []value.Value{value.I32(n)}); err != nil {
+			b.Fatal(err)
+		}
+		if err := pool.Put(sb); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
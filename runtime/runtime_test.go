@@ -1,6 +1,13 @@
 package runtime
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,6 +16,7 @@ import (
 	"github.com/terassyi/gowi/instruction"
 	"github.com/terassyi/gowi/runtime/debugger"
 	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/profiler"
 	"github.com/terassyi/gowi/runtime/stack"
 	"github.com/terassyi/gowi/runtime/value"
 	"github.com/terassyi/gowi/types"
@@ -38,8 +46,10 @@ func TestInvoke_Numeric(t *testing.T) {
 	}{
 		{path: "../examples/const.wasm", export: "i32", args: []value.Value{}, exp: []value.Value{value.I32(1)}},
 		{path: "../examples/const.wasm", export: "i64", args: []value.Value{}, exp: []value.Value{value.I64(0x1ff)}},
-		// {path: "../examples/const.wasm", export: "f32", args: []value.Value{}, exp: []value.Value{value.F32(0.1)}},
-		// {path: "../examples/const.wasm", export: "f64", args: []value.Value{}, exp: []value.Value{value.F64(0.1)}},
+		{path: "../examples/const.wasm", export: "f32", args: []value.Value{}, exp: []value.Value{value.F32(math.Copysign(0, -1))}},
+		{path: "../examples/const.wasm", export: "f64", args: []value.Value{}, exp: []value.Value{value.F64(0.1)}},
+		{path: "../examples/float.wasm", export: "f32_id", args: []value.Value{value.F32(1.5)}, exp: []value.Value{value.F32(1.5)}},
+		{path: "../examples/float.wasm", export: "f64_id", args: []value.Value{value.F64(math.Inf(-1))}, exp: []value.Value{value.F64(math.Inf(-1))}},
 		{path: "../examples/i32.wasm", export: "add", args: []value.Value{value.I32(1), value.I32(1)}, exp: []value.Value{value.I32(2)}},
 		{path: "../examples/i32.wasm", export: "add", args: []value.Value{value.I32(1), value.I32(0)}, exp: []value.Value{value.I32(1)}},
 		{path: "../examples/i32.wasm", export: "add", args: []value.Value{value.NewI32(int32(-1)), value.NewI32(int32(-1))}, exp: []value.Value{value.NewI32(int32(-2))}},
@@ -476,6 +486,18 @@ func TestInvoke_Numeric(t *testing.T) {
 		{path: "../examples/i64.wasm", export: "popcnt", args: []value.Value{value.I64(0xAAAAAAAA55555555)}, exp: []value.Value{value.I64(32)}},
 		{path: "../examples/i64.wasm", export: "popcnt", args: []value.Value{value.I64(0x99999999AAAAAAAA)}, exp: []value.Value{value.I64(32)}},
 		{path: "../examples/i64.wasm", export: "popcnt", args: []value.Value{value.I64(0xDEADBEEFDEADBEEF)}, exp: []value.Value{value.I64(48)}},
+		{path: "../examples/sign_extension.wasm", export: "i32.extend8_s", args: []value.Value{value.I32(0x7f)}, exp: []value.Value{value.I32(0x7f)}},
+		{path: "../examples/sign_extension.wasm", export: "i32.extend8_s", args: []value.Value{value.I32(0x80)}, exp: []value.Value{value.NewI32(int32(-128))}},
+		{path: "../examples/sign_extension.wasm", export: "i32.extend8_s", args: []value.Value{value.I32(0x12345680)}, exp: []value.Value{value.NewI32(int32(-128))}},
+		{path: "../examples/sign_extension.wasm", export: "i32.extend16_s", args: []value.Value{value.I32(0x7fff)}, exp: []value.Value{value.I32(0x7fff)}},
+		{path: "../examples/sign_extension.wasm", export: "i32.extend16_s", args: []value.Value{value.I32(0x8000)}, exp: []value.Value{value.NewI32(int32(-32768))}},
+		{path: "../examples/sign_extension.wasm", export: "i32.extend16_s", args: []value.Value{value.I32(0xdeadffff)}, exp: []value.Value{value.NewI32(int32(-1))}},
+		{path: "../examples/sign_extension.wasm", export: "i64.extend8_s", args: []value.Value{value.I64(0xff)}, exp: []value.Value{value.NewI64(int64(-1))}},
+		{path: "../examples/sign_extension.wasm", export: "i64.extend8_s", args: []value.Value{value.I64(0xffffffffffffff7f)}, exp: []value.Value{value.I64(0x7f)}},
+		{path: "../examples/sign_extension.wasm", export: "i64.extend16_s", args: []value.Value{value.I64(0x8000)}, exp: []value.Value{value.NewI64(int64(-32768))}},
+		{path: "../examples/sign_extension.wasm", export: "i64.extend16_s", args: []value.Value{value.I64(0x12347fff)}, exp: []value.Value{value.I64(0x7fff)}},
+		{path: "../examples/sign_extension.wasm", export: "i64.extend32_s", args: []value.Value{value.I64(0x80000000)}, exp: []value.Value{value.NewI64(int64(-2147483648))}},
+		{path: "../examples/sign_extension.wasm", export: "i64.extend32_s", args: []value.Value{value.I64(0x123456787fffffff)}, exp: []value.Value{value.I64(0x7fffffff)}},
 	} {
 		dec, err := decoder.New(d.path)
 		require.NoError(t, err)
@@ -857,32 +879,32 @@ func TestStep(t *testing.T) {
 			expCur:      nil,
 		},
 		{
-			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{}, []stack.Frame{}, []stack.Label{{}}), cur: &current{frame: &stack.Frame{Module: nil, Locals: []value.Value{value.I32(0), value.F64(0.1)}}}},
+			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{}, []stack.Frame{}, []stack.Label{{}}), cur: &current{frame: &stack.Frame{Module: nil, Locals: localsIgnoreError(value.I32(0), value.F64(0.1))}}},
 			instr:       &instruction.GetLocal{Imm: 0},
 			exp:         nil,
 			expStack:    stackWithValueIgnoreError([]value.Value{value.I32(0)}, []stack.Frame{}, []stack.Label{{}}),
-			expCur:      &current{frame: &stack.Frame{Module: nil, Locals: []value.Value{value.I32(0), value.F64(0.1)}}},
+			expCur:      &current{frame: &stack.Frame{Module: nil, Locals: localsIgnoreError(value.I32(0), value.F64(0.1))}},
 		},
 		{
-			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{}, []stack.Frame{}, []stack.Label{{}}), cur: &current{frame: &stack.Frame{Module: nil, Locals: []value.Value{value.I32(0), value.F64(0.1)}}}},
+			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{}, []stack.Frame{}, []stack.Label{{}}), cur: &current{frame: &stack.Frame{Module: nil, Locals: localsIgnoreError(value.I32(0), value.F64(0.1))}}},
 			instr:       &instruction.GetLocal{Imm: 1},
 			exp:         nil,
 			expStack:    stackWithValueIgnoreError([]value.Value{value.F64(0.1)}, []stack.Frame{}, []stack.Label{{}}),
-			expCur:      &current{frame: &stack.Frame{Module: nil, Locals: []value.Value{value.I32(0), value.F64(0.1)}}},
+			expCur:      &current{frame: &stack.Frame{Module: nil, Locals: localsIgnoreError(value.I32(0), value.F64(0.1))}},
 		},
 		{
-			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{value.F64(1.5)}, []stack.Frame{}, []stack.Label{}), cur: &current{frame: &stack.Frame{Module: nil, Locals: []value.Value{value.I32(0), value.F64(0.1)}}}},
+			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{value.F64(1.5)}, []stack.Frame{}, []stack.Label{}), cur: &current{frame: &stack.Frame{Module: nil, Locals: localsIgnoreError(value.I32(0), value.F64(0.1))}}},
 			instr:       &instruction.SetLocal{Imm: 1},
 			exp:         nil,
 			expStack:    stackWithValueIgnoreError([]value.Value{}, []stack.Frame{}, []stack.Label{}),
-			expCur:      &current{frame: &stack.Frame{Module: nil, Locals: []value.Value{value.I32(0), value.F64(1.5)}}, label: nil},
+			expCur:      &current{frame: &stack.Frame{Module: nil, Locals: localsIgnoreError(value.I32(0), value.F64(1.5))}, label: nil},
 		},
 		{
-			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{value.F64(9.0)}, []stack.Frame{}, []stack.Label{}), cur: &current{frame: &stack.Frame{Module: nil, Locals: []value.Value{value.I32(0), value.F64(0.1)}}}},
+			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{value.F64(9.0)}, []stack.Frame{}, []stack.Label{}), cur: &current{frame: &stack.Frame{Module: nil, Locals: localsIgnoreError(value.I32(0), value.F64(0.1))}}},
 			instr:       &instruction.TeeLocal{Imm: 1},
 			exp:         nil,
 			expStack:    stackWithValueIgnoreError([]value.Value{value.F64(9.0)}, []stack.Frame{}, []stack.Label{}),
-			expCur:      &current{frame: &stack.Frame{Module: nil, Locals: []value.Value{value.I32(0), value.F64(9.0)}}, label: nil},
+			expCur:      &current{frame: &stack.Frame{Module: nil, Locals: localsIgnoreError(value.I32(0), value.F64(9.0))}, label: nil},
 		},
 		{
 			interpreter: &interpreter{stack: stackWithValueIgnoreError([]value.Value{value.I32(0xf0)}, []stack.Frame{}, []stack.Label{}), cur: nil},
@@ -906,7 +928,286 @@ func TestStep(t *testing.T) {
 	}
 }
 
+func localsIgnoreError(values ...value.Value) stack.Locals {
+	l, _ := stack.NewLocals(values...)
+	return l
+}
+
 func stackWithValueIgnoreError(values []value.Value, frames []stack.Frame, labels []stack.Label) *stack.Stack {
 	s, _ := stack.WithValue(values, frames, labels)
 	return s
 }
+
+func TestInvoke_Interrupt(t *testing.T) {
+	for _, d := range []struct {
+		path     string
+		export   string
+		args     []value.Value
+		commands string
+		exp      []value.Value
+		expOut   []string
+	}{
+		{
+			path:     "../examples/factorial.wasm",
+			export:   "factorial",
+			args:     []value.Value{value.I32(3)},
+			commands: "break factorial 3\ncontinue\nlocals\nstack\nlabels\ncontinue\ndelete\nfinish\nwhere\ncontinue\n",
+			exp:      []value.Value{value.I32(6)},
+			expOut:   []string{"breakpoint 0 at factorial+3", "factorial+3: if i32", "$0 = i32:3", "[0] function arity=1 pc=14", "factorial+8: get_local $0 (depth=2)"},
+		},
+		{
+			path:     "../examples/factorial.wasm",
+			export:   "factorial",
+			args:     []value.Value{value.I32(1)},
+			commands: "mem 0\ncontinue\n",
+			exp:      []value.Value{value.I32(1)},
+			expOut:   []string{"memory instance is not exist"},
+		},
+		{
+			path:     "../examples/memory.wasm",
+			export:   "i32_load16_u",
+			args:     []value.Value{value.I32(0xbeef)},
+			commands: "mem 0 5\nmem 0xffff 2\ncontinue\n",
+			exp:      []value.Value{value.I32(0xbeef)},
+			expOut:   []string{"00000000  41 42 43 a7 44", "Out of bounds memory access"},
+		},
+	} {
+		dec, err := decoder.New(d.path)
+		require.NoError(t, err)
+		mod, err := dec.Decode()
+		require.NoError(t, err)
+		ins, err := instance.New(mod)
+		require.NoError(t, err)
+		out := &bytes.Buffer{}
+		interpreter := &interpreter{
+			instance: ins,
+			stack:    stack.WithSize(1024, 1024),
+			cur:      &current{},
+			debubber: debugger.WithIO(debugger.DebugLevelInterrupt, strings.NewReader(d.commands), out),
+		}
+		res, err := interpreter.Invoke(d.export, d.args)
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, res)
+		for _, o := range d.expOut {
+			assert.Contains(t, out.String(), o)
+		}
+	}
+}
+
+func TestInvoke_InterruptQuit(t *testing.T) {
+	dec, err := decoder.New("../examples/factorial.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	ins, err := instance.New(mod)
+	require.NoError(t, err)
+	interpreter := &interpreter{
+		instance: ins,
+		stack:    stack.WithSize(1024, 1024),
+		cur:      &current{},
+		debubber: debugger.WithIO(debugger.DebugLevelInterrupt, strings.NewReader("step\nquit\n"), io.Discard),
+	}
+	_, err = interpreter.Invoke("factorial", []value.Value{value.I32(3)})
+	require.ErrorIs(t, err, debugger.ExecutionAborted)
+}
+
+func TestInvoke_Profile(t *testing.T) {
+	dec, err := decoder.New("../examples/factorial.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	ins, err := instance.New(mod)
+	require.NoError(t, err)
+	prof := profiler.New()
+	d := debugger.WithIO(debugger.DebugLevelNoLog, nil, io.Discard)
+	d.SetProfiler(prof)
+	interpreter := &interpreter{
+		instance: ins,
+		stack:    stack.WithSize(1024, 1024),
+		cur:      &current{},
+		debubber: d,
+	}
+	res, err := interpreter.Invoke("factorial", []value.Value{value.I32(5)})
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.I32(120)}, res)
+	stats := prof.Functions()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "factorial", stats[0].Name)
+	assert.Equal(t, uint64(6), stats[0].Calls) // factorial(5) .. factorial(0)
+	assert.Equal(t, stats[0].Instructions, stats[0].InclusiveInstructions)
+}
+
+func TestInvoke_FloatNaNPayload(t *testing.T) {
+	d, err := decoder.New("../examples/float.wasm")
+	require.NoError(t, err)
+	mod, err := d.Decode()
+	require.NoError(t, err)
+	for _, d := range []struct {
+		export string
+		arg    string
+		typ    types.ValueType
+	}{
+		{export: "f32_id", arg: "nan:0x1", typ: types.F32},
+		{export: "f32_id", arg: "-nan", typ: types.F32},
+		{export: "f64_id", arg: "nan:0xdeadbeef", typ: types.F64},
+	} {
+		arg, err := value.FromString(d.arg, d.typ)
+		require.NoError(t, err)
+		r, err := New(mod, nil, debugger.DebugLevelNoLog)
+		require.NoError(t, err)
+		res, err := r.Invoke(d.export, []value.Value{arg})
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, d.arg, res[0].(fmt.Stringer).String())
+	}
+}
+
+func TestInvoke_ReuseAfterTrap(t *testing.T) {
+	dec, err := decoder.New("../examples/i32.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	ins, err := instance.New(mod)
+	require.NoError(t, err)
+	interpreter := &interpreter{
+		instance: ins,
+		stack:    stack.New(),
+		cur:      &current{},
+		debubber: debugger.New(debugger.DebugLevelNoLog),
+	}
+	_, err = interpreter.Invoke("div_s", []value.Value{value.I32(1), value.I32(0)})
+	require.ErrorIs(t, err, ExecutionErrorDivideByZero)
+	assert.Equal(t, 0, interpreter.stack.Len())
+	assert.Equal(t, 0, interpreter.stack.LenFrame())
+	assert.Equal(t, 0, interpreter.stack.LenLabel())
+
+	for n := 0; n < 3; n++ {
+		res, err := interpreter.Invoke("add", []value.Value{value.I32(n), value.I32(1)})
+		require.NoError(t, err)
+		assert.Equal(t, []value.Value{value.I32(n + 1)}, res)
+	}
+}
+
+func TestInvoke_GrowMemory(t *testing.T) {
+	i := newInterpreterFromFile(t, "../examples/grow.wasm")
+	mem := i.(*runner).instance.MemAddrs[0]
+	grown := uint32(0)
+	mem.OnGrow(func(prev, pages uint32) {
+		grown = pages
+	})
+	for _, d := range []struct {
+		name string
+		args []value.Value
+		exp  value.Value
+	}{
+		{name: "size", args: []value.Value{}, exp: value.I32(1)},
+		{name: "grow", args: []value.Value{value.I32(1)}, exp: value.I32(1)},
+		{name: "size", args: []value.Value{}, exp: value.I32(2)},
+		{name: "grow", args: []value.Value{value.I32(2)}, exp: value.I32(0xffffffff)}, // max is 3 pages
+		{name: "grow", args: []value.Value{value.I32(0)}, exp: value.I32(2)},
+	} {
+		res, err := i.Invoke(d.name, d.args)
+		require.NoError(t, err)
+		assert.Equal(t, []value.Value{d.exp}, res)
+	}
+	assert.Equal(t, uint32(2), grown)
+	assert.NoError(t, mem.WriteUint32LE(2*instance.PAGE_SIZE-4, 1))
+}
+
+func TestInvoke_Vector(t *testing.T) {
+	i := newInterpreterFromFile(t, "../examples/simd.wasm")
+	for _, d := range []struct {
+		name string
+		args []value.Value
+		exp  value.Value
+	}{
+		{name: "add", args: []value.Value{value.I32(3), value.I32(4)}, exp: value.I32(7)},
+		{name: "add", args: []value.Value{value.I32(0xffffffff), value.I32(2)}, exp: value.I32(1)},
+		{name: "sum", args: []value.Value{value.I32(0)}, exp: value.I32(10)},
+		{name: "sum", args: []value.Value{value.I32(4)}, exp: value.I32(9)}, // reads 2, 3, 4 and 0
+	} {
+		res, err := i.Invoke(d.name, d.args)
+		require.NoError(t, err)
+		assert.Equal(t, []value.Value{d.exp}, res)
+	}
+	_, err := i.Invoke("sum", []value.Value{value.I32(instance.PAGE_SIZE - 15)})
+	assert.ErrorIs(t, err, MemoryDoesNotHaveEnoughLength)
+}
+
+func TestInvoke_Concurrent(t *testing.T) {
+	dec, err := decoder.New("../examples/fibonacci.wasm")
+	require.NoError(t, err)
+	mod, err := dec.Decode()
+	require.NoError(t, err)
+	r, err := New(mod, nil, debugger.DebugLevelNoLog)
+	require.NoError(t, err)
+
+	exp := []value.Value{value.I32(0), value.I32(1), value.I32(1), value.I32(2), value.I32(3), value.I32(5), value.I32(8), value.I32(13), value.I32(21), value.I32(34)}
+	errs := make(chan error, 64)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for round := 0; round < 3; round++ {
+				for n := 1; n <= 10; n++ {
+					res, err := r.Invoke("fib", []value.Value{value.I32(n)})
+					if err != nil {
+						errs <- err
+						return
+					}
+					if res[0] != exp[n-1] {
+						errs <- fmt.Errorf("fib(%d) = %v, expected %v", n, res[0], exp[n-1])
+						return
+					}
+				}
+				// a trap deep in the recursion doesn't affect the others and leaves the stack to be reused clean
+				if g%2 == 0 {
+					if _, err := r.Invoke("fib_recursive", []value.Value{value.I32(0xffffffff)}); !errors.Is(err, stack.StackLimit) {
+						errs <- fmt.Errorf("unexpected error: %v", err)
+						return
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	s := r.(*runner).stacks.Get().(*stack.Stack)
+	assert.Empty(t, s.Values())
+	assert.Zero(t, s.LenFrame())
+	assert.Zero(t, s.LenLabel())
+}
+
+func TestInvoke_ConcurrentGrow(t *testing.T) {
+	r := newInterpreterFromFile(t, "../examples/memory.wasm")
+	mem := r.(*runner).instance.MemAddrs[0]
+
+	done := make(chan error)
+	go func() {
+		for n := 0; n < 200; n++ {
+			res, err := r.Invoke("i32_load16_s", []value.Value{value.I32(0xffff)})
+			if err != nil {
+				done <- err
+				return
+			}
+			if res[0] != value.I32(0xffffffff) {
+				done <- fmt.Errorf("i32_load16_s = %v", res[0])
+				return
+			}
+		}
+		done <- nil
+	}()
+	// the data moves while the invocation loads and stores
+	for n := 0; n < 16; n++ {
+		_, err := mem.Grow(1)
+		require.NoError(t, err)
+		_, err = mem.ReadBytes(0, 4)
+		require.NoError(t, err)
+	}
+	assert.NoError(t, <-done)
+	assert.Equal(t, uint32(17), mem.Pages())
+}
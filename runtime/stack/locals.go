@@ -0,0 +1,138 @@
+package stack
+
+import (
+	"fmt"
+
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/types"
+)
+
+// Locals stores the locals of a frame as raw 64 bit slots like ValueStack, indexed by the local index.
+// The upper half of a v128 local is kept in high.
+type Locals struct {
+	slots []uint64
+	types []types.ValueType // shared by the frames of the function, never modified
+	high  []uint64          // nil when there is no v128 local
+}
+
+// NewLocals creates locals holding the values.
+func NewLocals(values ...value.Value) (Locals, error) {
+	ts := make([]types.ValueType, 0, len(values))
+	vs := newValueStack(len(values))
+	for _, v := range values {
+		if err := vs.push(v); err != nil {
+			return Locals{}, fmt.Errorf("locals: %w", err)
+		}
+		ts = append(ts, vs.types[len(vs.types)-1])
+	}
+	l := newLocals(ts)
+	for idx, pos := 0, 0; pos < vs.len(); idx, pos = idx+1, pos+slotSize(vs.types[pos]) {
+		l.slots[idx] = vs.slots[pos]
+		if vs.types[pos] == types.V128 {
+			l.high[idx] = vs.slots[pos+1]
+		}
+	}
+	return l, nil
+}
+
+// newLocals creates zero valued locals typed ts.
+func newLocals(ts []types.ValueType) Locals {
+	if len(ts) == 0 {
+		return Locals{}
+	}
+	l := Locals{slots: make([]uint64, len(ts)), types: ts}
+	for _, t := range ts {
+		if t == types.V128 {
+			l.high = make([]uint64, len(ts))
+			break
+		}
+	}
+	return l
+}
+
+// Len returns the number of locals.
+func (l *Locals) Len() int {
+	return len(l.slots)
+}
+
+// Get decodes the local at idx.
+func (l *Locals) Get(idx int) value.Value {
+	vs := ValueStack{slots: []uint64{l.slots[idx]}, types: []types.ValueType{l.types[idx]}}
+	if l.types[idx] == types.V128 {
+		vs.slots = append(vs.slots, l.high[idx])
+		vs.types = append(vs.types, types.V128)
+	}
+	return vs.get(0)
+}
+
+// Values returns all locals in order.
+func (l *Locals) Values() []value.Value {
+	values := make([]value.Value, 0, l.Len())
+	for idx := 0; idx < l.Len(); idx++ {
+		values = append(values, l.Get(idx))
+	}
+	return values
+}
+
+// PopLocals pops the n params of a function typed ts[:n] from the value stack into new locals.
+// The rest of the locals are zero.
+func (s *Stack) PopLocals(ts []types.ValueType, n int) (Locals, error) {
+	size, err := s.Value.slotsOf(n)
+	if err != nil {
+		return Locals{}, fmt.Errorf("pop locals: %w", err)
+	}
+	l := newLocals(ts)
+	pos := s.Value.len() - size
+	for idx := 0; idx < n; idx++ {
+		t := s.Value.types[pos]
+		if t != ts[idx] {
+			return Locals{}, fmt.Errorf("pop locals: %w: expected=%s actual=%s", ValueStackTypeNotMatch, ts[idx], t)
+		}
+		l.slots[idx] = s.Value.slots[pos]
+		if t == types.V128 {
+			l.high[idx] = s.Value.slots[pos+1]
+		}
+		pos += slotSize(t)
+	}
+	s.Value.slots = s.Value.slots[:s.Value.len()-size]
+	s.Value.types = s.Value.types[:len(s.Value.types)-size]
+	return l, nil
+}
+
+// GetLocal pushes the local at idx.
+func (s *Stack) GetLocal(l *Locals, idx int) error {
+	t := l.types[idx]
+	if err := s.Value.pushSlot(l.slots[idx], t); err != nil {
+		return err
+	}
+	if t == types.V128 {
+		return s.Value.pushSlot(l.high[idx], t)
+	}
+	return nil
+}
+
+// SetLocal pops the top value into the local at idx.
+func (s *Stack) SetLocal(l *Locals, idx int) error {
+	t := l.types[idx]
+	if t == types.V128 {
+		high, err := s.PopRaw(t)
+		if err != nil {
+			return err
+		}
+		l.high[idx] = high
+	}
+	v, err := s.PopRaw(t)
+	if err != nil {
+		return err
+	}
+	l.slots[idx] = v
+	return nil
+}
+
+// TeeLocal copies the top value into the local at idx without popping it.
+func (s *Stack) TeeLocal(l *Locals, idx int) error {
+	if err := s.SetLocal(l, idx); err != nil {
+		return err
+	}
+	return s.GetLocal(l, idx)
+}
@@ -7,13 +7,15 @@ import (
 	"github.com/terassyi/gowi/instruction"
 	"github.com/terassyi/gowi/runtime/instance"
 	"github.com/terassyi/gowi/runtime/value"
-	"github.com/terassyi/gowi/types"
 )
 
 const (
 	VALUE_STACK_LIMIT = 1024 * 1024
 	FRAME_STACK_LIMIT = 64 * 1024
 	LABEL_STACK_LIMIT = 64 * 1024
+
+	VALUE_STACK_INITIAL_SIZE = 1024
+	FRAME_STACK_INITIAL_SIZE = 64
 )
 
 var (
@@ -31,25 +33,36 @@ type Stack struct {
 	Label *LabelStack
 }
 
+// New creates a stack with small buffers which grow up to the limits on demand.
 func New() *Stack {
-	return &Stack{
-		Value: &ValueStack{values: make([]value.Value, 0, VALUE_STACK_LIMIT)},
-		Frame: &FrameStack{frames: make([]Frame, 0, FRAME_STACK_LIMIT)},
-		Label: &LabelStack{labels: make([]Label, 0, LABEL_STACK_LIMIT)},
-	}
+	return WithSize(VALUE_STACK_INITIAL_SIZE, FRAME_STACK_INITIAL_SIZE)
 }
 
+// WithSize creates a stack with the initial capacity of v values and f frames and labels.
 func WithSize(v, f int) *Stack {
 	if v == 0 && f == 0 {
 		return New()
 	}
 	return &Stack{
-		Value: &ValueStack{values: make([]value.Value, 0, v)},
+		Value: newValueStack(v),
 		Frame: &FrameStack{frames: make([]Frame, 0, f)},
 		Label: &LabelStack{labels: make([]Label, 0, f)},
 	}
 }
 
+// Reset empties the stack keeping the allocated buffers.
+func (s *Stack) Reset() {
+	s.Value.slots = s.Value.slots[:0]
+	s.Value.types = s.Value.types[:0]
+	// drop the references to locals and functions of the finished frames
+	frames := s.Frame.frames[:cap(s.Frame.frames)]
+	for i := range frames {
+		frames[i] = Frame{}
+	}
+	s.Frame.frames = s.Frame.frames[:0]
+	s.Label.labels = s.Label.labels[:0]
+}
+
 func WithValue(values []value.Value, frames []Frame, labels []Label) (*Stack, error) {
 	stack := New()
 	for _, v := range values {
@@ -70,207 +83,6 @@ func WithValue(values []value.Value, frames []Frame, labels []Label) (*Stack, er
 	return stack, nil
 }
 
-func (s *Stack) Top() (value.Value, error) {
-	return s.Value.top()
-}
-
-func (s *Stack) PushValue(val value.Value) error {
-	return s.Value.push(val)
-}
-
-func (s *Stack) PopValue() (value.Value, error) {
-	poped := make([]value.Value, 0, 10)
-	for s.Value.len() > 0 {
-		val, err := s.Value.pop()
-		if err != nil {
-			return nil, fmt.Errorf("pop vaue: %w", err)
-		}
-		poped = append(poped, val)
-		if val.ValType() == value.ValTypeNum {
-			break
-		}
-	}
-	val := poped[len(poped)-1]
-	for i := len(poped) - 2; i > 0; i-- {
-		if err := s.PushValue(poped[i]); err != nil {
-			return nil, fmt.Errorf("pop value: %w", err)
-		}
-	}
-	return val, nil
-}
-
-func (s *Stack) PopValues(n int) ([]value.Value, error) {
-	values := make([]value.Value, 0, n)
-	for i := 0; i < n; i++ {
-		v, err := s.PopValue()
-		if err != nil {
-			return nil, fmt.Errorf("pop values: %w", err)
-		}
-		values = append(values, v)
-	}
-	return values, nil
-}
-
-func (s *Stack) PopValuesRev(n int) ([]value.Value, error) {
-	values, err := s.PopValues(n)
-	if err != nil {
-		return nil, err
-	}
-	for i := 0; i < len(values)/2; i++ {
-		values[i], values[len(values)-i-1] = values[len(values)-i-1], values[i]
-	}
-	return values, nil
-}
-
-func (s *Stack) TopValue() (value.Value, error) {
-	val, err := s.Value.top()
-	if err != nil {
-		return nil, err
-	}
-	if val.ValType() != value.ValTypeNum {
-		return s.TopValue()
-	}
-	return val, nil
-}
-
-func (s *Stack) Len() int {
-	return s.Value.len()
-}
-
-func (s *Stack) RefValue() (value.Value, error) {
-	if s.Value.isEmpty() {
-		return nil, fmt.Errorf("ref: %w", StackIsEmpty)
-	}
-	for i := s.Value.len() - 1; i > 0; i-- {
-		v := s.Value.values[i]
-		if v.ValType() == value.ValTypeNum {
-			return v, nil
-		}
-	}
-	return nil, fmt.Errorf("ref: Value is not found")
-}
-
-func (s *Stack) RefNValue(n int) ([]value.Value, error) {
-	if s.Value.len() < n {
-		return nil, StackIsEmpty
-	}
-	values := make([]value.Value, 0, n)
-	for i := s.Value.len() - 1; i > 0; i-- {
-		v := s.Value.values[i]
-		if v.ValType() == value.ValTypeNum {
-			values = append(values, v)
-		}
-		if len(values) == n {
-			break
-		}
-	}
-	return values, nil
-}
-
-func (s *Stack) RefNValueRev(n int) ([]value.Value, error) {
-	values, err := s.RefNValue(n)
-	if err != nil {
-		return nil, err
-	}
-	for i := 0; i < len(values)/2; i++ {
-		values[i], values[len(values)-i-1] = values[len(values)-i-1], values[i]
-	}
-	return values, nil
-}
-
-func (s *Stack) ValidateValue(values []types.ValueType) error {
-	for i, t := range values {
-		val := s.Value.values[len(s.Value.values)-i-1]
-		if val.ValType() == value.ValTypeNum {
-			if !val.(value.Number).ValidateValueType(t) {
-				return ValueStackTypeNotMatch
-			}
-		} else if val.ValType() == value.ValTypeVec {
-			if t != types.V128 {
-				return ValueStackTypeNotMatch
-			}
-		} else if val.ValType() == value.ValTypeFrame || val.ValType() == value.ValTypeLabel {
-			continue
-		} else {
-			return ValueStackTypeNotMatch
-		}
-	}
-	return nil
-}
-
-type ValueStack struct {
-	values []value.Value
-	sp     uint32
-}
-
-func (vs *ValueStack) push(val value.Value) error {
-	if len(vs.values) >= VALUE_STACK_LIMIT {
-		return fmt.Errorf("value stack push: %w", StackLimit)
-	}
-	vs.values = append(vs.values, val)
-	return nil
-}
-
-func (vs *ValueStack) pop() (value.Value, error) {
-	if len(vs.values) == 0 {
-		return nil, fmt.Errorf("value stack pop: %w", StackIsEmpty)
-	}
-	val := vs.values[len(vs.values)-1]
-	vs.values = vs.values[:len(vs.values)-1]
-	return val, nil
-}
-
-func (vs *ValueStack) top() (value.Value, error) {
-	if len(vs.values) == 0 {
-		return nil, fmt.Errorf("value stack pop: %w", StackIsEmpty)
-	}
-	return vs.values[len(vs.values)-1], nil
-}
-
-func (vs *ValueStack) len() int {
-	return len(vs.values)
-}
-
-func (vs *ValueStack) isEmpty() bool {
-	return len(vs.values) == 0
-}
-
-func (vs *ValueStack) popN(n int) ([]value.Value, error) {
-	values := make([]value.Value, 0, n)
-	for i := 0; i < n; i++ {
-		v, err := vs.pop()
-		if err != nil {
-			return nil, err
-		}
-		values = append(values, v)
-	}
-	return values, nil
-}
-
-func (vs *ValueStack) popNRev(n int) ([]value.Value, error) {
-	values := make([]value.Value, n)
-	for i := 0; i < n; i++ {
-		v, err := vs.pop()
-		if err != nil {
-			return nil, err
-		}
-		values[n-i-1] = v
-	}
-	return values, nil
-}
-
-func (vs *ValueStack) RefNRev(n int) ([]value.Value, error) {
-	if len(vs.values) < n {
-		return nil, StackIsEmpty
-	}
-	values := make([]value.Value, n)
-	for i := 0; i < n; i++ {
-		v := vs.values[len(vs.values)-i-1]
-		values[n-i-1] = v
-	}
-	return values, nil
-}
-
 type FrameStack struct {
 	frames []Frame
 }
@@ -283,13 +95,13 @@ func (fs *FrameStack) push(frame Frame) error {
 	return nil
 }
 
-func (fs *FrameStack) pop() (*Frame, error) {
+func (fs *FrameStack) pop() (Frame, error) {
 	if len(fs.frames) == 0 {
-		return nil, fmt.Errorf("frame stack pop: %w", StackIsEmpty)
+		return Frame{}, fmt.Errorf("frame stack pop: %w", StackIsEmpty)
 	}
 	f := fs.frames[len(fs.frames)-1]
 	fs.frames = fs.frames[:len(fs.frames)-1]
-	return &f, nil
+	return f, nil
 }
 
 func (fs *FrameStack) top() (*Frame, error) {
@@ -308,26 +120,14 @@ func (fs *FrameStack) isEmpty() bool {
 }
 
 func (s *Stack) PushFrame(frame Frame) error {
-	if err := s.PushValue(value.DummyFrame); err != nil {
-		return fmt.Errorf("push frame: %w", err)
-	}
 	return s.Frame.push(frame)
 }
 
-func (s *Stack) PopFrame() (*Frame, error) {
+func (s *Stack) PopFrame() (Frame, error) {
 	f, err := s.Frame.pop()
 	if err != nil {
-		return nil, fmt.Errorf("pop frame: %w", err)
-	}
-	frameIndex := 0
-	for i := s.Value.len() - 1; i > 0; i-- {
-		v := s.Value.values[i]
-		if v.ValType() == value.ValTypeFrame {
-			frameIndex = i
-			break
-		}
+		return Frame{}, fmt.Errorf("pop frame: %w", err)
 	}
-	s.Value.values = append(s.Value.values[:frameIndex], s.Value.values[frameIndex+1:]...)
 	return f, nil
 }
 
@@ -355,13 +155,13 @@ func (ls *LabelStack) push(label Label) error {
 	return nil
 }
 
-func (ls *LabelStack) pop() (*Label, error) {
+func (ls *LabelStack) pop() (Label, error) {
 	if len(ls.labels) == 0 {
-		return nil, fmt.Errorf("label stack pop: %w", StackIsEmpty)
+		return Label{}, fmt.Errorf("label stack pop: %w", StackIsEmpty)
 	}
 	l := ls.labels[len(ls.labels)-1]
 	ls.labels = ls.labels[:len(ls.labels)-1]
-	return &l, nil
+	return l, nil
 }
 
 func (ls *LabelStack) top() (*Label, error) {
@@ -372,7 +172,7 @@ func (ls *LabelStack) top() (*Label, error) {
 }
 
 func (ls *LabelStack) ref(n int) (*Label, error) {
-	if ls.len() < n {
+	if n < 0 || ls.len() <= n {
 		return nil, fmt.Errorf("label ref: %w", InvalidStackLength)
 	}
 	return &ls.labels[len(ls.labels)-1-n], nil
@@ -387,29 +187,26 @@ func (ls *LabelStack) isEmpty() bool {
 }
 
 func (s *Stack) PushLabel(label Label) error {
-	if err := s.PushValue(value.DummyLabel); err != nil {
-		return fmt.Errorf("push label: %w", err)
-	}
 	return s.Label.push(label)
 }
 
-func (s *Stack) PopLabel() (*Label, error) {
+func (s *Stack) PopLabel() (Label, error) {
 	label, err := s.Label.pop()
 	if err != nil {
-		return nil, fmt.Errorf("label pop: %w", err)
-	}
-	var labelIndex int
-	for i := s.Value.len() - 1; i > 0; i-- {
-		v := s.Value.values[i]
-		if v.ValType() == value.ValTypeLabel {
-			labelIndex = i
-			break
-		}
+		return Label{}, fmt.Errorf("label pop: %w", err)
 	}
-	s.Value.values = append(s.Value.values[:labelIndex], s.Value.values[labelIndex+1:]...)
 	return label, nil
 }
 
+// PopLabels drops n labels from the top of the label stack.
+func (s *Stack) PopLabels(n int) error {
+	if s.Label.len() < n {
+		return fmt.Errorf("pop labels: %w", InvalidStackLength)
+	}
+	s.Label.labels = s.Label.labels[:s.Label.len()-n]
+	return nil
+}
+
 func (s *Stack) RefLabel(n int) (*Label, error) {
 	return s.Label.ref(n)
 }
@@ -426,11 +223,25 @@ func (s *Stack) IsLabelEmpty() bool {
 	return s.Label.isEmpty()
 }
 
+// Frames returns the frames on the frame stack from bottom to top.
+func (s *Stack) Frames() []Frame {
+	frames := make([]Frame, len(s.Frame.frames))
+	copy(frames, s.Frame.frames)
+	return frames
+}
+
+// Labels returns the labels on the label stack from bottom to top.
+func (s *Stack) Labels() []Label {
+	labels := make([]Label, len(s.Label.labels))
+	copy(labels, s.Label.labels)
+	return labels
+}
+
 type Label struct {
-	Instructions []instruction.Instruction
-	N            uint8
-	Sp           int
-	Type         LabelType
+	N      uint8 // arity
+	Type   LabelType
+	Pc     int // position in the function body where a branch to the label continues
+	Height int // height of the value stack below the label
 }
 
 func (l *Label) IsFunction() bool {
@@ -449,8 +260,30 @@ const (
 	LabelTypeIf       LabelType = iota
 	LabelTypeLoop     LabelType = iota
 	LabelTypeUnknown  LabelType = iota
+	// labels of the exception handling proposal are appended to keep the values of checkpointed labels
+	LabelTypeTry   LabelType = iota
+	LabelTypeCatch LabelType = iota // try label while a handler of try is executing
 )
 
+func (t LabelType) String() string {
+	switch t {
+	case LabelTypeFunction:
+		return "function"
+	case LabelTypeBlock:
+		return "block"
+	case LabelTypeIf:
+		return "if"
+	case LabelTypeLoop:
+		return "loop"
+	case LabelTypeTry:
+		return "try"
+	case LabelTypeCatch:
+		return "catch"
+	default:
+		return "unknown"
+	}
+}
+
 func NewLabelType(instr instruction.Instruction) (LabelType, error) {
 	switch instr.Opcode() {
 	case instruction.CALL:
@@ -461,12 +294,16 @@ func NewLabelType(instr instruction.Instruction) (LabelType, error) {
 		return LabelTypeIf, nil
 	case instruction.LOOP:
 		return LabelTypeLoop, nil
+	case instruction.TRY:
+		return LabelTypeTry, nil
 	default:
 		return LabelTypeUnknown, InvalidLabelInstruction
 	}
 }
 
 type Frame struct {
-	Locals []value.Value
-	Module *instance.Module
+	Locals   Locals
+	Module   *instance.Module
+	Function *instance.Function
+	Pc       int // position of the next instruction in the function body
 }
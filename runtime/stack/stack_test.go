@@ -5,7 +5,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/instance"
 	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
 )
 
 func TestStackPushValue(t *testing.T) {
@@ -45,9 +49,9 @@ func TestValueStackPush(t *testing.T) {
 		val value.Value
 		exp *ValueStack
 	}{
-		{s: &ValueStack{values: []value.Value{}, sp: 0}, val: value.I32(0), exp: &ValueStack{values: []value.Value{value.I32(0)}, sp: 0}},
-		{s: &ValueStack{values: []value.Value{value.I32(0)}, sp: 0}, val: value.I32(0xff), exp: &ValueStack{values: []value.Value{value.I32(0), value.I32(0xff)}, sp: 0}},
-		{s: &ValueStack{values: []value.Value{value.I32(0)}, sp: 0}, val: value.F32(1.1), exp: &ValueStack{values: []value.Value{value.I32(0), value.F32(1.1)}, sp: 0}},
+		{s: valueStackWith(), val: value.I32(0), exp: valueStackWith(value.I32(0))},
+		{s: valueStackWith(value.I32(0)), val: value.I32(0xff), exp: valueStackWith(value.I32(0), value.I32(0xff))},
+		{s: valueStackWith(value.I32(0)), val: value.F32(1.1), exp: valueStackWith(value.I32(0), value.F32(1.1))},
 	} {
 		err := d.s.push(d.val)
 		require.NoError(t, err)
@@ -61,8 +65,8 @@ func TestValueStackPop(t *testing.T) {
 		expVal value.Value
 		expS   *ValueStack
 	}{
-		{s: &ValueStack{values: []value.Value{value.I32(0)}, sp: 0}, expVal: value.I32(0), expS: &ValueStack{values: []value.Value{}, sp: 0}},
-		{s: &ValueStack{values: []value.Value{value.I32(0), value.F32(1.1)}, sp: 0}, expVal: value.F32(1.1), expS: &ValueStack{values: []value.Value{value.I32(0)}, sp: 0}},
+		{s: valueStackWith(value.I32(0)), expVal: value.I32(0), expS: valueStackWith()},
+		{s: valueStackWith(value.I32(0), value.F32(1.1)), expVal: value.F32(1.1), expS: valueStackWith(value.I32(0))},
 	} {
 		v, err := d.s.pop()
 		require.NoError(t, err)
@@ -76,8 +80,8 @@ func TestValueStackPop_Err(t *testing.T) {
 		s *ValueStack
 		l int
 	}{
-		{s: &ValueStack{values: []value.Value{value.I32(0)}, sp: 0}, l: 1},
-		{s: &ValueStack{values: []value.Value{value.I32(0), value.F32(1.1)}, sp: 0}, l: 2},
+		{s: valueStackWith(value.I32(0)), l: 1},
+		{s: valueStackWith(value.I32(0), value.F32(1.1)), l: 2},
 	} {
 		for i := 0; i < d.l; i++ {
 			_, err := d.s.pop()
@@ -93,9 +97,9 @@ func TestValueStackPopNRev(t *testing.T) {
 		stack  *ValueStack
 		values []value.Value
 	}{
-		{stack: &ValueStack{values: []value.Value{value.I32(0)}}, values: []value.Value{value.I32(1), value.I32(2)}},
-		{stack: &ValueStack{values: []value.Value{value.I32(0), value.I32(1), value.I32(2), value.I32(3)}}, values: []value.Value{value.I32(0xff), value.F32(0.1)}},
-		{stack: &ValueStack{values: []value.Value{value.I32(0), value.I64(1)}}, values: []value.Value{}},
+		{stack: valueStackWith(value.I32(0)), values: []value.Value{value.I32(1), value.I32(2)}},
+		{stack: valueStackWith(value.I32(0), value.I32(1), value.I32(2), value.I32(3)), values: []value.Value{value.I32(0xff), value.F32(0.1)}},
+		{stack: valueStackWith(value.I32(0), value.I64(1)), values: []value.Value{}},
 	} {
 		for _, v := range d.values {
 			err := d.stack.push(v)
@@ -107,7 +111,138 @@ func TestValueStackPopNRev(t *testing.T) {
 	}
 }
 
+func TestValueStack_V128(t *testing.T) {
+	vec := value.Vector{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	s := stackWithValueIgnoreError([]value.Value{value.I32(1), vec, value.I64(2)}, []Frame{}, []Label{})
+	assert.Equal(t, 4, s.Len())
+	assert.Equal(t, []value.Value{value.I32(1), vec, value.I64(2)}, s.Values())
+	require.NoError(t, s.ValidateValue([]types.ValueType{types.I32, types.V128, types.I64}))
+
+	res, err := s.RefNValueRev(2)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{vec, value.I64(2)}, res)
+
+	_, err = s.PopValue()
+	require.NoError(t, err)
+	v, err := s.PopValue()
+	require.NoError(t, err)
+	assert.Equal(t, vec, v)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestStackUnwind(t *testing.T) {
+	vec := value.Vector{0xff}
+	for _, d := range []struct {
+		s      *Stack
+		height int
+		n      int
+		exp    []value.Value
+	}{
+		{s: stackWithValueIgnoreError([]value.Value{value.I32(0), value.I32(1), value.I32(2)}, []Frame{}, []Label{}), height: 1, n: 1, exp: []value.Value{value.I32(0), value.I32(2)}},
+		{s: stackWithValueIgnoreError([]value.Value{value.I32(0), value.I32(1), value.I32(2)}, []Frame{}, []Label{}), height: 0, n: 0, exp: []value.Value{}},
+		{s: stackWithValueIgnoreError([]value.Value{value.I32(0), value.I64(1), vec}, []Frame{}, []Label{}), height: 1, n: 1, exp: []value.Value{value.I32(0), vec}},
+		{s: stackWithValueIgnoreError([]value.Value{value.I32(0), vec, value.I64(1)}, []Frame{}, []Label{}), height: 0, n: 2, exp: []value.Value{vec, value.I64(1)}},
+	} {
+		err := d.s.Unwind(d.height, d.n)
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, d.s.Values())
+	}
+}
+
+func TestStackPopRaw(t *testing.T) {
+	s := stackWithValueIgnoreError([]value.Value{value.F32(1.5), value.I32(0xffffffff)}, []Frame{}, []Label{})
+	v, err := s.PopI32()
+	require.NoError(t, err)
+	assert.Equal(t, value.I32(0xffffffff), v)
+	_, err = s.PopRaw(types.I64)
+	assert.ErrorIs(t, err, ValueStackTypeNotMatch)
+}
+
+func TestStackLocals(t *testing.T) {
+	s := stackWithValueIgnoreError([]value.Value{value.F32(1.5), value.I32(7), value.Vector{1, 2, 3}}, []Frame{}, []Label{})
+	l, err := s.PopLocals([]types.ValueType{types.I32, types.V128, types.I64}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.I32(7), value.Vector{1, 2, 3}, value.I64(0)}, l.Values())
+	assert.Equal(t, []value.Value{value.F32(1.5)}, s.Values())
+
+	require.NoError(t, s.GetLocal(&l, 1))
+	require.NoError(t, s.SetLocal(&l, 1))
+	require.NoError(t, s.GetLocal(&l, 0))
+	require.NoError(t, s.TeeLocal(&l, 0))
+	assert.Equal(t, []value.Value{value.F32(1.5), value.I32(7)}, s.Values())
+	assert.ErrorIs(t, s.SetLocal(&l, 2), ValueStackTypeNotMatch)
+
+	_, err = s.PopLocals([]types.ValueType{types.I64}, 1)
+	assert.ErrorIs(t, err, ValueStackTypeNotMatch)
+}
+
+func TestStackValidateValue(t *testing.T) {
+	for _, d := range []struct {
+		values []types.ValueType
+		err    error
+	}{
+		{values: []types.ValueType{}},
+		{values: []types.ValueType{types.F64}},
+		{values: []types.ValueType{types.I32, types.F64}},
+		{values: []types.ValueType{types.F64, types.I32}, err: ValueStackTypeNotMatch},
+		{values: []types.ValueType{types.I32, types.I32, types.F64}, err: ValueStackTypeNotMatch},
+	} {
+		s := stackWithValueIgnoreError([]value.Value{value.I32(0), value.F64(1)}, []Frame{}, []Label{})
+		err := s.ValidateValue(d.values)
+		if d.err != nil {
+			assert.ErrorIs(t, err, d.err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestStackStateRestore(t *testing.T) {
+	f := &instance.Function{Type: &types.FuncType{}, Code: &structure.Function{Body: make([]instruction.Instruction, 4)}}
+	m := &instance.Module{FuncAddrs: []*instance.Function{f}}
+	s := stackWithValueIgnoreError(
+		[]value.Value{value.I32(1), value.Vector{1, 2, 3}, value.F64(0.5)},
+		[]Frame{{}, {Module: m, Function: f, Locals: localsIgnoreError(value.I64(2), value.F32(1.5)), Pc: 3}},
+		[]Label{{}, {N: 1, Type: LabelTypeFunction, Pc: 4, Height: 1}, {Type: LabelTypeLoop, Pc: 1, Height: 3}},
+	)
+	state, err := s.State(m)
+	require.NoError(t, err)
+	assert.Equal(t, 0, state.Frames[1].Function)
+	assert.Equal(t, -1, state.Frames[0].Function)
+
+	restored := New()
+	require.NoError(t, restored.Restore(state, m))
+	assert.Equal(t, s.Values(), restored.Values())
+	assert.Equal(t, s.Frames(), restored.Frames())
+	assert.Equal(t, s.Labels(), restored.Labels())
+
+	// a broken state doesn't modify the stack
+	state.Frames[1].Function = 1
+	assert.ErrorIs(t, restored.Restore(state, m), StateNotMatched)
+	state.Frames[1].Function = 0
+	state.Types = state.Types[:2]
+	assert.ErrorIs(t, restored.Restore(state, m), InvalidStackLength)
+	state.Slots = state.Slots[:2]
+	assert.ErrorIs(t, restored.Restore(state, m), ValueStackTypeNotMatch)
+	assert.Equal(t, s.Values(), restored.Values())
+}
+
+func valueStackWith(values ...value.Value) *ValueStack {
+	vs := newValueStack(0)
+	for _, v := range values {
+		if err := vs.push(v); err != nil {
+			panic(err)
+		}
+	}
+	return vs
+}
+
 func stackWithValueIgnoreError(values []value.Value, frames []Frame, labels []Label) *Stack {
 	s, _ := WithValue(values, frames, labels)
 	return s
 }
+
+func localsIgnoreError(values ...value.Value) Locals {
+	l, _ := NewLocals(values...)
+	return l
+}
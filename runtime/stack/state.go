@@ -0,0 +1,131 @@
+package stack
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/types"
+)
+
+var StateNotMatched error = errors.New("Stack state doesn't match the module instance")
+
+// State is the content of the stack in a form which can be encoded.
+// Values are the raw slots of the value stack, frames refer to functions by their index in the module instance
+// and labels are already positions in function bodies and the value stack.
+type State struct {
+	Slots  []uint64          `json:"slots"`
+	Types  []types.ValueType `json:"types"`
+	Frames []FrameState      `json:"frames"`
+	Labels []Label           `json:"labels"`
+}
+
+type FrameState struct {
+	Function int               `json:"function"` // index in the module instance, -1 for a frame without a function
+	Slots    []uint64          `json:"slots"`    // locals
+	Types    []types.ValueType `json:"types"`
+	Pc       int               `json:"pc"`
+}
+
+// State copies the stack. Functions of the frames must belong to m.
+func (s *Stack) State(m *instance.Module) (*State, error) {
+	state := &State{
+		Slots:  append([]uint64{}, s.Value.slots...),
+		Types:  append([]types.ValueType{}, s.Value.types...),
+		Frames: make([]FrameState, 0, s.Frame.len()),
+		Labels: s.Labels(),
+	}
+	for _, f := range s.Frame.frames {
+		fs := FrameState{Function: -1, Pc: f.Pc}
+		if f.Function != nil {
+			fs.Function = m.FuncIndex(f.Function)
+			if fs.Function < 0 {
+				return nil, fmt.Errorf("stack state: %w: function is not in the module", StateNotMatched)
+			}
+		}
+		locals := newValueStack(f.Locals.Len())
+		for _, l := range f.Locals.Values() {
+			if err := locals.push(l); err != nil {
+				return nil, fmt.Errorf("stack state: %w", err)
+			}
+		}
+		fs.Slots = locals.slots
+		fs.Types = locals.types
+		state.Frames = append(state.Frames, fs)
+	}
+	return state, nil
+}
+
+// Restore replaces the content of the stack with the state. Frames are bound to functions of m.
+// The stack is not modified when the state is broken or doesn't match m.
+func (s *Stack) Restore(state *State, m *instance.Module) error {
+	values, err := restoreValueStack(state.Slots, state.Types)
+	if err != nil {
+		return fmt.Errorf("restore stack: %w", err)
+	}
+	frames := make([]Frame, 0, len(state.Frames))
+	for i, fs := range state.Frames {
+		if fs.Function < -1 || fs.Function >= len(m.FuncAddrs) {
+			return fmt.Errorf("restore stack: %w: frame[%d] function index=%d", StateNotMatched, i, fs.Function)
+		}
+		locals, err := restoreValueStack(fs.Slots, fs.Types)
+		if err != nil {
+			return fmt.Errorf("restore stack: frame[%d]: %w", i, err)
+		}
+		frame := Frame{Pc: fs.Pc}
+		if fs.Function >= 0 {
+			f := m.FuncAddrs[fs.Function]
+			body, err := f.Code.Instructions()
+			if err != nil {
+				return fmt.Errorf("restore stack: frame[%d]: %w", i, err)
+			}
+			if fs.Pc < 0 || fs.Pc > len(body) {
+				return fmt.Errorf("restore stack: %w: frame[%d] pc=%d", StateNotMatched, i, fs.Pc)
+			}
+			frame.Module = m
+			frame.Function = f
+		}
+		localValues := make([]value.Value, 0, locals.len())
+		for pos := 0; pos < locals.len(); pos += slotSize(locals.types[pos]) {
+			localValues = append(localValues, locals.get(pos))
+		}
+		if frame.Locals, err = NewLocals(localValues...); err != nil {
+			return fmt.Errorf("restore stack: frame[%d]: %w", i, err)
+		}
+		frames = append(frames, frame)
+	}
+	for i, l := range state.Labels {
+		if l.Height < 0 || l.Height > values.len() || l.Pc < 0 {
+			return fmt.Errorf("restore stack: %w: label[%d]", StateNotMatched, i)
+		}
+	}
+	if values.len() > VALUE_STACK_LIMIT || len(frames) > FRAME_STACK_LIMIT || len(state.Labels) > LABEL_STACK_LIMIT {
+		return fmt.Errorf("restore stack: %w", StackLimit)
+	}
+	s.Reset()
+	s.Value.slots = append(s.Value.slots, values.slots...)
+	s.Value.types = append(s.Value.types, values.types...)
+	s.Frame.frames = append(s.Frame.frames, frames...)
+	s.Label.labels = append(s.Label.labels, state.Labels...)
+	return nil
+}
+
+// restoreValueStack checks that the slots are valid values.
+func restoreValueStack(slots []uint64, ts []types.ValueType) (*ValueStack, error) {
+	if len(slots) != len(ts) {
+		return nil, fmt.Errorf("%w: slots=%d types=%d", InvalidStackLength, len(slots), len(ts))
+	}
+	for pos := 0; pos < len(ts); pos += slotSize(ts[pos]) {
+		switch ts[pos] {
+		case types.I32, types.I64, types.F32, types.F64:
+		case types.V128:
+			if pos+1 >= len(ts) || ts[pos+1] != types.V128 {
+				return nil, fmt.Errorf("%w: v128 at %d is broken", ValueStackTypeNotMatch, pos)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s at %d", ValueStackTypeNotMatch, ts[pos], pos)
+		}
+	}
+	return &ValueStack{slots: slots, types: ts}, nil
+}
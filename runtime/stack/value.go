@@ -0,0 +1,356 @@
+package stack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/types"
+)
+
+// ValueStack stores values as raw 64 bit slots so that pushing a number doesn't box it into value.Value.
+// A v128 value occupies two slots, the lower half first.
+// The value type of each slot is kept beside it. The interpreter relies on the validated module for the types,
+// they are only checked when values cross the boundary such as function arguments, results and the debugger.
+type ValueStack struct {
+	slots []uint64
+	types []types.ValueType
+}
+
+func newValueStack(size int) *ValueStack {
+	return &ValueStack{
+		slots: make([]uint64, 0, size),
+		types: make([]types.ValueType, 0, size),
+	}
+}
+
+func (s *Stack) Top() (value.Value, error) {
+	return s.Value.top()
+}
+
+func (s *Stack) PushValue(val value.Value) error {
+	return s.Value.push(val)
+}
+
+func (s *Stack) PopValue() (value.Value, error) {
+	val, err := s.Value.pop()
+	if err != nil {
+		return nil, fmt.Errorf("pop value: %w", err)
+	}
+	return val, nil
+}
+
+func (s *Stack) PopValues(n int) ([]value.Value, error) {
+	values := make([]value.Value, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := s.PopValue()
+		if err != nil {
+			return nil, fmt.Errorf("pop values: %w", err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (s *Stack) PopValuesRev(n int) ([]value.Value, error) {
+	values, err := s.Value.popNRev(n)
+	if err != nil {
+		return nil, fmt.Errorf("pop values: %w", err)
+	}
+	return values, nil
+}
+
+// PushRaw pushes the bits of a number typed t.
+func (s *Stack) PushRaw(v uint64, t types.ValueType) error {
+	return s.Value.pushSlot(v, t)
+}
+
+// PopRaw pops the bits of a number typed t.
+func (s *Stack) PopRaw(t types.ValueType) (uint64, error) {
+	v, typ, err := s.Value.popSlot()
+	if err != nil {
+		return 0, fmt.Errorf("pop raw: %w", err)
+	}
+	if typ != t {
+		return 0, fmt.Errorf("pop raw: %w: expected=%s actual=%s", ValueStackTypeNotMatch, t, typ)
+	}
+	return v, nil
+}
+
+func (s *Stack) PushI32(v value.I32) error {
+	return s.Value.pushSlot(uint64(v), types.I32)
+}
+
+func (s *Stack) PopI32() (value.I32, error) {
+	v, err := s.PopRaw(types.I32)
+	if err != nil {
+		return 0, err
+	}
+	return value.I32(v), nil
+}
+
+func (s *Stack) PushVector(v value.Vector) error {
+	return s.Value.push(v)
+}
+
+func (s *Stack) PopVector() (value.Vector, error) {
+	if s.Value.isEmpty() {
+		return value.Vector{}, fmt.Errorf("pop vector: %w", StackIsEmpty)
+	}
+	pos := s.Value.topPos()
+	if s.Value.types[pos] != types.V128 {
+		return value.Vector{}, fmt.Errorf("pop vector: %w: expected=%s actual=%s", ValueStackTypeNotMatch, types.V128, s.Value.types[pos])
+	}
+	v := s.Value.get(pos).(value.Vector)
+	s.Value.slots = s.Value.slots[:pos]
+	s.Value.types = s.Value.types[:pos]
+	return v, nil
+}
+
+// Drop pops the top value without decoding it.
+func (s *Stack) Drop() error {
+	if s.Value.isEmpty() {
+		return fmt.Errorf("drop: %w", StackIsEmpty)
+	}
+	pos := s.Value.topPos()
+	s.Value.slots = s.Value.slots[:pos]
+	s.Value.types = s.Value.types[:pos]
+	return nil
+}
+
+// Select pops the i32 condition and two values of the same type,
+// then pushes the first value when the condition is not zero and the second one otherwise.
+func (s *Stack) Select() error {
+	c, err := s.PopI32()
+	if err != nil {
+		return fmt.Errorf("select: %w", err)
+	}
+	size, err := s.Value.slotsOf(2)
+	if err != nil {
+		return fmt.Errorf("select: %w", err)
+	}
+	second := s.Value.topPos()
+	first := s.Value.len() - size
+	if s.Value.types[first] != s.Value.types[second] {
+		return fmt.Errorf("select: %w: %s and %s", ValueStackTypeNotMatch, s.Value.types[first], s.Value.types[second])
+	}
+	if c == 0 {
+		copy(s.Value.slots[first:second], s.Value.slots[second:])
+	}
+	s.Value.slots = s.Value.slots[:second]
+	s.Value.types = s.Value.types[:second]
+	return nil
+}
+
+// Unwind drops values above height while keeping n values on the top.
+// height is the number of slots returned by Len.
+func (s *Stack) Unwind(height, n int) error {
+	l := s.Value.len()
+	size, err := s.Value.slotsOf(n)
+	if err != nil {
+		return fmt.Errorf("unwind: %w", err)
+	}
+	if height < 0 || height+size > l {
+		return fmt.Errorf("unwind: %w", InvalidStackLength)
+	}
+	copy(s.Value.slots[height:], s.Value.slots[l-size:])
+	copy(s.Value.types[height:], s.Value.types[l-size:])
+	s.Value.slots = s.Value.slots[:height+size]
+	s.Value.types = s.Value.types[:height+size]
+	return nil
+}
+
+func (s *Stack) TopValue() (value.Value, error) {
+	return s.Value.top()
+}
+
+// Len returns the number of slots on the value stack.
+func (s *Stack) Len() int {
+	return s.Value.len()
+}
+
+func (s *Stack) RefValue() (value.Value, error) {
+	if s.Value.isEmpty() {
+		return nil, fmt.Errorf("ref: %w", StackIsEmpty)
+	}
+	return s.Value.top()
+}
+
+// RefNValue returns n values from the top to the bottom without popping them.
+func (s *Stack) RefNValue(n int) ([]value.Value, error) {
+	values, err := s.Value.RefNRev(n)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(values)/2; i++ {
+		values[i], values[len(values)-i-1] = values[len(values)-i-1], values[i]
+	}
+	return values, nil
+}
+
+// RefNValueRev returns n values from the bottom to the top without popping them.
+func (s *Stack) RefNValueRev(n int) ([]value.Value, error) {
+	return s.Value.RefNRev(n)
+}
+
+// ValidateValue checks the types of values on the top of the stack.
+// values are ordered from the bottom to the top like a result type.
+func (s *Stack) ValidateValue(values []types.ValueType) error {
+	pos := s.Value.len()
+	for i := len(values) - 1; i >= 0; i-- {
+		pos -= slotSize(values[i])
+		if pos < 0 {
+			return fmt.Errorf("%w: %d values are required", ValueStackTypeNotMatch, len(values))
+		}
+		if s.Value.types[pos] != values[i] {
+			return ValueStackTypeNotMatch
+		}
+	}
+	return nil
+}
+
+// Values returns the values on the value stack from bottom to top.
+func (s *Stack) Values() []value.Value {
+	values := make([]value.Value, 0, s.Value.len())
+	for pos := 0; pos < s.Value.len(); pos += slotSize(s.Value.types[pos]) {
+		values = append(values, s.Value.get(pos))
+	}
+	return values
+}
+
+func slotSize(t types.ValueType) int {
+	if t == types.V128 {
+		return 2
+	}
+	return 1
+}
+
+func (vs *ValueStack) pushSlot(v uint64, t types.ValueType) error {
+	if len(vs.slots) >= VALUE_STACK_LIMIT {
+		return fmt.Errorf("value stack push: %w", StackLimit)
+	}
+	vs.slots = append(vs.slots, v)
+	vs.types = append(vs.types, t)
+	return nil
+}
+
+func (vs *ValueStack) popSlot() (uint64, types.ValueType, error) {
+	if len(vs.slots) == 0 {
+		return 0, 0, fmt.Errorf("value stack pop: %w", StackIsEmpty)
+	}
+	v := vs.slots[len(vs.slots)-1]
+	t := vs.types[len(vs.types)-1]
+	vs.slots = vs.slots[:len(vs.slots)-1]
+	vs.types = vs.types[:len(vs.types)-1]
+	return v, t, nil
+}
+
+func (vs *ValueStack) push(val value.Value) error {
+	switch v := val.(type) {
+	case value.I32:
+		return vs.pushSlot(uint64(v), types.I32)
+	case value.I64:
+		return vs.pushSlot(uint64(v), types.I64)
+	case value.F32:
+		return vs.pushSlot(uint64(math.Float32bits(float32(v))), types.F32)
+	case value.F64:
+		return vs.pushSlot(math.Float64bits(float64(v)), types.F64)
+	case value.Vector:
+		if err := vs.pushSlot(binary.LittleEndian.Uint64(v[:8]), types.V128); err != nil {
+			return err
+		}
+		return vs.pushSlot(binary.LittleEndian.Uint64(v[8:]), types.V128)
+	default:
+		return fmt.Errorf("value stack push: %w", ValueStackTypeNotMatch)
+	}
+}
+
+func (vs *ValueStack) pop() (value.Value, error) {
+	if len(vs.slots) == 0 {
+		return nil, fmt.Errorf("value stack pop: %w", StackIsEmpty)
+	}
+	pos := vs.topPos()
+	val := vs.get(pos)
+	vs.slots = vs.slots[:pos]
+	vs.types = vs.types[:pos]
+	return val, nil
+}
+
+func (vs *ValueStack) top() (value.Value, error) {
+	if len(vs.slots) == 0 {
+		return nil, fmt.Errorf("value stack top: %w", StackIsEmpty)
+	}
+	return vs.get(vs.topPos()), nil
+}
+
+// topPos returns the position of the first slot of the top value.
+func (vs *ValueStack) topPos() int {
+	return len(vs.slots) - slotSize(vs.types[len(vs.types)-1])
+}
+
+// get decodes the value starting at the slot pos.
+func (vs *ValueStack) get(pos int) value.Value {
+	v := vs.slots[pos]
+	switch vs.types[pos] {
+	case types.I32:
+		return value.I32(uint32(v))
+	case types.I64:
+		return value.I64(v)
+	case types.F32:
+		return value.F32(math.Float32frombits(uint32(v)))
+	case types.F64:
+		return value.F64(math.Float64frombits(v))
+	case types.V128:
+		var vec value.Vector
+		binary.LittleEndian.PutUint64(vec[:8], v)
+		binary.LittleEndian.PutUint64(vec[8:], vs.slots[pos+1])
+		return vec
+	default:
+		return nil
+	}
+}
+
+// slotsOf returns the number of slots used by n values on the top.
+func (vs *ValueStack) slotsOf(n int) (int, error) {
+	size := 0
+	for i := 0; i < n; i++ {
+		if size >= len(vs.slots) {
+			return 0, StackIsEmpty
+		}
+		size += slotSize(vs.types[len(vs.types)-1-size])
+	}
+	return size, nil
+}
+
+func (vs *ValueStack) len() int {
+	return len(vs.slots)
+}
+
+func (vs *ValueStack) isEmpty() bool {
+	return len(vs.slots) == 0
+}
+
+func (vs *ValueStack) popNRev(n int) ([]value.Value, error) {
+	values := make([]value.Value, n)
+	for i := 0; i < n; i++ {
+		v, err := vs.pop()
+		if err != nil {
+			return nil, err
+		}
+		values[n-i-1] = v
+	}
+	return values, nil
+}
+
+func (vs *ValueStack) RefNRev(n int) ([]value.Value, error) {
+	size, err := vs.slotsOf(n)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]value.Value, 0, n)
+	for pos := len(vs.slots) - size; pos < len(vs.slots); pos += slotSize(vs.types[pos]) {
+		values = append(values, vs.get(pos))
+	}
+	return values, nil
+}
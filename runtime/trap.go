@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"errors"
+
+	"github.com/terassyi/gowi/runtime/debugger"
+	"github.com/terassyi/gowi/runtime/instance"
+	"github.com/terassyi/gowi/runtime/stack"
+)
+
+// TrapKind classifies the error returned by Invoke.
+// It returns an empty string when err is nil.
+func TrapKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, TrapUnreachable):
+		return "unreachable"
+	case errors.Is(err, ExecutionErrorDivideByZero):
+		return "integer divide by zero"
+	case errors.Is(err, TrapUndefinedElement):
+		return "undefined element"
+	case errors.Is(err, TrapUninitializedElement):
+		return "uninitialized element"
+	case errors.Is(err, TrapIndirectCallTypeMismatch):
+		return "indirect call type mismatch"
+	case errors.Is(err, MemoryDoesNotHaveEnoughLength):
+		return "out of bounds memory access"
+	case errors.Is(err, instance.MemoryUnalignedAtomic):
+		return "unaligned atomic"
+	case errors.Is(err, instance.MemoryNotShared):
+		return "expected shared memory"
+	case errors.Is(err, UncaughtException):
+		return "uncaught exception"
+	case errors.Is(err, stack.StackLimit):
+		return "call stack exhausted"
+	case errors.Is(err, Suspended):
+		return "suspended"
+	case errors.Is(err, debugger.ExecutionAborted):
+		return "aborted"
+	case errors.Is(err, FunctionParamsDoesntMatch), errors.Is(err, FunctionParamTypesDoesntMatch), errors.Is(err, FunctionIsRequired):
+		return "invalid invocation"
+	default:
+		return "trap"
+	}
+}
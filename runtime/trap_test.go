@@ -0,0 +1,28 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terassyi/gowi/runtime/stack"
+)
+
+func TestTrapKind(t *testing.T) {
+	for _, d := range []struct {
+		err error
+		exp string
+	}{
+		{err: nil, exp: ""},
+		{err: fmt.Errorf("Invoke: \n\t%w", fmt.Errorf("execute: %w", TrapUnreachable)), exp: "unreachable"},
+		{err: fmt.Errorf("binop: %w", ExecutionErrorDivideByZero), exp: "integer divide by zero"},
+		{err: fmt.Errorf("call: %w", stack.StackLimit), exp: "call stack exhausted"},
+		{err: fmt.Errorf("Invoke: \n\t%w", FunctionParamsDoesntMatch), exp: "invalid invocation"},
+		{err: fmt.Errorf("Invoke: \n\t%w", &Exception{}), exp: "uncaught exception"},
+		{err: fmt.Errorf("call_indirect: %w", TrapIndirectCallTypeMismatch), exp: "indirect call type mismatch"},
+		{err: errors.New("something"), exp: "trap"},
+	} {
+		assert.Equal(t, d.exp, TrapKind(d.err))
+	}
+}
@@ -0,0 +1,142 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var InvalidFloatLiteral error = errors.New("Invalid float literal")
+
+const (
+	f32SignBit      uint32 = 1 << 31
+	f32ExponentMask uint32 = 0x7f80_0000
+	f32PayloadMask  uint32 = 0x007f_ffff
+	f32CanonicalNaN uint32 = 0x0040_0000
+
+	f64SignBit      uint64 = 1 << 63
+	f64ExponentMask uint64 = 0x7ff0_0000_0000_0000
+	f64PayloadMask  uint64 = 0x000f_ffff_ffff_ffff
+	f64CanonicalNaN uint64 = 0x0008_0000_0000_0000
+)
+
+// https://webassembly.github.io/spec/core/text/values.html#floating-point
+// ParseF32 parses a decimal, hex-float (0x1.8p3), inf or nan[:0x payload] literal.
+func ParseF32(s string) (F32, error) {
+	neg, body := splitSign(s)
+	var sign uint32
+	if neg {
+		sign = f32SignBit
+	}
+	switch {
+	case body == "inf":
+		return F32(math.Float32frombits(sign | f32ExponentMask)), nil
+	case body == "nan":
+		return F32(math.Float32frombits(sign | f32ExponentMask | f32CanonicalNaN)), nil
+	case strings.HasPrefix(body, "nan:0x"):
+		payload, err := strconv.ParseUint(strings.ReplaceAll(body[len("nan:0x"):], "_", ""), 16, 32)
+		if err != nil || payload == 0 || uint32(payload)&^f32PayloadMask != 0 {
+			return 0, fmt.Errorf("%w: %s", InvalidFloatLiteral, s)
+		}
+		return F32(math.Float32frombits(sign | f32ExponentMask | uint32(payload))), nil
+	}
+	f, err := parseFloat(s, 32)
+	if err != nil {
+		return 0, err
+	}
+	return F32(f), nil
+}
+
+// ParseF64 parses a decimal, hex-float (0x1.8p3), inf or nan[:0x payload] literal.
+func ParseF64(s string) (F64, error) {
+	neg, body := splitSign(s)
+	var sign uint64
+	if neg {
+		sign = f64SignBit
+	}
+	switch {
+	case body == "inf":
+		return F64(math.Float64frombits(sign | f64ExponentMask)), nil
+	case body == "nan":
+		return F64(math.Float64frombits(sign | f64ExponentMask | f64CanonicalNaN)), nil
+	case strings.HasPrefix(body, "nan:0x"):
+		payload, err := strconv.ParseUint(strings.ReplaceAll(body[len("nan:0x"):], "_", ""), 16, 64)
+		if err != nil || payload == 0 || payload&^f64PayloadMask != 0 {
+			return 0, fmt.Errorf("%w: %s", InvalidFloatLiteral, s)
+		}
+		return F64(math.Float64frombits(sign | f64ExponentMask | payload)), nil
+	}
+	f, err := parseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return F64(f), nil
+}
+
+func splitSign(s string) (bool, string) {
+	if strings.HasPrefix(s, "-") {
+		return true, s[1:]
+	}
+	return false, strings.TrimPrefix(s, "+")
+}
+
+func parseFloat(s string, bitSize int) (float64, error) {
+	_, body := splitSign(s)
+	if strings.HasPrefix(body, "0x") && !strings.ContainsAny(body, "pP") {
+		// the exponent of hex-float is optional in the text format
+		s += "p0"
+	}
+	// strconv accepts spellings of inf and nan which the text format doesn't
+	if lower := strings.ToLower(body); strings.HasPrefix(lower, "inf") || strings.HasPrefix(lower, "nan") {
+		return 0, fmt.Errorf("%w: %s", InvalidFloatLiteral, s)
+	}
+	f, err := strconv.ParseFloat(s, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", InvalidFloatLiteral, s)
+	}
+	return f, nil
+}
+
+// String formats the value so that ParseF32 restores the same bits.
+func (f F32) String() string {
+	bits := math.Float32bits(float32(f))
+	sign := ""
+	if bits&f32SignBit != 0 {
+		sign = "-"
+	}
+	if bits&f32ExponentMask == f32ExponentMask {
+		payload := bits & f32PayloadMask
+		switch payload {
+		case 0:
+			return sign + "inf"
+		case f32CanonicalNaN:
+			return sign + "nan"
+		default:
+			return fmt.Sprintf("%snan:0x%x", sign, payload)
+		}
+	}
+	return strconv.FormatFloat(float64(f), 'g', -1, 32)
+}
+
+// String formats the value so that ParseF64 restores the same bits.
+func (f F64) String() string {
+	bits := math.Float64bits(float64(f))
+	sign := ""
+	if bits&f64SignBit != 0 {
+		sign = "-"
+	}
+	if bits&f64ExponentMask == f64ExponentMask {
+		payload := bits & f64PayloadMask
+		switch payload {
+		case 0:
+			return sign + "inf"
+		case f64CanonicalNaN:
+			return sign + "nan"
+		default:
+			return fmt.Sprintf("%snan:0x%x", sign, payload)
+		}
+	}
+	return strconv.FormatFloat(float64(f), 'g', -1, 64)
+}
@@ -0,0 +1,94 @@
+package value
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestParseF32(t *testing.T) {
+	for _, d := range []struct {
+		s   string
+		exp uint32
+	}{
+		{s: "0", exp: 0},
+		{s: "-0", exp: 0x8000_0000},
+		{s: "1.5", exp: math.Float32bits(1.5)},
+		{s: "-2.25e2", exp: math.Float32bits(-225)},
+		{s: "0x1.8p3", exp: math.Float32bits(12)},
+		{s: "0x10", exp: math.Float32bits(16)},
+		{s: "inf", exp: 0x7f80_0000},
+		{s: "-inf", exp: 0xff80_0000},
+		{s: "nan", exp: 0x7fc0_0000},
+		{s: "-nan", exp: 0xffc0_0000},
+		{s: "nan:0x1", exp: 0x7f80_0001},
+		{s: "-nan:0x200000", exp: 0xffa0_0000},
+	} {
+		f, err := ParseF32(d.s)
+		require.NoError(t, err, d.s)
+		assert.Equal(t, d.exp, math.Float32bits(float32(f)), d.s)
+	}
+}
+
+func TestParseF64(t *testing.T) {
+	for _, d := range []struct {
+		s   string
+		exp uint64
+	}{
+		{s: "0.1", exp: math.Float64bits(0.1)},
+		{s: "-0x1.fffffffffffffp1023", exp: math.Float64bits(-math.MaxFloat64)},
+		{s: "inf", exp: 0x7ff0_0000_0000_0000},
+		{s: "nan", exp: 0x7ff8_0000_0000_0000},
+		{s: "nan:0xdead", exp: 0x7ff0_0000_0000_dead},
+	} {
+		f, err := ParseF64(d.s)
+		require.NoError(t, err, d.s)
+		assert.Equal(t, d.exp, math.Float64bits(float64(f)), d.s)
+	}
+}
+
+func TestParseFloat_Err(t *testing.T) {
+	for _, s := range []string{"", "abc", "1.5.5", "nan:0x0", "nan:0x800000", "infinity", "NaN"} {
+		_, err := ParseF32(s)
+		assert.ErrorIs(t, err, InvalidFloatLiteral, s)
+	}
+	_, err := ParseF64("nan:0x10000000000000")
+	assert.ErrorIs(t, err, InvalidFloatLiteral)
+}
+
+func TestFloatString_RoundTrip(t *testing.T) {
+	for _, s := range []string{"0", "-0", "1.5", "0.1", "3.4028235e+38", "1e-45", "inf", "-inf", "nan", "-nan", "nan:0x1", "nan:0x7fffff"} {
+		f, err := ParseF32(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, s, f.String())
+		g, err := ParseF32(f.String())
+		require.NoError(t, err, s)
+		assert.Equal(t, math.Float32bits(float32(f)), math.Float32bits(float32(g)), s)
+	}
+	for _, s := range []string{"0.1", "-1.7976931348623157e+308", "5e-324", "inf", "nan", "nan:0xfffffffffffff"} {
+		f, err := ParseF64(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, s, f.String())
+	}
+}
+
+func TestFromString(t *testing.T) {
+	for _, d := range []struct {
+		s   string
+		typ types.ValueType
+		exp Value
+	}{
+		{s: "-1", typ: types.I32, exp: I32(0xffff_ffff)},
+		{s: "0xff", typ: types.I64, exp: I64(0xff)},
+		{s: "1.5", typ: types.F32, exp: F32(1.5)},
+		{s: "-0x1p-2", typ: types.F64, exp: F64(-0.25)},
+		{s: "i32x4 1 2 3 -1", typ: types.V128, exp: Vector{1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}},
+	} {
+		v, err := FromString(d.s, d.typ)
+		require.NoError(t, err, d.s)
+		assert.Equal(t, d.exp, v)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"math"
 	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/terassyi/gowi/types"
@@ -37,6 +38,21 @@ const (
 	NumTypeF64 NumberType = 3
 )
 
+func (n NumberType) String() string {
+	switch n {
+	case NumTypeI32:
+		return "i32"
+	case NumTypeI64:
+		return "i64"
+	case NumTypeF32:
+		return "f32"
+	case NumTypeF64:
+		return "f64"
+	default:
+		return "unknown"
+	}
+}
+
 type VectorType uint8
 
 const (
@@ -53,11 +69,9 @@ const (
 type ValueType uint8
 
 const (
-	ValTypeNum   ValueType = 0
-	ValTypeVec   ValueType = 1
-	ValTypeRef   ValueType = 2
-	ValTypeFrame ValueType = 3
-	ValTypeLabel ValueType = 4
+	ValTypeNum ValueType = 0
+	ValTypeVec ValueType = 1
+	ValTypeRef ValueType = 2
 )
 
 type I32 uint32
@@ -265,21 +279,18 @@ func FromString(val string, typ types.ValueType) (Value, error) {
 		}
 		return NewI64(v), nil
 	case types.F32:
-		return nil, types.NotImplemented
+		return ParseF32(val)
 	case types.F64:
-		return nil, types.NotImplemented
+		return ParseF64(val)
 	case types.V128:
-		return nil, types.NotImplemented
+		return ParseV128(val)
 	default:
 		return nil, types.InvalidValueType
 	}
 }
 
 func isNeg(s string) bool {
-	if string(s[0]) == "-" {
-		return true
-	}
-	return false
+	return strings.HasPrefix(s, "-")
 }
 
 func baseNum(s string) int {
@@ -307,22 +318,3 @@ func trimBase(s string, base int) string {
 		return s
 	}
 }
-
-// dummy for stack value
-type FrameVal struct{}
-
-func (FrameVal) ValType() ValueType {
-	return ValTypeFrame
-}
-
-// dummy for label stack
-type LabelVal struct{}
-
-func (LabelVal) ValType() ValueType {
-	return ValTypeLabel
-}
-
-var (
-	DummyFrame FrameVal = FrameVal{}
-	DummyLabel LabelVal = LabelVal{}
-)
@@ -0,0 +1,90 @@
+package value
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var InvalidVectorLiteral error = errors.New("Invalid vector literal")
+
+// https://webassembly.github.io/spec/core/text/instructions.html#vector-instructions
+// ParseV128 parses a lane literal like "i32x4 1 2 3 4" or "f64x2 0.5 nan".
+// Lanes are separated by spaces and stored in little endian order.
+func ParseV128(s string) (Vector, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Vector{}, fmt.Errorf("%w: %q", InvalidVectorLiteral, s)
+	}
+	shape, lanes := fields[0], fields[1:]
+	var n int
+	switch shape {
+	case "i8x16":
+		n = 16
+	case "i16x8":
+		n = 8
+	case "i32x4", "f32x4":
+		n = 4
+	case "i64x2", "f64x2":
+		n = 2
+	default:
+		return Vector{}, fmt.Errorf("%w: unknown shape %s", InvalidVectorLiteral, shape)
+	}
+	if len(lanes) != n {
+		return Vector{}, fmt.Errorf("%w: %s requires %d lanes, got %d", InvalidVectorLiteral, shape, n, len(lanes))
+	}
+	var v Vector
+	width := 16 / n
+	for i, lane := range lanes {
+		b := v[i*width : (i+1)*width]
+		switch shape {
+		case "f32x4":
+			f, err := ParseF32(lane)
+			if err != nil {
+				return Vector{}, fmt.Errorf("%w: lane %d: %w", InvalidVectorLiteral, i, err)
+			}
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(f)))
+		case "f64x2":
+			f, err := ParseF64(lane)
+			if err != nil {
+				return Vector{}, fmt.Errorf("%w: lane %d: %w", InvalidVectorLiteral, i, err)
+			}
+			binary.LittleEndian.PutUint64(b, math.Float64bits(float64(f)))
+		default:
+			u, err := parseLane(lane, width*8)
+			if err != nil {
+				return Vector{}, fmt.Errorf("%w: lane %d: %w", InvalidVectorLiteral, i, err)
+			}
+			for j := range b {
+				b[j] = byte(u >> (8 * j))
+			}
+		}
+	}
+	return v, nil
+}
+
+// parseLane parses a signed or unsigned integer which fits in bitSize bits.
+func parseLane(s string, bitSize int) (uint64, error) {
+	if isNeg(s) {
+		v, err := strconv.ParseInt(s, 10, bitSize)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(v), nil
+	}
+	base := baseNum(s)
+	return strconv.ParseUint(trimBase(s, base), base, bitSize)
+}
+
+// String formats the value as i32x4 lanes in hex, which ParseV128 restores.
+func (v Vector) String() string {
+	lanes := make([]string, 0, 5)
+	lanes = append(lanes, "i32x4")
+	for i := 0; i < 4; i++ {
+		lanes = append(lanes, fmt.Sprintf("0x%08x", binary.LittleEndian.Uint32(v[i*4:])))
+	}
+	return strings.Join(lanes, " ")
+}
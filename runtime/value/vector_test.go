@@ -0,0 +1,41 @@
+package value
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseV128(t *testing.T) {
+	for _, d := range []struct {
+		s   string
+		exp Vector
+	}{
+		{s: "i8x16 0 1 2 3 4 5 6 7 8 9 10 11 12 13 14 255", exp: Vector{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 0xff}},
+		{s: "i16x8 0x0102 -1 0 0 0 0 0 0", exp: Vector{0x02, 0x01, 0xff, 0xff}},
+		{s: "i32x4 0xdeadbeef 0 0 1", exp: Vector{0xef, 0xbe, 0xad, 0xde, 12: 1}},
+		{s: "i64x2 -1 0", exp: Vector{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{s: "f32x4 1 0 0 -inf", exp: Vector{0, 0, 0x80, 0x3f, 12: 0, 13: 0, 14: 0x80, 15: 0xff}},
+		{s: "f64x2 0 nan", exp: Vector{14: 0xf8, 15: 0x7f}},
+	} {
+		v, err := ParseV128(d.s)
+		require.NoError(t, err, d.s)
+		assert.Equal(t, d.exp, v, d.s)
+	}
+}
+
+func TestParseV128_Err(t *testing.T) {
+	for _, s := range []string{"", "i32x4 1 2 3", "i8x16 256 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0", "v128 1", "f32x4 a 0 0 0"} {
+		_, err := ParseV128(s)
+		assert.ErrorIs(t, err, InvalidVectorLiteral, s)
+	}
+}
+
+func TestVectorString_RoundTrip(t *testing.T) {
+	v := Vector{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	assert.Equal(t, "i32x4 0x03020100 0x07060504 0x0b0a0908 0x0f0e0d0c", v.String())
+	res, err := ParseV128(v.String())
+	require.NoError(t, err)
+	assert.Equal(t, v, res)
+}
@@ -0,0 +1,1011 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	mbits "math/bits"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/types"
+)
+
+// lane is a type of the lanes of a vector. Lanes are stored in little endian.
+type lane interface {
+	int8 | uint8 | int16 | uint16 | int32 | uint32 | int64 | uint64 | float32 | float64
+}
+
+// https://webassembly.github.io/spec/core/exec/instructions.html#vector-instructions
+func (i *interpreter) execVector(instr instruction.Instruction) (instructionResult, error) {
+	v := instr.(*instruction.Vector)
+	var err error
+	switch v.Op {
+	case instruction.V128_LOAD, instruction.V128_LOAD8X8_S, instruction.V128_LOAD8X8_U,
+		instruction.V128_LOAD16X4_S, instruction.V128_LOAD16X4_U, instruction.V128_LOAD32X2_S, instruction.V128_LOAD32X2_U,
+		instruction.V128_LOAD8_SPLAT, instruction.V128_LOAD16_SPLAT, instruction.V128_LOAD32_SPLAT, instruction.V128_LOAD64_SPLAT,
+		instruction.V128_LOAD32_ZERO, instruction.V128_LOAD64_ZERO:
+		err = i.vectorLoad(v)
+	case instruction.V128_LOAD8_LANE, instruction.V128_LOAD16_LANE, instruction.V128_LOAD32_LANE, instruction.V128_LOAD64_LANE:
+		err = i.vectorLoadLane(v)
+	case instruction.V128_STORE, instruction.V128_STORE8_LANE, instruction.V128_STORE16_LANE, instruction.V128_STORE32_LANE, instruction.V128_STORE64_LANE:
+		err = i.vectorStore(v)
+	case instruction.V128_CONST:
+		err = i.stack.PushVector(value.Vector(v.Imm.Bytes))
+	case instruction.I8X16_SHUFFLE:
+		err = i.vectorShuffle(v)
+	case instruction.I8X16_SWIZZLE:
+		err = i.vectorBinop(func(a, s value.Vector) (r value.Vector) {
+			for n, l := range s {
+				if l < 16 {
+					r[n] = a[l]
+				}
+			}
+			return r
+		})
+	case instruction.I8X16_SPLAT, instruction.I16X8_SPLAT, instruction.I32X4_SPLAT, instruction.I64X2_SPLAT, instruction.F32X4_SPLAT, instruction.F64X2_SPLAT:
+		err = i.vectorSplat(v.Op)
+	case instruction.I8X16_EXTRACT_LANE_S, instruction.I8X16_EXTRACT_LANE_U, instruction.I16X8_EXTRACT_LANE_S, instruction.I16X8_EXTRACT_LANE_U,
+		instruction.I32X4_EXTRACT_LANE, instruction.I64X2_EXTRACT_LANE, instruction.F32X4_EXTRACT_LANE, instruction.F64X2_EXTRACT_LANE:
+		err = i.vectorExtractLane(v)
+	case instruction.I8X16_REPLACE_LANE, instruction.I16X8_REPLACE_LANE, instruction.I32X4_REPLACE_LANE,
+		instruction.I64X2_REPLACE_LANE, instruction.F32X4_REPLACE_LANE, instruction.F64X2_REPLACE_LANE:
+		err = i.vectorReplaceLane(v)
+
+	// comparisons
+	case instruction.I8X16_EQ:
+		err = i.vectorBinop(compareLanes(func(x, y uint8) bool { return x == y }))
+	case instruction.I8X16_NE:
+		err = i.vectorBinop(compareLanes(func(x, y uint8) bool { return x != y }))
+	case instruction.I8X16_LT_S:
+		err = i.vectorBinop(compareLanes(func(x, y int8) bool { return x < y }))
+	case instruction.I8X16_LT_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint8) bool { return x < y }))
+	case instruction.I8X16_GT_S:
+		err = i.vectorBinop(compareLanes(func(x, y int8) bool { return x > y }))
+	case instruction.I8X16_GT_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint8) bool { return x > y }))
+	case instruction.I8X16_LE_S:
+		err = i.vectorBinop(compareLanes(func(x, y int8) bool { return x <= y }))
+	case instruction.I8X16_LE_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint8) bool { return x <= y }))
+	case instruction.I8X16_GE_S:
+		err = i.vectorBinop(compareLanes(func(x, y int8) bool { return x >= y }))
+	case instruction.I8X16_GE_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint8) bool { return x >= y }))
+	case instruction.I16X8_EQ:
+		err = i.vectorBinop(compareLanes(func(x, y uint16) bool { return x == y }))
+	case instruction.I16X8_NE:
+		err = i.vectorBinop(compareLanes(func(x, y uint16) bool { return x != y }))
+	case instruction.I16X8_LT_S:
+		err = i.vectorBinop(compareLanes(func(x, y int16) bool { return x < y }))
+	case instruction.I16X8_LT_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint16) bool { return x < y }))
+	case instruction.I16X8_GT_S:
+		err = i.vectorBinop(compareLanes(func(x, y int16) bool { return x > y }))
+	case instruction.I16X8_GT_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint16) bool { return x > y }))
+	case instruction.I16X8_LE_S:
+		err = i.vectorBinop(compareLanes(func(x, y int16) bool { return x <= y }))
+	case instruction.I16X8_LE_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint16) bool { return x <= y }))
+	case instruction.I16X8_GE_S:
+		err = i.vectorBinop(compareLanes(func(x, y int16) bool { return x >= y }))
+	case instruction.I16X8_GE_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint16) bool { return x >= y }))
+	case instruction.I32X4_EQ:
+		err = i.vectorBinop(compareLanes(func(x, y uint32) bool { return x == y }))
+	case instruction.I32X4_NE:
+		err = i.vectorBinop(compareLanes(func(x, y uint32) bool { return x != y }))
+	case instruction.I32X4_LT_S:
+		err = i.vectorBinop(compareLanes(func(x, y int32) bool { return x < y }))
+	case instruction.I32X4_LT_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint32) bool { return x < y }))
+	case instruction.I32X4_GT_S:
+		err = i.vectorBinop(compareLanes(func(x, y int32) bool { return x > y }))
+	case instruction.I32X4_GT_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint32) bool { return x > y }))
+	case instruction.I32X4_LE_S:
+		err = i.vectorBinop(compareLanes(func(x, y int32) bool { return x <= y }))
+	case instruction.I32X4_LE_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint32) bool { return x <= y }))
+	case instruction.I32X4_GE_S:
+		err = i.vectorBinop(compareLanes(func(x, y int32) bool { return x >= y }))
+	case instruction.I32X4_GE_U:
+		err = i.vectorBinop(compareLanes(func(x, y uint32) bool { return x >= y }))
+	case instruction.I64X2_EQ:
+		err = i.vectorBinop(compareLanes(func(x, y uint64) bool { return x == y }))
+	case instruction.I64X2_NE:
+		err = i.vectorBinop(compareLanes(func(x, y uint64) bool { return x != y }))
+	case instruction.I64X2_LT_S:
+		err = i.vectorBinop(compareLanes(func(x, y int64) bool { return x < y }))
+	case instruction.I64X2_GT_S:
+		err = i.vectorBinop(compareLanes(func(x, y int64) bool { return x > y }))
+	case instruction.I64X2_LE_S:
+		err = i.vectorBinop(compareLanes(func(x, y int64) bool { return x <= y }))
+	case instruction.I64X2_GE_S:
+		err = i.vectorBinop(compareLanes(func(x, y int64) bool { return x >= y }))
+	case instruction.F32X4_EQ:
+		err = i.vectorBinop(compareLanes(func(x, y float32) bool { return x == y }))
+	case instruction.F32X4_NE:
+		err = i.vectorBinop(compareLanes(func(x, y float32) bool { return x != y }))
+	case instruction.F32X4_LT:
+		err = i.vectorBinop(compareLanes(func(x, y float32) bool { return x < y }))
+	case instruction.F32X4_GT:
+		err = i.vectorBinop(compareLanes(func(x, y float32) bool { return x > y }))
+	case instruction.F32X4_LE:
+		err = i.vectorBinop(compareLanes(func(x, y float32) bool { return x <= y }))
+	case instruction.F32X4_GE:
+		err = i.vectorBinop(compareLanes(func(x, y float32) bool { return x >= y }))
+	case instruction.F64X2_EQ:
+		err = i.vectorBinop(compareLanes(func(x, y float64) bool { return x == y }))
+	case instruction.F64X2_NE:
+		err = i.vectorBinop(compareLanes(func(x, y float64) bool { return x != y }))
+	case instruction.F64X2_LT:
+		err = i.vectorBinop(compareLanes(func(x, y float64) bool { return x < y }))
+	case instruction.F64X2_GT:
+		err = i.vectorBinop(compareLanes(func(x, y float64) bool { return x > y }))
+	case instruction.F64X2_LE:
+		err = i.vectorBinop(compareLanes(func(x, y float64) bool { return x <= y }))
+	case instruction.F64X2_GE:
+		err = i.vectorBinop(compareLanes(func(x, y float64) bool { return x >= y }))
+
+	// bitwise operations
+	case instruction.V128_NOT:
+		err = i.vectorUnop(mapLanes(func(x uint64) uint64 { return ^x }))
+	case instruction.V128_AND:
+		err = i.vectorBinop(zipLanes(func(x, y uint64) uint64 { return x & y }))
+	case instruction.V128_ANDNOT:
+		err = i.vectorBinop(zipLanes(func(x, y uint64) uint64 { return x &^ y }))
+	case instruction.V128_OR:
+		err = i.vectorBinop(zipLanes(func(x, y uint64) uint64 { return x | y }))
+	case instruction.V128_XOR:
+		err = i.vectorBinop(zipLanes(func(x, y uint64) uint64 { return x ^ y }))
+	case instruction.V128_BITSELECT:
+		err = i.vectorBitselect()
+	case instruction.V128_ANY_TRUE:
+		err = i.vectorTest(func(a value.Vector) bool { return a != value.Vector{} })
+
+	// integer operations
+	case instruction.I8X16_ABS:
+		err = i.vectorUnop(mapLanes(abs[int8]))
+	case instruction.I8X16_NEG:
+		err = i.vectorUnop(mapLanes(func(x int8) int8 { return -x }))
+	case instruction.I8X16_POPCNT:
+		err = i.vectorUnop(mapLanes(func(x uint8) uint8 { return uint8(mbits.OnesCount8(x)) }))
+	case instruction.I8X16_ALL_TRUE:
+		err = i.vectorTest(allTrue[uint8])
+	case instruction.I8X16_BITMASK:
+		err = i.vectorBitmask(bitmask[int8])
+	case instruction.I8X16_NARROW_I16X8_S:
+		err = i.vectorBinop(narrowLanes[int16, int8](math.MinInt8, math.MaxInt8))
+	case instruction.I8X16_NARROW_I16X8_U:
+		err = i.vectorBinop(narrowLanes[int16, uint8](0, math.MaxUint8))
+	case instruction.I8X16_SHL:
+		err = i.vectorShift(shiftLanes(func(x uint8, n uint32) uint8 { return x << n }))
+	case instruction.I8X16_SHR_S:
+		err = i.vectorShift(shiftLanes(func(x int8, n uint32) int8 { return x >> n }))
+	case instruction.I8X16_SHR_U:
+		err = i.vectorShift(shiftLanes(func(x uint8, n uint32) uint8 { return x >> n }))
+	case instruction.I8X16_ADD:
+		err = i.vectorBinop(zipLanes(func(x, y uint8) uint8 { return x + y }))
+	case instruction.I8X16_ADD_SAT_S:
+		err = i.vectorBinop(zipLanes(func(x, y int8) int8 { return int8(saturate(int64(x)+int64(y), math.MinInt8, math.MaxInt8)) }))
+	case instruction.I8X16_ADD_SAT_U:
+		err = i.vectorBinop(zipLanes(func(x, y uint8) uint8 { return uint8(saturate(int64(x)+int64(y), 0, math.MaxUint8)) }))
+	case instruction.I8X16_SUB:
+		err = i.vectorBinop(zipLanes(func(x, y uint8) uint8 { return x - y }))
+	case instruction.I8X16_SUB_SAT_S:
+		err = i.vectorBinop(zipLanes(func(x, y int8) int8 { return int8(saturate(int64(x)-int64(y), math.MinInt8, math.MaxInt8)) }))
+	case instruction.I8X16_SUB_SAT_U:
+		err = i.vectorBinop(zipLanes(func(x, y uint8) uint8 { return uint8(saturate(int64(x)-int64(y), 0, math.MaxUint8)) }))
+	case instruction.I8X16_MIN_S:
+		err = i.vectorBinop(zipLanes(minLane[int8]))
+	case instruction.I8X16_MIN_U:
+		err = i.vectorBinop(zipLanes(minLane[uint8]))
+	case instruction.I8X16_MAX_S:
+		err = i.vectorBinop(zipLanes(maxLane[int8]))
+	case instruction.I8X16_MAX_U:
+		err = i.vectorBinop(zipLanes(maxLane[uint8]))
+	case instruction.I8X16_AVGR_U:
+		err = i.vectorBinop(zipLanes(avgr[uint8]))
+	case instruction.I16X8_EXTADD_PAIRWISE_I8X16_S:
+		err = i.vectorUnop(extaddPairwise[int8, int16])
+	case instruction.I16X8_EXTADD_PAIRWISE_I8X16_U:
+		err = i.vectorUnop(extaddPairwise[uint8, uint16])
+	case instruction.I32X4_EXTADD_PAIRWISE_I16X8_S:
+		err = i.vectorUnop(extaddPairwise[int16, int32])
+	case instruction.I32X4_EXTADD_PAIRWISE_I16X8_U:
+		err = i.vectorUnop(extaddPairwise[uint16, uint32])
+	case instruction.I16X8_ABS:
+		err = i.vectorUnop(mapLanes(abs[int16]))
+	case instruction.I16X8_NEG:
+		err = i.vectorUnop(mapLanes(func(x int16) int16 { return -x }))
+	case instruction.I16X8_Q15MULR_SAT_S:
+		err = i.vectorBinop(zipLanes(func(x, y int16) int16 {
+			return int16(saturate((int64(x)*int64(y)+0x4000)>>15, math.MinInt16, math.MaxInt16))
+		}))
+	case instruction.I16X8_ALL_TRUE:
+		err = i.vectorTest(allTrue[uint16])
+	case instruction.I16X8_BITMASK:
+		err = i.vectorBitmask(bitmask[int16])
+	case instruction.I16X8_NARROW_I32X4_S:
+		err = i.vectorBinop(narrowLanes[int32, int16](math.MinInt16, math.MaxInt16))
+	case instruction.I16X8_NARROW_I32X4_U:
+		err = i.vectorBinop(narrowLanes[int32, uint16](0, math.MaxUint16))
+	case instruction.I16X8_EXTEND_LOW_I8X16_S:
+		err = i.vectorUnop(extendLanes[int8, int16](false))
+	case instruction.I16X8_EXTEND_HIGH_I8X16_S:
+		err = i.vectorUnop(extendLanes[int8, int16](true))
+	case instruction.I16X8_EXTEND_LOW_I8X16_U:
+		err = i.vectorUnop(extendLanes[uint8, uint16](false))
+	case instruction.I16X8_EXTEND_HIGH_I8X16_U:
+		err = i.vectorUnop(extendLanes[uint8, uint16](true))
+	case instruction.I16X8_SHL:
+		err = i.vectorShift(shiftLanes(func(x uint16, n uint32) uint16 { return x << n }))
+	case instruction.I16X8_SHR_S:
+		err = i.vectorShift(shiftLanes(func(x int16, n uint32) int16 { return x >> n }))
+	case instruction.I16X8_SHR_U:
+		err = i.vectorShift(shiftLanes(func(x uint16, n uint32) uint16 { return x >> n }))
+	case instruction.I16X8_ADD:
+		err = i.vectorBinop(zipLanes(func(x, y uint16) uint16 { return x + y }))
+	case instruction.I16X8_ADD_SAT_S:
+		err = i.vectorBinop(zipLanes(func(x, y int16) int16 { return int16(saturate(int64(x)+int64(y), math.MinInt16, math.MaxInt16)) }))
+	case instruction.I16X8_ADD_SAT_U:
+		err = i.vectorBinop(zipLanes(func(x, y uint16) uint16 { return uint16(saturate(int64(x)+int64(y), 0, math.MaxUint16)) }))
+	case instruction.I16X8_SUB:
+		err = i.vectorBinop(zipLanes(func(x, y uint16) uint16 { return x - y }))
+	case instruction.I16X8_SUB_SAT_S:
+		err = i.vectorBinop(zipLanes(func(x, y int16) int16 { return int16(saturate(int64(x)-int64(y), math.MinInt16, math.MaxInt16)) }))
+	case instruction.I16X8_SUB_SAT_U:
+		err = i.vectorBinop(zipLanes(func(x, y uint16) uint16 { return uint16(saturate(int64(x)-int64(y), 0, math.MaxUint16)) }))
+	case instruction.I16X8_MUL:
+		err = i.vectorBinop(zipLanes(func(x, y uint16) uint16 { return x * y }))
+	case instruction.I16X8_MIN_S:
+		err = i.vectorBinop(zipLanes(minLane[int16]))
+	case instruction.I16X8_MIN_U:
+		err = i.vectorBinop(zipLanes(minLane[uint16]))
+	case instruction.I16X8_MAX_S:
+		err = i.vectorBinop(zipLanes(maxLane[int16]))
+	case instruction.I16X8_MAX_U:
+		err = i.vectorBinop(zipLanes(maxLane[uint16]))
+	case instruction.I16X8_AVGR_U:
+		err = i.vectorBinop(zipLanes(avgr[uint16]))
+	case instruction.I16X8_EXTMUL_LOW_I8X16_S:
+		err = i.vectorBinop(extmulLanes[int8, int16](false))
+	case instruction.I16X8_EXTMUL_HIGH_I8X16_S:
+		err = i.vectorBinop(extmulLanes[int8, int16](true))
+	case instruction.I16X8_EXTMUL_LOW_I8X16_U:
+		err = i.vectorBinop(extmulLanes[uint8, uint16](false))
+	case instruction.I16X8_EXTMUL_HIGH_I8X16_U:
+		err = i.vectorBinop(extmulLanes[uint8, uint16](true))
+	case instruction.I32X4_ABS:
+		err = i.vectorUnop(mapLanes(abs[int32]))
+	case instruction.I32X4_NEG:
+		err = i.vectorUnop(mapLanes(func(x int32) int32 { return -x }))
+	case instruction.I32X4_ALL_TRUE:
+		err = i.vectorTest(allTrue[uint32])
+	case instruction.I32X4_BITMASK:
+		err = i.vectorBitmask(bitmask[int32])
+	case instruction.I32X4_EXTEND_LOW_I16X8_S:
+		err = i.vectorUnop(extendLanes[int16, int32](false))
+	case instruction.I32X4_EXTEND_HIGH_I16X8_S:
+		err = i.vectorUnop(extendLanes[int16, int32](true))
+	case instruction.I32X4_EXTEND_LOW_I16X8_U:
+		err = i.vectorUnop(extendLanes[uint16, uint32](false))
+	case instruction.I32X4_EXTEND_HIGH_I16X8_U:
+		err = i.vectorUnop(extendLanes[uint16, uint32](true))
+	case instruction.I32X4_SHL:
+		err = i.vectorShift(shiftLanes(func(x uint32, n uint32) uint32 { return x << n }))
+	case instruction.I32X4_SHR_S:
+		err = i.vectorShift(shiftLanes(func(x int32, n uint32) int32 { return x >> n }))
+	case instruction.I32X4_SHR_U:
+		err = i.vectorShift(shiftLanes(func(x uint32, n uint32) uint32 { return x >> n }))
+	case instruction.I32X4_ADD:
+		err = i.vectorBinop(zipLanes(func(x, y uint32) uint32 { return x + y }))
+	case instruction.I32X4_SUB:
+		err = i.vectorBinop(zipLanes(func(x, y uint32) uint32 { return x - y }))
+	case instruction.I32X4_MUL:
+		err = i.vectorBinop(zipLanes(func(x, y uint32) uint32 { return x * y }))
+	case instruction.I32X4_MIN_S:
+		err = i.vectorBinop(zipLanes(minLane[int32]))
+	case instruction.I32X4_MIN_U:
+		err = i.vectorBinop(zipLanes(minLane[uint32]))
+	case instruction.I32X4_MAX_S:
+		err = i.vectorBinop(zipLanes(maxLane[int32]))
+	case instruction.I32X4_MAX_U:
+		err = i.vectorBinop(zipLanes(maxLane[uint32]))
+	case instruction.I32X4_DOT_I16X8_S:
+		err = i.vectorBinop(func(a, b value.Vector) (r value.Vector) {
+			for n := 0; n < 4; n++ {
+				x := int32(getLane[int16](a, 2*n))*int32(getLane[int16](b, 2*n)) + int32(getLane[int16](a, 2*n+1))*int32(getLane[int16](b, 2*n+1))
+				setLane(&r, n, x)
+			}
+			return r
+		})
+	case instruction.I32X4_EXTMUL_LOW_I16X8_S:
+		err = i.vectorBinop(extmulLanes[int16, int32](false))
+	case instruction.I32X4_EXTMUL_HIGH_I16X8_S:
+		err = i.vectorBinop(extmulLanes[int16, int32](true))
+	case instruction.I32X4_EXTMUL_LOW_I16X8_U:
+		err = i.vectorBinop(extmulLanes[uint16, uint32](false))
+	case instruction.I32X4_EXTMUL_HIGH_I16X8_U:
+		err = i.vectorBinop(extmulLanes[uint16, uint32](true))
+	case instruction.I64X2_ABS:
+		err = i.vectorUnop(mapLanes(abs[int64]))
+	case instruction.I64X2_NEG:
+		err = i.vectorUnop(mapLanes(func(x int64) int64 { return -x }))
+	case instruction.I64X2_ALL_TRUE:
+		err = i.vectorTest(allTrue[uint64])
+	case instruction.I64X2_BITMASK:
+		err = i.vectorBitmask(bitmask[int64])
+	case instruction.I64X2_EXTEND_LOW_I32X4_S:
+		err = i.vectorUnop(extendLanes[int32, int64](false))
+	case instruction.I64X2_EXTEND_HIGH_I32X4_S:
+		err = i.vectorUnop(extendLanes[int32, int64](true))
+	case instruction.I64X2_EXTEND_LOW_I32X4_U:
+		err = i.vectorUnop(extendLanes[uint32, uint64](false))
+	case instruction.I64X2_EXTEND_HIGH_I32X4_U:
+		err = i.vectorUnop(extendLanes[uint32, uint64](true))
+	case instruction.I64X2_SHL:
+		err = i.vectorShift(shiftLanes(func(x uint64, n uint32) uint64 { return x << n }))
+	case instruction.I64X2_SHR_S:
+		err = i.vectorShift(shiftLanes(func(x int64, n uint32) int64 { return x >> n }))
+	case instruction.I64X2_SHR_U:
+		err = i.vectorShift(shiftLanes(func(x uint64, n uint32) uint64 { return x >> n }))
+	case instruction.I64X2_ADD:
+		err = i.vectorBinop(zipLanes(func(x, y uint64) uint64 { return x + y }))
+	case instruction.I64X2_SUB:
+		err = i.vectorBinop(zipLanes(func(x, y uint64) uint64 { return x - y }))
+	case instruction.I64X2_MUL:
+		err = i.vectorBinop(zipLanes(func(x, y uint64) uint64 { return x * y }))
+	case instruction.I64X2_EXTMUL_LOW_I32X4_S:
+		err = i.vectorBinop(extmulLanes[int32, int64](false))
+	case instruction.I64X2_EXTMUL_HIGH_I32X4_S:
+		err = i.vectorBinop(extmulLanes[int32, int64](true))
+	case instruction.I64X2_EXTMUL_LOW_I32X4_U:
+		err = i.vectorBinop(extmulLanes[uint32, uint64](false))
+	case instruction.I64X2_EXTMUL_HIGH_I32X4_U:
+		err = i.vectorBinop(extmulLanes[uint32, uint64](true))
+
+	// floating point operations
+	case instruction.F32X4_ABS:
+		err = i.vectorUnop(mapLanes(func(x uint32) uint32 { return x &^ (1 << 31) }))
+	case instruction.F32X4_NEG:
+		err = i.vectorUnop(mapLanes(func(x uint32) uint32 { return x ^ (1 << 31) }))
+	case instruction.F32X4_SQRT:
+		err = i.vectorUnop(mapLanes(func(x float32) float32 { return float32(math.Sqrt(float64(x))) }))
+	case instruction.F32X4_CEIL:
+		err = i.vectorUnop(mapLanes(func(x float32) float32 { return float32(math.Ceil(float64(x))) }))
+	case instruction.F32X4_FLOOR:
+		err = i.vectorUnop(mapLanes(func(x float32) float32 { return float32(math.Floor(float64(x))) }))
+	case instruction.F32X4_TRUNC:
+		err = i.vectorUnop(mapLanes(func(x float32) float32 { return float32(math.Trunc(float64(x))) }))
+	case instruction.F32X4_NEAREST:
+		err = i.vectorUnop(mapLanes(func(x float32) float32 { return float32(math.RoundToEven(float64(x))) }))
+	case instruction.F32X4_ADD:
+		err = i.vectorBinop(zipLanes(func(x, y float32) float32 { return x + y }))
+	case instruction.F32X4_SUB:
+		err = i.vectorBinop(zipLanes(func(x, y float32) float32 { return x - y }))
+	case instruction.F32X4_MUL:
+		err = i.vectorBinop(zipLanes(func(x, y float32) float32 { return x * y }))
+	case instruction.F32X4_DIV:
+		err = i.vectorBinop(zipLanes(func(x, y float32) float32 { return x / y }))
+	case instruction.F32X4_MIN:
+		err = i.vectorBinop(zipLanes(func(x, y float32) float32 { return float32(math.Min(float64(x), float64(y))) }))
+	case instruction.F32X4_MAX:
+		err = i.vectorBinop(zipLanes(func(x, y float32) float32 { return float32(math.Max(float64(x), float64(y))) }))
+	case instruction.F32X4_PMIN:
+		err = i.vectorBinop(zipLanes(pmin[float32]))
+	case instruction.F32X4_PMAX:
+		err = i.vectorBinop(zipLanes(pmax[float32]))
+	case instruction.F64X2_ABS:
+		err = i.vectorUnop(mapLanes(func(x uint64) uint64 { return x &^ (1 << 63) }))
+	case instruction.F64X2_NEG:
+		err = i.vectorUnop(mapLanes(func(x uint64) uint64 { return x ^ (1 << 63) }))
+	case instruction.F64X2_SQRT:
+		err = i.vectorUnop(mapLanes(math.Sqrt))
+	case instruction.F64X2_CEIL:
+		err = i.vectorUnop(mapLanes(math.Ceil))
+	case instruction.F64X2_FLOOR:
+		err = i.vectorUnop(mapLanes(math.Floor))
+	case instruction.F64X2_TRUNC:
+		err = i.vectorUnop(mapLanes(math.Trunc))
+	case instruction.F64X2_NEAREST:
+		err = i.vectorUnop(mapLanes(math.RoundToEven))
+	case instruction.F64X2_ADD:
+		err = i.vectorBinop(zipLanes(func(x, y float64) float64 { return x + y }))
+	case instruction.F64X2_SUB:
+		err = i.vectorBinop(zipLanes(func(x, y float64) float64 { return x - y }))
+	case instruction.F64X2_MUL:
+		err = i.vectorBinop(zipLanes(func(x, y float64) float64 { return x * y }))
+	case instruction.F64X2_DIV:
+		err = i.vectorBinop(zipLanes(func(x, y float64) float64 { return x / y }))
+	case instruction.F64X2_MIN:
+		err = i.vectorBinop(zipLanes(math.Min))
+	case instruction.F64X2_MAX:
+		err = i.vectorBinop(zipLanes(math.Max))
+	case instruction.F64X2_PMIN:
+		err = i.vectorBinop(zipLanes(pmin[float64]))
+	case instruction.F64X2_PMAX:
+		err = i.vectorBinop(zipLanes(pmax[float64]))
+
+	// conversions
+	case instruction.I32X4_TRUNC_SAT_F32X4_S:
+		err = i.vectorUnop(convertLanes(4, func(a value.Vector, n int) int32 {
+			return int32(truncSat(float64(getLane[float32](a, n)), math.MinInt32, math.MaxInt32))
+		}))
+	case instruction.I32X4_TRUNC_SAT_F32X4_U:
+		err = i.vectorUnop(convertLanes(4, func(a value.Vector, n int) uint32 {
+			return uint32(truncSat(float64(getLane[float32](a, n)), 0, math.MaxUint32))
+		}))
+	case instruction.F32X4_CONVERT_I32X4_S:
+		err = i.vectorUnop(convertLanes(4, func(a value.Vector, n int) float32 { return float32(getLane[int32](a, n)) }))
+	case instruction.F32X4_CONVERT_I32X4_U:
+		err = i.vectorUnop(convertLanes(4, func(a value.Vector, n int) float32 { return float32(getLane[uint32](a, n)) }))
+	case instruction.I32X4_TRUNC_SAT_F64X2_S_ZERO:
+		err = i.vectorUnop(convertLanes(2, func(a value.Vector, n int) int32 {
+			return int32(truncSat(getLane[float64](a, n), math.MinInt32, math.MaxInt32))
+		}))
+	case instruction.I32X4_TRUNC_SAT_F64X2_U_ZERO:
+		err = i.vectorUnop(convertLanes(2, func(a value.Vector, n int) uint32 {
+			return uint32(truncSat(getLane[float64](a, n), 0, math.MaxUint32))
+		}))
+	case instruction.F64X2_CONVERT_LOW_I32X4_S:
+		err = i.vectorUnop(convertLanes(2, func(a value.Vector, n int) float64 { return float64(getLane[int32](a, n)) }))
+	case instruction.F64X2_CONVERT_LOW_I32X4_U:
+		err = i.vectorUnop(convertLanes(2, func(a value.Vector, n int) float64 { return float64(getLane[uint32](a, n)) }))
+	case instruction.F32X4_DEMOTE_F64X2_ZERO:
+		err = i.vectorUnop(convertLanes(2, func(a value.Vector, n int) float32 { return float32(getLane[float64](a, n)) }))
+	case instruction.F64X2_PROMOTE_LOW_F32X4:
+		err = i.vectorUnop(convertLanes(2, func(a value.Vector, n int) float64 { return float64(getLane[float32](a, n)) }))
+	default:
+		return instructionResultTrap, fmt.Errorf("%s: %w", v, instruction.NotImplemented)
+	}
+	if err != nil {
+		return instructionResultTrap, fmt.Errorf("%s: %w", v, err)
+	}
+	return instructionResultRunNext, nil
+}
+
+func (i *interpreter) vectorUnop(f func(a value.Vector) value.Vector) error {
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	return i.stack.PushVector(f(a))
+}
+
+func (i *interpreter) vectorBinop(f func(a, b value.Vector) value.Vector) error {
+	b, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	return i.stack.PushVector(f(a, b))
+}
+
+func (i *interpreter) vectorShift(f func(a value.Vector, n uint32) value.Vector) error {
+	n, err := i.stack.PopI32()
+	if err != nil {
+		return err
+	}
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	return i.stack.PushVector(f(a, uint32(n)))
+}
+
+func (i *interpreter) vectorTest(f func(a value.Vector) bool) error {
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	if f(a) {
+		return i.stack.PushI32(1)
+	}
+	return i.stack.PushI32(0)
+}
+
+func (i *interpreter) vectorBitmask(f func(a value.Vector) uint32) error {
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	return i.stack.PushI32(value.I32(f(a)))
+}
+
+func (i *interpreter) vectorBitselect() error {
+	c, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	return i.vectorBinop(func(a, b value.Vector) (r value.Vector) {
+		for n := range r {
+			r[n] = a[n]&c[n] | b[n]&^c[n]
+		}
+		return r
+	})
+}
+
+func (i *interpreter) vectorSplat(op instruction.VectorOpcode) error {
+	var r value.Vector
+	switch op {
+	case instruction.I8X16_SPLAT:
+		x, err := i.stack.PopRaw(types.I32)
+		if err != nil {
+			return err
+		}
+		r = splat(uint8(x))
+	case instruction.I16X8_SPLAT:
+		x, err := i.stack.PopRaw(types.I32)
+		if err != nil {
+			return err
+		}
+		r = splat(uint16(x))
+	case instruction.I32X4_SPLAT:
+		x, err := i.stack.PopRaw(types.I32)
+		if err != nil {
+			return err
+		}
+		r = splat(uint32(x))
+	case instruction.I64X2_SPLAT:
+		x, err := i.stack.PopRaw(types.I64)
+		if err != nil {
+			return err
+		}
+		r = splat(x)
+	case instruction.F32X4_SPLAT:
+		// the bits are copied, so NaN payloads are kept
+		x, err := i.stack.PopRaw(types.F32)
+		if err != nil {
+			return err
+		}
+		r = splat(uint32(x))
+	case instruction.F64X2_SPLAT:
+		x, err := i.stack.PopRaw(types.F64)
+		if err != nil {
+			return err
+		}
+		r = splat(x)
+	}
+	return i.stack.PushVector(r)
+}
+
+func (i *interpreter) vectorShuffle(v *instruction.Vector) error {
+	b, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	var r value.Vector
+	for n, l := range v.Imm.Bytes {
+		switch {
+		case l < 16:
+			r[n] = a[l]
+		case l < 32:
+			r[n] = b[l-16]
+		default:
+			return fmt.Errorf("%w: %d", instruction.InvalidLaneIndex, l)
+		}
+	}
+	return i.stack.PushVector(r)
+}
+
+func (i *interpreter) vectorExtractLane(v *instruction.Vector) error {
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	l := int(v.Imm.Lane)
+	if l >= 16/vectorLaneSize(v.Op) {
+		return fmt.Errorf("invalid lane index %d", l)
+	}
+	switch v.Op {
+	case instruction.I8X16_EXTRACT_LANE_S:
+		return i.stack.PushRaw(uint64(uint32(getLane[int8](a, l))), types.I32)
+	case instruction.I8X16_EXTRACT_LANE_U:
+		return i.stack.PushRaw(uint64(getLane[uint8](a, l)), types.I32)
+	case instruction.I16X8_EXTRACT_LANE_S:
+		return i.stack.PushRaw(uint64(uint32(getLane[int16](a, l))), types.I32)
+	case instruction.I16X8_EXTRACT_LANE_U:
+		return i.stack.PushRaw(uint64(getLane[uint16](a, l)), types.I32)
+	case instruction.I32X4_EXTRACT_LANE:
+		return i.stack.PushRaw(uint64(getLane[uint32](a, l)), types.I32)
+	case instruction.I64X2_EXTRACT_LANE:
+		return i.stack.PushRaw(getLane[uint64](a, l), types.I64)
+	case instruction.F32X4_EXTRACT_LANE:
+		return i.stack.PushRaw(uint64(getLane[uint32](a, l)), types.F32)
+	default:
+		return i.stack.PushRaw(getLane[uint64](a, l), types.F64)
+	}
+}
+
+func (i *interpreter) vectorReplaceLane(v *instruction.Vector) error {
+	t := types.I32
+	switch v.Op {
+	case instruction.I64X2_REPLACE_LANE:
+		t = types.I64
+	case instruction.F32X4_REPLACE_LANE:
+		t = types.F32
+	case instruction.F64X2_REPLACE_LANE:
+		t = types.F64
+	}
+	x, err := i.stack.PopRaw(t)
+	if err != nil {
+		return err
+	}
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	l := int(v.Imm.Lane)
+	size := vectorLaneSize(v.Op)
+	if l >= 16/size {
+		return fmt.Errorf("invalid lane index %d", l)
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], x)
+	copy(a[l*size:(l+1)*size], buf[:size])
+	return i.stack.PushVector(a)
+}
+
+func (i *interpreter) vectorLoad(v *instruction.Vector) error {
+	mem, err := i.memory(v)
+	if err != nil {
+		return err
+	}
+	base, err := i.stack.PopI32()
+	if err != nil {
+		return err
+	}
+	var b value.Vector
+	if err := load(mem, uint32(base), v.Imm.Memory, b[:vectorAccessWidth(v.Op)]); err != nil {
+		return err
+	}
+	var r value.Vector
+	switch v.Op {
+	case instruction.V128_LOAD, instruction.V128_LOAD32_ZERO, instruction.V128_LOAD64_ZERO:
+		r = b
+	case instruction.V128_LOAD8X8_S:
+		r = extendLanes[int8, int16](false)(b)
+	case instruction.V128_LOAD8X8_U:
+		r = extendLanes[uint8, uint16](false)(b)
+	case instruction.V128_LOAD16X4_S:
+		r = extendLanes[int16, int32](false)(b)
+	case instruction.V128_LOAD16X4_U:
+		r = extendLanes[uint16, uint32](false)(b)
+	case instruction.V128_LOAD32X2_S:
+		r = extendLanes[int32, int64](false)(b)
+	case instruction.V128_LOAD32X2_U:
+		r = extendLanes[uint32, uint64](false)(b)
+	case instruction.V128_LOAD8_SPLAT:
+		r = splat(getLane[uint8](b, 0))
+	case instruction.V128_LOAD16_SPLAT:
+		r = splat(getLane[uint16](b, 0))
+	case instruction.V128_LOAD32_SPLAT:
+		r = splat(getLane[uint32](b, 0))
+	case instruction.V128_LOAD64_SPLAT:
+		r = splat(getLane[uint64](b, 0))
+	}
+	return i.stack.PushVector(r)
+}
+
+func (i *interpreter) vectorLoadLane(v *instruction.Vector) error {
+	mem, err := i.memory(v)
+	if err != nil {
+		return err
+	}
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	base, err := i.stack.PopI32()
+	if err != nil {
+		return err
+	}
+	width := vectorAccessWidth(v.Op)
+	l := int(v.Imm.Lane)
+	if l >= 16/int(width) {
+		return fmt.Errorf("invalid lane index %d", l)
+	}
+	if err := load(mem, uint32(base), v.Imm.Memory, a[l*int(width):(l+1)*int(width)]); err != nil {
+		return err
+	}
+	return i.stack.PushVector(a)
+}
+
+func (i *interpreter) vectorStore(v *instruction.Vector) error {
+	mem, err := i.memory(v)
+	if err != nil {
+		return err
+	}
+	a, err := i.stack.PopVector()
+	if err != nil {
+		return err
+	}
+	base, err := i.stack.PopI32()
+	if err != nil {
+		return err
+	}
+	width := vectorAccessWidth(v.Op)
+	l := int(v.Imm.Lane)
+	if l >= 16/int(width) {
+		return fmt.Errorf("invalid lane index %d", l)
+	}
+	return store(mem, uint32(base), v.Imm.Memory, a[l*int(width):(l+1)*int(width)])
+}
+
+// vectorAccessWidth returns the number of bytes accessed by a vector load or store.
+func vectorAccessWidth(op instruction.VectorOpcode) uint32 {
+	switch op {
+	case instruction.V128_LOAD8_SPLAT, instruction.V128_LOAD8_LANE, instruction.V128_STORE8_LANE:
+		return 1
+	case instruction.V128_LOAD16_SPLAT, instruction.V128_LOAD16_LANE, instruction.V128_STORE16_LANE:
+		return 2
+	case instruction.V128_LOAD32_SPLAT, instruction.V128_LOAD32_ZERO, instruction.V128_LOAD32_LANE, instruction.V128_STORE32_LANE:
+		return 4
+	case instruction.V128_LOAD8X8_S, instruction.V128_LOAD8X8_U, instruction.V128_LOAD16X4_S, instruction.V128_LOAD16X4_U,
+		instruction.V128_LOAD32X2_S, instruction.V128_LOAD32X2_U,
+		instruction.V128_LOAD64_SPLAT, instruction.V128_LOAD64_ZERO, instruction.V128_LOAD64_LANE, instruction.V128_STORE64_LANE:
+		return 8
+	default:
+		return 16
+	}
+}
+
+// vectorLaneSize returns the lane size in bytes of extract_lane and replace_lane.
+func vectorLaneSize(op instruction.VectorOpcode) int {
+	switch op {
+	case instruction.I8X16_EXTRACT_LANE_S, instruction.I8X16_EXTRACT_LANE_U, instruction.I8X16_REPLACE_LANE:
+		return 1
+	case instruction.I16X8_EXTRACT_LANE_S, instruction.I16X8_EXTRACT_LANE_U, instruction.I16X8_REPLACE_LANE:
+		return 2
+	case instruction.I32X4_EXTRACT_LANE, instruction.I32X4_REPLACE_LANE, instruction.F32X4_EXTRACT_LANE, instruction.F32X4_REPLACE_LANE:
+		return 4
+	default:
+		return 8
+	}
+}
+
+func laneSize[T lane]() int {
+	var x T
+	switch any(x).(type) {
+	case int8, uint8:
+		return 1
+	case int16, uint16:
+		return 2
+	case int32, uint32, float32:
+		return 4
+	default:
+		return 8
+	}
+}
+
+func getLane[T lane](v value.Vector, n int) T {
+	var x T
+	switch any(x).(type) {
+	case int8, uint8:
+		return T(v[n])
+	case int16, uint16:
+		return T(binary.LittleEndian.Uint16(v[2*n:]))
+	case int32, uint32:
+		return T(binary.LittleEndian.Uint32(v[4*n:]))
+	case float32:
+		return T(math.Float32frombits(binary.LittleEndian.Uint32(v[4*n:])))
+	case float64:
+		return T(math.Float64frombits(binary.LittleEndian.Uint64(v[8*n:])))
+	default:
+		return T(binary.LittleEndian.Uint64(v[8*n:]))
+	}
+}
+
+func setLane[T lane](v *value.Vector, n int, x T) {
+	switch any(x).(type) {
+	case int8, uint8:
+		v[n] = uint8(x)
+	case int16, uint16:
+		binary.LittleEndian.PutUint16(v[2*n:], uint16(x))
+	case int32, uint32:
+		binary.LittleEndian.PutUint32(v[4*n:], uint32(x))
+	case float32:
+		binary.LittleEndian.PutUint32(v[4*n:], math.Float32bits(float32(x)))
+	case float64:
+		binary.LittleEndian.PutUint64(v[8*n:], math.Float64bits(float64(x)))
+	default:
+		binary.LittleEndian.PutUint64(v[8*n:], uint64(x))
+	}
+}
+
+func splat[T lane](x T) (r value.Vector) {
+	for n := 0; n < 16/laneSize[T](); n++ {
+		setLane(&r, n, x)
+	}
+	return r
+}
+
+func mapLanes[T lane](f func(x T) T) func(a value.Vector) value.Vector {
+	return func(a value.Vector) (r value.Vector) {
+		for n := 0; n < 16/laneSize[T](); n++ {
+			setLane(&r, n, f(getLane[T](a, n)))
+		}
+		return r
+	}
+}
+
+func zipLanes[T lane](f func(x, y T) T) func(a, b value.Vector) value.Vector {
+	return func(a, b value.Vector) (r value.Vector) {
+		for n := 0; n < 16/laneSize[T](); n++ {
+			setLane(&r, n, f(getLane[T](a, n), getLane[T](b, n)))
+		}
+		return r
+	}
+}
+
+// compareLanes sets all bits of the lanes where f is true.
+func compareLanes[T lane](f func(x, y T) bool) func(a, b value.Vector) value.Vector {
+	return func(a, b value.Vector) (r value.Vector) {
+		size := laneSize[T]()
+		for n := 0; n < 16/size; n++ {
+			if f(getLane[T](a, n), getLane[T](b, n)) {
+				for j := n * size; j < (n+1)*size; j++ {
+					r[j] = 0xff
+				}
+			}
+		}
+		return r
+	}
+}
+
+// shiftLanes shifts lanes by the count modulo the lane width.
+func shiftLanes[T lane](f func(x T, n uint32) T) func(a value.Vector, n uint32) value.Vector {
+	return func(a value.Vector, count uint32) (r value.Vector) {
+		size := laneSize[T]()
+		for n := 0; n < 16/size; n++ {
+			setLane(&r, n, f(getLane[T](a, n), count%uint32(size*8)))
+		}
+		return r
+	}
+}
+
+// extendLanes extends the lower or the upper half of the lanes to the double width.
+func extendLanes[S, D lane](high bool) func(a value.Vector) value.Vector {
+	return func(a value.Vector) (r value.Vector) {
+		count := 16 / laneSize[D]()
+		offset := 0
+		if high {
+			offset = count
+		}
+		for n := 0; n < count; n++ {
+			setLane(&r, n, D(getLane[S](a, offset+n)))
+		}
+		return r
+	}
+}
+
+func extmulLanes[S, D lane](high bool) func(a, b value.Vector) value.Vector {
+	extend := extendLanes[S, D](high)
+	mul := zipLanes(func(x, y D) D { return x * y })
+	return func(a, b value.Vector) value.Vector {
+		return mul(extend(a), extend(b))
+	}
+}
+
+func extaddPairwise[S, D lane](a value.Vector) (r value.Vector) {
+	for n := 0; n < 16/laneSize[D](); n++ {
+		setLane(&r, n, D(getLane[S](a, 2*n))+D(getLane[S](a, 2*n+1)))
+	}
+	return r
+}
+
+// narrowLanes packs the lanes of a and b into the half width with saturation.
+func narrowLanes[S, D lane](lo, hi int64) func(a, b value.Vector) value.Vector {
+	return func(a, b value.Vector) (r value.Vector) {
+		count := 16 / laneSize[S]()
+		for n := 0; n < count; n++ {
+			setLane(&r, n, D(saturate(int64(getLane[S](a, n)), lo, hi)))
+			setLane(&r, count+n, D(saturate(int64(getLane[S](b, n)), lo, hi)))
+		}
+		return r
+	}
+}
+
+// convertLanes sets count lanes converted by f and zeros the rest.
+func convertLanes[D lane](count int, f func(a value.Vector, n int) D) func(a value.Vector) value.Vector {
+	return func(a value.Vector) (r value.Vector) {
+		for n := 0; n < count; n++ {
+			setLane(&r, n, f(a, n))
+		}
+		return r
+	}
+}
+
+func allTrue[T lane](a value.Vector) bool {
+	for n := 0; n < 16/laneSize[T](); n++ {
+		if getLane[T](a, n) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bitmask collects the sign bits of the lanes.
+func bitmask[T int8 | int16 | int32 | int64](a value.Vector) uint32 {
+	mask := uint32(0)
+	for n := 0; n < 16/laneSize[T](); n++ {
+		if getLane[T](a, n) < 0 {
+			mask |= 1 << n
+		}
+	}
+	return mask
+}
+
+func abs[T int8 | int16 | int32 | int64](x T) T {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func minLane[T int8 | uint8 | int16 | uint16 | int32 | uint32](x, y T) T {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func maxLane[T int8 | uint8 | int16 | uint16 | int32 | uint32](x, y T) T {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+func avgr[T uint8 | uint16](x, y T) T {
+	return T((uint32(x) + uint32(y) + 1) / 2)
+}
+
+func pmin[T float32 | float64](x, y T) T {
+	if y < x {
+		return y
+	}
+	return x
+}
+
+func pmax[T float32 | float64](x, y T) T {
+	if x < y {
+		return y
+	}
+	return x
+}
+
+func saturate(x, lo, hi int64) int64 {
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
+
+// truncSat truncates f to an integer in [lo, hi]. NaN is converted to 0.
+func truncSat(f float64, lo, hi int64) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f <= float64(lo):
+		return lo
+	case f >= float64(hi):
+		return hi
+	default:
+		return int64(math.Trunc(f))
+	}
+}
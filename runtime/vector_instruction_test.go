@@ -0,0 +1,316 @@
+package runtime
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/runtime/stack"
+	"github.com/terassyi/gowi/runtime/value"
+	"github.com/terassyi/gowi/types"
+)
+
+func vectorOf[T lane](xs ...T) (r value.Vector) {
+	for n, x := range xs {
+		setLane(&r, n, x)
+	}
+	return r
+}
+
+func TestExecVector(t *testing.T) {
+	nan := float32(math.NaN())
+	for _, d := range []struct {
+		instr    *instruction.Vector
+		operands []value.Vector
+		exp      value.Vector
+	}{
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_ADD},
+			operands: []value.Vector{splat(uint8(0xff)), splat(uint8(2))},
+			exp:      splat(uint8(1)),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_ADD_SAT_S},
+			operands: []value.Vector{vectorOf[int8](100, -100, 1), vectorOf[int8](100, -100, 1)},
+			exp:      vectorOf[int8](127, -128, 2),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_SUB_SAT_U},
+			operands: []value.Vector{vectorOf[uint8](1, 5), vectorOf[uint8](2, 3)},
+			exp:      vectorOf[uint8](0, 2),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_ABS},
+			operands: []value.Vector{vectorOf[int8](-1, 1, -128)},
+			exp:      vectorOf[int8](1, 1, -128),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_POPCNT},
+			operands: []value.Vector{vectorOf[uint8](0xff, 0x81)},
+			exp:      vectorOf[uint8](8, 2),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_AVGR_U},
+			operands: []value.Vector{vectorOf[uint8](1, 255), vectorOf[uint8](2, 255)},
+			exp:      vectorOf[uint8](2, 255),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_LT_S},
+			operands: []value.Vector{vectorOf[int8](-1, 1), vectorOf[int8](0, 0)},
+			exp:      vectorOf[uint8](0xff, 0),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_LT_U},
+			operands: []value.Vector{vectorOf[int8](-1, 1), vectorOf[int8](0, 2)},
+			exp:      vectorOf[uint8](0, 0xff),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_SWIZZLE},
+			operands: []value.Vector{vectorOf[uint8](10, 11, 12, 13), vectorOf[uint8](3, 0, 16, 0xff, 1)},
+			exp:      vectorOf[uint8](13, 10, 0, 0, 11, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_SHUFFLE, Imm: instruction.VectorImm{Bytes: [16]byte{16, 0, 31, 15}}},
+			operands: []value.Vector{vectorOf[uint8](1, 2), vectorOf[uint8](3, 4)},
+			exp:      vectorOf[uint8](3, 1, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_NARROW_I16X8_S},
+			operands: []value.Vector{vectorOf[int16](300, -300, 5), vectorOf[int16](-1)},
+			exp:      vectorOf[int8](127, -128, 5, 0, 0, 0, 0, 0, -1),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I8X16_NARROW_I16X8_U},
+			operands: []value.Vector{vectorOf[int16](300, -300, 5), vectorOf[int16](-1)},
+			exp:      vectorOf[uint8](255, 0, 5),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I16X8_EXTEND_HIGH_I8X16_S},
+			operands: []value.Vector{vectorOf[int8](0, 0, 0, 0, 0, 0, 0, 0, -2, 3)},
+			exp:      vectorOf[int16](-2, 3),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I16X8_EXTEND_LOW_I8X16_U},
+			operands: []value.Vector{vectorOf[int8](-2, 3)},
+			exp:      vectorOf[uint16](0xfe, 3),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I16X8_EXTADD_PAIRWISE_I8X16_S},
+			operands: []value.Vector{vectorOf[int8](-128, -128, 1, 2)},
+			exp:      vectorOf[int16](-256, 3),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I16X8_Q15MULR_SAT_S},
+			operands: []value.Vector{vectorOf[int16](-0x8000, 0x4000), vectorOf[int16](-0x8000, 0x4000)},
+			exp:      vectorOf[int16](0x7fff, 0x2000),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I16X8_EXTMUL_LOW_I8X16_S},
+			operands: []value.Vector{vectorOf[int8](-128, 3), vectorOf[int8](-128, -3)},
+			exp:      vectorOf[int16](0x4000, -9),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I32X4_DOT_I16X8_S},
+			operands: []value.Vector{vectorOf[int16](1, 2, -3, 4), vectorOf[int16](5, 6, 7, 8)},
+			exp:      vectorOf[int32](17, 11),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I32X4_MIN_U},
+			operands: []value.Vector{vectorOf[int32](-1, 1), vectorOf[int32](1, -1)},
+			exp:      vectorOf[int32](1, 1),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I32X4_MAX_S},
+			operands: []value.Vector{vectorOf[int32](-1, 1), vectorOf[int32](1, -1)},
+			exp:      vectorOf[int32](1, 1),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I64X2_MUL},
+			operands: []value.Vector{vectorOf[int64](-2, 1<<62), vectorOf[int64](3, 4)},
+			exp:      vectorOf[int64](-6, 0),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I64X2_GT_S},
+			operands: []value.Vector{vectorOf[int64](-2, 5), vectorOf[int64](3, 4)},
+			exp:      vectorOf[uint64](0, math.MaxUint64),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.V128_ANDNOT},
+			operands: []value.Vector{splat(uint8(0xff)), splat(uint8(0x0f))},
+			exp:      splat(uint8(0xf0)),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.V128_BITSELECT},
+			operands: []value.Vector{splat(uint8(0xaa)), splat(uint8(0x55)), splat(uint8(0xf0))},
+			exp:      splat(uint8(0xa5)),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F32X4_ADD},
+			operands: []value.Vector{vectorOf[float32](1.5, -1), vectorOf[float32](2, 1)},
+			exp:      vectorOf[float32](3.5, 0),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F32X4_MIN},
+			operands: []value.Vector{vectorOf[float32](1, nan, float32(math.Copysign(0, -1))), vectorOf[float32](2, 1)},
+			exp:      vectorOf[float32](1, nan, float32(math.Copysign(0, -1))),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F32X4_PMAX},
+			operands: []value.Vector{vectorOf[float32](1, nan), vectorOf[float32](2, 1)},
+			exp:      vectorOf[float32](2, nan),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F32X4_NEG},
+			operands: []value.Vector{vectorOf[float32](1, 0)},
+			exp:      vectorOf[float32](-1, float32(math.Copysign(0, -1)), float32(math.Copysign(0, -1)), float32(math.Copysign(0, -1))),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F32X4_NEAREST},
+			operands: []value.Vector{vectorOf[float32](2.5, -1.5, 0.4)},
+			exp:      vectorOf[float32](2, -2, 0),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F32X4_EQ},
+			operands: []value.Vector{vectorOf[float32](1, nan), vectorOf[float32](1, nan)},
+			exp:      vectorOf[uint32](0xffffffff, 0, 0xffffffff, 0xffffffff),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F64X2_SQRT},
+			operands: []value.Vector{vectorOf[float64](4, 2.25)},
+			exp:      vectorOf[float64](2, 1.5),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I32X4_TRUNC_SAT_F32X4_S},
+			operands: []value.Vector{vectorOf[float32](1e10, -1e10, nan, -1.9)},
+			exp:      vectorOf[int32](math.MaxInt32, math.MinInt32, 0, -1),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I32X4_TRUNC_SAT_F64X2_U_ZERO},
+			operands: []value.Vector{vectorOf[float64](-1, 1e10)},
+			exp:      vectorOf[uint32](0, math.MaxUint32),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F64X2_CONVERT_LOW_I32X4_U},
+			operands: []value.Vector{vectorOf[int32](-1, 2, 3, 4)},
+			exp:      vectorOf[float64](math.MaxUint32, 2),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.F32X4_DEMOTE_F64X2_ZERO},
+			operands: []value.Vector{vectorOf[float64](1.5, -2)},
+			exp:      vectorOf[float32](1.5, -2),
+		},
+	} {
+		i := &interpreter{stack: stack.New()}
+		for _, o := range d.operands {
+			require.NoError(t, i.stack.PushVector(o))
+		}
+		_, err := i.execVector(d.instr)
+		require.NoError(t, err, d.instr.String())
+		res, err := i.stack.PopVector()
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, res, d.instr.String())
+		assert.Equal(t, 0, len(i.stack.Values()), d.instr.String())
+	}
+}
+
+func TestExecVector_Lane(t *testing.T) {
+	v := vectorOf[int8](0, -1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, -15)
+	for _, d := range []struct {
+		instr    *instruction.Vector
+		operands []uint64 // pushed after the vector
+		exp      value.Value
+	}{
+		{instr: &instruction.Vector{Op: instruction.I8X16_EXTRACT_LANE_S, Imm: instruction.VectorImm{Lane: 1}}, exp: value.I32(0xffffffff)},
+		{instr: &instruction.Vector{Op: instruction.I8X16_EXTRACT_LANE_U, Imm: instruction.VectorImm{Lane: 15}}, exp: value.I32(0xf1)},
+		{instr: &instruction.Vector{Op: instruction.I16X8_EXTRACT_LANE_S, Imm: instruction.VectorImm{Lane: 0}}, exp: value.I32(0xffffff00)},
+		{instr: &instruction.Vector{Op: instruction.I64X2_EXTRACT_LANE, Imm: instruction.VectorImm{Lane: 1}}, exp: value.I64(0xf10e0d0c0b0a0908)},
+		{instr: &instruction.Vector{Op: instruction.I8X16_BITMASK}, exp: value.I32(0x8002)},
+		{instr: &instruction.Vector{Op: instruction.I8X16_ALL_TRUE}, exp: value.I32(0)},
+		{instr: &instruction.Vector{Op: instruction.I16X8_ALL_TRUE}, exp: value.I32(1)},
+		{instr: &instruction.Vector{Op: instruction.V128_ANY_TRUE}, exp: value.I32(1)},
+		{
+			instr:    &instruction.Vector{Op: instruction.I16X8_SHR_S},
+			operands: []uint64{17}, // shifts by 1
+			exp:      value.Vector(vectorOf[int16](-128, 0x0181, 0x0282, 0x0383, 0x0484, 0x0585, 0x0686, -1913)),
+		},
+		{
+			instr:    &instruction.Vector{Op: instruction.I32X4_REPLACE_LANE, Imm: instruction.VectorImm{Lane: 3}},
+			operands: []uint64{0xdeadbeef},
+			exp:      value.Vector(vectorOf[uint32](0x0302ff00, 0x07060504, 0x0b0a0908, 0xdeadbeef)),
+		},
+	} {
+		i := &interpreter{stack: stack.New()}
+		require.NoError(t, i.stack.PushVector(v))
+		for _, o := range d.operands {
+			require.NoError(t, i.stack.PushRaw(o, types.I32))
+		}
+		_, err := i.execVector(d.instr)
+		require.NoError(t, err, d.instr.String())
+		res, err := i.stack.PopValue()
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, res, d.instr.String())
+	}
+
+	i := &interpreter{stack: stack.New()}
+	require.NoError(t, i.stack.PushVector(v))
+	_, err := i.execVector(&instruction.Vector{Op: instruction.I32X4_EXTRACT_LANE, Imm: instruction.VectorImm{Lane: 4}})
+	assert.Error(t, err)
+
+	i = &interpreter{stack: stack.New()}
+	require.NoError(t, i.stack.PushVector(v))
+	require.NoError(t, i.stack.PushVector(v))
+	_, err = i.execVector(&instruction.Vector{Op: instruction.I8X16_SHUFFLE, Imm: instruction.VectorImm{Bytes: [16]byte{15: 40}}})
+	assert.ErrorIs(t, err, instruction.InvalidLaneIndex)
+}
+
+func TestExecVector_MemoryBounds(t *testing.T) {
+	for _, op := range []instruction.VectorOpcode{
+		instruction.V128_LOAD, instruction.V128_LOAD8X8_S, instruction.V128_LOAD32_SPLAT, instruction.V128_LOAD64_ZERO,
+		instruction.V128_LOAD8_LANE, instruction.V128_LOAD64_LANE,
+		instruction.V128_STORE, instruction.V128_STORE16_LANE,
+	} {
+		width := vectorAccessWidth(op)
+		for _, d := range memoryAccessEdges {
+			i, mem := newMemoryInterpreter(1)
+			instr := &instruction.Vector{Op: op, Imm: instruction.VectorImm{Memory: instruction.MemoryImm{Offset: d.offset(uint32(mem.Size()), width)}}}
+			require.NoError(t, i.stack.PushI32(value.I32(d.base(uint32(mem.Size()), width))))
+			switch op {
+			case instruction.V128_LOAD8_LANE, instruction.V128_LOAD64_LANE, instruction.V128_STORE, instruction.V128_STORE16_LANE:
+				require.NoError(t, i.stack.PushVector(splat(uint8(0xff))))
+			}
+			_, err := i.execVector(instr)
+			if d.trap {
+				assert.ErrorIs(t, err, MemoryDoesNotHaveEnoughLength, "%s: %s", instr, d.name)
+				assert.Equal(t, make([]byte, mem.Size()), mem.Data, "%s: %s", instr, d.name)
+				continue
+			}
+			require.NoError(t, err, "%s: %s", instr, d.name)
+		}
+	}
+}
+
+func TestExecVector_Load(t *testing.T) {
+	for _, d := range []struct {
+		op  instruction.VectorOpcode
+		exp value.Vector
+	}{
+		{op: instruction.V128_LOAD, exp: vectorOf[uint8](0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f)},
+		{op: instruction.V128_LOAD8X8_S, exp: vectorOf[int16](-128, -127, -126, -125, -124, -123, -122, -121)},
+		{op: instruction.V128_LOAD16X4_U, exp: vectorOf[uint32](0x8180, 0x8382, 0x8584, 0x8786)},
+		{op: instruction.V128_LOAD32X2_S, exp: vectorOf[int64](-0x7c7d7e80, -0x78797a7c)},
+		{op: instruction.V128_LOAD16_SPLAT, exp: splat(uint16(0x8180))},
+		{op: instruction.V128_LOAD32_ZERO, exp: vectorOf[uint32](0x83828180)},
+	} {
+		i, mem := newMemoryInterpreter(1)
+		ea := uint32(mem.Size()) - vectorAccessWidth(d.op)
+		copy(mem.Data[ea:], []byte{0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f})
+		require.NoError(t, i.stack.PushI32(value.I32(ea)))
+		_, err := i.execVector(&instruction.Vector{Op: d.op})
+		require.NoError(t, err)
+		res, err := i.stack.PopVector()
+		require.NoError(t, err)
+		assert.Equal(t, d.exp, res, d.op.String())
+	}
+}
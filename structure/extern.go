@@ -0,0 +1,182 @@
+package structure
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/types"
+)
+
+var (
+	InvalidTypeIndex   error = errors.New("type index is out of range")
+	InvalidExportIndex error = errors.New("export index is out of range")
+)
+
+// ExternType is the type of an imported or exported entity.
+// The field for the kind is set.
+type ExternType struct {
+	Kind   DescType
+	Func   *types.FuncType // for functions and tags
+	Table  *types.TableType
+	Memory *types.MemoryType
+	Global *types.GlobalType
+}
+
+// ImportType is an import with its type.
+type ImportType struct {
+	Module string
+	Name   string
+	Type   *ExternType
+}
+
+// ExportType is an export with the type of the exported entity.
+type ExportType struct {
+	Name string
+	Type *ExternType
+}
+
+func (e *ExternType) String() string {
+	switch e.Kind {
+	case DescTypeFunc:
+		return fmt.Sprintf("func (%s) -> (%s)", e.Func.Params, e.Func.Returns)
+	case DescTypeTable:
+		return fmt.Sprintf("table %s %s", e.Table.ElementType, limitsString(e.Table.Limits))
+	case DescTypeMemory:
+		if e.Memory.Shared {
+			return fmt.Sprintf("memory %s shared", limitsString(e.Memory.Limits))
+		}
+		return fmt.Sprintf("memory %s", limitsString(e.Memory.Limits))
+	case DescTypeGlobal:
+		if e.Global.Mut {
+			return fmt.Sprintf("global mut %s", e.Global.ContentType)
+		}
+		return fmt.Sprintf("global %s", e.Global.ContentType)
+	case DescTypeTag:
+		return fmt.Sprintf("tag (%s)", e.Func.Params)
+	default:
+		return e.Kind.String()
+	}
+}
+
+func limitsString(l *types.Limits) string {
+	if l.Max == 0 {
+		return fmt.Sprintf("min=%d", l.Min)
+	}
+	return fmt.Sprintf("min=%d max=%d", l.Min, l.Max)
+}
+
+// ImportTypes returns the imports of the module with their types.
+func (m *Module) ImportTypes() ([]*ImportType, error) {
+	imports := make([]*ImportType, 0, len(m.Imports))
+	for _, imp := range m.Imports {
+		t, err := m.importType(imp.Desc)
+		if err != nil {
+			return nil, fmt.Errorf("import %s.%s: %w", imp.Module, imp.Name, err)
+		}
+		imports = append(imports, &ImportType{Module: imp.Module, Name: imp.Name, Type: t})
+	}
+	return imports, nil
+}
+
+// ExportTypes returns the exports of the module with the types of the exported entities.
+// The index spaces start with the imports.
+func (m *Module) ExportTypes() ([]*ExportType, error) {
+	exports := make([]*ExportType, 0, len(m.Exports))
+	for _, exp := range m.Exports {
+		t, err := m.exportType(exp.Desc)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", exp.Name, err)
+		}
+		exports = append(exports, &ExportType{Name: exp.Name, Type: t})
+	}
+	return exports, nil
+}
+
+func (m *Module) importType(desc *ImportDesc) (*ExternType, error) {
+	switch desc.Type {
+	case DescTypeFunc:
+		ft, err := m.funcType(desc.Func)
+		if err != nil {
+			return nil, err
+		}
+		return &ExternType{Kind: DescTypeFunc, Func: ft}, nil
+	case DescTypeTable:
+		return &ExternType{Kind: DescTypeTable, Table: desc.Table}, nil
+	case DescTypeMemory:
+		return &ExternType{Kind: DescTypeMemory, Memory: desc.Mem}, nil
+	case DescTypeGlobal:
+		return &ExternType{Kind: DescTypeGlobal, Global: desc.Global}, nil
+	case DescTypeTag:
+		ft, err := m.funcType(desc.Tag)
+		if err != nil {
+			return nil, err
+		}
+		return &ExternType{Kind: DescTypeTag, Func: ft}, nil
+	default:
+		return nil, InvalidDesType
+	}
+}
+
+func (m *Module) exportType(desc *ExportDesc) (*ExternType, error) {
+	// imported entities come first in the index spaces
+	imported := make([]*ImportDesc, 0)
+	for _, imp := range m.Imports {
+		if imp.Desc.Type == desc.Type {
+			imported = append(imported, imp.Desc)
+		}
+	}
+	if int(desc.Val) < len(imported) {
+		return m.importType(imported[desc.Val])
+	}
+	idx := int(desc.Val) - len(imported)
+	switch desc.Type {
+	case DescTypeFunc:
+		defined := make([]*Function, 0, len(m.Functions))
+		for _, f := range m.Functions {
+			if !f.Imported {
+				defined = append(defined, f)
+			}
+		}
+		if idx >= len(defined) {
+			return nil, fmt.Errorf("%w: func %d", InvalidExportIndex, desc.Val)
+		}
+		ft, err := m.funcType(defined[idx].Type)
+		if err != nil {
+			return nil, err
+		}
+		return &ExternType{Kind: DescTypeFunc, Func: ft}, nil
+	case DescTypeTable:
+		if idx >= len(m.Tables) {
+			return nil, fmt.Errorf("%w: table %d", InvalidExportIndex, desc.Val)
+		}
+		return &ExternType{Kind: DescTypeTable, Table: m.Tables[idx].Type}, nil
+	case DescTypeMemory:
+		if idx >= len(m.Memories) {
+			return nil, fmt.Errorf("%w: memory %d", InvalidExportIndex, desc.Val)
+		}
+		return &ExternType{Kind: DescTypeMemory, Memory: m.Memories[idx].Type}, nil
+	case DescTypeGlobal:
+		if idx >= len(m.Globals) {
+			return nil, fmt.Errorf("%w: global %d", InvalidExportIndex, desc.Val)
+		}
+		return &ExternType{Kind: DescTypeGlobal, Global: m.Globals[idx].Type}, nil
+	case DescTypeTag:
+		if idx >= len(m.Tags) {
+			return nil, fmt.Errorf("%w: tag %d", InvalidExportIndex, desc.Val)
+		}
+		ft, err := m.funcType(m.Tags[idx].Type)
+		if err != nil {
+			return nil, err
+		}
+		return &ExternType{Kind: DescTypeTag, Func: ft}, nil
+	default:
+		return nil, InvalidDesType
+	}
+}
+
+func (m *Module) funcType(idx uint32) (*types.FuncType, error) {
+	if int(idx) >= len(m.Types) {
+		return nil, fmt.Errorf("%w: %d", InvalidTypeIndex, idx)
+	}
+	return m.Types[idx], nil
+}
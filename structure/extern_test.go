@@ -0,0 +1,67 @@
+package structure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terassyi/gowi/types"
+)
+
+func TestModule_ExternTypes(t *testing.T) {
+	ft := &types.FuncType{Params: []types.ValueType{types.I32}, Returns: []types.ValueType{types.I64}}
+	table := &types.TableType{ElementType: types.ElemTypeFuncref, Limits: &types.Limits{Min: 1}}
+	mem := &types.MemoryType{Limits: &types.Limits{Min: 1, Max: 2}}
+	imported := &types.GlobalType{ContentType: types.I32}
+	defined := &types.GlobalType{ContentType: types.F64, Mut: true}
+	mod := &Module{
+		Types: []*types.FuncType{{}, ft},
+		Functions: []*Function{
+			{Type: 1, Imported: true},
+			{Type: 0},
+		},
+		Tables:   []*Table{{Type: table}},
+		Memories: []*Memory{{Type: mem}},
+		Globals:  []*Global{{Type: defined}},
+		Tags:     []*Tag{{Type: 1}},
+		Imports: []*Import{
+			{Module: "env", Name: "f", Desc: &ImportDesc{Type: DescTypeFunc, Func: 1}},
+			{Module: "env", Name: "g", Desc: &ImportDesc{Type: DescTypeGlobal, Global: imported}},
+		},
+		Exports: []*Export{
+			{Name: "imported_f", Desc: &ExportDesc{Type: DescTypeFunc, Val: 0}},
+			{Name: "f", Desc: &ExportDesc{Type: DescTypeFunc, Val: 1}},
+			{Name: "table", Desc: &ExportDesc{Type: DescTypeTable, Val: 0}},
+			{Name: "memory", Desc: &ExportDesc{Type: DescTypeMemory, Val: 0}},
+			{Name: "imported_g", Desc: &ExportDesc{Type: DescTypeGlobal, Val: 0}},
+			{Name: "g", Desc: &ExportDesc{Type: DescTypeGlobal, Val: 1}},
+			{Name: "tag", Desc: &ExportDesc{Type: DescTypeTag, Val: 0}},
+		},
+	}
+
+	imports, err := mod.ImportTypes()
+	require.NoError(t, err)
+	assert.Equal(t, []*ImportType{
+		{Module: "env", Name: "f", Type: &ExternType{Kind: DescTypeFunc, Func: ft}},
+		{Module: "env", Name: "g", Type: &ExternType{Kind: DescTypeGlobal, Global: imported}},
+	}, imports)
+
+	exports, err := mod.ExportTypes()
+	require.NoError(t, err)
+	assert.Equal(t, []*ExportType{
+		{Name: "imported_f", Type: &ExternType{Kind: DescTypeFunc, Func: ft}},
+		{Name: "f", Type: &ExternType{Kind: DescTypeFunc, Func: mod.Types[0]}},
+		{Name: "table", Type: &ExternType{Kind: DescTypeTable, Table: table}},
+		{Name: "memory", Type: &ExternType{Kind: DescTypeMemory, Memory: mem}},
+		{Name: "imported_g", Type: &ExternType{Kind: DescTypeGlobal, Global: imported}},
+		{Name: "g", Type: &ExternType{Kind: DescTypeGlobal, Global: defined}},
+		{Name: "tag", Type: &ExternType{Kind: DescTypeTag, Func: ft}},
+	}, exports)
+	for i, exp := range []string{"func (i32) -> (i64)", "func () -> ()", "table funcref min=1", "memory min=1 max=2", "global i32", "global mut f64", "tag (i32)"} {
+		assert.Equal(t, exp, exports[i].Type.String())
+	}
+
+	mod.Exports = []*Export{{Name: "missing", Desc: &ExportDesc{Type: DescTypeMemory, Val: 1}}}
+	_, err = mod.ExportTypes()
+	assert.ErrorIs(t, err, InvalidExportIndex)
+}
@@ -2,6 +2,8 @@ package structure
 
 import (
 	"errors"
+	"sync"
+	"sync/atomic"
 
 	"github.com/terassyi/gowi/instruction"
 	"github.com/terassyi/gowi/types"
@@ -14,6 +16,7 @@ type Module struct {
 	Tables    []*Table
 	Memories  []*Memory
 	Globals   []*Global
+	Tags      []*Tag
 	Elements  []*Element
 	Datas     []*Data
 	Start     *Start
@@ -26,6 +29,44 @@ type Function struct {
 	Locals   []types.ValueType
 	Body     []instruction.Instruction
 	Imported bool
+	lazy     *lazyBody // nil when the body is decoded with the module
+}
+
+// lazyBody decodes the body of a function on first use.
+type lazyBody struct {
+	once   sync.Once
+	decode func() ([]instruction.Instruction, error)
+	err    error
+	done   uint32 // set atomically after decode returns
+}
+
+// NewLazyFunction returns a function whose body is decoded by decode when Instructions is called first.
+func NewLazyFunction(typ uint32, locals []types.ValueType, decode func() ([]instruction.Instruction, error)) *Function {
+	return &Function{Type: typ, Locals: locals, lazy: &lazyBody{decode: decode}}
+}
+
+// Decoded reports whether Body is available without decoding.
+func (f *Function) Decoded() bool {
+	return f.lazy == nil || atomic.LoadUint32(&f.lazy.done) == 1
+}
+
+// Instructions returns the body of the function.
+// The body of a lazy function is decoded on the first call and the result is kept,
+// so that it is safe for concurrent use.
+func (f *Function) Instructions() ([]instruction.Instruction, error) {
+	if f.lazy == nil {
+		return f.Body, nil
+	}
+	f.lazy.once.Do(func() {
+		body, err := f.lazy.decode()
+		if err != nil {
+			f.lazy.err = err
+		} else {
+			f.Body = body
+		}
+		atomic.StoreUint32(&f.lazy.done, 1)
+	})
+	return f.Body, f.lazy.err
 }
 
 type Table struct {
@@ -36,6 +77,12 @@ type Memory struct {
 	Type *types.MemoryType
 }
 
+// https://webassembly.github.io/exception-handling/core/syntax/modules.html#tags
+// The params of the type are the values carried by an exception.
+type Tag struct {
+	Type uint32 // typeidx
+}
+
 type Global struct {
 	Type *types.GlobalType
 	Init instruction.Instruction
@@ -74,6 +121,7 @@ type ImportDesc struct {
 	Table  *types.TableType
 	Mem    *types.MemoryType
 	Global *types.GlobalType
+	Tag    uint32 // typeidx
 }
 
 type Export struct {
@@ -93,6 +141,24 @@ const (
 	DescTypeTable  DescType = 1
 	DescTypeMemory DescType = 2
 	DescTypeGlobal DescType = 3
+	DescTypeTag    DescType = 4
 )
 
 var InvalidDesType error = errors.New("Invalid desc type")
+
+func (d DescType) String() string {
+	switch d {
+	case DescTypeFunc:
+		return "func"
+	case DescTypeTable:
+		return "table"
+	case DescTypeMemory:
+		return "memory"
+	case DescTypeGlobal:
+		return "global"
+	case DescTypeTag:
+		return "tag"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var InvalidFeature error = errors.New("Invalid feature")
+
+// Features is a set of WebAssembly proposals.
+// Only proposals implemented by the decoder and the runtime can be toggled.
+// https://github.com/WebAssembly/proposals/blob/main/finished-proposals.md
+type Features uint32
+
+const (
+	FeatureMultiValue     Features = 1 << iota
+	FeatureReferenceTypes Features = 1 << iota
+	FeatureSIMD           Features = 1 << iota
+	FeatureThreads        Features = 1 << iota
+	FeatureTailCall       Features = 1 << iota
+	FeatureSignExt        Features = 1 << iota
+	FeatureExceptions     Features = 1 << iota
+)
+
+const (
+	FeaturesNone Features = 0
+	FeaturesAll  Features = FeatureMultiValue | FeatureReferenceTypes | FeatureSIMD | FeatureThreads |
+		FeatureTailCall | FeatureSignExt | FeatureExceptions
+)
+
+var featureNames = []struct {
+	feature Features
+	name    string
+}{
+	{feature: FeatureMultiValue, name: "multi-value"},
+	{feature: FeatureReferenceTypes, name: "reference-types"},
+	{feature: FeatureSIMD, name: "simd"},
+	{feature: FeatureThreads, name: "threads"},
+	{feature: FeatureTailCall, name: "tail-call"},
+	{feature: FeatureSignExt, name: "sign-ext"},
+	{feature: FeatureExceptions, name: "exceptions"},
+}
+
+// Has returns true when all of the features f are enabled.
+func (fs Features) Has(f Features) bool {
+	return fs&f == f
+}
+
+// String returns the names of the features separated by commas, or all or none.
+func (fs Features) String() string {
+	if fs == FeaturesAll {
+		return "all"
+	}
+	names := make([]string, 0, len(featureNames))
+	for _, n := range featureNames {
+		if fs.Has(n.feature) {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseFeatures parses features separated by commas from all features.
+// A name prefixed with "-" disables the feature and a name with or without "+" enables it.
+// "none" and "all" disable and enable every feature, so that "none,+simd" enables only SIMD.
+func ParseFeatures(s string) (Features, error) {
+	fs := FeaturesAll
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		switch item {
+		case "":
+			continue
+		case "none":
+			fs = FeaturesNone
+			continue
+		case "all":
+			fs = FeaturesAll
+			continue
+		}
+		disable := strings.HasPrefix(item, "-")
+		name := strings.TrimLeft(item, "+-")
+		f, err := featureByName(name)
+		if err != nil {
+			return 0, err
+		}
+		if disable {
+			fs &^= f
+		} else {
+			fs |= f
+		}
+	}
+	return fs, nil
+}
+
+func featureByName(name string) (Features, error) {
+	for _, n := range featureNames {
+		if n.name == name {
+			return n.feature, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", InvalidFeature, name)
+}
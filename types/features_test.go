@@ -0,0 +1,39 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFeatures(t *testing.T) {
+	for _, d := range []struct {
+		s   string
+		fs  Features
+		str string
+		err bool
+	}{
+		{s: "", fs: FeaturesAll, str: "all"},
+		{s: "all", fs: FeaturesAll, str: "all"},
+		{s: "none", fs: FeaturesNone, str: "none"},
+		{s: "none,+simd,tail-call", fs: FeatureSIMD | FeatureTailCall, str: "simd,tail-call"},
+		{s: "-threads, -exceptions", fs: FeaturesAll &^ (FeatureThreads | FeatureExceptions), str: "multi-value,reference-types,simd,tail-call,sign-ext"},
+		{s: "none,simd,-simd", fs: FeaturesNone, str: "none"},
+		{s: "gc", err: true},
+		// not implemented yet
+		{s: "-bulk-memory", err: true},
+		{s: "none,+memory64", err: true},
+	} {
+		fs, err := ParseFeatures(d.s)
+		if d.err {
+			assert.ErrorIs(t, err, InvalidFeature)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, d.fs, fs, d.s)
+		assert.Equal(t, d.str, fs.String(), d.s)
+	}
+	assert.True(t, (FeatureSIMD | FeatureThreads).Has(FeatureSIMD))
+	assert.False(t, FeatureSIMD.Has(FeatureSIMD|FeatureThreads))
+}
@@ -209,16 +209,29 @@ func NewTableType(buf *bytes.Buffer) (*TableType, error) {
 	}, nil
 }
 
+const (
+	LIMITS_FLAG_MAX    uint8 = 0x01
+	LIMITS_FLAG_SHARED uint8 = 0x02 // threads proposal
+)
+
 type MemoryType struct {
 	Limits *Limits
+	Shared bool
 }
 
 func NewMemoryType(buf *bytes.Buffer) (*MemoryType, error) {
-	l, err := NewLimits(buf)
+	flag, err := buf.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("NewMemoryType: decode flag: %w", err)
+	}
+	if flag&^(LIMITS_FLAG_MAX|LIMITS_FLAG_SHARED) != 0 {
+		return nil, fmt.Errorf("NewMemoryType: %w: flag=0x%x", InvalidLimitsValue, flag)
+	}
+	l, err := decodeLimits(buf, flag&LIMITS_FLAG_MAX != 0)
 	if err != nil {
 		return nil, fmt.Errorf("NewMemoryType: decoder resizable_limits: %w", err)
 	}
-	return &MemoryType{Limits: l}, nil
+	return &MemoryType{Limits: l, Shared: flag&LIMITS_FLAG_SHARED != 0}, nil
 }
 
 type ExternalKind uint8
@@ -228,6 +241,7 @@ const (
 	EXTERNAL_KIND_TABLE    ExternalKind = iota
 	EXTERNAL_KIND_MEMORY   ExternalKind = iota
 	EXTERNAL_KIND_GLOBAL   ExternalKind = iota
+	EXTERNAL_KIND_TAG      ExternalKind = iota
 )
 
 func NewExternalKind(val uint8) (ExternalKind, error) {
@@ -240,6 +254,8 @@ func NewExternalKind(val uint8) (ExternalKind, error) {
 		return EXTERNAL_KIND_MEMORY, nil
 	case 3:
 		return EXTERNAL_KIND_GLOBAL, nil
+	case 4:
+		return EXTERNAL_KIND_TAG, nil
 	default:
 		return 0xff, fmt.Errorf("%w: %x", InvalidExternalKind, val)
 	}
@@ -255,6 +271,8 @@ func (k ExternalKind) String() string {
 		return "memory"
 	case EXTERNAL_KIND_GLOBAL:
 		return "global"
+	case EXTERNAL_KIND_TAG:
+		return "tag"
 	default:
 		return "unknown"
 	}
@@ -296,16 +314,16 @@ type Limits struct {
 }
 
 func NewLimits(buf *bytes.Buffer) (*Limits, error) {
-	limits := &Limits{}
 	b, err := buf.ReadByte()
 	if err != nil {
 		return nil, fmt.Errorf("NewResizableLimits: decode flag: %w", err)
 	}
-	flag := false
-	if b == 1 {
-		flag = true
-	}
-	b, err = buf.ReadByte()
+	return decodeLimits(buf, b == LIMITS_FLAG_MAX)
+}
+
+func decodeLimits(buf *bytes.Buffer, flag bool) (*Limits, error) {
+	limits := &Limits{}
+	b, err := buf.ReadByte()
 	if err != nil {
 		return nil, fmt.Errorf("NewResizableLimits: decode init: %w", err)
 	}
@@ -340,6 +358,19 @@ func (r ResultType) IsEmpty() bool {
 	return false
 }
 
+// Equal reports whether r and o have the same value types in the same order.
+func (r ResultType) Equal(o ResultType) bool {
+	if len(r) != len(o) {
+		return false
+	}
+	for i := range r {
+		if r[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (r ResultType) String() string {
 	str := ""
 	for _, v := range r {
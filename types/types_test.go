@@ -1,6 +1,7 @@
 package types
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,3 +25,26 @@ func TestDecodeFuncType(t *testing.T) {
 		assert.Equal(t, d.f, f)
 	}
 }
+
+func TestNewMemoryType(t *testing.T) {
+	for _, d := range []struct {
+		payload []byte
+		m       *MemoryType
+		err     bool
+	}{
+		{payload: []byte{0x00, 0x01}, m: &MemoryType{Limits: &Limits{Min: 1}}},
+		{payload: []byte{0x01, 0x01, 0x02}, m: &MemoryType{Limits: &Limits{Min: 1, Max: 2}}},
+		{payload: []byte{0x03, 0x01, 0x02}, m: &MemoryType{Limits: &Limits{Min: 1, Max: 2}, Shared: true}},
+		{payload: []byte{0x02, 0x01}, m: &MemoryType{Limits: &Limits{Min: 1}, Shared: true}},
+		{payload: []byte{0x04, 0x01}, err: true},
+		{payload: []byte{0x03, 0x01}, err: true},
+	} {
+		m, err := NewMemoryType(bytes.NewBuffer(d.payload))
+		if d.err {
+			assert.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, d.m, m)
+	}
+}
@@ -13,6 +13,7 @@ type context struct {
 	tables     []*types.TableType
 	memories   []*types.MemoryType
 	globals    []*types.GlobalType
+	tags       []*types.FuncType
 	elements   []types.ReferenceType
 	datas      []bool
 	locals     []types.ValueType
@@ -34,28 +35,53 @@ func newContext(mod *structure.Module) (*context, error) {
 		ctx.functions = make([]*types.FuncType, 0, len(mod.Functions))
 		ctx.locals = make([]types.ValueType, 0)
 		for _, idx := range mod.Functions {
+			if int(idx.Type) >= len(mod.Types) {
+				return nil, fmt.Errorf("function type index is not valid: %d", idx.Type)
+			}
 			ctx.functions = append(ctx.functions, mod.Types[idx.Type])
 			ctx.locals = append(ctx.locals, idx.Locals...)
 		}
 	}
+	// imported tables, memories and globals come first in their index spaces
+	for _, i := range mod.Imports {
+		switch i.Desc.Type {
+		case structure.DescTypeTable:
+			ctx.tables = append(ctx.tables, i.Desc.Table)
+		case structure.DescTypeMemory:
+			ctx.memories = append(ctx.memories, i.Desc.Mem)
+		case structure.DescTypeGlobal:
+			ctx.globals = append(ctx.globals, i.Desc.Global)
+		}
+	}
 	if mod.Tables != nil {
-		ctx.tables = make([]*types.TableType, 0, len(mod.Tables))
 		for _, t := range mod.Tables {
 			ctx.tables = append(ctx.tables, t.Type)
 		}
 	}
 	if mod.Memories != nil {
-		ctx.memories = make([]*types.MemoryType, 0, len(mod.Memories))
 		for _, m := range mod.Memories {
 			ctx.memories = append(ctx.memories, m.Type)
 		}
 	}
 	if mod.Globals != nil {
-		ctx.globals = []*types.GlobalType{}
 		for _, g := range mod.Globals {
 			ctx.globals = append(ctx.globals, g.Type)
 		}
 	}
+	for _, i := range mod.Imports {
+		if i.Desc.Type == structure.DescTypeTag {
+			if int(i.Desc.Tag) >= len(mod.Types) {
+				return nil, fmt.Errorf("tag type index is not valid: %d", i.Desc.Tag)
+			}
+			ctx.tags = append(ctx.tags, mod.Types[i.Desc.Tag])
+		}
+	}
+	for _, t := range mod.Tags {
+		if int(t.Type) >= len(mod.Types) {
+			return nil, fmt.Errorf("tag type index is not valid: %d", t.Type)
+		}
+		ctx.tags = append(ctx.tags, mod.Types[t.Type])
+	}
 	if mod.Elements != nil {
 		ctx.elements = []types.ReferenceType{}
 		for _, e := range mod.Elements {
@@ -75,7 +101,7 @@ func (c *context) reqiureFunc(index uint32) (*types.FuncType, error) {
 	if len(c.functions) == 0 || c.functions == nil {
 		return nil, fmt.Errorf("function section is not exist.")
 	}
-	if int(index) > len(c.functions) {
+	if int(index) >= len(c.functions) {
 		return nil, fmt.Errorf("function section index is not valid: %d", index)
 	}
 	return c.functions[index], nil
@@ -85,7 +111,7 @@ func (c *context) requireTable(index uint32) (*types.TableType, error) {
 	if len(c.tables) == 0 || c.tables == nil {
 		return nil, fmt.Errorf("talbe section is not exist.")
 	}
-	if int(index) > len(c.tables) {
+	if int(index) >= len(c.tables) {
 		return nil, fmt.Errorf("type section index is not valid: %d", index)
 	}
 	return c.tables[index], nil
@@ -95,7 +121,7 @@ func (c *context) requireMemory(index uint32) (*types.MemoryType, error) {
 	if len(c.memories) == 0 || c.memories == nil {
 		return nil, fmt.Errorf("talbe section is not exist.")
 	}
-	if int(index) > len(c.memories) {
+	if int(index) >= len(c.memories) {
 		return nil, fmt.Errorf("type section index is not valid: %d", index)
 	}
 	return c.memories[index], nil
@@ -105,8 +131,15 @@ func (c *context) requireGlobal(index uint32) (*types.GlobalType, error) {
 	if len(c.globals) == 0 || c.globals == nil {
 		return nil, fmt.Errorf("talbe section is not exist.")
 	}
-	if int(index) > len(c.globals) {
+	if int(index) >= len(c.globals) {
 		return nil, fmt.Errorf("type section index is not valid: %d", index)
 	}
 	return c.globals[index], nil
 }
+
+func (c *context) requireTag(index uint32) (*types.FuncType, error) {
+	if int(index) >= len(c.tags) {
+		return nil, fmt.Errorf("tag index is not valid: %d", index)
+	}
+	return c.tags[index], nil
+}
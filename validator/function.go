@@ -1,10 +1,173 @@
 package validator
 
-import "github.com/terassyi/gowi/instruction"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
+)
+
+var (
+	InvalidFunctionIndex  error = errors.New("invalid function index")
+	InvalidTableIndex     error = errors.New("invalid table index")
+	InvalidLocalIndex     error = errors.New("invalid local index")
+	InvalidGlobalIndex    error = errors.New("invalid global index")
+	InvalidTagIndex       error = errors.New("invalid tag index")
+	InvalidLabelIndex     error = errors.New("invalid label index")
+	ImmutableGlobal       error = errors.New("global is immutable")
+	MemoryNotFound        error = errors.New("memory is not defined")
+	UnexpectedInstruction error = errors.New("unexpected instruction")
+	FunctionNotTerminated error = errors.New("function body is not terminated by end")
+)
+
+// label is a block which instructions are in.
+type label struct {
+	op       instruction.Opcode // the opcode starting the block, END for the function itself
+	els      bool               // else of if has appeared
+	catch    bool               // catch or catch_all of try has appeared
+	catchAll bool
+}
 
 type funcValidator struct {
+	ctx      *context // nil when the function is validated without the module
+	features types.Features
+	locals   int // the number of params and locals
+	labels   []*label
+	ended    bool
+}
+
+// validateBody validates every instruction of the function and returns errors without the function index.
+func validateBody(ctx *context, features types.Features, f *structure.Function) []*Error {
+	if f.Imported || !f.Decoded() {
+		return nil
+	}
+	if len(f.Body) == 0 {
+		return []*Error{{Function: -1, Err: NotEmptyFuncBodyExpected}}
+	}
+	v := &funcValidator{
+		ctx:      ctx,
+		features: features,
+		labels:   []*label{{op: instruction.END}},
+	}
+	if ctx != nil {
+		v.locals = len(ctx.types[f.Type].Params) + len(f.Locals)
+	}
+	errs := make([]*Error, 0)
+	for i, instr := range f.Body {
+		if err := v.step(instr); err != nil {
+			errs = append(errs, &Error{Function: -1, Instruction: i, Err: fmt.Errorf("%s: %w", instr, err)})
+		}
+	}
+	if !v.ended {
+		errs = append(errs, &Error{Function: -1, Instruction: len(f.Body) - 1, Err: FunctionNotTerminated})
+	}
+	return errs
 }
 
 func (v *funcValidator) step(instr instruction.Instruction) error {
+	if v.ended {
+		return fmt.Errorf("%w: after the end of the function", UnexpectedInstruction)
+	}
+	// blocks are tracked before the features are checked so that the following instructions are validated in the right blocks
+	if err := v.control(instr); err != nil {
+		return err
+	}
+	if fs := instruction.RequiredFeatures(instr); !v.features.Has(fs) {
+		return fmt.Errorf("%w: %s", FeatureNotEnabled, fs&^v.features)
+	}
+	if v.ctx == nil {
+		return nil
+	}
+	return v.indices(instr)
+}
+
+// control checks that blocks are nested and branches refer to the enclosing blocks.
+func (v *funcValidator) control(instr instruction.Instruction) error {
+	top := v.labels[len(v.labels)-1]
+	switch instr.Opcode() {
+	case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+		v.labels = append(v.labels, &label{op: instr.Opcode()})
+	case instruction.ELSE:
+		if top.op != instruction.IF || top.els {
+			return fmt.Errorf("%w: else without if", UnexpectedInstruction)
+		}
+		top.els = true
+	case instruction.CATCH, instruction.CATCH_ALL:
+		if top.op != instruction.TRY || top.catchAll {
+			return fmt.Errorf("%w: catch without try", UnexpectedInstruction)
+		}
+		top.catch = true
+		top.catchAll = instr.Opcode() == instruction.CATCH_ALL
+	case instruction.DELEGATE:
+		if top.op != instruction.TRY || top.catch {
+			return fmt.Errorf("%w: delegate without try", UnexpectedInstruction)
+		}
+		v.labels = v.labels[:len(v.labels)-1]
+		return v.label(instruction.Imm[uint32](instr))
+	case instruction.END:
+		v.labels = v.labels[:len(v.labels)-1]
+		v.ended = len(v.labels) == 0
+	case instruction.BR, instruction.BR_IF, instruction.RETHROW:
+		// rethrow of a label which is not catching traps in the runtime
+		return v.label(instruction.Imm[uint32](instr))
+	case instruction.BR_TABLE:
+		imm := instruction.Imm[instruction.BrTableImm](instr)
+		for _, l := range append(imm.TargetTable, imm.DefaultTarget) {
+			if err := v.label(l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *funcValidator) label(idx uint32) error {
+	if int(idx) >= len(v.labels) {
+		return fmt.Errorf("%w: %d", InvalidLabelIndex, idx)
+	}
+	return nil
+}
+
+// indices checks that the indices in the immediates exist in the module.
+func (v *funcValidator) indices(instr instruction.Instruction) error {
+	if instruction.UsesMemory(instr) && len(v.ctx.memories) == 0 {
+		return MemoryNotFound
+	}
+	switch instr.Opcode() {
+	case instruction.BLOCK, instruction.LOOP, instruction.IF, instruction.TRY:
+		if bt := instruction.Imm[types.BlockType](instr); types.ValueType(bt) < types.EMPTY && int(bt) >= len(v.ctx.types) {
+			return fmt.Errorf("%w: %d", InvalidTypeIndex, bt)
+		}
+	case instruction.CALL, instruction.RETURN_CALL:
+		if idx := instruction.Imm[uint32](instr); int(idx) >= len(v.ctx.functions) {
+			return fmt.Errorf("%w: %d", InvalidFunctionIndex, idx)
+		}
+	case instruction.CALL_INDIRECT, instruction.RETURN_CALL_INDIRECT:
+		imm := instruction.Imm[instruction.CallIndirectImm](instr)
+		if int(imm.TypeIndex) >= len(v.ctx.types) {
+			return fmt.Errorf("%w: %d", InvalidTypeIndex, imm.TypeIndex)
+		}
+		if int(imm.TableIndex) >= len(v.ctx.tables) {
+			return fmt.Errorf("%w: %d", InvalidTableIndex, imm.TableIndex)
+		}
+	case instruction.GET_LOCAL, instruction.SET_LOCAL, instruction.TEE_LOCAL:
+		if idx := instruction.Imm[uint32](instr); int(idx) >= v.locals {
+			return fmt.Errorf("%w: %d", InvalidLocalIndex, idx)
+		}
+	case instruction.GET_GLOBAL, instruction.SET_GLOBAL:
+		idx := instruction.Imm[uint32](instr)
+		if int(idx) >= len(v.ctx.globals) {
+			return fmt.Errorf("%w: %d", InvalidGlobalIndex, idx)
+		}
+		if instr.Opcode() == instruction.SET_GLOBAL && !v.ctx.globals[idx].Mut {
+			return fmt.Errorf("%w: %d", ImmutableGlobal, idx)
+		}
+	case instruction.THROW, instruction.CATCH:
+		if idx := instruction.Imm[uint32](instr); int(idx) >= len(v.ctx.tags) {
+			return fmt.Errorf("%w: %d", InvalidTagIndex, idx)
+		}
+	}
 	return nil
 }
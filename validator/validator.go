@@ -13,151 +13,215 @@ var (
 	ContentTypeIsNotMatched  error = errors.New("content type is not matched")
 	NotEmptyFuncBodyExpected error = errors.New("empty function body is not expected")
 	TooManyIndexSpace        error = errors.New("too many index space")
+	SharedMemoryWithoutMax   error = errors.New("shared memory must have a maximum")
+	TagTypeWithResults       error = errors.New("tag type must not have results")
+	FeatureNotEnabled        error = errors.New("feature is not enabled")
+	InvalidTypeIndex         error = errors.New("invalid type index")
 )
 
 type Validator struct {
-	mod *structure.Module
-	ctx *context
+	mod      *structure.Module
+	ctx      *context
+	features types.Features
 }
 
+// Error is a validation error with the place where it is found.
+type Error struct {
+	Function    int // the index of the function, -1 when the error is not in a function body
+	Instruction int // the position of the instruction in the body
+	Err         error
+}
+
+func (e *Error) Error() string {
+	if e.Function < 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("func[%d] instruction %d: %s", e.Function, e.Instruction, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates a validator which allows all features.
 func New(mod *structure.Module) (*Validator, error) {
+	return NewWithFeatures(mod, types.FeaturesAll)
+}
+
+// NewWithFeatures creates a validator which rejects the proposals not in the features.
+func NewWithFeatures(mod *structure.Module, features types.Features) (*Validator, error) {
 	ctx, err := newContext(mod)
 	if err != nil {
 		return nil, fmt.Errorf("Validator new: %w", err)
 	}
 	return &Validator{
-		mod: mod,
-		ctx: ctx,
+		mod:      mod,
+		ctx:      ctx,
+		features: features,
 	}, nil
 }
 
+// Validate returns the first error found by ValidateAll.
 func (v *Validator) Validate() (bool, error) {
-	if v.mod.Tables != nil {
-		if len(v.ctx.tables) > 1 {
-			return false, fmt.Errorf("Validate error: %w", TooManyIndexSpace)
-		}
-		for _, t := range v.ctx.tables {
-			if err := validateTable(t); err != nil {
-				return false, fmt.Errorf("Validate error: %w", err)
-			}
-		}
+	if errs := v.ValidateAll(); len(errs) > 0 {
+		return false, fmt.Errorf("Validate error: %w", errs[0])
 	}
-	if v.mod.Memories != nil {
-		if len(v.ctx.memories) > 1 {
-			return false, fmt.Errorf("Validate error: %w", TooManyIndexSpace)
+	return true, nil
+}
+
+// ValidateAll validates the whole module and returns all errors instead of stopping at the first one.
+func (v *Validator) ValidateAll() []*Error {
+	errs := make([]*Error, 0)
+	report := func(err error) {
+		errs = append(errs, &Error{Function: -1, Err: err})
+	}
+	for _, err := range v.validateFeatures() {
+		report(err)
+	}
+	// multiple tables are allowed by reference types
+	if len(v.ctx.tables) > 1 && !v.features.Has(types.FeatureReferenceTypes) {
+		report(fmt.Errorf("%w: tables", TooManyIndexSpace))
+	}
+	for i, t := range v.ctx.tables {
+		if err := validateTable(t); err != nil {
+			report(fmt.Errorf("table[%d]: %w", i, err))
 		}
-		for _, m := range v.ctx.memories {
-			if err := validateMemory(m); err != nil {
-				return false, fmt.Errorf("Validate error: %w", err)
-			}
+	}
+	if len(v.ctx.memories) > 1 {
+		report(fmt.Errorf("%w: memories", TooManyIndexSpace))
+	}
+	for i, m := range v.ctx.memories {
+		if err := validateMemory(m); err != nil {
+			report(fmt.Errorf("memory[%d]: %w", i, err))
 		}
 	}
-	if v.mod.Globals != nil {
-		for _, g := range v.mod.Globals {
-			if err := validateGlobal(g); err != nil {
-				return false, fmt.Errorf("Validate error: %w", err)
-			}
+	for i, g := range v.mod.Globals {
+		if err := validateGlobal(g); err != nil {
+			report(fmt.Errorf("global[%d]: %w", i, err))
 		}
 	}
-	if v.mod.Functions != nil {
-		for _, f := range v.mod.Functions {
-			if err := validateFunction(f); err != nil {
-				return false, fmt.Errorf("Validate error: %w", err)
-			}
+	for i, t := range v.ctx.tags {
+		if !t.Returns.IsEmpty() {
+			report(fmt.Errorf("tag[%d]: %w", i, TagTypeWithResults))
 		}
 	}
+	for i, f := range v.mod.Functions {
+		errs = append(errs, v.validateFunction(i, f)...)
+	}
 	if v.mod.Start != nil {
-		startFunc := v.ctx.functions[v.mod.Start.Index]
-		if !startFunc.Params.IsEmpty() || !startFunc.Returns.IsEmpty() {
-			return false, fmt.Errorf("Validate error: the start function is expected to have type [] -> []")
+		startFunc, err := v.ctx.reqiureFunc(v.mod.Start.Index)
+		if err != nil {
+			report(fmt.Errorf("start: %w", err))
+		} else if !startFunc.Params.IsEmpty() || !startFunc.Returns.IsEmpty() {
+			report(fmt.Errorf("the start function is expected to have type [] -> []"))
 		}
 	}
-	if v.mod.Exports != nil {
-		dup := map[string]bool{}
-		for _, e := range v.mod.Exports {
-			// export name duplication check
-			if _, ok := dup[e.Name]; ok {
-				return false, fmt.Errorf("Validate error: duplicate export %s", e.Name)
+	dup := map[string]bool{}
+	for _, e := range v.mod.Exports {
+		// export name duplication check
+		if _, ok := dup[e.Name]; ok {
+			report(fmt.Errorf("duplicate export %s", e.Name))
+		}
+		dup[e.Name] = true
+		// check export type
+		var err error
+		switch e.Desc.Type {
+		case structure.DescTypeFunc:
+			_, err = v.ctx.reqiureFunc(e.Desc.Val)
+		case structure.DescTypeTable:
+			_, err = v.ctx.requireTable(e.Desc.Val)
+		case structure.DescTypeMemory:
+			_, err = v.ctx.requireMemory(e.Desc.Val)
+		case structure.DescTypeGlobal:
+			_, err = v.ctx.requireGlobal(e.Desc.Val)
+		case structure.DescTypeTag:
+			_, err = v.ctx.requireTag(e.Desc.Val)
+		default:
+			err = fmt.Errorf("invalid export type")
+		}
+		if err != nil {
+			report(fmt.Errorf("export desc %s: %w", e.Name, err))
+		}
+	}
+	for _, i := range v.mod.Imports {
+		var err error
+		switch i.Desc.Type {
+		case structure.DescTypeFunc:
+			if int(i.Desc.Func) >= len(v.ctx.types) {
+				err = fmt.Errorf("%w: %d", InvalidTypeIndex, i.Desc.Func)
 			}
-			dup[e.Name] = true
-			// check export type
-			switch e.Desc.Type {
-			case structure.DescTypeFunc:
-				if _, err := v.ctx.reqiureFunc(e.Desc.Val); err != nil {
-					return false, fmt.Errorf("Validate error: export desc: %w", err)
-				}
-			case structure.DescTypeTable:
-				if _, err := v.ctx.requireTable(e.Desc.Val); err != nil {
-					return false, fmt.Errorf("Validate error: export desc: %w", err)
-				}
-			case structure.DescTypeMemory:
-				if _, err := v.ctx.requireMemory(e.Desc.Val); err != nil {
-					return false, fmt.Errorf("Validate error: export desc: %w", err)
-				}
-			case structure.DescTypeGlobal:
-				if _, err := v.ctx.requireGlobal(e.Desc.Val); err != nil {
-					return false, fmt.Errorf("Validate error: export desc: %w", err)
-				}
-			default:
-				return false, fmt.Errorf("Validate error: invalid export type")
-
+		case structure.DescTypeTable, structure.DescTypeMemory:
+			// validated with the defined ones
+		case structure.DescTypeGlobal:
+			if i.Desc.Global == nil {
+				err = fmt.Errorf("global param is nil")
 			}
+		case structure.DescTypeTag:
+			// the type is resolved in the context
+		default:
+			err = fmt.Errorf("invalid import type")
+		}
+		if err != nil {
+			report(fmt.Errorf("import desc %s.%s: %w", i.Module, i.Name, err))
 		}
 	}
-	if v.mod.Imports != nil {
-		for _, i := range v.mod.Imports {
-			switch i.Desc.Type {
-			case structure.DescTypeFunc:
-				if _, err := v.ctx.reqiureFunc(i.Desc.Func); err != nil {
-					return false, fmt.Errorf("Validate error: import desc: %w", err)
-				}
-			case structure.DescTypeTable:
-				if err := validateTable(i.Desc.Table); err != nil {
-					return false, fmt.Errorf("Validate error: import desc: %w", err)
-				}
-			case structure.DescTypeMemory:
-				if err := validateMemory(i.Desc.Mem); err != nil {
-					return false, fmt.Errorf("Validate error: import desc: %w", err)
-				}
-			case structure.DescTypeGlobal:
-				if i.Desc.Global == nil {
-					return false, fmt.Errorf("Validate error: import desc: global param is nil")
-				}
-			default:
-				return false, fmt.Errorf("Validate error: invalid import type")
-			}
+	for i, d := range v.mod.Datas {
+		if _, err := v.ctx.requireMemory(d.MemoryIndex); err != nil {
+			report(fmt.Errorf("data[%d]: %w", i, err))
+		}
+		if err := validateOffset(d.Offset); err != nil {
+			report(fmt.Errorf("data[%d]: %w", i, err))
 		}
 	}
-	if v.mod.Datas != nil {
-		for _, d := range v.mod.Datas {
-			if _, err := v.ctx.requireMemory(d.MemoryIndex); err != nil {
-				return false, fmt.Errorf("Validate error: %w", err)
-			}
-			typ, err := instruction.GetConstType(d.Offset)
-			if err != nil {
-				return false, fmt.Errorf("Validate error: %w", err)
-			}
-			if typ != types.I32 {
-				return false, fmt.Errorf("Validate error: global init instruction is not i32.const: given %s", typ)
+	for i, e := range v.mod.Elements {
+		if _, err := v.ctx.requireTable(e.TableIndex); err != nil {
+			report(fmt.Errorf("element[%d]: %w", i, err))
+		}
+		if err := validateOffset(e.Offset); err != nil {
+			report(fmt.Errorf("element[%d]: %w", i, err))
+		}
+		for _, f := range e.Init {
+			if _, err := v.ctx.reqiureFunc(f); err != nil {
+				report(fmt.Errorf("element[%d]: %w", i, err))
 			}
 		}
 	}
-	if v.mod.Elements != nil {
-		for _, e := range v.mod.Elements {
-			if _, err := v.ctx.requireTable(e.TableIndex); err != nil {
-				return false, fmt.Errorf("Validate error: %w", err)
-			}
-			typ, err := instruction.GetConstType(e.Offset)
-			if err != nil {
-				return false, fmt.Errorf("Validate error: %w", err)
-			}
-			if typ != types.I32 {
-				return false, fmt.Errorf("Validate error: global init instruction is not i32.const: given %s", typ)
-			}
+	return errs
+}
+
+// validateFeatures returns errors for the types, tables, memories and tags which need disabled features.
+func (v *Validator) validateFeatures() []error {
+	errs := make([]error, 0)
+	for i, t := range v.mod.Types {
+		if len(t.Returns) > 1 && !v.features.Has(types.FeatureMultiValue) {
+			errs = append(errs, fmt.Errorf("type[%d]: %w: %s", i, FeatureNotEnabled, types.FeatureMultiValue))
 		}
 	}
+	for i, t := range v.ctx.tables {
+		if t.ElementType == types.ElemTypeExternref && !v.features.Has(types.FeatureReferenceTypes) {
+			errs = append(errs, fmt.Errorf("table[%d]: %w: %s", i, FeatureNotEnabled, types.FeatureReferenceTypes))
+		}
+	}
+	for i, m := range v.ctx.memories {
+		if m.Shared && !v.features.Has(types.FeatureThreads) {
+			errs = append(errs, fmt.Errorf("memory[%d]: %w: %s", i, FeatureNotEnabled, types.FeatureThreads))
+		}
+	}
+	if len(v.ctx.tags) > 0 && !v.features.Has(types.FeatureExceptions) {
+		errs = append(errs, fmt.Errorf("tag: %w: %s", FeatureNotEnabled, types.FeatureExceptions))
+	}
+	return errs
+}
 
-	return true, nil
+func validateOffset(offset instruction.Instruction) error {
+	typ, err := instruction.GetConstType(offset)
+	if err != nil {
+		return err
+	}
+	if typ != types.I32 {
+		return fmt.Errorf("offset instruction is not i32.const: given %s", typ)
+	}
+	return nil
 }
 
 func validateTable(tableType *types.TableType) error {
@@ -165,6 +229,9 @@ func validateTable(tableType *types.TableType) error {
 }
 
 func validateMemory(memType *types.MemoryType) error {
+	if memType.Shared && memType.Limits.Max == 0 {
+		return SharedMemoryWithoutMax
+	}
 	return memType.Limits.Validate()
 }
 
@@ -179,18 +246,23 @@ func validateGlobal(global *structure.Global) error {
 	return nil
 }
 
-func validateFunction(f *structure.Function) error {
-	if f.Imported {
-		return nil
+// ValidateFunction decodes the body of the lazy function and validates it with the features.
+// Validate skips function bodies which are not decoded yet.
+// The indices in the body are not checked because the function is validated without the module.
+func ValidateFunction(f *structure.Function, features types.Features) error {
+	if _, err := f.Instructions(); err != nil {
+		return fmt.Errorf("ValidateFunction: %w", err)
 	}
-	if f.Body == nil || len(f.Body) == 0 {
-		return fmt.Errorf("validateFunction: %w", NotEmptyFuncBodyExpected)
-	}
-	v := &funcValidator{}
-	for i, instr := range f.Body {
-		if err := v.step(instr); err != nil {
-			return fmt.Errorf("validateaFunction: validation error at %d th instruction in function(%d)", i, f.Type)
-		}
+	if errs := validateBody(nil, features, f); len(errs) > 0 {
+		return fmt.Errorf("ValidateFunction: instruction %d: %w", errs[0].Instruction, errs[0].Err)
 	}
 	return nil
 }
+
+func (v *Validator) validateFunction(idx int, f *structure.Function) []*Error {
+	errs := validateBody(v.ctx, v.features, f)
+	for _, err := range errs {
+		err.Function = idx
+	}
+	return errs
+}
@@ -1,11 +1,17 @@
 package validator
 
 import (
+	"errors"
+	"io"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/terassyi/gowi/decoder"
+	"github.com/terassyi/gowi/instruction"
+	"github.com/terassyi/gowi/structure"
+	"github.com/terassyi/gowi/types"
 )
 
 func TestValidate(t *testing.T) {
@@ -24,9 +30,12 @@ func TestValidate(t *testing.T) {
 		{path: "../examples/mem0.wasm", res: true},
 		{path: "../examples/table.wasm", res: true},
 		{path: "../examples/start0.wasm", res: true},
-		// {path: "../examples/shared0.wasm", res: true},
-		// {path: "../examples/shared1.wasm", res: true},
+		{path: "../examples/shared0.wasm", res: true},
+		{path: "../examples/shared1.wasm", res: true},
 		{path: "../examples/import_js.wasm", res: true},
+		{path: "../examples/exception.wasm", res: true},
+		{path: "../examples/tail_call.wasm", res: true},
+		{path: "../examples/sign_extension.wasm", res: true},
 		// I should prepare invalid wasm file to pass test cases
 		// {path: "../examples/invalid_table.wasm", res: false},
 	} {
@@ -41,3 +50,132 @@ func TestValidate(t *testing.T) {
 		assert.Equal(t, d.res, res)
 	}
 }
+
+func TestValidate_Stream(t *testing.T) {
+	file, err := os.Open("../examples/tail_call.wasm")
+	require.NoError(t, err)
+	defer file.Close()
+	s, err := decoder.NewStream(file, decoder.ModeLazy)
+	require.NoError(t, err)
+	// the sections decoded so far are validated before the rest arrives
+	for {
+		_, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		mod, err := s.Module()
+		require.NoError(t, err)
+		v, err := New(mod)
+		require.NoError(t, err)
+		res, err := v.Validate()
+		require.NoError(t, err)
+		assert.True(t, res)
+	}
+	mod, err := s.Module()
+	require.NoError(t, err)
+	for _, f := range mod.Functions {
+		assert.NoError(t, ValidateFunction(f, types.FeaturesAll))
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	mod := &structure.Module{
+		Types:   []*types.FuncType{{Params: []types.ValueType{types.I32}, Returns: []types.ValueType{}}},
+		Globals: []*structure.Global{{Type: &types.GlobalType{ContentType: types.I32}, Init: &instruction.I32Const{Imm: 0}}},
+		Functions: []*structure.Function{{
+			Type: 0,
+			Body: []instruction.Instruction{
+				&instruction.Call{Imm: 3},
+				&instruction.GetLocal{Imm: 1},
+				&instruction.SetGlobal{Imm: 0},
+				&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)},
+				&instruction.Br{Imm: 2},
+				&instruction.Else{},
+				&instruction.End{},
+				&instruction.I32Load{},
+				&instruction.I32Extend8S{},
+				&instruction.End{},
+			},
+		}},
+		Exports: []*structure.Export{
+			{Name: "f", Desc: &structure.ExportDesc{Type: structure.DescTypeFunc, Val: 0}},
+			{Name: "f", Desc: &structure.ExportDesc{Type: structure.DescTypeFunc, Val: 1}},
+		},
+	}
+	v, err := NewWithFeatures(mod, types.FeaturesAll&^types.FeatureSignExt)
+	require.NoError(t, err)
+	errs := v.ValidateAll()
+	for i, d := range []struct {
+		function    int
+		instruction int
+		err         error
+	}{
+		{function: 0, instruction: 0, err: InvalidFunctionIndex},
+		{function: 0, instruction: 1, err: InvalidLocalIndex},
+		{function: 0, instruction: 2, err: ImmutableGlobal},
+		{function: 0, instruction: 4, err: InvalidLabelIndex},
+		{function: 0, instruction: 5, err: UnexpectedInstruction},
+		{function: 0, instruction: 7, err: MemoryNotFound},
+		{function: 0, instruction: 8, err: FeatureNotEnabled},
+		{function: -1, instruction: 0, err: nil},
+		{function: -1, instruction: 0, err: nil},
+	} {
+		require.Greater(t, len(errs), i)
+		assert.Equal(t, d.function, errs[i].Function, errs[i])
+		assert.Equal(t, d.instruction, errs[i].Instruction, errs[i])
+		if d.err != nil {
+			assert.ErrorIs(t, errs[i], d.err)
+		}
+	}
+	assert.Len(t, errs, 9)
+	assert.Contains(t, errs[7].Error(), "duplicate export f")
+
+	res, err := v.Validate()
+	assert.False(t, res)
+	assert.ErrorIs(t, err, InvalidFunctionIndex)
+
+	// the function is not terminated
+	mod.Functions[0].Body = []instruction.Instruction{&instruction.Block{Imm: types.BlockType(types.BLOCKTYPE)}, &instruction.End{}}
+	mod.Exports = nil
+	v, err = New(mod)
+	require.NoError(t, err)
+	errs = v.ValidateAll()
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], FunctionNotTerminated)
+}
+
+func TestValidateAll_Features(t *testing.T) {
+	for _, d := range []struct {
+		path     string
+		features string
+		err      bool
+	}{
+		{path: "../examples/tail_call.wasm", features: "all"},
+		{path: "../examples/tail_call.wasm", features: "-tail-call", err: true},
+		{path: "../examples/sign_extension.wasm", features: "none", err: true},
+		{path: "../examples/sign_extension.wasm", features: "none,+sign-ext"},
+		{path: "../examples/exception.wasm", features: "-exceptions", err: true},
+		{path: "../examples/simd.wasm", features: "-simd", err: true},
+		{path: "../examples/atomic.wasm", features: "-threads", err: true},
+		{path: "../examples/loop.wasm", features: "-multi-value", err: true},
+	} {
+		features, err := types.ParseFeatures(d.features)
+		require.NoError(t, err)
+		dec, err := decoder.New(d.path)
+		require.NoError(t, err)
+		mod, err := dec.Decode()
+		require.NoError(t, err)
+		v, err := NewWithFeatures(mod, features)
+		require.NoError(t, err)
+		errs := v.ValidateAll()
+		if d.err {
+			require.NotEmpty(t, errs, d.path)
+			for _, e := range errs {
+				assert.ErrorIs(t, e, FeatureNotEnabled, d.path)
+			}
+		} else {
+			assert.Empty(t, errs, d.path)
+		}
+	}
+}